@@ -0,0 +1,107 @@
+// Package activityimport reads runs, rides and other cardio activities
+// from device exports: GPX, Garmin TCX and FIT files. Files are decoded
+// point by point, so only the summary, splits and heart rate series of an
+// activity are held in memory.
+package activityimport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Supported formats.
+const (
+	GPX = "gpx"
+	TCX = "tcx"
+	FIT = "fit"
+)
+
+// Sports an activity can be filed under.
+const (
+	Running  = "running"
+	Cycling  = "cycling"
+	Walking  = "walking"
+	Hiking   = "hiking"
+	Swimming = "swimming"
+	Rowing   = "rowing"
+	Other    = "other"
+)
+
+var sportAliases = map[string]string{
+	"run": Running, "running": Running, "trail_running": Running, "treadmill_running": Running,
+	"ride": Cycling, "biking": Cycling, "bike": Cycling, "cycling": Cycling, "road_biking": Cycling, "mountain_biking": Cycling,
+	"walk": Walking, "walking": Walking,
+	"hike": Hiking, "hiking": Hiking,
+	"swim": Swimming, "swimming": Swimming, "open_water_swimming": Swimming,
+	"row": Rowing, "rowing": Rowing,
+}
+
+// ParseSport maps a device's activity type to one of the known sports.
+func ParseSport(s string) string {
+	k := strings.ToLower(strings.TrimSpace(s))
+	k = strings.NewReplacer(" ", "_", "-", "_").Replace(k)
+	if sport, ok := sportAliases[k]; ok {
+		return sport
+	}
+	return Other
+}
+
+// Result is one activity read from the input. Error is set when it could
+// not be turned into a session; DuplicateOf names an already stored session
+// of the same activity.
+type Result struct {
+	Session     models.CardioSession `json:"session"`
+	Error       string               `json:"error,omitempty"`
+	DuplicateOf string               `json:"duplicate_of,omitempty"`
+}
+
+// Detect guesses the format of a file from its first bytes. FIT files
+// carry ".FIT" in their header; XML files are told apart by their root
+// element.
+func Detect(head []byte) string {
+	if len(head) >= 12 && string(head[8:12]) == ".FIT" {
+		return FIT
+	}
+	switch {
+	case bytes.Contains(head, []byte("<TrainingCenterDatabase")):
+		return TCX
+	case bytes.Contains(head, []byte("<gpx")):
+		return GPX
+	}
+	return ""
+}
+
+// Parse reads every activity in r. When format is empty it is detected
+// from the start of the input. An error means the input as a whole could
+// not be read.
+func Parse(format string, r io.Reader) (string, []Result, error) {
+	br := bufio.NewReaderSize(r, 4096)
+	if format == "" {
+		head, _ := br.Peek(1024)
+		if format = Detect(head); format == "" {
+			return "", nil, fmt.Errorf("unrecognised activity file; expected GPX, TCX or FIT")
+		}
+	}
+
+	var results []Result
+	var err error
+	switch format {
+	case GPX:
+		results, err = ParseGPX(br)
+	case TCX:
+		results, err = ParseTCX(br)
+	case FIT:
+		results, err = ParseFIT(br)
+	default:
+		return format, nil, fmt.Errorf("unknown format %q", format)
+	}
+	for i := range results {
+		results[i].Session.Source = format
+	}
+	return format, results, err
+}
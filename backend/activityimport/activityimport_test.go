@@ -0,0 +1,223 @@
+package activityimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gpxTrack builds a run along the equator: one point every 30 s, 0.001°
+// (111.2 m) apart, with a two-minute stop after the tenth point.
+func gpxTrack() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+<metadata><name>ignored</name></metadata>
+<trk><name>Morning Run</name><type>running</type><trkseg>
+`)
+	start := time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC)
+	at := start
+	for i := 0; i < 25; i++ {
+		if i == 10 {
+			// Standing at the same spot.
+			at = at.Add(90 * time.Second)
+			fmt.Fprintf(&b, `<trkpt lat="0" lon="%.3f"><time>%s</time></trkpt>
+`, float64(i-1)*0.001, at.Format(time.RFC3339))
+			at = at.Add(30 * time.Second)
+		}
+		ele := []int{10, 11, 12, 16, 15, 20}[i%6]
+		fmt.Fprintf(&b, `<trkpt lat="0" lon="%.3f"><ele>%d</ele><time>%s</time>
+<extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>%d</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+`, float64(i)*0.001, ele, at.Format(time.RFC3339), 140+i)
+		at = at.Add(30 * time.Second)
+	}
+	b.WriteString("</trkseg></trk></gpx>\n")
+	return b.String()
+}
+
+func TestParseGPX(t *testing.T) {
+	data := gpxTrack()
+	format, results, err := Parse("", strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, GPX, format)
+	require.Len(t, results, 1)
+
+	s := results[0].Session
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "Morning Run", s.Name)
+	assert.Equal(t, Running, s.Sport)
+	assert.Equal(t, GPX, s.Source)
+	assert.Equal(t, time.Date(2026, 10, 18, 6, 0, 0, 0, time.UTC), s.StartedAt)
+	assert.InDelta(t, 2668.7, s.DistanceMeters, 0.5)
+	// 24 legs of 30 s plus the stop, which is not moving time.
+	assert.Equal(t, 840.0, s.DurationSeconds)
+	assert.Equal(t, 720.0, s.MovingSeconds)
+	assert.InDelta(t, 269.8, s.PaceSecondsPerKm, 0.2)
+	assert.InDelta(t, 13.3, s.SpeedKmh, 0.1)
+	// Each cycle climbs 10 -> 16 and 15 -> 20; the 1 m steps are noise.
+	assert.Equal(t, 44.0, s.ElevationGain)
+	assert.Equal(t, 152, s.AvgHeartRate)
+	assert.Equal(t, 164, s.MaxHeartRate)
+	assert.Len(t, s.HeartRate, 25)
+	assert.Equal(t, 570, s.HeartRate[15].Offset)
+
+	require.Len(t, s.Splits, 3)
+	assert.Equal(t, 1000.0, s.Splits[0].DistanceMeters)
+	assert.InDelta(t, 269.8, s.Splits[0].DurationSeconds, 0.2)
+	assert.InDelta(t, 668.7, s.Splits[2].DistanceMeters, 0.5)
+	assert.InDelta(t, 720.0, s.Splits[0].DurationSeconds+s.Splits[1].DurationSeconds+s.Splits[2].DurationSeconds, 0.2)
+	assert.Equal(t, 144, s.Splits[0].AvgHeartRate)
+}
+
+func TestParseGPXErrors(t *testing.T) {
+	_, _, err := Parse("", strings.NewReader("hello"))
+	assert.Error(t, err)
+
+	_, _, err = Parse(GPX, strings.NewReader(`<gpx><wpt lat="1" lon="2"/></gpx>`))
+	assert.Error(t, err)
+
+	_, results, err := Parse(GPX, strings.NewReader(`<gpx><trk><trkseg><trkpt lat="0" lon="0"/><trkpt lat="0" lon="0.001"/></trkseg></trk></gpx>`))
+	require.NoError(t, err)
+	assert.Equal(t, "activity has no timestamps", results[0].Error)
+}
+
+const tcxActivity = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+ <Activities>
+  <Activity Sport="Biking">
+   <Id>2026-10-18T15:00:00Z</Id>
+   <Lap StartTime="2026-10-18T15:00:00Z">
+    <Track>
+     <Trackpoint><Time>2026-10-18T15:00:00Z</Time><AltitudeMeters>100</AltitudeMeters><DistanceMeters>0</DistanceMeters><HeartRateBpm><Value>120</Value></HeartRateBpm></Trackpoint>
+     <Trackpoint><Time>2026-10-18T15:01:00Z</Time><AltitudeMeters>110</AltitudeMeters><DistanceMeters>500</DistanceMeters><HeartRateBpm><Value>130</Value></HeartRateBpm></Trackpoint>
+    </Track>
+   </Lap>
+   <Lap StartTime="2026-10-18T15:01:00Z">
+    <Track>
+     <Trackpoint><Time>2026-10-18T15:03:00Z</Time><AltitudeMeters>105</AltitudeMeters><DistanceMeters>1500</DistanceMeters><HeartRateBpm><Value>150</Value></HeartRateBpm></Trackpoint>
+    </Track>
+   </Lap>
+   <Notes>Hill loop</Notes>
+  </Activity>
+ </Activities>
+</TrainingCenterDatabase>`
+
+func TestParseTCX(t *testing.T) {
+	format, results, err := Parse("", strings.NewReader(tcxActivity))
+	require.NoError(t, err)
+	assert.Equal(t, TCX, format)
+	require.Len(t, results, 1)
+
+	s := results[0].Session
+	assert.Equal(t, Cycling, s.Sport)
+	assert.Equal(t, "Hill loop", s.Name)
+	assert.Equal(t, 1500.0, s.DistanceMeters)
+	assert.Equal(t, 180.0, s.MovingSeconds)
+	assert.Equal(t, 10.0, s.ElevationGain)
+	assert.Equal(t, 30.0, s.SpeedKmh)
+	assert.Equal(t, 133, s.AvgHeartRate)
+	require.Len(t, s.Splits, 2)
+	// 500 m in the first minute, then 500 m of the 1000 m in two minutes.
+	assert.Equal(t, 120.0, s.Splits[0].DurationSeconds)
+	assert.Equal(t, 500.0, s.Splits[1].DistanceMeters)
+	assert.Equal(t, 60.0, s.Splits[1].DurationSeconds)
+}
+
+// fitFile writes a minimal FIT activity: record messages with timestamp,
+// position, altitude, heart rate and distance, one of them using a
+// compressed timestamp header, and a cycling session.
+func fitFile() []byte {
+	var body bytes.Buffer
+	le := binary.LittleEndian
+	// Definition: local 0 = record.
+	body.Write([]byte{0x40, 0, 0})
+	binary.Write(&body, le, uint16(fitMesgRecord))
+	body.Write([]byte{6,
+		fitTimestamp, 4, 0x86,
+		fitRecordLat, 4, 0x85,
+		fitRecordLon, 4, 0x85,
+		fitRecordAltitude, 2, 0x84,
+		fitRecordHeartRate, 1, 0x02,
+		fitRecordDistance, 4, 0x86,
+	})
+	base := uint32(time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC).Sub(fitEpoch).Seconds())
+	record := func(header byte, ts uint32, withTS bool, dist float64, alt float64, hr byte) {
+		body.WriteByte(header)
+		if withTS {
+			binary.Write(&body, le, ts)
+		}
+		binary.Write(&body, le, int32(0))
+		binary.Write(&body, le, int32(0))
+		binary.Write(&body, le, uint16((alt+500)*5))
+		body.WriteByte(hr)
+		binary.Write(&body, le, uint32(dist*100))
+	}
+	record(0x00, base, true, 0, 50, 110)
+	record(0x00, base+300, true, 1200, 60, 150)
+	// Definition for the compressed record: local 0 again, without the
+	// timestamp field.
+	body.Write([]byte{0x40, 0, 0})
+	binary.Write(&body, le, uint16(fitMesgRecord))
+	body.Write([]byte{5,
+		fitRecordLat, 4, 0x85,
+		fitRecordLon, 4, 0x85,
+		fitRecordAltitude, 2, 0x84,
+		fitRecordHeartRate, 1, 0x02,
+		fitRecordDistance, 4, 0x86,
+	})
+	// Offset 30 s after base+300: 0x80 | local 0 | (base+330)&0x1f.
+	record(0x80|byte((base+330)&0x1f), 0, false, 1300, 60, 0xff)
+	// Definition: local 1 = session, with the sport only.
+	body.Write([]byte{0x41, 0, 0})
+	binary.Write(&body, le, uint16(fitMesgSession))
+	body.Write([]byte{1, fitSessionSport, 1, 0x00})
+	body.Write([]byte{0x01, 2})
+
+	var f bytes.Buffer
+	f.Write([]byte{14, 0x10})
+	binary.Write(&f, le, uint16(2132))
+	binary.Write(&f, le, uint32(body.Len()))
+	f.WriteString(".FIT")
+	f.Write([]byte{0, 0})
+	f.Write(body.Bytes())
+	f.Write([]byte{0, 0}) // file CRC, not checked
+	return f.Bytes()
+}
+
+func TestParseFIT(t *testing.T) {
+	data := fitFile()
+	assert.Equal(t, FIT, Detect(data))
+
+	format, results, err := Parse("", bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, FIT, format)
+	require.Len(t, results, 1)
+
+	s := results[0].Session
+	assert.Equal(t, Cycling, s.Sport)
+	assert.Equal(t, time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC), s.StartedAt)
+	assert.Equal(t, 330.0, s.DurationSeconds)
+	assert.Equal(t, 1300.0, s.DistanceMeters)
+	assert.Equal(t, 10.0, s.ElevationGain)
+	// The invalid 0xff reading of the last record is no heart rate.
+	assert.Equal(t, 130, s.AvgHeartRate)
+	assert.Equal(t, 150, s.MaxHeartRate)
+	require.Len(t, s.Splits, 2)
+	assert.Equal(t, 250.0, s.Splits[0].DurationSeconds)
+
+	_, _, err = Parse(FIT, bytes.NewReader(data[:40]))
+	assert.Error(t, err)
+}
+
+func TestParseSport(t *testing.T) {
+	assert.Equal(t, Running, ParseSport("Run"))
+	assert.Equal(t, Cycling, ParseSport("Mountain Biking"))
+	assert.Equal(t, Other, ParseSport("9"))
+}
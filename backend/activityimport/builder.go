@@ -0,0 +1,210 @@
+package activityimport
+
+import (
+	"math"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// point is one sample of an activity. Distance, when the device recorded
+// it, is the cumulative distance in metres and wins over the one computed
+// from positions.
+type point struct {
+	Time         time.Time
+	Lat, Lon     float64
+	HasPosition  bool
+	Elevation    float64
+	HasElevation bool
+	Distance     float64
+	HasDistance  bool
+	HeartRate    int
+}
+
+const (
+	earthRadius = 6371008.8
+	splitLength = 1000.0
+	// Segments slower than this are treated as standing still.
+	minMovingSpeed = 0.5
+	// Climbs are only counted once the elevation rose this much, so GPS
+	// noise on flat ground adds nothing.
+	elevationThreshold = 3.0
+	// A trailing split shorter than this is dropped.
+	minSplitLength = 10.0
+)
+
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+type splitAcc struct {
+	moving  float64
+	gain    float64
+	hrSum   int
+	hrCount int
+}
+
+// builder turns a stream of points into a session summary without keeping
+// the points.
+type builder struct {
+	session models.CardioSession
+	points  int
+	last    *point
+	// elevRef is the low point the current climb is measured from.
+	elevRef  *float64
+	split    splitAcc
+	hrSum    int
+	hrCount  int
+	lastTime time.Time
+}
+
+func newBuilder() *builder {
+	return &builder{session: models.CardioSession{Splits: []models.Split{}}}
+}
+
+// breakSegment starts a new track segment: the gap to the next point is
+// neither distance nor moving time.
+func (b *builder) breakSegment() {
+	b.last = nil
+}
+
+func (b *builder) add(p point) {
+	s := &b.session
+	b.points++
+	if !p.Time.IsZero() {
+		if s.StartedAt.IsZero() || p.Time.Before(s.StartedAt) {
+			s.StartedAt = p.Time
+		}
+		if p.Time.After(b.lastTime) {
+			b.lastTime = p.Time
+		}
+	}
+
+	// Distance first, so a reading lands in the split its point ends up in.
+	if last := b.last; last != nil {
+		var d float64
+		switch {
+		case p.HasDistance && last.HasDistance:
+			d = math.Max(0, p.Distance-last.Distance)
+		case p.HasPosition && last.HasPosition:
+			d = haversine(last.Lat, last.Lon, p.Lat, p.Lon)
+		}
+		var moving float64
+		if !p.Time.IsZero() && !last.Time.IsZero() {
+			if dt := p.Time.Sub(last.Time).Seconds(); dt > 0 && d/dt >= minMovingSpeed {
+				moving = dt
+			}
+		}
+		b.advance(d, moving)
+	}
+	if p.HeartRate > 0 {
+		b.hrSum += p.HeartRate
+		b.hrCount++
+		b.split.hrSum += p.HeartRate
+		b.split.hrCount++
+		if p.HeartRate > s.MaxHeartRate {
+			s.MaxHeartRate = p.HeartRate
+		}
+		if !p.Time.IsZero() {
+			s.HeartRate = append(s.HeartRate, models.HeartRateSample{Offset: int(p.Time.Sub(s.StartedAt).Seconds()), BPM: p.HeartRate})
+		}
+	}
+
+	if p.HasElevation {
+		switch {
+		case b.elevRef == nil || p.Elevation < *b.elevRef:
+			e := p.Elevation
+			b.elevRef = &e
+		case p.Elevation-*b.elevRef >= elevationThreshold:
+			gain := p.Elevation - *b.elevRef
+			s.ElevationGain += gain
+			b.split.gain += gain
+			e := p.Elevation
+			b.elevRef = &e
+		}
+	}
+
+	b.last = &p
+}
+
+// advance adds a stretch of d metres covered in moving seconds, closing
+// every split boundary it crosses. Time is shared out in proportion to
+// distance.
+func (b *builder) advance(d, moving float64) {
+	s := &b.session
+	for d > 0 {
+		remaining := splitLength*float64(len(s.Splits)+1) - s.DistanceMeters
+		if d < remaining {
+			break
+		}
+		share := moving * remaining / d
+		s.DistanceMeters += remaining
+		s.MovingSeconds += share
+		b.split.moving += share
+		b.closeSplit(splitLength)
+		d -= remaining
+		moving -= share
+	}
+	s.DistanceMeters += d
+	s.MovingSeconds += moving
+	b.split.moving += moving
+}
+
+func (b *builder) closeSplit(distance float64) {
+	split := models.Split{
+		Index:           len(b.session.Splits) + 1,
+		DistanceMeters:  round1(distance),
+		DurationSeconds: round1(b.split.moving),
+		ElevationGain:   round1(b.split.gain),
+	}
+	split.PaceSecondsPerKm, split.SpeedKmh = paceAndSpeed(distance, b.split.moving)
+	if b.split.hrCount > 0 {
+		split.AvgHeartRate = int(math.Round(float64(b.split.hrSum) / float64(b.split.hrCount)))
+	}
+	b.session.Splits = append(b.session.Splits, split)
+	b.split = splitAcc{}
+}
+
+func paceAndSpeed(distance, seconds float64) (float64, float64) {
+	if distance <= 0 || seconds <= 0 {
+		return 0, 0
+	}
+	return round1(seconds / distance * 1000), round1(distance / seconds * 3.6)
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// result finishes the session. Activities without timestamps cannot be
+// placed in time and are reported as errors.
+func (b *builder) result() Result {
+	s := b.session
+	if partial := s.DistanceMeters - splitLength*float64(len(s.Splits)); partial >= minSplitLength {
+		b.closeSplit(partial)
+		s.Splits = b.session.Splits
+	}
+	if s.Sport == "" {
+		s.Sport = Other
+	}
+	switch {
+	case b.points == 0:
+		return Result{Session: s, Error: "activity has no track points"}
+	case s.StartedAt.IsZero():
+		return Result{Session: s, Error: "activity has no timestamps"}
+	}
+
+	s.DurationSeconds = round1(b.lastTime.Sub(s.StartedAt).Seconds())
+	s.MovingSeconds = round1(s.MovingSeconds)
+	s.DistanceMeters = round1(s.DistanceMeters)
+	s.ElevationGain = round1(s.ElevationGain)
+	s.PaceSecondsPerKm, s.SpeedKmh = paceAndSpeed(s.DistanceMeters, s.MovingSeconds)
+	if b.hrCount > 0 {
+		s.AvgHeartRate = int(math.Round(float64(b.hrSum) / float64(b.hrCount)))
+	}
+	return Result{Session: s}
+}
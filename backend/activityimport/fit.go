@@ -0,0 +1,232 @@
+package activityimport
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// FIT global message numbers and fields read by the importer. Everything
+// else in the file is skipped.
+const (
+	fitMesgSession = 18
+	fitMesgRecord  = 20
+
+	fitSessionSport = 5
+
+	fitRecordLat              = 0
+	fitRecordLon              = 1
+	fitRecordAltitude         = 2
+	fitRecordHeartRate        = 3
+	fitRecordDistance         = 5
+	fitRecordEnhancedAltitude = 78
+	fitTimestamp              = 253
+)
+
+// FIT timestamps count seconds from 1989-12-31T00:00:00Z.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+var fitSports = map[uint64]string{
+	1: Running, 2: Cycling, 5: Swimming, 11: Walking, 15: Rowing, 17: Hiking,
+}
+
+type fitField struct {
+	num, size, baseType byte
+}
+
+type fitDefinition struct {
+	global   uint16
+	order    binary.ByteOrder
+	fields   []fitField
+	devBytes int
+}
+
+// Sizes of the integer base types by number.
+var fitSizes = map[byte]int{0x00: 1, 0x01: 1, 0x02: 1, 0x0a: 1, 0x03: 2, 0x04: 2, 0x0b: 2, 0x05: 4, 0x06: 4, 0x0c: 4}
+
+// fitValue decodes an unsigned or signed integer field, reporting false for
+// the type's invalid marker.
+func fitValue(b []byte, baseType byte, order binary.ByteOrder) (uint64, bool) {
+	if len(b) != fitSizes[baseType&0x1f] {
+		// Arrays, strings and floats carry nothing the importer reads.
+		return 0, false
+	}
+	switch baseType & 0x1f {
+	case 0x00, 0x02, 0x0a: // enum, uint8, uint8z
+		return uint64(b[0]), b[0] != 0xff && !(baseType == 0x0a && b[0] == 0)
+	case 0x01: // sint8
+		return uint64(int8(b[0])), b[0] != 0x7f
+	case 0x04, 0x0b: // uint16, uint16z
+		v := order.Uint16(b)
+		return uint64(v), v != 0xffff && !(baseType&0x1f == 0x0b && v == 0)
+	case 0x03: // sint16
+		v := order.Uint16(b)
+		return uint64(int16(v)), v != 0x7fff
+	case 0x06, 0x0c: // uint32, uint32z
+		v := order.Uint32(b)
+		return uint64(v), v != 0xffffffff && !(baseType&0x1f == 0x0c && v == 0)
+	case 0x05: // sint32
+		v := order.Uint32(b)
+		return uint64(int32(v)), v != 0x7fffffff
+	}
+	return 0, false
+}
+
+func semicircles(v uint64) float64 {
+	return float64(int32(v)) * 180 / math.Pow(2, 31)
+}
+
+// ParseFIT reads the activity in a FIT file, streaming through its
+// records. Developer fields and messages other than records and the
+// session's sport are skipped.
+func ParseFIT(r io.Reader) ([]Result, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("invalid FIT header: %w", err)
+	}
+	size := int(header[0])
+	if size < 12 || string(header[8:12]) != ".FIT" {
+		return nil, fmt.Errorf("not a FIT file")
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(size-12)); err != nil {
+		return nil, fmt.Errorf("invalid FIT header: %w", err)
+	}
+	remaining := int64(binary.LittleEndian.Uint32(header[4:8]))
+	r = io.LimitReader(r, remaining)
+
+	defs := map[byte]*fitDefinition{}
+	b := newBuilder()
+	var lastTimestamp uint32
+	buf := make([]byte, 3*255)
+	for {
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("truncated FIT file: %w", err)
+		}
+		h := buf[0]
+
+		var local byte
+		var compressed bool
+		var offset uint32
+		switch {
+		case h&0x80 != 0:
+			// Compressed timestamp header: a data message whose
+			// timestamp is an offset from the previous one.
+			compressed = true
+			local = (h >> 5) & 0x03
+			offset = uint32(h & 0x1f)
+		case h&0x40 != 0:
+			def, err := readFitDefinition(r, h&0x20 != 0, buf)
+			if err != nil {
+				return nil, err
+			}
+			defs[h&0x0f] = def
+			continue
+		default:
+			local = h & 0x0f
+		}
+
+		def := defs[local]
+		if def == nil {
+			return nil, fmt.Errorf("FIT data message without definition")
+		}
+		values := map[byte]uint64{}
+		for _, f := range def.fields {
+			if _, err := io.ReadFull(r, buf[:f.size]); err != nil {
+				return nil, fmt.Errorf("truncated FIT file: %w", err)
+			}
+			if v, ok := fitValue(buf[:f.size], f.baseType, def.order); ok {
+				values[f.num] = v
+			}
+		}
+		if def.devBytes > 0 {
+			if _, err := io.CopyN(io.Discard, r, int64(def.devBytes)); err != nil {
+				return nil, fmt.Errorf("truncated FIT file: %w", err)
+			}
+		}
+
+		if ts, ok := values[fitTimestamp]; ok {
+			lastTimestamp = uint32(ts)
+		} else if compressed {
+			ts := lastTimestamp&^0x1f + offset
+			if offset < lastTimestamp&0x1f {
+				ts += 0x20
+			}
+			lastTimestamp = ts
+			values[fitTimestamp] = uint64(ts)
+		}
+
+		switch def.global {
+		case fitMesgSession:
+			if sport, ok := values[fitSessionSport]; ok {
+				b.session.Sport = fitSports[sport]
+			}
+		case fitMesgRecord:
+			b.add(fitPoint(values))
+		}
+	}
+
+	if b.points == 0 {
+		return nil, fmt.Errorf("FIT file has no records")
+	}
+	return []Result{b.result()}, nil
+}
+
+func readFitDefinition(r io.Reader, developer bool, buf []byte) (*fitDefinition, error) {
+	if _, err := io.ReadFull(r, buf[:5]); err != nil {
+		return nil, fmt.Errorf("truncated FIT definition: %w", err)
+	}
+	def := &fitDefinition{order: binary.LittleEndian}
+	if buf[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(buf[2:4])
+	n := int(buf[4])
+	if _, err := io.ReadFull(r, buf[:3*n]); err != nil {
+		return nil, fmt.Errorf("truncated FIT definition: %w", err)
+	}
+	for i := 0; i < n; i++ {
+		def.fields = append(def.fields, fitField{num: buf[3*i], size: buf[3*i+1], baseType: buf[3*i+2]})
+	}
+	if developer {
+		if _, err := io.ReadFull(r, buf[:1]); err != nil {
+			return nil, fmt.Errorf("truncated FIT definition: %w", err)
+		}
+		n := int(buf[0])
+		if _, err := io.ReadFull(r, buf[:3*n]); err != nil {
+			return nil, fmt.Errorf("truncated FIT definition: %w", err)
+		}
+		for i := 0; i < n; i++ {
+			def.devBytes += int(buf[3*i+1])
+		}
+	}
+	return def, nil
+}
+
+func fitPoint(values map[byte]uint64) point {
+	var p point
+	if ts, ok := values[fitTimestamp]; ok {
+		p.Time = fitEpoch.Add(time.Duration(ts) * time.Second)
+	}
+	lat, okLat := values[fitRecordLat]
+	lon, okLon := values[fitRecordLon]
+	if okLat && okLon {
+		p.Lat, p.Lon, p.HasPosition = semicircles(lat), semicircles(lon), true
+	}
+	if alt, ok := values[fitRecordEnhancedAltitude]; ok {
+		p.Elevation, p.HasElevation = float64(alt)/5-500, true
+	} else if alt, ok := values[fitRecordAltitude]; ok {
+		p.Elevation, p.HasElevation = float64(alt)/5-500, true
+	}
+	if d, ok := values[fitRecordDistance]; ok {
+		p.Distance, p.HasDistance = float64(d)/100, true
+	}
+	if hr, ok := values[fitRecordHeartRate]; ok {
+		p.HeartRate = int(hr)
+	}
+	return p
+}
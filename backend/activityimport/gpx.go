@@ -0,0 +1,96 @@
+package activityimport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type gpxPoint struct {
+	Lat       float64  `xml:"lat,attr"`
+	Lon       float64  `xml:"lon,attr"`
+	Elevation *float64 `xml:"ele"`
+	Time      string   `xml:"time"`
+	// Garmin's TrackPointExtension and most other extensions use an hr
+	// element; namespaces are ignored when matching.
+	HeartRate int `xml:"extensions>TrackPointExtension>hr"`
+	HRPlain   int `xml:"extensions>hr"`
+}
+
+func (g gpxPoint) point() point {
+	p := point{Lat: g.Lat, Lon: g.Lon, HasPosition: true, HeartRate: g.HeartRate}
+	if p.HeartRate == 0 {
+		p.HeartRate = g.HRPlain
+	}
+	if g.Elevation != nil {
+		p.Elevation, p.HasElevation = *g.Elevation, true
+	}
+	p.Time, _ = time.Parse(time.RFC3339, strings.TrimSpace(g.Time))
+	return p
+}
+
+// ParseGPX reads every track of a GPX file as one activity. Routes and
+// waypoints are ignored.
+func ParseGPX(r io.Reader) ([]Result, error) {
+	dec := xml.NewDecoder(r)
+	var results []Result
+	var b *builder
+	var path []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, fmt.Errorf("invalid GPX: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			switch {
+			case name == "trk":
+				b = newBuilder()
+			case b != nil && name == "trkseg":
+				b.breakSegment()
+			case b != nil && name == "trkpt":
+				var g gpxPoint
+				if err := dec.DecodeElement(&g, &t); err != nil {
+					return results, fmt.Errorf("invalid GPX track point: %w", err)
+				}
+				b.add(g.point())
+				continue
+			case b != nil && parent == "trk" && (name == "name" || name == "type"):
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return results, fmt.Errorf("invalid GPX: %w", err)
+				}
+				if name == "name" {
+					b.session.Name = strings.TrimSpace(text)
+				} else {
+					b.session.Sport = ParseSport(text)
+				}
+				continue
+			}
+			path = append(path, name)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			if t.Name.Local == "trk" && b != nil {
+				results = append(results, b.result())
+				b = nil
+			}
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("GPX file has no tracks")
+	}
+	return results, nil
+}
@@ -0,0 +1,64 @@
+package activityimport
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Activities of the same user starting within this window of each other
+// are taken to be the same one, e.g. a run uploaded once as GPX and again
+// as FIT.
+const duplicateWindow = 2 * time.Minute
+
+// FindDuplicate returns the ID of a stored session of the user that
+// starts close enough to s to be the same activity, or "".
+func FindDuplicate(userID string, s models.CardioSession) (string, error) {
+	var id string
+	err := db.DB.QueryRow(`SELECT id FROM cardio_sessions WHERE user_id = ? AND started_at BETWEEN ? AND ? ORDER BY started_at LIMIT 1`,
+		userID, utils.FormatTime(s.StartedAt.Add(-duplicateWindow)), utils.FormatTime(s.StartedAt.Add(duplicateWindow))).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+func nullIfZero(v int) interface{} {
+	if v == 0 {
+		return nil
+	}
+	return v
+}
+
+// Save stores an imported session for the user and returns its ID. The
+// caller rates its heart rate load, which depends on the user's profile.
+func Save(userID string, s models.CardioSession) (string, error) {
+	s.ID = uuid.New().String()
+	splits, _ := json.Marshal(s.Splits)
+	hr := []byte("[]")
+	if len(s.HeartRate) > 0 {
+		hr, _ = json.Marshal(s.HeartRate)
+	}
+	var name, hrLoad interface{}
+	var load float64
+	if s.Name != "" {
+		name = s.Name
+	}
+	if s.HRLoad != nil {
+		b, _ := json.Marshal(s.HRLoad)
+		hrLoad, load = string(b), s.HRLoad.Load
+	}
+	_, err := db.DB.Exec(`INSERT INTO cardio_sessions (id, user_id, sport, name, source, started_at, duration_seconds, moving_seconds, distance,
+		elevation_gain, avg_heart_rate, max_heart_rate, splits, heart_rate, hr_load, load) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, userID, s.Sport, name, s.Source, utils.FormatTime(s.StartedAt), s.DurationSeconds, s.MovingSeconds, s.DistanceMeters,
+		s.ElevationGain, nullIfZero(s.AvgHeartRate), nullIfZero(s.MaxHeartRate), string(splits), string(hr), hrLoad, load)
+	if err != nil {
+		return "", err
+	}
+	return s.ID, nil
+}
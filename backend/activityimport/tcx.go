@@ -0,0 +1,96 @@
+package activityimport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type tcxPoint struct {
+	Time      string   `xml:"Time"`
+	Lat       *float64 `xml:"Position>LatitudeDegrees"`
+	Lon       *float64 `xml:"Position>LongitudeDegrees"`
+	Altitude  *float64 `xml:"AltitudeMeters"`
+	Distance  *float64 `xml:"DistanceMeters"`
+	HeartRate int      `xml:"HeartRateBpm>Value"`
+}
+
+func (x tcxPoint) point() point {
+	p := point{HeartRate: x.HeartRate}
+	if x.Lat != nil && x.Lon != nil {
+		p.Lat, p.Lon, p.HasPosition = *x.Lat, *x.Lon, true
+	}
+	if x.Altitude != nil {
+		p.Elevation, p.HasElevation = *x.Altitude, true
+	}
+	if x.Distance != nil {
+		p.Distance, p.HasDistance = *x.Distance, true
+	}
+	p.Time, _ = time.Parse(time.RFC3339, strings.TrimSpace(x.Time))
+	return p
+}
+
+// ParseTCX reads every activity of a Training Center file. Courses are
+// ignored.
+func ParseTCX(r io.Reader) ([]Result, error) {
+	dec := xml.NewDecoder(r)
+	var results []Result
+	var b *builder
+	var path []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return results, fmt.Errorf("invalid TCX: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			parent := ""
+			if len(path) > 0 {
+				parent = path[len(path)-1]
+			}
+			switch {
+			case name == "Activity":
+				b = newBuilder()
+				for _, a := range t.Attr {
+					if a.Name.Local == "Sport" {
+						b.session.Sport = ParseSport(a.Value)
+					}
+				}
+			case b != nil && name == "Trackpoint":
+				var x tcxPoint
+				if err := dec.DecodeElement(&x, &t); err != nil {
+					return results, fmt.Errorf("invalid TCX track point: %w", err)
+				}
+				b.add(x.point())
+				continue
+			case b != nil && parent == "Activity" && name == "Notes":
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return results, fmt.Errorf("invalid TCX: %w", err)
+				}
+				b.session.Name = strings.TrimSpace(text)
+				continue
+			}
+			path = append(path, name)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+			if t.Name.Local == "Activity" && b != nil {
+				results = append(results, b.result())
+				b = nil
+			}
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("TCX file has no activities")
+	}
+	return results, nil
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"math"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/habits"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupAdherenceRoutes(r chi.Router) {
+	r.Route("/api/adherence", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", getAdherence)
+	})
+}
+
+// adherenceDay collects what a day's score is built from.
+type adherenceDay struct {
+	scheduled, completed int
+	calories             float64
+	foodLogged           bool
+	habitsMet            int
+}
+
+func ratio(n, of int) *float64 {
+	if of == 0 {
+		return nil
+	}
+	v := math.Round(float64(n)/float64(of)*1000) / 1000
+	return &v
+}
+
+// getAdherence compares the user's training and eating with their plan
+// over ?from= and ?to= (default the last twelve weeks): sessions done
+// versus scheduled and days within the calorie target per week, and a
+// daily score combining training, nutrition and habits. Days after today
+// are left out, and today's pending sessions and open habits do not count
+// against it.
+func getAdherence(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	loc := utils.UserLocation(userID)
+	from, to, err := parseAnalyticsRange(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	today := localToday(loc)
+	last := to
+	if last.After(today) {
+		last = today
+	}
+	user, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	days := map[string]*adherenceDay{}
+	for d := from; !d.After(last); d = d.AddDate(0, 0, 1) {
+		days[d.Format(dateLayout)] = &adherenceDay{}
+	}
+
+	if !last.Before(from) {
+		if err := materializeSchedule(userID, from, last); err != nil {
+			http.Error(w, "Failed to build schedule", http.StatusInternalServerError)
+			return
+		}
+	}
+	sessions, err := loadSchedule(r.Context(), userID, from, last)
+	if err != nil {
+		http.Error(w, "Failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+	todayKey := today.Format(dateLayout)
+	for _, s := range sessions {
+		day := days[s.Date]
+		if day == nil || (s.Date == todayKey && s.Status == "scheduled") {
+			continue
+		}
+		day.scheduled++
+		if s.Status == "completed" {
+			day.completed++
+		}
+	}
+
+	entries, err := loadFoodEntries(r.Context(), userID, from, last)
+	if err != nil {
+		http.Error(w, "Failed to load food log", http.StatusInternalServerError)
+		return
+	}
+	for _, e := range entries {
+		if day := days[e.Date]; day != nil {
+			day.foodLogged = true
+			day.calories += nutrition.EntryMacros(e.Data).Calories
+		}
+	}
+	var calorieTarget *float64
+	if targets, err := nutrition.Targets(user); err == nil {
+		calorieTarget = &targets.Calories
+	}
+
+	userHabits, err := loadHabits(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to load habits", http.StatusInternalServerError)
+		return
+	}
+	for _, h := range userHabits {
+		values, err := habitValues(r.Context(), userID, h)
+		if err != nil {
+			http.Error(w, "Failed to evaluate habits", http.StatusInternalServerError)
+			return
+		}
+		for date, v := range values {
+			if day := days[date]; day != nil && habits.Met(h, v) {
+				day.habitsMet++
+			}
+		}
+	}
+
+	resp := struct {
+		From          string                 `json:"from"`
+		To            string                 `json:"to"`
+		CalorieTarget *float64               `json:"calorie_target"`
+		Weeks         []models.AdherenceWeek `json:"weeks"`
+		Days          []models.AdherenceDay  `json:"days"`
+	}{From: from.Format(dateLayout), To: to.Format(dateLayout), CalorieTarget: calorieTarget,
+		Weeks: []models.AdherenceWeek{}, Days: []models.AdherenceDay{}}
+
+	var week *models.AdherenceWeek
+	var scoreSum float64
+	var scored int
+	closeWeek := func() {
+		if week == nil {
+			return
+		}
+		week.SessionRate = ratio(week.SessionsCompleted, week.SessionsScheduled)
+		if scored > 0 {
+			avg := math.Round(scoreSum/float64(scored)*10) / 10
+			week.AverageScore = &avg
+		}
+		resp.Weeks = append(resp.Weeks, *week)
+	}
+	for d := from; !d.After(last); d = d.AddDate(0, 0, 1) {
+		key := d.Format(dateLayout)
+		day := days[key]
+		if start := training.PeriodStart(d, training.PeriodWeek); week == nil || week.Start != start.Format(dateLayout) {
+			closeWeek()
+			week = &models.AdherenceWeek{Start: start.Format(dateLayout), End: start.AddDate(0, 0, 6).Format(dateLayout)}
+			scoreSum, scored = 0, 0
+		}
+
+		a := models.AdherenceDay{Date: key, Training: ratio(day.completed, day.scheduled)}
+		if calorieTarget != nil && day.foodLogged {
+			score := habits.CalorieScore(day.calories, *calorieTarget)
+			a.Nutrition = &score
+			if score == 1 {
+				week.DaysOnCalorieTarget++
+			}
+		}
+		if len(userHabits) > 0 && (key != todayKey || day.habitsMet > 0) {
+			a.Habits = ratio(day.habitsMet, len(userHabits))
+		}
+		a.Score = habits.Score(a.Training, a.Nutrition, a.Habits)
+		resp.Days = append(resp.Days, a)
+
+		week.SessionsScheduled += day.scheduled
+		week.SessionsCompleted += day.completed
+		if day.foodLogged {
+			week.DaysFoodLogged++
+		}
+		if a.Score != nil {
+			scoreSum += *a.Score
+			scored++
+		}
+	}
+	closeWeek()
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const (
+	defaultAnalyticsWeeks = 12
+	maxAnalyticsRangeDays = 731
+	workloadLookbackDays  = 27
+	// Days of history the fitness curve is run over before the range, so
+	// its 42-day average has settled.
+	fitnessWarmupDays = 180
+)
+
+func SetupAnalyticsRoutes(r chi.Router) {
+	r.Route("/api/analytics", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/training", getTrainingAnalytics)
+		r.Get("/fitness", getFitnessAnalytics)
+	})
+}
+
+// parseAnalyticsRange reads from/to query parameters, defaulting to the
+// last 12 weeks up to today.
+func parseAnalyticsRange(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+	to := localToday(loc)
+	var err error
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			return to, to, fmt.Errorf("invalid 'to' date")
+		}
+	}
+	from := to.AddDate(0, 0, -7*defaultAnalyticsWeeks+1)
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			return from, to, fmt.Errorf("invalid 'from' date")
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("'to' must not be before 'from'")
+	}
+	if to.Sub(from) > maxAnalyticsRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("date range may span at most %d days", maxAnalyticsRangeDays)
+	}
+	return from, to, nil
+}
+
+// localMidnight converts a calendar date to the instant it starts in loc.
+func localMidnight(date time.Time, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+}
+
+// loadExerciseLoads aggregates a user's working sets per session and
+// exercise for sessions started on local dates from..to. Intensity rates
+// each set against the best e1RM recorded for the exercise up to its
+// session, so the whole history never leaves the database.
+func loadExerciseLoads(userID string, from, to time.Time, loc *time.Location) ([]training.ExerciseLoad, error) {
+	rows, err := db.DB.Query(`WITH sets AS (
+			SELECT s.id AS session_id, s.started_at, t.exercise_id, COALESCE(t.reps, 0) AS reps, COALESCE(t.load, 0) AS load,
+				COALESCE(t.rpe, 0) AS rpe,
+				(SELECT MAX(p.value) FROM pr_events p WHERE p.user_id = s.user_id AND p.exercise_id = t.exercise_id
+					AND p.kind = ? AND p.achieved_at <= s.started_at) AS e1rm
+			FROM training_sessions s JOIN training_sets t ON t.session_id = s.id
+			WHERE s.user_id = ? AND s.started_at >= ? AND s.started_at < ? AND t.warmup = 0
+		)
+		SELECT session_id, started_at, exercise_id, COUNT(*),
+			SUM(CASE WHEN rpe = 0 OR rpe >= ? THEN 1 ELSE 0 END),
+			SUM(reps), SUM(reps * load),
+			COALESCE(SUM(CASE WHEN load > 0 AND e1rm > 0 THEN load / e1rm END), 0),
+			COUNT(CASE WHEN load > 0 AND e1rm > 0 THEN 1 END)
+		FROM sets GROUP BY session_id, exercise_id`,
+		training.RecordE1RM, userID, utils.FormatTime(localMidnight(from, loc)), utils.FormatTime(localMidnight(to.AddDate(0, 0, 1), loc)), training.HardSetRPE)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var loads []training.ExerciseLoad
+	for rows.Next() {
+		var l training.ExerciseLoad
+		var started string
+		if err := rows.Scan(&l.SessionID, &started, &l.ExerciseID, &l.Sets, &l.HardSets, &l.Reps, &l.Tonnage, &l.IntensitySum, &l.IntensitySets); err != nil {
+			return nil, err
+		}
+		at, _ := time.Parse(time.RFC3339, started)
+		at = at.In(loc)
+		l.Date = time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+		loads = append(loads, l)
+	}
+	return loads, rows.Err()
+}
+
+// loadExerciseMap returns the library entries of the exercises in loads.
+func loadExerciseMap(loads []training.ExerciseLoad) (map[string]models.Exercise, error) {
+	var ids []string
+	for _, l := range loads {
+		if !containsString(ids, l.ExerciseID) {
+			ids = append(ids, l.ExerciseID)
+		}
+	}
+	exercises := map[string]models.Exercise{}
+	if len(ids) == 0 {
+		return exercises, nil
+	}
+	cond, args := inClause("e.id", ids)
+	rows, err := db.DB.Query(`SELECT `+exerciseColumns+` FROM exercises e WHERE `+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		ex, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		exercises[ex.ID] = ex
+	}
+	return exercises, rows.Err()
+}
+
+// getTrainingAnalytics buckets the user's working sets by ?period=
+// (day, week or month) over ?from=..?to= and reports the acute:chronic
+// workload ratio on the last day up to today, using ?metric= (tonnage or
+// hard_sets) as the load.
+func getTrainingAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	loc := utils.UserLocation(userID)
+	from, to, err := parseAnalyticsRange(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	period, err := training.ParsePeriod(r.URL.Query().Get("period"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	metric, err := training.ParseMetric(r.URL.Query().Get("metric"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	workloadDate := to
+	if today := localToday(loc); today.Before(workloadDate) {
+		workloadDate = today
+	}
+	loadFrom := from
+	if start := workloadDate.AddDate(0, 0, -workloadLookbackDays); start.Before(loadFrom) {
+		loadFrom = start
+	}
+	loads, err := loadExerciseLoads(userID, loadFrom, to, loc)
+	if err != nil {
+		http.Error(w, "Failed to load training history", http.StatusInternalServerError)
+		return
+	}
+	exercises, err := loadExerciseMap(loads)
+	if err != nil {
+		http.Error(w, "Failed to load training history", http.StatusInternalServerError)
+		return
+	}
+
+	buckets := training.Buckets(loads, exercises, period, from, to)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":             from.Format(dateLayout),
+		"to":               to.Format(dateLayout),
+		"period":           period,
+		"buckets":          buckets,
+		"weekly_hard_sets": training.WeeklyHardSets(buckets, from, to),
+		"workload":         training.Workload(loads, metric, workloadDate),
+	})
+}
+
+// loadDailyCardioLoad sums the load of the user's cardio sessions per local
+// date from..to.
+func loadDailyCardioLoad(userID string, from, to time.Time, loc *time.Location) (map[time.Time]float64, error) {
+	rows, err := db.DB.Query(`SELECT started_at, load FROM cardio_sessions WHERE user_id = ? AND started_at >= ? AND started_at < ? AND load > 0`,
+		userID, utils.FormatTime(localMidnight(from, loc)), utils.FormatTime(localMidnight(to.AddDate(0, 0, 1), loc)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	daily := map[time.Time]float64{}
+	for rows.Next() {
+		var started string
+		var load float64
+		if err := rows.Scan(&started, &load); err != nil {
+			return nil, err
+		}
+		at, _ := time.Parse(time.RFC3339, started)
+		at = at.In(loc)
+		daily[time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)] += load
+	}
+	return daily, rows.Err()
+}
+
+// getFitnessAnalytics returns the fitness (CTL), fatigue (ATL) and form
+// (TSB) curves over ?from=..?to= from the heart rate load of cardio
+// sessions, with the heart rate zones the loads were rated against.
+func getFitnessAnalytics(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	user, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	loc := utils.UserLocation(userID)
+	from, to, err := parseAnalyticsRange(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := from.AddDate(0, 0, -fitnessWarmupDays)
+	daily, err := loadDailyCardioLoad(userID, start, to, loc)
+	if err != nil {
+		http.Error(w, "Failed to load training history", http.StatusInternalServerError)
+		return
+	}
+
+	profile := userHRProfile(user)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":       from.Format(dateLayout),
+		"to":         to.Format(dateLayout),
+		"hr_profile": profile,
+		"zones":      training.Zones(profile),
+		"days":       training.FitnessCurve(daily, start, from, to),
+	})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestTrainingAnalytics(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupSessionRoutes(router)
+	SetupAnalyticsRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	logSession := func(started string, sets ...map[string]interface{}) {
+		rr := doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{"started_at": started})
+		id := decodeSession(t, rr.Body.Bytes()).Session.ID
+		for _, set := range sets {
+			rr = doJSON(router, "POST", "/api/sessions/"+id+"/sets", token, set)
+			assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+	bench := func(reps int, load float64, rpe float64) map[string]interface{} {
+		return map[string]interface{}{"exercise_id": "bench-press", "reps": reps, "load": load, "rpe": rpe}
+	}
+
+	logSession("2025-09-29T07:00:00Z", bench(5, 60, 0))
+	logSession("2025-10-06T07:00:00Z", bench(5, 60, 0))
+	logSession("2025-10-13T07:00:00Z", bench(5, 60, 0))
+	logSession("2025-10-20T07:00:00Z",
+		map[string]interface{}{"exercise_id": "bench-press", "reps": 5, "load": 40, "warmup": true},
+		bench(5, 80, 8), bench(5, 80, 9), bench(10, 40, 5),
+	)
+	logSession("2025-10-22T07:00:00Z", map[string]interface{}{"exercise_id": "back-squat", "reps": 5, "load": 100})
+
+	var resp struct {
+		Period         string                  `json:"period"`
+		Buckets        []models.TrainingBucket `json:"buckets"`
+		WeeklyHardSets map[string]float64      `json:"weekly_hard_sets"`
+		Workload       models.Workload         `json:"workload"`
+	}
+	rr := doJSON(router, "GET", "/api/analytics/training?from=2025-10-13&to=2025-10-26", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "week", resp.Period)
+	assert.Len(t, resp.Buckets, 2)
+
+	week := resp.Buckets[1]
+	assert.Equal(t, "2025-10-20", week.Start)
+	assert.Equal(t, 2, week.Sessions)
+	assert.Equal(t, 4, week.Sets)
+	// The RPE 5 back-off set is not a hard one; the warm-up is no set at all.
+	assert.Equal(t, 3, week.HardSets)
+	assert.Equal(t, 1700.0, week.Tonnage)
+	assert.Equal(t, 2.0, week.HardSetsPerMuscle["chest"])
+	assert.Equal(t, 1, week.Frequency["chest"])
+	// 80 kg against the 93.3 kg e1RM of that session, 40 kg against the
+	// same, and the squat at its own 116.7 kg estimate.
+	assert.Equal(t, 75.0, *week.AvgIntensity)
+	assert.Equal(t, 0.5, resp.WeeklyHardSets["quads"])
+
+	// The last week more than doubles the 300 kg weeks before it.
+	assert.Equal(t, "2025-10-26", resp.Workload.Date)
+	assert.Equal(t, 1700.0, resp.Workload.Acute)
+	assert.Equal(t, "spike", resp.Workload.Status)
+	assert.NotEmpty(t, resp.Workload.Warning)
+
+	rr = doJSON(router, "GET", "/api/analytics/training?from=2025-10-20&to=2025-10-22&period=day&metric=hard_sets", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Len(t, resp.Buckets, 3)
+	assert.Equal(t, 0, resp.Buckets[1].Sessions)
+	assert.Equal(t, 1.0, resp.Buckets[2].HardSetsPerMuscle["quads"])
+	assert.Equal(t, "hard_sets", resp.Workload.Metric)
+
+	for _, bad := range []string{"?period=fortnight", "?metric=vibes", "?from=2025-10-20&to=2025-10-01", "?from=2020-01-01&to=2026-01-01"} {
+		rr = doJSON(router, "GET", "/api/analytics/training"+bad, token, nil)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, bad)
+	}
+}
+
+func TestFitnessAnalytics(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupUserRoutes(router)
+	SetupCardioRoutes(router)
+	SetupAnalyticsRoutes(router)
+	token := createTestUser(t, "user-123")
+	db.DB.Exec(`UPDATE users SET age = 40, gender = 'male' WHERE id = 'user-123'`)
+
+	// An hour at 162 bpm: threshold for an estimated 180/60 profile.
+	var gpx strings.Builder
+	gpx.WriteString(`<gpx xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1"><trk><type>running</type><trkseg>`)
+	start := time.Date(2025, 10, 1, 7, 0, 0, 0, time.UTC)
+	for i := 0; i <= 60; i++ {
+		fmt.Fprintf(&gpx, `<trkpt lat="0" lon="%.3f"><time>%s</time><extensions><ns3:TrackPointExtension><ns3:hr>162</ns3:hr></ns3:TrackPointExtension></extensions></trkpt>`,
+			float64(i)*0.002, start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339))
+	}
+	gpx.WriteString(`</trkseg></trk></gpx>`)
+	rr := postActivity(router, token, "", gpx.String())
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var imported struct {
+		Results []struct {
+			Session models.CardioSession `json:"session"`
+		} `json:"results"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &imported)
+	session := imported.Results[0].Session
+	assert.Equal(t, 100.0, session.HRLoad.Load)
+	assert.Equal(t, 3600.0, session.HRLoad.TimeInZones[3])
+
+	var resp struct {
+		HRProfile models.HRProfile    `json:"hr_profile"`
+		Zones     []models.HRZone     `json:"zones"`
+		Days      []models.FitnessDay `json:"days"`
+	}
+	rr = doJSON(router, "GET", "/api/analytics/fitness?from=2025-10-01&to=2025-10-02", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 180, resp.HRProfile.Max)
+	assert.True(t, resp.HRProfile.MaxEstimated)
+	assert.Len(t, resp.Zones, 5)
+	assert.Equal(t, []models.FitnessDay{
+		{Date: "2025-10-01", Load: 100, CTL: 2.4, ATL: 14.3, TSB: 0},
+		{Date: "2025-10-02", Load: 0, CTL: 2.3, ATL: 12.2, TSB: -11.9},
+	}, resp.Days)
+
+	// A measured max heart rate re-rates the session as easier.
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"age": 40, "gender": "male", "max_heart_rate": 200, "resting_heart_rate": 50})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"max_heart_rate":200`)
+	rr = doJSON(router, "GET", "/api/cardio/"+session.ID, token, nil)
+	var one struct {
+		Session models.CardioSession `json:"session"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &one)
+	assert.Less(t, one.Session.HRLoad.Load, 100.0)
+	assert.Equal(t, 3600.0, one.Session.HRLoad.TimeInZones[2])
+
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"max_heart_rate": 90})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
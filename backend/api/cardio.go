@@ -0,0 +1,267 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/activityimport"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Largest activity file accepted. Files are decoded as they stream in, so
+// this only bounds the upload.
+const maxActivityImportSize = 200 << 20
+
+func SetupCardioRoutes(r chi.Router) {
+	r.Route("/api/cardio", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listCardioSessions)
+		r.Post("/import", importActivities)
+		r.Get("/{id}", getCardioSession)
+		r.Delete("/{id}", deleteCardioSession)
+	})
+}
+
+const cardioColumns = `c.id, c.sport, COALESCE(c.name, ''), c.source, c.started_at, c.duration_seconds, c.moving_seconds, c.distance,
+	c.elevation_gain, COALESCE(c.avg_heart_rate, 0), COALESCE(c.max_heart_rate, 0), c.splits, COALESCE(c.hr_load, ''), c.created_at`
+
+func scanCardioSession(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.CardioSession, error) {
+	var s models.CardioSession
+	var started, splits, hrLoad string
+	dest := append([]interface{}{&s.ID, &s.Sport, &s.Name, &s.Source, &started, &s.DurationSeconds, &s.MovingSeconds, &s.DistanceMeters,
+		&s.ElevationGain, &s.AvgHeartRate, &s.MaxHeartRate, &splits, &hrLoad, &s.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return s, err
+	}
+	s.StartedAt, _ = time.Parse(time.RFC3339, started)
+	json.Unmarshal([]byte(splits), &s.Splits)
+	if s.Splits == nil {
+		s.Splits = []models.Split{}
+	}
+	if hrLoad != "" {
+		json.Unmarshal([]byte(hrLoad), &s.HRLoad)
+	}
+	if s.MovingSeconds > 0 && s.DistanceMeters > 0 {
+		s.PaceSecondsPerKm = math.Round(s.MovingSeconds/s.DistanceMeters*10000) / 10
+		s.SpeedKmh = math.Round(s.DistanceMeters/s.MovingSeconds*36) / 10
+	}
+	return s, nil
+}
+
+func userHRProfile(u models.User) models.HRProfile {
+	return training.NewHRProfile(u.Age, u.Gender, u.MaxHeartRate, u.RestingHeartRate)
+}
+
+// refreshCardioLoads re-rates every cardio session of the user after their
+// heart rate profile changed.
+func refreshCardioLoads(userID string) error {
+	u, err := loadUser(userID)
+	if err != nil {
+		return err
+	}
+	profile := userHRProfile(u)
+
+	rows, err := db.DB.Query(`SELECT id, heart_rate FROM cardio_sessions WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	series := map[string][]models.HeartRateSample{}
+	for rows.Next() {
+		var id, hr string
+		if err := rows.Scan(&id, &hr); err != nil {
+			rows.Close()
+			return err
+		}
+		var samples []models.HeartRateSample
+		json.Unmarshal([]byte(hr), &samples)
+		series[id] = samples
+	}
+	rows.Close()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for id, samples := range series {
+		var hrLoad interface{}
+		var load float64
+		if l := training.HeartRateLoad(samples, profile); l != nil {
+			hrLoad, load = encodeJSON(l), l.Load
+		}
+		if _, err := tx.Exec(`UPDATE cardio_sessions SET hr_load = ?, load = ? WHERE id = ?`, hrLoad, load, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// loadCardioSession returns a user's cardio session with its heart rate
+// series.
+func loadCardioSession(userID, id string) (models.CardioSession, error) {
+	var hr string
+	s, err := scanCardioSession(db.DB.QueryRow(`SELECT `+cardioColumns+`, c.heart_rate FROM cardio_sessions c WHERE c.id = ? AND c.user_id = ?`, id, userID), &hr)
+	if err != nil {
+		return s, err
+	}
+	json.Unmarshal([]byte(hr), &s.HeartRate)
+	return s, nil
+}
+
+// listCardioSessions returns the user's cardio sessions, newest first,
+// without their heart rate series. Filters: ?from= and ?to= dates and
+// ?sport=.
+func listCardioSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	loc := utils.UserLocation(userID)
+
+	conds := []string{`c.user_id = ?`}
+	args := []interface{}{userID}
+	for _, p := range []struct {
+		param, cond string
+		days        int
+	}{{"from", `c.started_at >= ?`, 0}, {"to", `c.started_at < ?`, 1}} {
+		v := r.URL.Query().Get(p.param)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseInLocation(dateLayout, v, loc)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid '%s' date", p.param), http.StatusBadRequest)
+			return
+		}
+		conds = append(conds, p.cond)
+		args = append(args, utils.FormatTime(d.AddDate(0, 0, p.days)))
+	}
+	if sports := queryList(r, "sport"); len(sports) > 0 {
+		cond, sportArgs := inClause("c.sport", sports)
+		conds = append(conds, cond)
+		args = append(args, sportArgs...)
+	}
+	args = append(args, parseLimit(r))
+
+	rows, err := db.DB.Query(`SELECT `+cardioColumns+` FROM cardio_sessions c WHERE `+strings.Join(conds, " AND ")+
+		` ORDER BY c.started_at DESC LIMIT ?`, args...)
+	if err != nil {
+		http.Error(w, "Failed to list cardio sessions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	sessions := []models.CardioSession{}
+	for rows.Next() {
+		s, err := scanCardioSession(rows)
+		if err != nil {
+			http.Error(w, "Failed to list cardio sessions", http.StatusInternalServerError)
+			return
+		}
+		sessions = append(sessions, s)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": sessions})
+}
+
+func getCardioSession(w http.ResponseWriter, r *http.Request) {
+	s, err := loadCardioSession(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Cardio session not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"session": s})
+}
+
+func deleteCardioSession(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM cardio_sessions WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete cardio session", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Cardio session not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// importActivities reads a GPX, TCX or FIT file from the request body and
+// stores each activity in it as a cardio session. The format is detected
+// unless given with ?format=. Activities already stored are reported with
+// duplicate_of and skipped; with ?dry_run=true nothing is saved.
+func importActivities(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	body := http.MaxBytesReader(w, r.Body, maxActivityImportSize)
+	format, results, err := activityimport.Parse(r.URL.Query().Get("format"), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Import file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	profile := userHRProfile(user)
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	imported := 0
+	for i, res := range results {
+		if res.Error != "" {
+			continue
+		}
+		res.Session.HRLoad = training.HeartRateLoad(res.Session.HeartRate, profile)
+		results[i].Session.HRLoad = res.Session.HRLoad
+		dup, err := activityimport.FindDuplicate(userID, res.Session)
+		if err != nil {
+			http.Error(w, "Failed to check for duplicates", http.StatusInternalServerError)
+			return
+		}
+		if dup != "" {
+			results[i].DuplicateOf = dup
+			continue
+		}
+		if dryRun {
+			continue
+		}
+		id, err := activityimport.Save(userID, res.Session)
+		if err != nil {
+			http.Error(w, "Failed to save cardio session", http.StatusInternalServerError)
+			return
+		}
+		if saved, err := loadCardioSession(userID, id); err == nil {
+			results[i].Session = saved
+		}
+		imported++
+	}
+	// The series can be long; it is fetched with the session instead.
+	for i := range results {
+		results[i].Session.HeartRate = nil
+	}
+
+	status := http.StatusCreated
+	switch {
+	case dryRun:
+		status = http.StatusOK
+	case imported == 0:
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"format":   format,
+		"dry_run":  dryRun,
+		"imported": imported,
+		"results":  results,
+	})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+const testGPX = `<?xml version="1.0"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1" xmlns:ns3="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+<trk><name>Lunch Run</name><type>running</type><trkseg>
+<trkpt lat="0" lon="0"><ele>5</ele><time>2026-10-18T10:00:00Z</time><extensions><ns3:TrackPointExtension><ns3:hr>120</ns3:hr></ns3:TrackPointExtension></extensions></trkpt>
+<trkpt lat="0" lon="0.01"><ele>9</ele><time>2026-10-18T10:05:00Z</time><extensions><ns3:TrackPointExtension><ns3:hr>150</ns3:hr></ns3:TrackPointExtension></extensions></trkpt>
+</trkseg></trk>
+</gpx>`
+
+func postActivity(router http.Handler, token, query, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/cardio/import"+query, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestImportActivities(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupCardioRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+
+	type importResponse struct {
+		Format   string `json:"format"`
+		Imported int    `json:"imported"`
+		Results  []struct {
+			Session     models.CardioSession `json:"session"`
+			Error       string               `json:"error"`
+			DuplicateOf string               `json:"duplicate_of"`
+		} `json:"results"`
+	}
+
+	rr := postActivity(router, token, "?dry_run=true", testGPX)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/cardio", token, nil)
+	assert.Contains(t, rr.Body.String(), `"sessions":[]`)
+
+	rr = postActivity(router, token, "", testGPX)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var resp importResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "gpx", resp.Format)
+	assert.Equal(t, 1, resp.Imported)
+	saved := resp.Results[0].Session
+	assert.NotEmpty(t, saved.ID)
+	assert.Equal(t, "Lunch Run", saved.Name)
+	assert.Equal(t, 1112.0, saved.DistanceMeters)
+	assert.Equal(t, 300.0, saved.MovingSeconds)
+	assert.Equal(t, 269.8, saved.PaceSecondsPerKm)
+	assert.Equal(t, 4.0, saved.ElevationGain)
+	assert.Len(t, saved.Splits, 2)
+	assert.Nil(t, saved.HeartRate)
+
+	// The same run from another device, starting 30 s later, is a duplicate.
+	rr = postActivity(router, token, "?format=gpx", strings.Replace(testGPX, "10:00:00Z", "10:00:30Z", 1))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 0, resp.Imported)
+	assert.Equal(t, saved.ID, resp.Results[0].DuplicateOf)
+
+	// Another user may import it.
+	rr = postActivity(router, otherToken, "", testGPX)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/cardio/"+saved.ID, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var one struct {
+		Session models.CardioSession `json:"session"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &one)
+	assert.Equal(t, []models.HeartRateSample{{Offset: 0, BPM: 120}, {Offset: 300, BPM: 150}}, one.Session.HeartRate)
+	assert.Equal(t, 135, one.Session.AvgHeartRate)
+
+	rr = doJSON(router, "GET", "/api/cardio?sport=cycling", token, nil)
+	assert.Contains(t, rr.Body.String(), `"sessions":[]`)
+	rr = doJSON(router, "GET", "/api/cardio?from=2026-10-18&to=2026-10-18&sport=running", token, nil)
+	var list struct {
+		Sessions []models.CardioSession `json:"sessions"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Sessions, 1)
+	assert.Nil(t, list.Sessions[0].HeartRate)
+
+	rr = postActivity(router, token, "", "not an activity")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(router, "DELETE", "/api/cardio/"+saved.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/cardio/"+saved.ID, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "GET", "/api/cardio/"+saved.ID, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
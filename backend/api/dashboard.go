@@ -0,0 +1,259 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const (
+	// dashboardTimeout bounds each section; a slow section is left out
+	// rather than holding up the rest.
+	dashboardTimeout = 2 * time.Second
+	dashboardRecords = 5
+	// Days of weigh-ins in the weight trend.
+	weightTrendDays = 30
+)
+
+func SetupDashboardRoutes(r chi.Router) {
+	r.Route("/api/dashboard", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", getDashboard)
+	})
+}
+
+// dashboardSection loads one part of the dashboard.
+type dashboardSection struct {
+	name string
+	load func(ctx context.Context) (interface{}, error)
+}
+
+// loadSections runs the sections concurrently, each with its own timeout.
+// Sections that fail, panic or time out are nil in the results and listed
+// in the second map with the reason.
+func loadSections(ctx context.Context, timeout time.Duration, sections []dashboardSection) (map[string]interface{}, map[string]string) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]interface{}, len(sections))
+	unavailable := map[string]string{}
+	for _, s := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			// Buffered so an abandoned load can still finish and exit.
+			done := make(chan result, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						done <- result{err: fmt.Errorf("panic: %v", p)}
+					}
+				}()
+				v, err := s.load(sctx)
+				done <- result{v, err}
+			}()
+
+			var res result
+			select {
+			case res = <-done:
+			case <-sctx.Done():
+				res.err = sctx.Err()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[s.name] = nil
+			switch {
+			case res.err == context.DeadlineExceeded:
+				unavailable[s.name] = "timed out"
+			case res.err != nil:
+				unavailable[s.name] = "failed"
+			default:
+				results[s.name] = res.value
+			}
+		}()
+	}
+	wg.Wait()
+	return results, unavailable
+}
+
+// getDashboard summarises the user's day in one response: profile
+// completeness, today's sessions, nutrition, recent records, habit goals,
+// logging streaks and the weight trend. Sections load concurrently; one
+// that fails or is slow comes back null and is named in "unavailable".
+func getDashboard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	user, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	today := localToday(utils.UserLocation(userID))
+	// Today's sessions are written before the sections start, which then
+	// only read and so cannot block each other on SQLite's write lock.
+	scheduleErr := materializeSchedule(userID, today, today)
+
+	sections, unavailable := loadSections(r.Context(), dashboardTimeout, []dashboardSection{
+		{"profile", func(context.Context) (interface{}, error) {
+			return profileCompleteness(user), nil
+		}},
+		{"sessions", func(ctx context.Context) (interface{}, error) {
+			if scheduleErr != nil {
+				return nil, scheduleErr
+			}
+			return loadSchedule(ctx, userID, today, today)
+		}},
+		{"nutrition", func(ctx context.Context) (interface{}, error) {
+			return dailyNutrition(ctx, user, today)
+		}},
+		{"records", func(ctx context.Context) (interface{}, error) {
+			return loadRecentRecords(ctx, userID, dashboardRecords)
+		}},
+		{"goals", func(ctx context.Context) (interface{}, error) {
+			list, err := loadHabits(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			for i := range list {
+				if list[i].Progress, err = habitProgress(ctx, userID, list[i], today.AddDate(0, 0, -habitListDays+1), today, today); err != nil {
+					return nil, err
+				}
+			}
+			return list, nil
+		}},
+		{"streaks", func(ctx context.Context) (interface{}, error) {
+			streaks, overall, err := loadStreaks(ctx, userID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"streaks": streaks, "overall": overall}, nil
+		}},
+		{"weight", func(ctx context.Context) (interface{}, error) {
+			return loadWeightTrend(ctx, userID, today)
+		}},
+	})
+
+	sections["date"] = today.Format(dateLayout)
+	sections["unavailable"] = unavailable
+	writeJSON(w, http.StatusOK, sections)
+}
+
+// profileCompleteness checks the profile fields the targets, schedule and
+// recommendations rely on.
+func profileCompleteness(u models.User) models.ProfileCompleteness {
+	fields := []struct {
+		name string
+		set  bool
+	}{
+		{"name", u.Name != ""},
+		{"age", u.Age > 0},
+		{"gender", u.Gender != ""},
+		{"height", u.Height > 0},
+		{"weight", u.Weight > 0},
+		{"activity_level", u.Activity != ""},
+		{"goals", u.Goals != ""},
+		{"timezone", u.Timezone != ""},
+	}
+	p := models.ProfileCompleteness{Missing: []string{}}
+	for _, f := range fields {
+		if !f.set {
+			p.Missing = append(p.Missing, f.name)
+		}
+	}
+	p.Percent = (len(fields) - len(p.Missing)) * 100 / len(fields)
+	return p
+}
+
+// loadWeightTrend reads weigh-ins from the weight journal. Entries from
+// before the trend window are read too, as the baseline for the changes.
+func loadWeightTrend(ctx context.Context, userID string, today time.Time) (models.WeightTrend, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT j.date, AVG(json_extract(j.entry_data, '$.weight')) FROM journals j
+		WHERE j.user_id = ? AND j.type = 'weight' AND j.date BETWEEN ? AND ?
+		GROUP BY j.date ORDER BY j.date`,
+		userID, today.AddDate(0, 0, -3*weightTrendDays).Format(dateLayout), today.Format(dateLayout))
+	if err != nil {
+		return models.WeightTrend{}, err
+	}
+	defer rows.Close()
+
+	var points []models.WeightPoint
+	for rows.Next() {
+		var p models.WeightPoint
+		var weight *float64
+		if err := rows.Scan(&p.Date, &weight); err != nil {
+			return models.WeightTrend{}, err
+		}
+		if weight != nil {
+			p.Date, p.Weight = trimDate(p.Date), *weight
+			points = append(points, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.WeightTrend{}, err
+	}
+	return weightTrend(points, today), nil
+}
+
+// weightTrend summarises ascending daily weigh-ins as of today.
+func weightTrend(points []models.WeightPoint, today time.Time) models.WeightTrend {
+	t := models.WeightTrend{Points: []models.WeightPoint{}}
+	if len(points) == 0 {
+		return t
+	}
+	latest := points[len(points)-1]
+	t.Latest = &latest
+	latestDate, _ := time.Parse(dateLayout, latest.Date)
+	// change returns the difference from the last weigh-in at least days
+	// before the latest one.
+	change := func(days int) *float64 {
+		cutoff := latestDate.AddDate(0, 0, -days).Format(dateLayout)
+		for i := len(points) - 1; i >= 0; i-- {
+			if points[i].Date <= cutoff {
+				v := math.Round((latest.Weight-points[i].Weight)*10) / 10
+				return &v
+			}
+		}
+		return nil
+	}
+	t.Change7, t.Change30 = change(7), change(30)
+
+	start := today.AddDate(0, 0, -weightTrendDays+1)
+	var xs, ys []float64
+	for _, p := range points {
+		d, err := time.Parse(dateLayout, p.Date)
+		if err != nil || d.Before(start) {
+			continue
+		}
+		p.Weight = math.Round(p.Weight*10) / 10
+		t.Points = append(t.Points, p)
+		xs = append(xs, d.Sub(start).Hours()/24)
+		ys = append(ys, p.Weight)
+	}
+	// Least-squares slope in kg per day, scaled to a week.
+	if n := float64(len(xs)); n >= 2 {
+		var sx, sy, sxx, sxy float64
+		for i := range xs {
+			sx, sy = sx+xs[i], sy+ys[i]
+			sxx, sxy = sxx+xs[i]*xs[i], sxy+xs[i]*ys[i]
+		}
+		if den := n*sxx - sx*sx; den != 0 {
+			rate := math.Round((n*sxy-sx*sy)/den*7*100) / 100
+			t.WeeklyRate = &rate
+		}
+	}
+	t.Latest.Weight = math.Round(t.Latest.Weight*10) / 10
+	return t
+}
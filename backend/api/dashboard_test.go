@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestDashboard(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	SetupHabitRoutes(router)
+	SetupDashboardRoutes(router)
+	token := createTestUser(t, "user-123")
+	db.DB.Exec(`UPDATE users SET age = 30, gender = 'male', height = 180, weight = 80, activity_level = 'moderate' WHERE id = 'user-123'`)
+
+	today := time.Now().UTC()
+	daysAgo := func(n int) string { return today.AddDate(0, 0, -n).Format(dateLayout) }
+	logEntry := func(date, entryType string, data map[string]interface{}) {
+		rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"date": date, "type": entryType, "data": data})
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	logEntry(daysAgo(40), "weight", map[string]interface{}{"weight": 84})
+	logEntry(daysAgo(14), "weight", map[string]interface{}{"weight": 82})
+	logEntry(daysAgo(7), "weight", map[string]interface{}{"weight": 81.5})
+	logEntry(daysAgo(0), "weight", map[string]interface{}{"weight": 80.6})
+	logEntry(daysAgo(0), "weight", map[string]interface{}{"weight": 81})
+	logEntry(daysAgo(0), "food", map[string]interface{}{"name": "Lunch", "calories": 700})
+	logEntry(daysAgo(0), "steps", map[string]interface{}{"count": 11000})
+	doJSON(router, "POST", "/api/habits", token, map[string]interface{}{
+		"name": "10k steps", "entry_type": "steps", "field": "count", "target": 10000,
+	})
+
+	rr := doJSON(router, "GET", "/api/dashboard", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		Date      string                     `json:"date"`
+		Profile   models.ProfileCompleteness `json:"profile"`
+		Sessions  []models.ScheduledSession  `json:"sessions"`
+		Nutrition models.DailyNutrition      `json:"nutrition"`
+		Records   []models.RecordEvent       `json:"records"`
+		Goals     []models.Habit             `json:"goals"`
+		Streaks   struct {
+			Overall models.Streak `json:"overall"`
+		} `json:"streaks"`
+		Weight      models.WeightTrend `json:"weight"`
+		Unavailable map[string]string  `json:"unavailable"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Empty(t, resp.Unavailable)
+	assert.Equal(t, daysAgo(0), resp.Date)
+	assert.Equal(t, models.ProfileCompleteness{Percent: 62, Missing: []string{"name", "goals", "timezone"}}, resp.Profile)
+	assert.Empty(t, resp.Sessions)
+	assert.NotNil(t, resp.Sessions)
+	assert.Equal(t, 700.0, resp.Nutrition.Totals.Calories)
+	assert.Equal(t, 2059.0, resp.Nutrition.Remaining.Calories)
+	assert.Empty(t, resp.Records)
+	if assert.Len(t, resp.Goals, 1) {
+		days := resp.Goals[0].Progress.Days
+		assert.True(t, days[len(days)-1].Met)
+	}
+	assert.Equal(t, 1, resp.Streaks.Overall.Current)
+
+	assert.Equal(t, &models.WeightPoint{Date: daysAgo(0), Weight: 80.8}, resp.Weight.Latest)
+	assert.Equal(t, -0.7, *resp.Weight.Change7)
+	assert.Equal(t, -3.2, *resp.Weight.Change30)
+	assert.Len(t, resp.Weight.Points, 3)
+	assert.Equal(t, -0.6, *resp.Weight.WeeklyRate)
+}
+
+func TestLoadSections(t *testing.T) {
+	ok := func(v interface{}) func(context.Context) (interface{}, error) {
+		return func(context.Context) (interface{}, error) { return v, nil }
+	}
+	release := make(chan struct{})
+	defer close(release)
+
+	start := time.Now()
+	results, unavailable := loadSections(context.Background(), 50*time.Millisecond, []dashboardSection{
+		{"fast", ok(1)},
+		{"empty", ok([]string{})},
+		{"slow", func(context.Context) (interface{}, error) {
+			<-release
+			return 2, nil
+		}},
+		{"broken", func(context.Context) (interface{}, error) { return nil, errors.New("boom") }},
+		{"panics", func(context.Context) (interface{}, error) { panic("oops") }},
+	})
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, map[string]interface{}{"fast": 1, "empty": []string{}, "slow": nil, "broken": nil, "panics": nil}, results)
+	assert.Equal(t, map[string]string{"slow": "timed out", "broken": "failed", "panics": "failed"}, unavailable)
+}
+
+func TestWeightTrend(t *testing.T) {
+	today, _ := time.Parse(dateLayout, "2025-03-31")
+	trend := weightTrend(nil, today)
+	assert.Nil(t, trend.Latest)
+	assert.Empty(t, trend.Points)
+
+	trend = weightTrend([]models.WeightPoint{{Date: "2025-03-30", Weight: 80}}, today)
+	assert.Equal(t, 80.0, trend.Latest.Weight)
+	assert.Nil(t, trend.Change7)
+	assert.Nil(t, trend.WeeklyRate)
+
+	// Losing 0.1 kg a day is 0.7 kg a week.
+	var points []models.WeightPoint
+	for d := 0; d < 10; d++ {
+		points = append(points, models.WeightPoint{Date: today.AddDate(0, 0, d-9).Format(dateLayout), Weight: 80 - 0.1*float64(d)})
+	}
+	trend = weightTrend(points, today)
+	assert.Equal(t, -0.7, *trend.Change7)
+	assert.Nil(t, trend.Change30)
+	assert.Equal(t, -0.7, *trend.WeeklyRate)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupExerciseRoutes(r chi.Router) {
+	r.Route("/api/exercises", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listExercises)
+		r.Post("/", createExercise)
+		r.Get("/{id}", getExercise)
+		r.Put("/{id}", updateExercise)
+		r.Delete("/{id}", deleteExercise)
+	})
+}
+
+const exerciseColumns = `e.id, e.name, e.primary_muscles, e.secondary_muscles, e.equipment, COALESCE(e.movement_pattern, ''), e.unilateral, e.instructions`
+
+func scanExercise(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Exercise, error) {
+	var ex models.Exercise
+	var primary, secondary, equipment, instructions string
+	dest := append([]interface{}{&ex.ID, &ex.Name, &primary, &secondary, &equipment, &ex.MovementPattern, &ex.Unilateral, &instructions}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return ex, err
+	}
+	ex.PrimaryMuscles = decodeStringList(primary)
+	ex.SecondaryMuscles = decodeStringList(secondary)
+	ex.Equipment = decodeStringList(equipment)
+	ex.Instructions = decodeStringList(instructions)
+	return ex, nil
+}
+
+func loadExercise(id string) (models.Exercise, error) {
+	return scanExercise(db.DB.QueryRow(`SELECT `+exerciseColumns+` FROM exercises e WHERE e.id = ?`, id))
+}
+
+// jsonListHas is an SQL condition matching rows whose JSON array column
+// contains the argument.
+func jsonListHas(column string) string {
+	return `EXISTS (SELECT 1 FROM json_each(` + column + `) WHERE value = ?)`
+}
+
+func listExercises(w http.ResponseWriter, r *http.Request) {
+	var conds []string
+	var args []interface{}
+
+	// muscle matches primary or secondary muscles, primary_muscle only the
+	// former.
+	for _, m := range queryList(r, "muscle") {
+		conds = append(conds, `(`+jsonListHas("e.primary_muscles")+` OR `+jsonListHas("e.secondary_muscles")+`)`)
+		args = append(args, training.Key(m), training.Key(m))
+	}
+	for _, m := range queryList(r, "primary_muscle") {
+		conds = append(conds, jsonListHas("e.primary_muscles"))
+		args = append(args, training.Key(m))
+	}
+	for _, eq := range queryList(r, "equipment") {
+		conds = append(conds, jsonListHas("e.equipment"))
+		args = append(args, training.Key(eq))
+	}
+	if p := r.URL.Query().Get("pattern"); p != "" {
+		conds = append(conds, `e.movement_pattern = ?`)
+		args = append(args, training.Key(p))
+	}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		conds = append(conds, `lower(e.name) LIKE ? ESCAPE '\'`)
+		args = append(args, likeContains(strings.ToLower(q)))
+	}
+
+	query := `SELECT ` + exerciseColumns + ` FROM exercises e`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY e.name`
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to list exercises", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	exercises := []models.Exercise{}
+	for rows.Next() {
+		ex, err := scanExercise(rows)
+		if err != nil {
+			http.Error(w, "Failed to list exercises", http.StatusInternalServerError)
+			return
+		}
+		exercises = append(exercises, ex)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"exercises": exercises})
+}
+
+func decodeExercise(r *http.Request) (models.Exercise, error) {
+	var ex models.Exercise
+	if err := json.NewDecoder(r.Body).Decode(&ex); err != nil {
+		return ex, fmt.Errorf("Invalid payload")
+	}
+	ex.Name = strings.TrimSpace(ex.Name)
+	if ex.Name == "" {
+		return ex, fmt.Errorf("Exercise name is required")
+	}
+	var err error
+	if ex.PrimaryMuscles, err = training.Normalize("muscle group", training.MuscleGroups, ex.PrimaryMuscles); err != nil {
+		return ex, err
+	}
+	if len(ex.PrimaryMuscles) == 0 {
+		return ex, fmt.Errorf("At least one primary muscle group is required")
+	}
+	if ex.SecondaryMuscles, err = training.Normalize("muscle group", training.MuscleGroups, ex.SecondaryMuscles); err != nil {
+		return ex, err
+	}
+	if ex.Equipment, err = training.Normalize("equipment", training.Equipment, ex.Equipment); err != nil {
+		return ex, err
+	}
+	if ex.MovementPattern, err = training.NormalizeOne("movement pattern", training.MovementPatterns, ex.MovementPattern); err != nil {
+		return ex, err
+	}
+	if ex.Instructions == nil {
+		ex.Instructions = []string{}
+	}
+	return ex, nil
+}
+
+// isUniqueViolation reports whether err comes from a UNIQUE constraint.
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func createExercise(w http.ResponseWriter, r *http.Request) {
+	ex, err := decodeExercise(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ex.ID = uuid.New().String()
+
+	_, err = db.DB.Exec(`INSERT INTO exercises (id, name, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral, instructions) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		ex.ID, ex.Name, encodeJSON(ex.PrimaryMuscles), encodeJSON(ex.SecondaryMuscles), encodeJSON(ex.Equipment), utils.NullIfEmpty(ex.MovementPattern), ex.Unilateral, encodeJSON(ex.Instructions))
+	if isUniqueViolation(err) {
+		http.Error(w, "An exercise with that name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create exercise", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"exercise": ex})
+}
+
+func getExercise(w http.ResponseWriter, r *http.Request) {
+	ex, err := loadExercise(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"exercise": ex})
+}
+
+func updateExercise(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := loadExercise(id); err != nil {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+
+	ex, err := decodeExercise(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ex.ID = id
+
+	_, err = db.DB.Exec(`UPDATE exercises SET name = ?, primary_muscles = ?, secondary_muscles = ?, equipment = ?, movement_pattern = ?, unilateral = ?, instructions = ? WHERE id = ?`,
+		ex.Name, encodeJSON(ex.PrimaryMuscles), encodeJSON(ex.SecondaryMuscles), encodeJSON(ex.Equipment), utils.NullIfEmpty(ex.MovementPattern), ex.Unilateral, encodeJSON(ex.Instructions), id)
+	if isUniqueViolation(err) {
+		http.Error(w, "An exercise with that name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update exercise", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"exercise": ex})
+}
+
+// deleteExercise refuses to remove exercises that workouts still use.
+func deleteExercise(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var workouts, sets int
+	err := db.DB.QueryRow(`SELECT (SELECT COUNT(*) FROM workout_exercises WHERE exercise_id = ?1),
+		(SELECT COUNT(*) FROM training_sets WHERE exercise_id = ?1)`, id).Scan(&workouts, &sets)
+	if err != nil {
+		http.Error(w, "Failed to delete exercise", http.StatusInternalServerError)
+		return
+	}
+	if workouts > 0 {
+		http.Error(w, "Exercise is used by a workout", http.StatusConflict)
+		return
+	}
+	if sets > 0 {
+		http.Error(w, "Exercise has logged sets", http.StatusConflict)
+		return
+	}
+
+	res, err := db.DB.Exec(`DELETE FROM exercises WHERE id = ?`, id)
+	if err != nil {
+		http.Error(w, "Failed to delete exercise", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Exercise not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
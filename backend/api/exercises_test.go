@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func listExerciseNames(t *testing.T, router http.Handler, token, query string) []string {
+	t.Helper()
+	rr := doJSON(router, "GET", "/api/exercises"+query, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Exercises []models.Exercise `json:"exercises"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	names := []string{}
+	for _, ex := range resp.Exercises {
+		names = append(names, ex.Name)
+	}
+	return names
+}
+
+func TestExercises(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupExerciseRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	// The starter library is seeded by the schema.
+	assert.Equal(t, []string{"Barbell Curl", "Barbell Row", "Lat Pulldown", "One-arm Dumbbell Row", "Pull-up", "Rowing Machine"},
+		listExerciseNames(t, router, token, "?muscle=biceps"))
+	assert.Equal(t, []string{"Barbell Curl"}, listExerciseNames(t, router, token, "?primary_muscle=biceps"))
+	assert.Equal(t, []string{"Bulgarian Split Squat", "Walking Lunge"}, listExerciseNames(t, router, token, "?pattern=lunge"))
+	assert.Equal(t, []string{"Barbell Row"}, listExerciseNames(t, router, token, "?equipment=barbell&muscle=Upper+back&q=row"))
+
+	rr := doJSON(router, "POST", "/api/exercises", token, map[string]interface{}{
+		"name": "Cable Fly", "primary_muscles": []string{"Chest"}, "secondary_muscles": []string{"front-delts"},
+		"equipment": []string{"cable"}, "movement_pattern": "isolation",
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created struct {
+		Exercise models.Exercise `json:"exercise"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, []string{"chest"}, created.Exercise.PrimaryMuscles)
+	assert.Equal(t, []string{"front_delts"}, created.Exercise.SecondaryMuscles)
+
+	rr = doJSON(router, "POST", "/api/exercises", token, map[string]interface{}{"name": "Cable Fly", "primary_muscles": []string{"chest"}})
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = doJSON(router, "POST", "/api/exercises", token, map[string]interface{}{"name": "Wing Flap", "primary_muscles": []string{"wings"}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `unknown muscle group "wings"`)
+	rr = doJSON(router, "POST", "/api/exercises", token, map[string]interface{}{"name": "Nothing"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	path := "/api/exercises/" + created.Exercise.ID
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{
+		"name": "Single-arm Cable Fly", "primary_muscles": []string{"chest"}, "equipment": []string{"cable"}, "unilateral": true,
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Contains(t, rr.Body.String(), `"name":"Single-arm Cable Fly"`)
+	assert.Contains(t, rr.Body.String(), `"unilateral":true`)
+
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
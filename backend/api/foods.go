@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+)
+
+func SetupFoodRoutes(r chi.Router) {
+	r.Route("/api/foods", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", searchFoods)
+		r.Get("/barcode/{code}", getProduct)
+		r.Put("/barcode/{code}", overrideProduct)
+		r.Delete("/barcode/{code}", deleteProductOverride)
+		r.Get("/{id}", getFood)
+	})
+}
+
+func searchFoods(w http.ResponseWriter, r *http.Request) {
+	foods, err := nutrition.SearchFoods(r.URL.Query().Get("q"), parseLimit(r))
+	if err != nil {
+		http.Error(w, "Failed to search foods", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"foods": foods})
+}
+
+func getFood(w http.ResponseWriter, r *http.Request) {
+	food, err := nutrition.LoadFood(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Food not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"food": food})
+}
+
+// barcodeParam reads and normalises the {code} URL parameter, writing a
+// bad request when it is not a valid EAN/UPC.
+func barcodeParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	code, ok := nutrition.NormalizeBarcode(chi.URLParam(r, "code"))
+	if !ok {
+		http.Error(w, "Invalid barcode; expected an EAN-13, EAN-8 or UPC-A code", http.StatusBadRequest)
+	}
+	return code, ok
+}
+
+// getProduct looks a packaged food up by barcode, with the user's override
+// applied.
+func getProduct(w http.ResponseWriter, r *http.Request) {
+	code, ok := barcodeParam(w, r)
+	if !ok {
+		return
+	}
+	p, err := nutrition.LookupProduct(r.Header.Get("X-User-ID"), code)
+	if errors.Is(err, nutrition.ErrProductNotFound) {
+		http.Error(w, "Product not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to look up product", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"product": p})
+}
+
+// overrideProduct stores the user's corrections to a product, or the
+// product itself when the database lacks it. The body replaces any
+// earlier override.
+func overrideProduct(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	code, ok := barcodeParam(w, r)
+	if !ok {
+		return
+	}
+	var o models.ProductOverride
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&o); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	err := nutrition.SaveProductOverride(userID, code, o)
+	if errors.Is(err, nutrition.ErrInvalidOverride) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save override", http.StatusInternalServerError)
+		return
+	}
+	p, err := nutrition.LookupProduct(userID, code)
+	if err != nil {
+		http.Error(w, "Failed to look up product", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"product": p})
+}
+
+func deleteProductOverride(w http.ResponseWriter, r *http.Request) {
+	code, ok := barcodeParam(w, r)
+	if !ok {
+		return
+	}
+	found, err := nutrition.DeleteProductOverride(r.Header.Get("X-User-ID"), code)
+	if err != nil {
+		http.Error(w, "Failed to delete override", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Override not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
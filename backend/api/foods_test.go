@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestBarcodeLookup(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupFoodRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+
+	tx, _ := db.DB.Begin()
+	assert.NoError(t, nutrition.SaveProduct(tx, models.Product{
+		Barcode: "3017620422003", Name: "Nutella", Brand: "Ferrero", ServingSize: "15 g", ServingGrams: 15,
+		Per100g: models.Nutrients{Calories: 539, Protein: 6.3, Carbs: 57.5, Fat: 30.9},
+	}))
+	assert.NoError(t, tx.Commit())
+
+	var resp struct {
+		Product models.Product `json:"product"`
+	}
+	rr := doJSON(router, "GET", "/api/foods/barcode/3017620422003", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "openfoodfacts", resp.Product.Source)
+	assert.Equal(t, 539.0, resp.Product.Per100g.Calories)
+	assert.Equal(t, &models.Nutrients{Calories: 81, Protein: 0.9, Carbs: 8.6, Fat: 4.6}, resp.Product.PerServing)
+
+	rr = doJSON(router, "GET", "/api/foods/barcode/3017620422004", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "GET", "/api/foods/barcode/049000028911", token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Corrections apply to the user who made them.
+	rr = doJSON(router, "PUT", "/api/foods/barcode/3017620422003", token, map[string]interface{}{
+		"serving_grams": 20, "per_100g": map[string]float64{"protein": 6},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	resp.Product = models.Product{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, []string{"serving_grams", "per_100g.protein"}, resp.Product.Overridden)
+	assert.Equal(t, 6.0, resp.Product.Per100g.Protein)
+	assert.Equal(t, 108.0, resp.Product.PerServing.Calories)
+
+	rr = doJSON(router, "GET", "/api/foods/barcode/3017620422003", otherToken, nil)
+	resp.Product = models.Product{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Empty(t, resp.Product.Overridden)
+	assert.Equal(t, 6.3, resp.Product.Per100g.Protein)
+
+	rr = doJSON(router, "PUT", "/api/foods/barcode/3017620422003", token, map[string]interface{}{"per_100g": map[string]float64{"protein": 150}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "PUT", "/api/foods/barcode/3017620422003", token, map[string]interface{}{"per_100g": map[string]float64{"caffeine": 1}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Products missing from the dump can be added, but need a name.
+	rr = doJSON(router, "PUT", "/api/foods/barcode/049000028911", token, map[string]interface{}{"per_100g": map[string]float64{"calories": 42}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "PUT", "/api/foods/barcode/049000028911", token, map[string]interface{}{
+		"name": "Cola", "per_100g": map[string]float64{"calories": 42, "carbs": 10.6},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/foods/barcode/0049000028911", token, nil)
+	resp.Product = models.Product{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "user", resp.Product.Source)
+	assert.Equal(t, "Cola", resp.Product.Name)
+	assert.Nil(t, resp.Product.PerServing)
+
+	rr = doJSON(router, "DELETE", "/api/foods/barcode/049000028911", token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/foods/barcode/049000028911", token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "GET", "/api/foods/barcode/049000028911", token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
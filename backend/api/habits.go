@@ -0,0 +1,316 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/habits"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+// Days of progress shown with each habit in the list.
+const habitListDays = 7
+
+func SetupHabitRoutes(r chi.Router) {
+	r.Route("/api/habits", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listHabits)
+		r.Post("/", createHabit)
+		r.Get("/streaks", getStreaks)
+		r.Get("/{id}", getHabit)
+		r.Put("/{id}", updateHabit)
+		r.Delete("/{id}", deleteHabit)
+	})
+}
+
+const habitColumns = `h.id, h.name, h.entry_type, COALESCE(h.field, ''), h.aggregate, h.comparison, h.target, h.created_at`
+
+func scanHabit(row interface{ Scan(...interface{}) error }) (models.Habit, error) {
+	var h models.Habit
+	err := row.Scan(&h.ID, &h.Name, &h.EntryType, &h.Field, &h.Aggregate, &h.Comparison, &h.Target, &h.CreatedAt)
+	return h, err
+}
+
+func loadHabit(userID, id string) (models.Habit, error) {
+	return scanHabit(db.DB.QueryRow(`SELECT `+habitColumns+` FROM habits h WHERE h.id = ? AND h.user_id = ?`, id, userID))
+}
+
+func loadHabits(ctx context.Context, userID string) ([]models.Habit, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+habitColumns+` FROM habits h WHERE h.user_id = ? ORDER BY h.created_at, h.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.Habit{}
+	for rows.Next() {
+		h, err := scanHabit(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+// habitValues aggregates the user's entries of the habit's type per day,
+// in the database. Days whose entries lack the field are left out.
+func habitValues(ctx context.Context, userID string, h models.Habit) (map[string]float64, error) {
+	expr, args := `COUNT(*)`, []interface{}{}
+	switch h.Aggregate {
+	case habits.Sum, habits.Max, habits.Min, habits.Average:
+		expr = h.Aggregate + `(json_extract(j.entry_data, ?))`
+		args = append(args, "$."+h.Field)
+	}
+	args = append(args, userID, h.EntryType)
+	rows, err := db.DB.QueryContext(ctx, `SELECT j.date, `+expr+` FROM journals j WHERE j.user_id = ? AND j.type = ? GROUP BY j.date`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := map[string]float64{}
+	for rows.Next() {
+		var date string
+		var v *float64
+		if err := rows.Scan(&date, &v); err != nil {
+			return nil, err
+		}
+		if v != nil {
+			values[trimDate(date)] = *v
+		}
+	}
+	return values, rows.Err()
+}
+
+// habitProgress evaluates a habit from..to, with streaks over its whole
+// history.
+func habitProgress(ctx context.Context, userID string, h models.Habit, from, to, today time.Time) (*models.HabitProgress, error) {
+	values, err := habitValues(ctx, userID, h)
+	if err != nil {
+		return nil, err
+	}
+	var met []time.Time
+	for date, v := range values {
+		if d, err := time.Parse(dateLayout, date); err == nil && habits.Met(h, v) {
+			met = append(met, d)
+		}
+	}
+	sort.Slice(met, func(i, j int) bool { return met[i].Before(met[j]) })
+	p := habits.Evaluate(h, values, met, from, to, today)
+	return &p, nil
+}
+
+// metHabits returns the user's habits on entries of entryType that are
+// met on date, by ID.
+func metHabits(ctx context.Context, userID, entryType, date string) map[string]models.Habit {
+	list, err := loadHabits(ctx, userID)
+	if err != nil {
+		return nil
+	}
+	met := map[string]models.Habit{}
+	for _, h := range list {
+		if h.EntryType != entryType {
+			continue
+		}
+		values, err := habitValues(ctx, userID, h)
+		if err != nil {
+			continue
+		}
+		if v, ok := values[date]; ok && habits.Met(h, v) {
+			met[h.ID] = h
+		}
+	}
+	return met
+}
+
+// emitCompletedGoals sends goal.completed for the habits a journal change
+// met on date, given those met before it.
+func emitCompletedGoals(ctx context.Context, userID, entryType, date string, before map[string]models.Habit) {
+	for id, h := range metHabits(ctx, userID, entryType, date) {
+		if _, ok := before[id]; !ok {
+			webhooks.Emit(userID, webhooks.GoalCompleted, map[string]interface{}{"habit": h, "date": date})
+		}
+	}
+}
+
+// listHabits returns the user's habits with their last week of progress.
+func listHabits(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	list, err := loadHabits(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to list habits", http.StatusInternalServerError)
+		return
+	}
+	today := localToday(utils.UserLocation(userID))
+	for i := range list {
+		if list[i].Progress, err = habitProgress(r.Context(), userID, list[i], today.AddDate(0, 0, -habitListDays+1), today, today); err != nil {
+			http.Error(w, "Failed to evaluate habits", http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"habits": list})
+}
+
+// getHabit returns a habit with its day-by-day progress over ?from= and
+// ?to= (default the last twelve weeks).
+func getHabit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	h, err := loadHabit(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Habit not found", http.StatusNotFound)
+		return
+	}
+	loc := utils.UserLocation(userID)
+	from, to, err := parseAnalyticsRange(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Progress, err = habitProgress(r.Context(), userID, h, from, to, localToday(loc)); err != nil {
+		http.Error(w, "Failed to evaluate habit", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"habit": h})
+}
+
+func decodeHabit(r *http.Request) (models.Habit, error) {
+	var h models.Habit
+	if err := json.NewDecoder(r.Body).Decode(&h); err != nil {
+		return h, fmt.Errorf("Invalid payload")
+	}
+	return h, habits.Validate(&h)
+}
+
+// respondHabit writes a saved habit with its last week of progress.
+func respondHabit(ctx context.Context, w http.ResponseWriter, status int, userID, id string) {
+	h, err := loadHabit(userID, id)
+	if err != nil {
+		http.Error(w, "Habit not found", http.StatusNotFound)
+		return
+	}
+	today := localToday(utils.UserLocation(userID))
+	if h.Progress, err = habitProgress(ctx, userID, h, today.AddDate(0, 0, -habitListDays+1), today, today); err != nil {
+		http.Error(w, "Failed to evaluate habit", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, status, map[string]interface{}{"habit": h})
+}
+
+func createHabit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	h, err := decodeHabit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.ID = uuid.New().String()
+	_, err = db.DB.Exec(`INSERT INTO habits (id, user_id, name, entry_type, field, aggregate, comparison, target, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.ID, userID, h.Name, h.EntryType, utils.NullIfEmpty(h.Field), h.Aggregate, h.Comparison, h.Target, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to create habit", http.StatusInternalServerError)
+		return
+	}
+	respondHabit(r.Context(), w, http.StatusCreated, userID, h.ID)
+}
+
+func updateHabit(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadHabit(userID, id); err != nil {
+		http.Error(w, "Habit not found", http.StatusNotFound)
+		return
+	}
+	h, err := decodeHabit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err = db.DB.Exec(`UPDATE habits SET name = ?, entry_type = ?, field = ?, aggregate = ?, comparison = ?, target = ? WHERE id = ? AND user_id = ?`,
+		h.Name, h.EntryType, utils.NullIfEmpty(h.Field), h.Aggregate, h.Comparison, h.Target, id, userID)
+	if err != nil {
+		http.Error(w, "Failed to update habit", http.StatusInternalServerError)
+		return
+	}
+	respondHabit(r.Context(), w, http.StatusOK, userID, id)
+}
+
+func deleteHabit(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM habits WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete habit", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Habit not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getStreaks reports the current and longest run of days with entries of
+// each journal type, and of any type.
+func getStreaks(w http.ResponseWriter, r *http.Request) {
+	streaks, overall, err := loadStreaks(r.Context(), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to load journal", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"streaks": streaks,
+		"overall": overall,
+	})
+}
+
+func loadStreaks(ctx context.Context, userID string) ([]models.Streak, models.Streak, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT DISTINCT j.type, j.date FROM journals j WHERE j.user_id = ? ORDER BY j.type, j.date`, userID)
+	if err != nil {
+		return nil, models.Streak{}, err
+	}
+	defer rows.Close()
+
+	byType := map[string][]time.Time{}
+	var types []string
+	anyDay := map[time.Time]bool{}
+	for rows.Next() {
+		var entryType, date string
+		if err := rows.Scan(&entryType, &date); err != nil {
+			return nil, models.Streak{}, err
+		}
+		d, err := time.Parse(dateLayout, trimDate(date))
+		if err != nil {
+			continue
+		}
+		if _, ok := byType[entryType]; !ok {
+			types = append(types, entryType)
+		}
+		byType[entryType] = append(byType[entryType], d)
+		anyDay[d] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, models.Streak{}, err
+	}
+
+	today := localToday(utils.UserLocation(userID))
+	streaks := []models.Streak{}
+	for _, t := range types {
+		s := habits.Streaks(byType[t], today)
+		s.EntryType = t
+		streaks = append(streaks, s)
+	}
+	days := make([]time.Time, 0, len(anyDay))
+	for d := range anyDay {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return streaks, habits.Streaks(days, today), nil
+}
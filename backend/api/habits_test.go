@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestHabits(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	SetupHabitRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	logEntry := func(date, entryType string, data map[string]interface{}) {
+		rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"date": date, "type": entryType, "data": data})
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	logEntry("2025-03-03", "steps", map[string]interface{}{"count": 12000})
+	logEntry("2025-03-04", "steps", map[string]interface{}{"count": 5000})
+	logEntry("2025-03-04", "steps", map[string]interface{}{"count": 3000})
+	logEntry("2025-03-05", "steps", map[string]interface{}{"count": 6000})
+	logEntry("2025-03-05", "steps", map[string]interface{}{"count": 4000})
+
+	var resp struct {
+		Habit models.Habit `json:"habit"`
+	}
+	rr := doJSON(router, "POST", "/api/habits", token, map[string]interface{}{
+		"name": "10k steps", "entry_type": "steps", "field": "count", "target": 10000,
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	steps := resp.Habit
+	assert.Equal(t, "sum", steps.Aggregate)
+	assert.Equal(t, "at_least", steps.Comparison)
+	assert.Len(t, steps.Progress.Days, 7)
+
+	rr = doJSON(router, "POST", "/api/habits", token, map[string]interface{}{"name": "Sleep", "entry_type": "sleep", "field": "bedtime"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "POST", "/api/habits", token, map[string]interface{}{"name": "Sleep", "entry_type": "dreams"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/habits/"+steps.ID+"?from=2025-03-01&to=2025-03-07", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	resp.Habit = models.Habit{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	p := resp.Habit.Progress
+	var met []string
+	for _, d := range p.Days {
+		if d.Met {
+			met = append(met, d.Date)
+		}
+	}
+	assert.Equal(t, []string{"2025-03-03", "2025-03-05"}, met)
+	assert.Equal(t, 8000.0, *p.Days[3].Value)
+	assert.Nil(t, p.Days[0].Value)
+	assert.Equal(t, 0.286, p.CompletionRate)
+	assert.Equal(t, 1, p.LongestStreak)
+	assert.Equal(t, 0, p.CurrentStreak)
+
+	// Lowering the target joins the days into one streak.
+	rr = doJSON(router, "PUT", "/api/habits/"+steps.ID, token, map[string]interface{}{
+		"name": "8k steps", "entry_type": "steps", "field": "count", "target": 8000,
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/habits/"+steps.ID+"?from=2025-03-01&to=2025-03-07", token, nil)
+	resp.Habit = models.Habit{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "8k steps", resp.Habit.Name)
+	assert.Equal(t, 3, resp.Habit.Progress.LongestStreak)
+
+	rr = doJSON(router, "GET", "/api/habits", token, nil)
+	var list struct {
+		Habits []models.Habit `json:"habits"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Habits, 1)
+
+	otherToken := createTestUser(t, "user-456")
+	rr = doJSON(router, "GET", "/api/habits/"+steps.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = doJSON(router, "DELETE", "/api/habits/"+steps.ID, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/habits/"+steps.ID, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestStreaks(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	SetupHabitRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	today := time.Now().UTC()
+	daysAgo := func(n int) string { return today.AddDate(0, 0, -n).Format(dateLayout) }
+	for _, n := range []int{1, 2, 5, 6, 7} {
+		rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+			"date": daysAgo(n), "type": "note", "data": map[string]interface{}{"text": "ok"},
+		})
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": daysAgo(3), "type": "weight", "data": map[string]interface{}{"weight": 80},
+	})
+
+	rr := doJSON(router, "GET", "/api/habits/streaks", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Streaks []models.Streak `json:"streaks"`
+		Overall models.Streak   `json:"overall"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, []models.Streak{
+		{EntryType: "note", Current: 2, Longest: 3, Last: daysAgo(1)},
+		{EntryType: "weight", Current: 0, Longest: 1, Last: daysAgo(3)},
+	}, resp.Streaks)
+	assert.Equal(t, models.Streak{Current: 3, Longest: 3, Last: daysAgo(1)}, resp.Overall)
+}
+
+func TestAdherence(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	SetupScheduleRoutes(router)
+	SetupJournalRoutes(router)
+	SetupHabitRoutes(router)
+	SetupAdherenceRoutes(router)
+	token := createTestUser(t, "user-123")
+	db.DB.Exec(`UPDATE users SET age = 30, gender = 'male', height = 180, weight = 80, activity_level = 'moderate' WHERE id = 'user-123'`)
+
+	rr := doJSON(router, "POST", "/api/plans", token, map[string]interface{}{
+		"type":       "workout",
+		"start_date": "2025-03-03",
+		"content": map[string]interface{}{"days": []interface{}{
+			map[string]interface{}{"day": 1, "weekday": "monday", "title": "Upper", "exercises": []interface{}{map[string]interface{}{"name": "Bench"}}},
+			map[string]interface{}{"day": 2, "weekday": "wednesday", "title": "Lower", "exercises": []interface{}{map[string]interface{}{"name": "Squat"}}},
+		}},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/schedule?from=2025-03-03&to=2025-03-09", token, nil)
+	var sched scheduleResponse
+	json.Unmarshal(rr.Body.Bytes(), &sched)
+	if !assert.Len(t, sched.Sessions, 2) {
+		return
+	}
+	db.DB.Exec(`UPDATE scheduled_sessions SET status = 'completed' WHERE id = ?`, sched.Sessions[0].ID)
+
+	logEntry := func(date, entryType string, data map[string]interface{}) {
+		rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"date": date, "type": entryType, "data": data})
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	logEntry("2025-03-03", "food", map[string]interface{}{"name": "Meals", "calories": 2759})
+	logEntry("2025-03-04", "food", map[string]interface{}{"name": "Meals", "calories": 1900})
+	logEntry("2025-03-03", "steps", map[string]interface{}{"count": 12000})
+	logEntry("2025-03-04", "steps", map[string]interface{}{"count": 8000})
+	rr = doJSON(router, "POST", "/api/habits", token, map[string]interface{}{
+		"name": "10k steps", "entry_type": "steps", "field": "count", "target": 10000,
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/adherence?from=2025-03-03&to=2025-03-09", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		CalorieTarget *float64               `json:"calorie_target"`
+		Weeks         []models.AdherenceWeek `json:"weeks"`
+		Days          []models.AdherenceDay  `json:"days"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 2759.0, *resp.CalorieTarget)
+	if !assert.Len(t, resp.Days, 7) || !assert.Len(t, resp.Weeks, 1) {
+		return
+	}
+
+	monday := resp.Days[0]
+	assert.Equal(t, 1.0, *monday.Training)
+	assert.Equal(t, 1.0, *monday.Nutrition)
+	assert.Equal(t, 100.0, *monday.Score)
+	tuesday := resp.Days[1]
+	assert.Nil(t, tuesday.Training)
+	assert.Equal(t, 0.472, *tuesday.Nutrition)
+	assert.Equal(t, 23.6, *tuesday.Score)
+	wednesday := resp.Days[2]
+	assert.Equal(t, 0.0, *wednesday.Training)
+	assert.Nil(t, wednesday.Nutrition)
+
+	week := resp.Weeks[0]
+	assert.Equal(t, "2025-03-03", week.Start)
+	assert.Equal(t, 2, week.SessionsScheduled)
+	assert.Equal(t, 1, week.SessionsCompleted)
+	assert.Equal(t, 0.5, *week.SessionRate)
+	assert.Equal(t, 2, week.DaysFoodLogged)
+	assert.Equal(t, 1, week.DaysOnCalorieTarget)
+	assert.Equal(t, 17.7, *week.AverageScore)
+
+	rr = doJSON(router, "GET", "/api/adherence?from=2025-03-09&to=2025-03-03", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/healthimport"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+const (
+	// Largest Apple Health export accepted; years of watch data run to a
+	// few hundred megabytes zipped.
+	maxHealthImportSize = 2 << 30
+	// How often a running import writes its progress.
+	importProgressInterval = time.Second
+	// Share of an import's progress spent reading the file; saving takes
+	// the rest.
+	importReadShare = 0.9
+)
+
+// Job kinds of imports; /api/imports lists every kind under "import.".
+// They keep their completed jobs, which are the import history.
+const (
+	importJobs           = "import."
+	importJobAppleHealth = "import.apple_health"
+)
+
+func init() {
+	jobs.Register(importJobAppleHealth, jobs.Handle(runHealthImport), jobs.Options{MaxAttempts: 3, KeepCompleted: true})
+}
+
+func SetupImportRoutes(r chi.Router) {
+	r.Route("/api/imports", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listImportJobs)
+		r.Post("/apple-health", importAppleHealth)
+		r.Get("/{id}", getImportJob)
+	})
+}
+
+// loadImportJob loads one of the user's imports.
+func loadImportJob(userID, id string) (models.Job, error) {
+	j, err := jobs.Get(id)
+	if err == nil && (j.UserID != userID || !strings.HasPrefix(j.Kind, importJobs)) {
+		err = sql.ErrNoRows
+	}
+	return j, err
+}
+
+func listImportJobs(w http.ResponseWriter, r *http.Request) {
+	list, err := jobs.List(jobs.Filter{UserID: r.Header.Get("X-User-ID"), Kind: importJobs, Limit: parseLimit(r)})
+	if err != nil {
+		http.Error(w, "Failed to list imports", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"imports": list})
+}
+
+func getImportJob(w http.ResponseWriter, r *http.Request) {
+	j, err := loadImportJob(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Import not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"import": j})
+}
+
+// importAppleHealth accepts export.zip from the Health app, or the
+// export.xml inside it, as the request body. The file is imported in the
+// background; the response is the job to follow at /api/imports/{id}.
+func importAppleHealth(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	f, err := os.CreateTemp("", "apple-health-*")
+	if err != nil {
+		http.Error(w, "Failed to store upload", http.StatusInternalServerError)
+		return
+	}
+	path := f.Name()
+	_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, maxHealthImportSize))
+	f.Close()
+	if err != nil {
+		os.Remove(path)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Import file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read upload", http.StatusBadRequest)
+		return
+	}
+	// Catch a zip without an export before queueing anything.
+	export, _, err := healthimport.Open(path)
+	if err != nil {
+		os.Remove(path)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	export.Close()
+
+	j, err := jobs.Enqueue(userID, importJobAppleHealth, healthImportPayload{Path: path})
+	if err != nil {
+		os.Remove(path)
+		http.Error(w, "Failed to queue import", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/api/imports/"+j.ID)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"import": j})
+}
+
+// progressReader reports how much of a stream has been read, at most once
+// per importProgressInterval.
+type progressReader struct {
+	r           io.Reader
+	read, total int64
+	last        time.Time
+	report      func(read, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if time.Since(p.last) >= importProgressInterval {
+		p.last = time.Now()
+		p.report(p.read, p.total)
+	}
+	return n, err
+}
+
+type healthImportPayload struct {
+	Path string `json:"path"`
+}
+
+// runHealthImport reads and saves an uploaded export, recording progress
+// on the job. The upload is removed once the import is done with it: on
+// success, on a broken file and after the last attempt.
+func runHealthImport(ctx context.Context, job models.Job, payload healthImportPayload) (result interface{}, err error) {
+	defer func() {
+		if ctx.Err() == nil && (err == nil || jobs.IsPermanent(err) || job.Attempts >= job.MaxAttempts) {
+			os.Remove(payload.Path)
+		}
+	}()
+	jobs.SetProgress(job.ID, "reading", 0)
+
+	f, size, err := healthimport.Open(payload.Path)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	export, err := healthimport.Parse(&progressReader{r: f, total: size, last: time.Now(), report: func(read, total int64) {
+		if total > 0 {
+			jobs.SetProgress(job.ID, "reading", importReadShare*math.Min(1, float64(read)/float64(total)))
+		}
+	}})
+	f.Close()
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	jobs.SetProgress(job.ID, "saving", importReadShare)
+	return healthimport.Save(job.UserID, export, func(done, total int) {
+		jobs.SetProgress(job.ID, "saving", importReadShare+(1-importReadShare)*float64(done)/float64(total))
+	})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+const healthExport = `<?xml version="1.0" encoding="UTF-8"?>
+<HealthData locale="en_GB">
+ <ExportDate value="2025-03-10 09:00:00 +0000"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="80.2" startDate="2025-03-03 07:15:00 +0000" endDate="2025-03-03 07:15:00 +0000"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="Watch" unit="count" value="9000" startDate="2025-03-03 08:00:00 +0000" endDate="2025-03-03 18:00:00 +0000"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="Watch" unit="count" value="12000" startDate="2025-03-04 08:00:00 +0000" endDate="2025-03-04 18:00:00 +0000"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="Watch" unit="count" value="250000" startDate="2025-03-05 08:00:00 +0000" endDate="2025-03-05 18:00:00 +0000"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" value="HKCategoryValueSleepAnalysisAsleepCore" startDate="2025-03-03 23:00:00 +0000" endDate="2025-03-04 06:30:00 +0000"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeRunning" duration="30" durationUnit="min" totalDistance="5" totalDistanceUnit="km" sourceName="Watch" startDate="2025-03-03 18:00:00 +0000" endDate="2025-03-03 18:30:00 +0000"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeFunctionalStrengthTraining" duration="40" durationUnit="min" sourceName="Watch" startDate="2025-03-04 18:00:00 +0000" endDate="2025-03-04 18:40:00 +0000"/>
+</HealthData>
+`
+
+func TestImportAppleHealth(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupImportRoutes(router)
+	SetupJournalRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	upload := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/imports/apple-health", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	var resp struct {
+		Import models.Job `json:"import"`
+	}
+	// wait runs the queued import and returns the job when it is done.
+	wait := func(id string) models.Job {
+		_, err := jobs.RunDue(context.Background())
+		require.NoError(t, err)
+		resp.Import = models.Job{}
+		rr := doJSON(router, "GET", "/api/imports/"+id, token, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.Import
+	}
+
+	rr := upload(healthExport)
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "import.apple_health", resp.Import.Kind)
+	assert.Equal(t, "queued", resp.Import.Status)
+	assert.Equal(t, "/api/imports/"+resp.Import.ID, rr.Header().Get("Location"))
+
+	job := wait(resp.Import.ID)
+	require.Equal(t, "completed", job.Status, job.Error)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.NotNil(t, job.FinishedAt)
+	var summary models.HealthImportSummary
+	json.Unmarshal(job.Result, &summary)
+	assert.Equal(t, 7, summary.Records)
+	assert.Equal(t, map[string]int{"weight": 1, "steps": 2, "sleep": 1, "cardio": 1, "strength": 1}, summary.Imported)
+	// More steps than the journal allows in a day.
+	assert.Equal(t, map[string]int{"steps": 1}, summary.Invalid)
+	assert.Empty(t, summary.Duplicates)
+
+	var count int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM journals WHERE user_id = 'user-123'`).Scan(&count)
+	assert.Equal(t, 4, count)
+	var sport, source string
+	var distance float64
+	db.DB.QueryRow(`SELECT sport, source, distance FROM cardio_sessions WHERE user_id = 'user-123'`).Scan(&sport, &source, &distance)
+	assert.Equal(t, []interface{}{"running", "apple_health", 5000.0}, []interface{}{sport, source, distance})
+	var name, started string
+	db.DB.QueryRow(`SELECT name, started_at FROM training_sessions WHERE user_id = 'user-123'`).Scan(&name, &started)
+	assert.Equal(t, "Functional Strength Training", name)
+	assert.Equal(t, "2025-03-04T18:00:00Z", started)
+
+	// Importing again adds nothing; a day logged by hand is kept too.
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-05", "type": "steps", "data": map[string]interface{}{"count": 7000},
+	})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	rr = upload(healthExport)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	job = wait(resp.Import.ID)
+	require.Equal(t, "completed", job.Status, job.Error)
+	summary = models.HealthImportSummary{}
+	json.Unmarshal(job.Result, &summary)
+	assert.Empty(t, summary.Imported)
+	assert.Equal(t, map[string]int{"weight": 1, "steps": 3, "sleep": 1, "cardio": 1, "strength": 1}, summary.Duplicates)
+
+	rr = doJSON(router, "GET", "/api/imports", token, nil)
+	var list struct {
+		Imports []models.Job `json:"imports"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Imports, 2)
+
+	// A broken file fails in the background; a zip without an export is
+	// refused up front.
+	rr = upload(`<HealthData><Record`)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	job = wait(resp.Import.ID)
+	assert.Equal(t, "failed", job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Contains(t, job.Error, "invalid export.xml")
+
+	rr = upload("PK\x03\x04not really a zip")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	otherToken := createTestUser(t, "user-456")
+	rr = doJSON(router, "GET", "/api/imports/"+job.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func SetupJobRoutes(r chi.Router) {
+	r.Route("/api/jobs", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listJobs)
+		r.Get("/{id}", getJob)
+		r.Post("/{id}/retry", retryJob)
+	})
+}
+
+// loadJob loads a job the user may see: their own, or any job for admins.
+func loadJob(userID, id string) (models.Job, bool) {
+	j, err := jobs.Get(id)
+	if err != nil || (j.UserID != userID && !isAdmin(userID)) {
+		return j, false
+	}
+	return j, true
+}
+
+// listJobs returns the user's jobs, newest first. Filters: ?status= and
+// ?kind=, where a kind ending in '.' matches all kinds under it. Admins
+// can pass ?all=true to include every user's and system jobs, such as
+// failed webhook deliveries.
+func listJobs(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	f := jobs.Filter{UserID: userID, Status: r.URL.Query().Get("status"), Kind: r.URL.Query().Get("kind"), Limit: parseLimit(r)}
+	switch f.Status {
+	case "", jobs.Queued, jobs.Running, jobs.Completed, jobs.Failed:
+	default:
+		http.Error(w, "status must be queued, running, completed or failed", http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("all") == "true" {
+		if !isAdmin(userID) {
+			http.Error(w, "Only admins can list all jobs", http.StatusForbidden)
+			return
+		}
+		f.UserID = ""
+	}
+
+	list, err := jobs.List(f)
+	if err != nil {
+		http.Error(w, "Failed to list jobs", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": list})
+}
+
+func getJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := loadJob(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job": j})
+}
+
+// retryJob queues a failed job again with a fresh set of attempts.
+func retryJob(w http.ResponseWriter, r *http.Request) {
+	j, ok := loadJob(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	j, err := jobs.Retry(j.ID)
+	if errors.Is(err, jobs.ErrNotFailed) {
+		http.Error(w, "Only failed jobs can be retried", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to retry job", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"job": j})
+}
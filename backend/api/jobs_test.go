@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestJobs(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+	t.Setenv("ADMIN_EMAILS", "admin-1@example.com")
+
+	router := chi.NewRouter()
+	SetupJobRoutes(router)
+	SetupPlanRoutes(router)
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+	adminToken := createTestUser(t, "admin-1")
+
+	var resp struct {
+		Job models.Job `json:"job"`
+	}
+	get := func(tok, id string) models.Job {
+		resp.Job = models.Job{}
+		rr := doJSON(router, "GET", "/api/jobs/"+id, tok, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return resp.Job
+	}
+	run := func() {
+		_, err := jobs.RunDue(context.Background())
+		require.NoError(t, err)
+	}
+
+	// Generating a plan in the background; without a profile it fails
+	// straight away, as retrying would not help.
+	rr := doJSON(router, "POST", "/api/plans/generate/diet?async=true", token, map[string]interface{}{
+		"start_date": "2026-10-19", "slots": []string{"breakfast", "lunch", "dinner"}, "seed": 7,
+	})
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	job := resp.Job
+	assert.Equal(t, "plan.generate_diet", job.Kind)
+	assert.Equal(t, "queued", job.Status)
+	assert.Equal(t, "/api/jobs/"+job.ID, rr.Header().Get("Location"))
+	assert.NotContains(t, rr.Body.String(), "payload")
+	rr = doJSON(router, "POST", "/api/plans/generate/diet?async=true", token, map[string]interface{}{"days": 40})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	run()
+	job = get(token, job.ID)
+	assert.Equal(t, "failed", job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.NotEmpty(t, job.Error)
+	assert.NotNil(t, job.FinishedAt)
+
+	var list struct {
+		Jobs []models.Job `json:"jobs"`
+	}
+	rr = doJSON(router, "GET", "/api/jobs?status=failed", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	require.Len(t, list.Jobs, 1)
+	assert.Equal(t, job.ID, list.Jobs[0].ID)
+	rr = doJSON(router, "GET", "/api/jobs?status=lost", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Other users cannot see the job; admins can see everyone's.
+	rr = doJSON(router, "GET", "/api/jobs/"+job.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "POST", "/api/jobs/"+job.ID+"/retry", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "GET", "/api/jobs?all=true", otherToken, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, job.ID, get(adminToken, job.ID).ID)
+	list.Jobs = nil
+	rr = doJSON(router, "GET", "/api/jobs?all=true&kind=plan.", adminToken, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Jobs, 1)
+
+	// Once the profile is filled in, retrying the job builds the plan.
+	db.DB.Exec(`UPDATE users SET age = 30, gender = 'male', height = 180, weight = 80, activity_level = 'moderate' WHERE id = 'user-123'`)
+	for i := 0; i < 5; i++ {
+		doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+			"name": fmt.Sprintf("Oats %d", i), "tags": []string{"breakfast"},
+			"macros": map[string]float64{"calories": 500, "protein": 30, "carbs": 60, "fat": 15},
+		})
+		doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+			"name":   fmt.Sprintf("Lentil Curry %d", i),
+			"macros": map[string]float64{"calories": 800, "protein": 40, "carbs": 100, "fat": 25},
+		})
+	}
+	rr = doJSON(router, "POST", "/api/jobs/"+job.ID+"/retry", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "queued", resp.Job.Status)
+	assert.Zero(t, resp.Job.Attempts)
+
+	run()
+	job = get(token, job.ID)
+	require.Equal(t, "completed", job.Status, job.Error)
+	var result struct {
+		Plan    models.Plan        `json:"plan"`
+		Targets map[string]float64 `json:"targets"`
+	}
+	json.Unmarshal(job.Result, &result)
+	assert.Equal(t, "diet", result.Plan.Type)
+	assert.Equal(t, "2026-10-25", result.Plan.EndDate)
+	assert.Equal(t, 2759.0, result.Targets["calories"])
+
+	rr = doJSON(router, "POST", "/api/jobs/"+job.ID+"/retry", token, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = doJSON(router, "GET", "/api/jobs/missing", token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/journalcsv"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Largest CSV accepted by the journal importer.
+const maxJournalImportSize = 20 << 20
+
+// exportJournalCSV writes the user's entries of one ?type= as CSV, oldest
+// first, optionally limited to ?from= and ?to= dates. The columns are
+// described in package journalcsv.
+func exportJournalCSV(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	q := r.URL.Query()
+	t, ok := journal.Lookup(q.Get("type"))
+	if !ok {
+		http.Error(w, "type must be a journal type", http.StatusBadRequest)
+		return
+	}
+
+	query := `SELECT ` + journalColumns + ` FROM journals j WHERE j.user_id = ? AND j.type = ?`
+	args := []interface{}{userID, t.Name}
+	for _, p := range []struct{ param, cond string }{{"from", ` AND j.date >= ?`}, {"to", ` AND j.date <= ?`}} {
+		v := q.Get(p.param)
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid '%s' date", p.param), http.StatusBadRequest)
+			return
+		}
+		query += p.cond
+		args = append(args, v)
+	}
+	rows, err := db.DB.Query(query+` ORDER BY j.date, j.created_at`, args...)
+	if err != nil {
+		http.Error(w, "Failed to export journal entries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			http.Error(w, "Failed to export journal entries", http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	// Written to a buffer first, so a failure can still be reported.
+	var buf bytes.Buffer
+	if err := journalcsv.Write(&buf, t, entries); err != nil {
+		http.Error(w, "Failed to export journal", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="journal-%s.csv"`, t.Name))
+	w.Write(buf.Bytes())
+}
+
+func listJournalImportPresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"presets":      journalcsv.Presets(),
+		"date_formats": journalcsv.DateFormats,
+	})
+}
+
+// importJournalCSV reads journal entries from a CSV request body. The
+// columns follow ?preset= (native by default, which also needs ?type=);
+// ?date_format=, ?decimal=comma and ?weight_unit=lb adjust how values are
+// read. Each row is reported with its errors, and rows with errors are
+// skipped; with ?dry_run=true nothing is saved.
+func importJournalCSV(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	q := r.URL.Query()
+
+	name := q.Get("preset")
+	if name == "" {
+		name = journalcsv.Native
+	}
+	preset, ok := journalcsv.LookupPreset(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown preset %q", name), http.StatusBadRequest)
+		return
+	}
+	typeName := preset.Type
+	if typeName == "" {
+		typeName = q.Get("type")
+	}
+	t, ok := journal.Lookup(typeName)
+	if !ok {
+		http.Error(w, "type must be a journal type", http.StatusBadRequest)
+		return
+	}
+	switch q.Get("weight_unit") {
+	case "", "kg", "lb":
+	default:
+		http.Error(w, "weight_unit must be kg or lb", http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := journalcsv.Parse(http.MaxBytesReader(w, r.Body, maxJournalImportSize), journalcsv.Options{
+		Preset:       preset,
+		Type:         t,
+		DateFormat:   q.Get("date_format"),
+		DecimalComma: q.Get("decimal") == "comma",
+		Pounds:       q.Get("weight_unit") == "lb",
+	})
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Import file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Food rows take the nutrients of the recipe or food they name.
+	if t.Name == journal.Food {
+		for i, row := range parsed.Rows {
+			if len(row.Errors) > 0 {
+				continue
+			}
+			err := resolveFoodEntry(row.Data)
+			var verr *journal.ValidationError
+			switch {
+			case errors.As(err, &verr):
+				parsed.Rows[i].Errors = verr.Errors
+			case err != nil:
+				parsed.Rows[i].Errors = []journal.FieldError{{Message: err.Error()}}
+			}
+		}
+	}
+
+	dryRun := q.Get("dry_run") == "true"
+	imported := 0
+	if !dryRun {
+		tx, err := db.DB.Begin()
+		if err != nil {
+			http.Error(w, "Failed to import journal entries", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+		now := time.Now()
+		for i, row := range parsed.Rows {
+			if len(row.Errors) > 0 {
+				continue
+			}
+			id := uuid.New().String()
+			_, err := tx.Exec(`INSERT INTO journals (id, user_id, date, type, entry_data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				id, userID, row.Date, t.Name, encodeJSON(row.Data), now, now)
+			if err != nil {
+				http.Error(w, "Failed to import journal entries", http.StatusInternalServerError)
+				return
+			}
+			parsed.Rows[i].ID = id
+			imported++
+		}
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Failed to import journal entries", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	status := http.StatusCreated
+	switch {
+	case dryRun:
+		status = http.StatusOK
+	case imported == 0:
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"preset":   preset.Name,
+		"type":     t.Name,
+		"dry_run":  dryRun,
+		"imported": imported,
+		"columns":  parsed.Columns,
+		"unmapped": parsed.Unmapped,
+		"results":  parsed.Rows,
+	})
+}
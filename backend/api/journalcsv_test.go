@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journalcsv"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestJournalCSV(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	upload := func(query, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/journals/import"+query, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	var resp struct {
+		Type     string           `json:"type"`
+		DryRun   bool             `json:"dry_run"`
+		Imported int              `json:"imported"`
+		Unmapped []string         `json:"unmapped"`
+		Results  []journalcsv.Row `json:"results"`
+	}
+	count := func() int {
+		var n int
+		db.DB.QueryRow(`SELECT COUNT(*) FROM journals WHERE user_id = 'user-123'`).Scan(&n)
+		return n
+	}
+
+	rr := doJSON(router, "GET", "/api/journals/import/presets", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"myfitnesspal"`)
+
+	mfp := "Date,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Protein (g),Sodium (mg)\n" +
+		"2025-03-03,Breakfast,410,12,48,6,22,300\n" +
+		"2025-03-03,Lunch,-5,10,60,8,35,700\n" +
+		"2025-03-04,Dinner,780,30,70,9,50,1100\n"
+
+	// A dry run previews every row and saves nothing.
+	rr = upload("?preset=myfitnesspal&dry_run=true", mfp)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.True(t, resp.DryRun)
+	assert.Equal(t, "food", resp.Type)
+	assert.Equal(t, []string{"Sodium (mg)"}, resp.Unmapped)
+	require.Len(t, resp.Results, 3)
+	assert.Empty(t, resp.Results[0].Errors)
+	require.Len(t, resp.Results[1].Errors, 1)
+	assert.Equal(t, 3, resp.Results[1].Line)
+	assert.Equal(t, "calories", resp.Results[1].Errors[0].Field)
+	assert.Equal(t, 0, count())
+
+	resp.Results = nil
+	rr = upload("?preset=myfitnesspal", mfp)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 2, resp.Imported)
+	assert.NotEmpty(t, resp.Results[0].ID)
+	assert.Empty(t, resp.Results[1].ID)
+	assert.Equal(t, 2, count())
+
+	// Pounds with a decimal comma, in our own format.
+	rr = upload("?type=weight&decimal=comma&weight_unit=lb&date_format=eu", "date;weight;time\n05/03/2025;176,4;07:15\n")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	rr = upload("?type=weight", "date,weight\nyesterday,80\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = upload("?preset=fitbit", "date\n")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = upload("", "date,weight\n")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = upload("?type=weight", "when,weight\n2025-03-03,80\n")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/journals/export?type=food&from=2025-03-04", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "journal-food.csv")
+	lines := bytes.Split(bytes.TrimSpace(rr.Body.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	assert.Equal(t, "date,name,meal,recipe_id,servings,food_id,grams,calories,protein,carbs,fat,fiber,notes,id", string(lines[0]))
+	assert.Contains(t, string(lines[1]), "2025-03-04,MyFitnessPal nutrition dinner,dinner,,,,,780,50,70,30,9,,")
+
+	// An export imports back as it was.
+	rr = doJSON(router, "GET", "/api/journals/export?type=weight", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Regexp(t, `^date,weight,body_fat,time,id\n2025-03-05,80.01,,07:15,[0-9a-f-]{36}\n$`, rr.Body.String())
+	resp.Results = nil
+	rr = upload("?type=weight&dry_run=true", rr.Body.String())
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, 80.01, resp.Results[0].Data["weight"])
+	assert.Empty(t, resp.Unmapped)
+
+	rr = doJSON(router, "GET", "/api/journals/export?type=dream", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "GET", "/api/journals/export?type=food&to=soon", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
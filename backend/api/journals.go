@@ -0,0 +1,245 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+func SetupJournalRoutes(r chi.Router) {
+	r.Route("/api/journals", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listJournalEntries)
+		r.Post("/", createJournalEntry)
+		r.Get("/types", listJournalTypes)
+		r.Get("/export", exportJournalCSV)
+		r.Get("/import/presets", listJournalImportPresets)
+		r.Post("/import", importJournalCSV)
+		r.Get("/{id}", getJournalEntry)
+		r.Put("/{id}", updateJournalEntry)
+		r.Delete("/{id}", deleteJournalEntry)
+	})
+}
+
+const journalColumns = `j.id, j.date, j.type, COALESCE(j.entry_data, '{}'), j.created_at, j.updated_at`
+
+func scanJournalEntry(row interface{ Scan(...interface{}) error }) (models.JournalEntry, error) {
+	var e models.JournalEntry
+	var data string
+	if err := row.Scan(&e.ID, &e.Date, &e.Type, &data, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return e, err
+	}
+	e.Date = trimDate(e.Date)
+	json.Unmarshal([]byte(data), &e.Data)
+	if e.Data == nil {
+		e.Data = map[string]interface{}{}
+	}
+	return e, nil
+}
+
+func loadJournalEntry(userID, id string) (models.JournalEntry, error) {
+	return scanJournalEntry(db.DB.QueryRow(`SELECT `+journalColumns+` FROM journals j WHERE j.id = ? AND j.user_id = ?`, id, userID))
+}
+
+type journalTypeInfo struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Schema      map[string]interface{} `json:"schema"`
+}
+
+// listJournalTypes returns the registered entry types with their JSON
+// Schemas, so clients can build entry forms.
+func listJournalTypes(w http.ResponseWriter, r *http.Request) {
+	types := []journalTypeInfo{}
+	for _, t := range journal.Types() {
+		types = append(types, journalTypeInfo{Name: t.Name, Title: t.Title, Description: t.Description, Schema: t.Schema()})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"types": types})
+}
+
+// listJournalEntries returns the user's entries, newest first. Filters:
+// ?from= and ?to= dates and ?type= (repeatable or comma separated).
+func listJournalEntries(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	conds := []string{`j.user_id = ?`}
+	args := []interface{}{userID}
+	for _, p := range []struct{ param, cond string }{{"from", `j.date >= ?`}, {"to", `j.date <= ?`}} {
+		v := r.URL.Query().Get(p.param)
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid '%s' date", p.param), http.StatusBadRequest)
+			return
+		}
+		conds = append(conds, p.cond)
+		args = append(args, v)
+	}
+	if types := queryList(r, "type"); len(types) > 0 {
+		for i, name := range types {
+			t, ok := journal.Lookup(name)
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown journal type %q", name), http.StatusBadRequest)
+				return
+			}
+			types[i] = t.Name
+		}
+		cond, typeArgs := inClause("j.type", types)
+		conds = append(conds, cond)
+		args = append(args, typeArgs...)
+	}
+	args = append(args, parseLimit(r))
+
+	rows, err := db.DB.Query(`SELECT `+journalColumns+` FROM journals j WHERE `+strings.Join(conds, " AND ")+
+		` ORDER BY j.date DESC, j.created_at DESC LIMIT ?`, args...)
+	if err != nil {
+		http.Error(w, "Failed to list journal entries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			http.Error(w, "Failed to list journal entries", http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, e)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+type journalRequest struct {
+	Date string          `json:"date"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// decodeJournalEntry reads and validates an entry. The date defaults to
+// the user's today; food entries get the nutrients of what they reference.
+func decodeJournalEntry(r *http.Request, userID string) (models.JournalEntry, error) {
+	var req journalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return models.JournalEntry{}, fmt.Errorf("Invalid payload")
+	}
+	t, ok := journal.Lookup(req.Type)
+	if !ok {
+		return models.JournalEntry{}, fmt.Errorf("unknown journal type %q", req.Type)
+	}
+	e := models.JournalEntry{Type: t.Name, Date: req.Date}
+	if e.Date == "" {
+		e.Date = localToday(utils.UserLocation(userID)).Format(dateLayout)
+	} else if _, err := time.Parse(dateLayout, e.Date); err != nil {
+		return e, fmt.Errorf("invalid date; use YYYY-MM-DD")
+	}
+	data, err := t.Validate(req.Data)
+	if err != nil {
+		return e, err
+	}
+	if t.Name == journal.Food {
+		if err := resolveFoodEntry(data); err != nil {
+			return e, err
+		}
+	}
+	e.Data = data
+	return e, nil
+}
+
+// respondJournalError writes validation problems as 422 with the failing
+// fields, and anything else as a bad request.
+func respondJournalError(w http.ResponseWriter, err error) {
+	var verr *journal.ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  err.Error(),
+			"fields": verr.Errors,
+		})
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+func createJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	e, err := decodeJournalEntry(r, userID)
+	if err != nil {
+		respondJournalError(w, err)
+		return
+	}
+
+	goals := metHabits(r.Context(), userID, e.Type, e.Date)
+	e.ID = uuid.New().String()
+	now := time.Now()
+	_, err = db.DB.Exec(`INSERT INTO journals (id, user_id, date, type, entry_data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, userID, e.Date, e.Type, encodeJSON(e.Data), now, now)
+	if err != nil {
+		http.Error(w, "Failed to create journal entry", http.StatusInternalServerError)
+		return
+	}
+	saved, _ := loadJournalEntry(userID, e.ID)
+	webhooks.Emit(userID, webhooks.JournalCreated, saved)
+	emitCompletedGoals(r.Context(), userID, e.Type, e.Date, goals)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"entry": saved})
+}
+
+func getJournalEntry(w http.ResponseWriter, r *http.Request) {
+	e, err := loadJournalEntry(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entry": e})
+}
+
+// updateJournalEntry replaces an entry's date, type and data.
+func updateJournalEntry(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadJournalEntry(userID, id); err != nil {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+	e, err := decodeJournalEntry(r, userID)
+	if err != nil {
+		respondJournalError(w, err)
+		return
+	}
+
+	goals := metHabits(r.Context(), userID, e.Type, e.Date)
+	_, err = db.DB.Exec(`UPDATE journals SET date = ?, type = ?, entry_data = ?, updated_at = ? WHERE id = ? AND user_id = ?`,
+		e.Date, e.Type, encodeJSON(e.Data), time.Now(), id, userID)
+	if err != nil {
+		http.Error(w, "Failed to update journal entry", http.StatusInternalServerError)
+		return
+	}
+	emitCompletedGoals(r.Context(), userID, e.Type, e.Date, goals)
+	saved, _ := loadJournalEntry(userID, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entry": saved})
+}
+
+func deleteJournalEntry(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM journals WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete journal entry", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Journal entry not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
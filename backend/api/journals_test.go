@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestJournalEntries(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+
+	rr := doJSON(router, "GET", "/api/journals/types", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"weight"`)
+	assert.Contains(t, rr.Body.String(), `"additionalProperties":false`)
+
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-01", "type": "weight", "data": map[string]interface{}{"weight": 81.5, "time": "07:30"},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created struct {
+		Entry models.JournalEntry `json:"entry"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, "2025-03-01", created.Entry.Date)
+	assert.Equal(t, 81.5, created.Entry.Data["weight"])
+
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-02", "type": "mood", "data": map[string]interface{}{"score": 4},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	// Invalid data is rejected field by field.
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-02", "type": "weight", "data": map[string]interface{}{"weight": 5, "mood": 3},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var verr struct {
+		Error  string `json:"error"`
+		Fields []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"fields"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &verr)
+	assert.Len(t, verr.Fields, 2)
+	assert.Equal(t, "weight", verr.Fields[0].Field)
+
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"type": "dream", "data": map[string]interface{}{}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"date": "03/02/2025", "type": "note", "data": map[string]interface{}{"text": "hi"}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var list struct {
+		Entries []models.JournalEntry `json:"entries"`
+	}
+	rr = doJSON(router, "GET", "/api/journals?from=2025-03-01&to=2025-03-31", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Entries, 2)
+	assert.Equal(t, "mood", list.Entries[0].Type)
+
+	rr = doJSON(router, "GET", "/api/journals?type=weight", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Entries, 1)
+	rr = doJSON(router, "GET", "/api/journals?type=dream", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	path := "/api/journals/" + created.Entry.ID
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{
+		"date": "2025-03-01", "type": "weight", "data": map[string]interface{}{"weight": 80.9},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Contains(t, rr.Body.String(), `"weight":80.9`)
+	assert.NotContains(t, rr.Body.String(), `"time"`)
+
+	// Entries are private.
+	rr = doJSON(router, "GET", path, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "DELETE", path, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
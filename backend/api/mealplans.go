@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/planning"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Upper bound on recipes considered by the generator.
+const mealPlanCandidateLimit = 500
+
+type DietPlanRequest struct {
+	StartDate          string         `json:"start_date"`
+	Days               int            `json:"days"`
+	Slots              []string       `json:"slots"`
+	Targets            *models.Macros `json:"targets"`
+	Tolerance          float64        `json:"tolerance"`
+	NoRepeatDays       int            `json:"no_repeat_days"`
+	Tags               []string       `json:"tags"`
+	ExcludeIngredients []string       `json:"exclude_ingredients"`
+	Seed               int64          `json:"seed"`
+}
+
+// dietPlanJob generates a diet plan in the background; see
+// generateDietPlan.
+const dietPlanJob = "plan.generate_diet"
+
+func init() {
+	jobs.Register(dietPlanJob, jobs.Handle(func(ctx context.Context, job models.Job, req DietPlanRequest) (interface{}, error) {
+		result, err := buildDietPlan(job.UserID, req)
+		var perr *dietPlanError
+		if errors.As(err, &perr) && perr.status < http.StatusInternalServerError {
+			return nil, jobs.Permanent(err)
+		}
+		return result, err
+	}), jobs.Options{MaxAttempts: 3, Timeout: 5 * time.Minute})
+}
+
+// dietPlanError is a plan that could not be built, with the status to
+// respond with and, when the generator gave up, the recipes left out.
+type dietPlanError struct {
+	status   int
+	Message  string           `json:"error"`
+	Excluded []excludedRecipe `json:"excluded"`
+}
+
+func (e *dietPlanError) Error() string { return e.Message }
+
+// generateDietPlan builds and saves a meal plan. With ?async=true the plan
+// is built by a job instead, and the response is that job to follow at
+// /api/jobs/{id}; its result is what this would have responded with.
+func generateDietPlan(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	var req DietPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 1 || req.Days > 28 {
+		http.Error(w, "days must be between 1 and 28", http.StatusBadRequest)
+		return
+	}
+	if req.NoRepeatDays == 0 {
+		req.NoRepeatDays = 3
+	}
+	if req.StartDate == "" {
+		req.StartDate = localToday(utils.UserLocation(userID)).Format(dateLayout)
+	} else if _, err := time.Parse(dateLayout, req.StartDate); err != nil {
+		http.Error(w, "start_date must be YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	// Fixed up front so a retried job builds the same plan.
+	if req.Seed == 0 {
+		req.Seed = time.Now().UnixNano()
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		job, err := jobs.Enqueue(userID, dietPlanJob, req)
+		if err != nil {
+			http.Error(w, "Failed to queue plan generation", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Location", "/api/jobs/"+job.ID)
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"job": job})
+		return
+	}
+
+	result, err := buildDietPlan(userID, req)
+	var perr *dietPlanError
+	if errors.As(err, &perr) && perr.Excluded != nil {
+		writeJSON(w, perr.status, perr)
+		return
+	}
+	if errors.As(err, &perr) {
+		http.Error(w, perr.Message, perr.status)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save plan", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// buildDietPlan generates a plan for a checked request and saves it.
+// Problems with the request or the user come back as dietPlanErrors.
+func buildDietPlan(userID string, req DietPlanRequest) (map[string]interface{}, error) {
+	user, err := loadUser(userID)
+	if err != nil {
+		return nil, &dietPlanError{status: http.StatusNotFound, Message: "User not found"}
+	}
+
+	targets := req.Targets
+	if targets == nil {
+		t, err := nutrition.Targets(user)
+		if err != nil {
+			return nil, &dietPlanError{status: http.StatusUnprocessableEntity, Message: err.Error()}
+		}
+		targets = &t
+	}
+
+	start, err := time.Parse(dateLayout, req.StartDate)
+	if err != nil {
+		return nil, &dietPlanError{status: http.StatusBadRequest, Message: "start_date must be YYYY-MM-DD"}
+	}
+
+	restrictions, err := userRestrictions(user)
+	if err != nil {
+		return nil, &dietPlanError{status: http.StatusInternalServerError, Message: "Failed to load dietary restrictions"}
+	}
+
+	candidates, err := sampleRecipes(recipeFilter{Tags: req.Tags, ExcludeIngredients: req.ExcludeIngredients, UserID: userID}, mealPlanCandidateLimit, req.Seed)
+	if err != nil {
+		return nil, &dietPlanError{status: http.StatusInternalServerError, Message: "Failed to load recipes"}
+	}
+	recipes := []models.Recipe{}
+	excluded := []excludedRecipe{}
+	for _, rec := range candidates {
+		if reasons := checkRecipe(restrictions, rec); len(reasons) > 0 {
+			excluded = append(excluded, excludedRecipe{ID: rec.ID, Name: rec.Name, Reasons: reasons})
+			continue
+		}
+		recipes = append(recipes, rec)
+	}
+
+	content, reports, err := planning.GenerateMealPlan(recipes, planning.MealPlanOptions{
+		Days:         req.Days,
+		Slots:        req.Slots,
+		Targets:      *targets,
+		Tolerance:    req.Tolerance,
+		NoRepeatDays: req.NoRepeatDays,
+		Seed:         req.Seed,
+	})
+	if err != nil {
+		return nil, &dietPlanError{status: http.StatusUnprocessableEntity, Message: err.Error(), Excluded: excluded}
+	}
+
+	plan, err := insertPlan(userID, models.Plan{
+		Type:      "diet",
+		Content:   encodeJSON(content),
+		StartDate: start.Format(dateLayout),
+		EndDate:   start.AddDate(0, 0, req.Days-1).Format(dateLayout),
+	}, userID, RevisionGenerator, "Generated weekly meal plan")
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"plan":     plan,
+		"targets":  targets,
+		"report":   reports,
+		"excluded": excluded,
+	}, nil
+}
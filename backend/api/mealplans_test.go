@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestGenerateDietPlan(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	db.DB.Exec(`UPDATE users SET age = 30, gender = 'male', height = 180, weight = 80, activity_level = 'moderate' WHERE id = 'user-123'`)
+
+	for i := 0; i < 5; i++ {
+		doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+			"name": fmt.Sprintf("Oats %d", i), "tags": []string{"breakfast", "vegetarian"},
+			"macros": map[string]float64{"calories": 500, "protein": 30, "carbs": 60, "fat": 15},
+		})
+		doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+			"name": fmt.Sprintf("Lentil Curry %d", i), "tags": []string{"vegetarian"},
+			"macros": map[string]float64{"calories": 800, "protein": 40, "carbs": 100, "fat": 25},
+		})
+		doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+			"name": fmt.Sprintf("Steak %d", i), "ingredients": []string{"300 g beef"},
+			"macros": map[string]float64{"calories": 800, "protein": 70, "carbs": 10, "fat": 50},
+		})
+	}
+
+	rr := doJSON(router, "POST", "/api/plans/generate/diet", token, map[string]interface{}{
+		"start_date": "2026-10-19",
+		"slots":      []string{"breakfast", "lunch", "dinner"},
+		"tags":       []string{"vegetarian"},
+		"seed":       7,
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var resp struct {
+		Plan struct {
+			Type    string `json:"type"`
+			Content string `json:"content"`
+			EndDate string `json:"end_date"`
+		} `json:"plan"`
+		Targets map[string]float64 `json:"targets"`
+		Report  []struct {
+			Deviations map[string]map[string]float64 `json:"deviations"`
+		} `json:"report"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Equal(t, "diet", resp.Plan.Type)
+	assert.Equal(t, "2026-10-25", resp.Plan.EndDate)
+	assert.Equal(t, 2759.0, resp.Targets["calories"])
+	assert.Len(t, resp.Report, 7)
+	assert.Contains(t, resp.Report[0].Deviations, "calories")
+	assert.NotContains(t, resp.Plan.Content, "Steak")
+
+	var source string
+	db.DB.QueryRow(`SELECT source FROM plan_revisions`).Scan(&source)
+	assert.Equal(t, "generator", source)
+
+	// A vegetarian profile drops the steaks without any tag filter, and
+	// says why.
+	db.DB.Exec(`UPDATE users SET diets = '["vegetarian"]' WHERE id = 'user-123'`)
+	rr = doJSON(router, "POST", "/api/plans/generate/diet", token, map[string]interface{}{
+		"start_date": "2026-10-26",
+		"slots":      []string{"breakfast", "lunch", "dinner"},
+		"seed":       7,
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var restricted struct {
+		Plan struct {
+			Content string `json:"content"`
+		} `json:"plan"`
+		Excluded []struct {
+			Name    string `json:"name"`
+			Reasons []struct {
+				Rule string `json:"rule"`
+			} `json:"reasons"`
+		} `json:"excluded"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &restricted)
+	assert.NotContains(t, restricted.Plan.Content, "Steak")
+	assert.Len(t, restricted.Excluded, 5)
+	assert.Equal(t, "diet:vegetarian", restricted.Excluded[0].Reasons[0].Rule)
+}
+
+func TestGenerateDietPlan_RequiresProfile(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	rr := doJSON(router, "POST", "/api/plans/generate/diet", token, map[string]interface{}{})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
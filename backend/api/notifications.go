@@ -0,0 +1,256 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/notify"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webpush"
+)
+
+// Browsers a user can receive Web Push on.
+const maxPushSubscriptions = 20
+
+func SetupNotificationRoutes(r chi.Router) {
+	r.Route("/api/notifications", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listNotifications)
+		r.Post("/read-all", readAllNotifications)
+		r.Get("/settings", getNotificationSettings)
+		r.Put("/settings", updateNotificationSettings)
+		r.Get("/push/key", getPushKey)
+		r.Get("/push/subscriptions", listPushSubscriptions)
+		r.Post("/push/subscriptions", createPushSubscription)
+		r.Delete("/push/subscriptions/{id}", deletePushSubscription)
+		r.Post("/{id}/read", markNotification(true))
+		r.Post("/{id}/unread", markNotification(false))
+		r.Delete("/{id}", deleteNotification)
+	})
+}
+
+func unreadNotifications(userID string) int {
+	var n int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL`, userID).Scan(&n)
+	return n
+}
+
+// listNotifications returns the inbox, newest first, with the number of
+// unread notifications; ?unread=true leaves out those already read.
+func listNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	query := `SELECT ` + notify.Columns + ` FROM notifications n WHERE n.user_id = ?`
+	if r.URL.Query().Get("unread") == "true" {
+		query += ` AND n.read_at IS NULL`
+	}
+	rows, err := db.DB.Query(query+` ORDER BY n.created_at DESC, n.id LIMIT ?`, userID, parseLimit(r))
+	if err != nil {
+		http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Notification{}
+	for rows.Next() {
+		n, err := notify.Scan(rows)
+		if err != nil {
+			http.Error(w, "Failed to list notifications", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, n)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"notifications": list, "unread": unreadNotifications(userID)})
+}
+
+// markNotification marks a notification read or unread.
+func markNotification(read bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("X-User-ID")
+		// Marking read again keeps the time it was first read.
+		query, args := `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`,
+			[]interface{}{clock.Now(), chi.URLParam(r, "id"), userID}
+		if !read {
+			query, args = `UPDATE notifications SET read_at = NULL WHERE id = ? AND user_id = ?`, args[1:]
+		}
+		res, err := db.DB.Exec(query, args...)
+		if err != nil {
+			http.Error(w, "Failed to update notification", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		n, err := notify.Get(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"notification": n, "unread": unreadNotifications(userID)})
+	}
+}
+
+func readAllNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	res, err := db.DB.Exec(`UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL`, clock.Now(), userID)
+	if err != nil {
+		http.Error(w, "Failed to update notifications", http.StatusInternalServerError)
+		return
+	}
+	n, _ := res.RowsAffected()
+	writeJSON(w, http.StatusOK, map[string]interface{}{"marked": n, "unread": 0})
+}
+
+// deleteNotification removes a notification from the inbox; copies not
+// yet sent by email or push are dropped too.
+func deleteNotification(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM notifications WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete notification", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func getNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"settings": notify.Settings(r.Header.Get("X-User-ID"))})
+}
+
+// updateNotificationSettings sets the quiet hours; empty times clear them.
+func updateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	var s models.NotificationSettings
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	s.QuietStart = strings.TrimSpace(s.QuietStart)
+	s.QuietEnd = strings.TrimSpace(s.QuietEnd)
+	if err := notify.ValidateSettings(s); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	_, err := db.DB.Exec(`INSERT INTO notification_settings (user_id, quiet_start, quiet_end, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end, updated_at = excluded.updated_at`,
+		userID, utils.NullIfEmpty(s.QuietStart), utils.NullIfEmpty(s.QuietEnd), clock.Now())
+	if err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"settings": notify.Settings(userID)})
+}
+
+// getPushKey returns the VAPID public key browsers subscribe with.
+func getPushKey(w http.ResponseWriter, r *http.Request) {
+	keys, err := webpush.KeysFromEnv()
+	if err != nil {
+		http.Error(w, "Web Push is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"public_key": keys.Public})
+}
+
+const pushSubscriptionColumns = `s.id, s.endpoint, s.p256dh, s.auth, COALESCE(s.user_agent, ''), s.created_at`
+
+func scanPushSubscription(row interface{ Scan(...interface{}) error }) (models.PushSubscription, error) {
+	var s models.PushSubscription
+	err := row.Scan(&s.ID, &s.Endpoint, &s.P256dh, &s.Auth, &s.UserAgent, &s.CreatedAt)
+	return s, err
+}
+
+func listPushSubscriptions(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.DB.Query(`SELECT `+pushSubscriptionColumns+` FROM push_subscriptions s WHERE s.user_id = ? ORDER BY s.created_at`, r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to list subscriptions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.PushSubscription{}
+	for rows.Next() {
+		s, err := scanPushSubscription(rows)
+		if err != nil {
+			http.Error(w, "Failed to list subscriptions", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, s)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"subscriptions": list})
+}
+
+// createPushSubscription stores the subscription a browser got from
+// PushManager.subscribe, in its toJSON form. Subscribing an endpoint
+// again replaces its keys, and moves it to the current user.
+func createPushSubscription(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	var req struct {
+		Endpoint string `json:"endpoint"`
+		Keys     struct {
+			P256dh string `json:"p256dh"`
+			Auth   string `json:"auth"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	sub := webpush.Subscription{Endpoint: strings.TrimSpace(req.Endpoint), P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := sub.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var count int
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM push_subscriptions WHERE user_id = ? AND endpoint <> ?`, userID, sub.Endpoint).Scan(&count)
+	if err != nil {
+		http.Error(w, "Failed to save subscription", http.StatusInternalServerError)
+		return
+	}
+	if count >= maxPushSubscriptions {
+		http.Error(w, "Too many push subscriptions; remove an old one first", http.StatusConflict)
+		return
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 200 {
+		userAgent = userAgent[:200]
+	}
+	var id string
+	err = db.DB.QueryRow(`INSERT INTO push_subscriptions (id, user_id, endpoint, p256dh, auth, user_agent, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (endpoint) DO UPDATE SET user_id = excluded.user_id, p256dh = excluded.p256dh, auth = excluded.auth, user_agent = excluded.user_agent
+		RETURNING id`,
+		uuid.New().String(), userID, sub.Endpoint, sub.P256dh, sub.Auth, utils.NullIfEmpty(userAgent), clock.Now()).Scan(&id)
+	if err != nil {
+		http.Error(w, "Failed to save subscription", http.StatusInternalServerError)
+		return
+	}
+	s, err := scanPushSubscription(db.DB.QueryRow(`SELECT `+pushSubscriptionColumns+` FROM push_subscriptions s WHERE s.id = ?`, id))
+	if err != nil {
+		http.Error(w, "Failed to save subscription", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"subscription": s})
+}
+
+func deletePushSubscription(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM push_subscriptions WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
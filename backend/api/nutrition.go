@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupNutritionRoutes(r chi.Router) {
+	r.Route("/api/nutrition", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/daily", getDailyNutrition)
+	})
+}
+
+// resolveFoodEntry fills in the name and nutrients of a food entry that
+// references a recipe or a food. Nutrients are copied onto the entry so
+// later recipe edits do not rewrite what was eaten.
+func resolveFoodEntry(data map[string]interface{}) error {
+	var name string
+	var m models.Macros
+	if id, ok := data["recipe_id"].(string); ok {
+		rec, err := loadRecipe(id)
+		if err != nil {
+			return &journal.ValidationError{Type: journal.Food, Errors: []journal.FieldError{{Field: "recipe_id", Message: "does not match a recipe"}}}
+		}
+		// Hand-entered macros stand in when nothing could be computed.
+		m = rec.Macros
+		if rec.Nutrition != nil {
+			m = rec.Nutrition.Macros()
+		}
+		name = rec.Name
+		m = nutrition.ScaleMacros(m, data["servings"].(float64))
+	} else if id, ok := data["food_id"].(string); ok {
+		food, err := nutrition.LoadFood(id)
+		if err != nil {
+			return &journal.ValidationError{Type: journal.Food, Errors: []journal.FieldError{{Field: "food_id", Message: "does not match a food"}}}
+		}
+		name = food.Name
+		m = nutrition.ScaleMacros(food.Per100g.Macros(), data["grams"].(float64)/100)
+	} else {
+		return nil
+	}
+
+	if _, ok := data["name"]; !ok {
+		data["name"] = name
+	}
+	m = nutrition.RoundMacros(m)
+	data["calories"] = m.Calories
+	data["protein"] = m.Protein
+	data["carbs"] = m.Carbs
+	data["fat"] = m.Fat
+	data["fiber"] = m.Fiber
+	return nil
+}
+
+// loadFoodEntries returns the user's food entries from..to, oldest first.
+func loadFoodEntries(ctx context.Context, userID string, from, to time.Time) ([]models.JournalEntry, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+journalColumns+` FROM journals j WHERE j.user_id = ? AND j.type = ? AND j.date >= ? AND j.date <= ?
+		ORDER BY j.date, j.created_at`, userID, journal.Food, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.JournalEntry{}
+	for rows.Next() {
+		e, err := scanJournalEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// unassignedMeal groups food entries logged without a meal.
+const unassignedMeal = "other"
+
+// getDailyNutrition totals the food logged on ?date= (default today) by
+// meal, compares the day with the user's targets and averages the week
+// ending that day.
+func getDailyNutrition(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	date := localToday(utils.UserLocation(userID))
+	if v := r.URL.Query().Get("date"); v != "" {
+		var err error
+		if date, err = time.Parse(dateLayout, v); err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+	user, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	resp, err := dailyNutrition(r.Context(), user, date)
+	if err != nil {
+		http.Error(w, "Failed to load food log", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// dailyNutrition builds the user's nutrition summary for date.
+func dailyNutrition(ctx context.Context, user models.User, date time.Time) (models.DailyNutrition, error) {
+	weekStart := date.AddDate(0, 0, -6)
+	entries, err := loadFoodEntries(ctx, user.ID, weekStart, date)
+	if err != nil {
+		return models.DailyNutrition{}, err
+	}
+
+	day := date.Format(dateLayout)
+	order := append(append([]string{}, journal.Meals...), unassignedMeal)
+	meals := map[string]*models.MealNutrition{}
+	for _, name := range order {
+		meals[name] = &models.MealNutrition{Meal: name, Entries: []models.JournalEntry{}}
+	}
+	daily := map[string]models.Macros{}
+	for _, e := range entries {
+		m := nutrition.EntryMacros(e.Data)
+		daily[e.Date] = nutrition.AddMacros(daily[e.Date], m)
+		if e.Date != day {
+			continue
+		}
+		meal, _ := e.Data["meal"].(string)
+		if meals[meal] == nil {
+			meal = unassignedMeal
+		}
+		meals[meal].Totals = nutrition.AddMacros(meals[meal].Totals, m)
+		meals[meal].Entries = append(meals[meal].Entries, e)
+	}
+
+	resp := models.DailyNutrition{
+		Date:   day,
+		Totals: nutrition.RoundMacros(daily[day]),
+		Meals:  []models.MealNutrition{},
+	}
+	for _, name := range order {
+		m := meals[name]
+		if name == unassignedMeal && len(m.Entries) == 0 {
+			continue
+		}
+		m.Totals = nutrition.RoundMacros(m.Totals)
+		resp.Meals = append(resp.Meals, *m)
+	}
+
+	// Targets need body stats; without them only totals are reported.
+	if targets, err := nutrition.Targets(user); err == nil {
+		remaining := nutrition.Remaining(targets, resp.Totals)
+		resp.Targets, resp.Remaining = &targets, &remaining
+	}
+
+	var logged []models.Macros
+	for d := weekStart; !d.After(date); d = d.AddDate(0, 0, 1) {
+		if m, ok := daily[d.Format(dateLayout)]; ok {
+			logged = append(logged, m)
+		}
+	}
+	resp.Week = models.WeeklyNutrition{
+		From:       weekStart.Format(dateLayout),
+		To:         day,
+		DaysLogged: len(logged),
+		Average:    nutrition.Average(logged),
+	}
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestDailyNutrition(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupJournalRoutes(router)
+	SetupNutritionRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedFoods(t)
+	db.DB.Exec(`INSERT INTO recipes (id, name, ingredients, instructions, macros, tags, servings)
+		VALUES ('rec-1', 'Oat Bowl', '[]', '[]', '{"calories":400,"protein":20,"carbs":60,"fat":8,"fiber":6}', '[]', 1)`)
+
+	logFood := func(date string, data map[string]interface{}) *models.JournalEntry {
+		rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{"date": date, "type": "food", "data": data})
+		if !assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String()) {
+			return nil
+		}
+		var resp struct {
+			Entry models.JournalEntry `json:"entry"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &resp)
+		return &resp.Entry
+	}
+
+	// Referenced recipes and foods are resolved to nutrients when logged.
+	bowl := logFood("2025-03-10", map[string]interface{}{"recipe_id": "rec-1", "servings": 1.5, "meal": "breakfast"})
+	assert.Equal(t, "Oat Bowl", bowl.Data["name"])
+	assert.Equal(t, 600.0, bowl.Data["calories"])
+	chicken := logFood("2025-03-10", map[string]interface{}{"food_id": "fdc:1", "grams": 200, "meal": "lunch", "name": "Roast chicken"})
+	assert.Equal(t, "Roast chicken", chicken.Data["name"])
+	assert.Equal(t, 62.0, chicken.Data["protein"])
+	logFood("2025-03-10", map[string]interface{}{"name": "Protein bar", "calories": 200, "protein": 20})
+	logFood("2025-03-08", map[string]interface{}{"name": "Pizza", "calories": 870, "meal": "dinner"})
+	logFood("2025-03-02", map[string]interface{}{"name": "Cake", "calories": 500})
+
+	rr := doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-10", "type": "food", "data": map[string]interface{}{"recipe_id": "missing"},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "does not match a recipe")
+
+	var daily models.DailyNutrition
+	rr = doJSON(router, "GET", "/api/nutrition/daily?date=2025-03-10", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &daily)
+	assert.Equal(t, models.Macros{Calories: 1130, Protein: 112, Carbs: 90, Fat: 19.2, Fiber: 9}, daily.Totals)
+	assert.Nil(t, daily.Targets)
+	assert.Nil(t, daily.Remaining)
+
+	meals := []string{}
+	for _, m := range daily.Meals {
+		meals = append(meals, m.Meal)
+	}
+	assert.Equal(t, []string{"breakfast", "lunch", "dinner", "snack", "other"}, meals)
+	assert.Equal(t, 600.0, daily.Meals[0].Totals.Calories)
+	assert.Empty(t, daily.Meals[2].Entries)
+	assert.Equal(t, "Protein bar", daily.Meals[4].Entries[0].Data["name"])
+
+	assert.Equal(t, models.WeeklyNutrition{From: "2025-03-04", To: "2025-03-10", DaysLogged: 2,
+		Average: models.Macros{Calories: 1000, Protein: 56, Carbs: 45, Fat: 9.6, Fiber: 4.5}}, daily.Week)
+
+	// With body stats the day is measured against computed targets.
+	db.DB.Exec(`UPDATE users SET age = 30, gender = 'male', height = 180, weight = 80, activity_level = 'moderate' WHERE id = 'user-123'`)
+	rr = doJSON(router, "GET", "/api/nutrition/daily?date=2025-03-10", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &daily)
+	assert.Equal(t, 2759.0, daily.Targets.Calories)
+	assert.Equal(t, models.Macros{Calories: 1629, Protein: 16, Carbs: 299, Fat: 57.8, Fiber: 30}, *daily.Remaining)
+
+	rr = doJSON(router, "GET", "/api/nutrition/daily?date=10-03-2025", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
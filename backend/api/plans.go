@@ -0,0 +1,449 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/planning"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+// Revision sources recorded alongside every plan change.
+const (
+	RevisionManual    = "manual"
+	RevisionGenerator = "generator"
+	RevisionAdaptive  = "adaptive"
+	RevisionRollback  = "rollback"
+)
+
+func SetupPlanRoutes(r chi.Router) {
+	r.Route("/api/plans", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listPlans)
+		r.Post("/", createPlan)
+		r.Post("/generate/diet", generateDietPlan)
+		r.Get("/{id}", getPlan)
+		r.Put("/{id}", updatePlan)
+		r.Get("/{id}/revisions", listPlanRevisions)
+		r.Get("/{id}/revisions/{rev}", getPlanRevision)
+		r.Get("/{id}/diff", diffPlanRevisions)
+		r.Post("/{id}/rollback", rollbackPlan)
+		r.Get("/{id}/shopping-list", getShoppingList)
+	})
+}
+
+type PlanRequest struct {
+	Type      string          `json:"type"`
+	Content   json.RawMessage `json:"content"`
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	Status    string          `json:"status"`
+	Source    string          `json:"source"`
+	Reason    string          `json:"reason"`
+}
+
+const planColumns = `p.id, p.user_id, p.type, COALESCE(p.content, ''), COALESCE(p.start_date, ''), COALESCE(p.end_date, ''), COALESCE(p.status, 'active'),
+	(SELECT COALESCE(MAX(revision), 0) FROM plan_revisions WHERE plan_id = p.id)`
+
+func scanPlan(row interface{ Scan(...interface{}) error }) (models.Plan, error) {
+	var p models.Plan
+	err := row.Scan(&p.ID, &p.UserID, &p.Type, &p.Content, &p.StartDate, &p.EndDate, &p.Status, &p.Revision)
+	p.StartDate = trimDate(p.StartDate)
+	p.EndDate = trimDate(p.EndDate)
+	return p, err
+}
+
+// trimDate strips the time component SQLite adds when a DATE column was
+// written from a time.Time.
+func trimDate(s string) string {
+	if len(s) > 10 {
+		return s[:10]
+	}
+	return s
+}
+
+// loadPlan fetches a plan owned by userID.
+func loadPlan(userID, planID string) (models.Plan, error) {
+	return scanPlan(db.DB.QueryRow(`SELECT `+planColumns+` FROM plans p WHERE p.id = ? AND p.user_id = ?`, planID, userID))
+}
+
+// normalizePlanContent validates raw plan content and returns it compacted.
+func normalizePlanContent(raw json.RawMessage) (string, error) {
+	var content models.PlanContent
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// savePlanRevision writes content to the plan and records it as the next
+// immutable revision. It must run inside the caller's transaction so the plan
+// and its history never disagree.
+func savePlanRevision(tx *sql.Tx, planID, content, author, source, reason string) (int, error) {
+	var next int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(revision), 0) + 1 FROM plan_revisions WHERE plan_id = ?`, planID).Scan(&next); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE plans SET content = ? WHERE id = ?`, content, planID); err != nil {
+		return 0, err
+	}
+
+	_, err := tx.Exec(`INSERT INTO plan_revisions (id, plan_id, revision, content, author, source, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), planID, next, content, author, source, reason, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// insertPlan creates a plan together with its first revision.
+func insertPlan(userID string, plan models.Plan, author, source, reason string) (models.Plan, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return plan, err
+	}
+	defer tx.Rollback()
+
+	plan.ID = uuid.New().String()
+	plan.UserID = userID
+	if plan.Status == "" {
+		plan.Status = "active"
+	}
+
+	_, err = tx.Exec(`INSERT INTO plans (id, user_id, type, content, start_date, end_date, status) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		plan.ID, plan.UserID, plan.Type, plan.Content, utils.NullIfEmpty(plan.StartDate), utils.NullIfEmpty(plan.EndDate), plan.Status)
+	if err != nil {
+		return plan, err
+	}
+
+	plan.Revision, err = savePlanRevision(tx, plan.ID, plan.Content, author, source, reason)
+	if err != nil {
+		return plan, err
+	}
+	return plan, tx.Commit()
+}
+
+func listPlans(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	query := `SELECT ` + planColumns + ` FROM plans p WHERE p.user_id = ?`
+	args := []interface{}{userID}
+	if t := r.URL.Query().Get("type"); t != "" {
+		query += ` AND p.type = ?`
+		args = append(args, t)
+	}
+	if s := r.URL.Query().Get("status"); s != "" {
+		query += ` AND p.status = ?`
+		args = append(args, s)
+	}
+	query += ` ORDER BY p.start_date DESC`
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to list plans", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	plans := []models.Plan{}
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			http.Error(w, "Failed to list plans", http.StatusInternalServerError)
+			return
+		}
+		plans = append(plans, p)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"plans": plans})
+}
+
+func createPlan(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	var req PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if req.Type == "" {
+		http.Error(w, "Plan type is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Content) == 0 {
+		req.Content = json.RawMessage(`{"days":[]}`)
+	}
+	content, err := normalizePlanContent(req.Content)
+	if err != nil {
+		http.Error(w, "Invalid plan content", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := insertPlan(userID, models.Plan{
+		Type:      req.Type,
+		Content:   content,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Status:    req.Status,
+	}, userID, RevisionManual, req.Reason)
+	if err != nil {
+		http.Error(w, "Failed to create plan", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"plan": plan})
+}
+
+func getPlan(w http.ResponseWriter, r *http.Request) {
+	plan, err := loadPlan(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"plan": plan})
+}
+
+func updatePlan(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	planID := chi.URLParam(r, "id")
+
+	plan, err := loadPlan(userID, planID)
+	if err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	var req PlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	source := req.Source
+	if source == "" {
+		source = RevisionManual
+	}
+	if source != RevisionManual && source != RevisionAdaptive {
+		http.Error(w, "Source must be manual or adaptive", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to update plan", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if req.Status != "" || req.StartDate != "" || req.EndDate != "" {
+		_, err = tx.Exec(`UPDATE plans SET status = COALESCE(?, status), start_date = COALESCE(?, start_date), end_date = COALESCE(?, end_date) WHERE id = ?`,
+			utils.NullIfEmpty(req.Status), utils.NullIfEmpty(req.StartDate), utils.NullIfEmpty(req.EndDate), planID)
+		if err != nil {
+			http.Error(w, "Failed to update plan", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if len(req.Content) > 0 {
+		content, err := normalizePlanContent(req.Content)
+		if err != nil {
+			http.Error(w, "Invalid plan content", http.StatusBadRequest)
+			return
+		}
+		// Identical content is not a change and gets no revision.
+		if content != plan.Content {
+			if _, err := savePlanRevision(tx, planID, content, userID, source, req.Reason); err != nil {
+				http.Error(w, "Failed to update plan", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to update plan", http.StatusInternalServerError)
+		return
+	}
+	emitPlanUpdated(userID, planID)
+
+	getPlan(w, r)
+}
+
+func listPlanRevisions(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+	if _, err := loadPlan(r.Header.Get("X-User-ID"), planID); err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := db.DB.Query(`SELECT id, plan_id, revision, author, source, COALESCE(reason, ''), created_at FROM plan_revisions WHERE plan_id = ? ORDER BY revision DESC`, planID)
+	if err != nil {
+		http.Error(w, "Failed to list revisions", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	revisions := []models.PlanRevision{}
+	for rows.Next() {
+		var rev models.PlanRevision
+		if err := rows.Scan(&rev.ID, &rev.PlanID, &rev.Revision, &rev.Author, &rev.Source, &rev.Reason, &rev.CreatedAt); err != nil {
+			http.Error(w, "Failed to list revisions", http.StatusInternalServerError)
+			return
+		}
+		revisions = append(revisions, rev)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"revisions": revisions})
+}
+
+func loadPlanRevision(planID string, revision int) (models.PlanRevision, error) {
+	var rev models.PlanRevision
+	err := db.DB.QueryRow(`SELECT id, plan_id, revision, content, author, source, COALESCE(reason, ''), created_at FROM plan_revisions WHERE plan_id = ? AND revision = ?`, planID, revision).
+		Scan(&rev.ID, &rev.PlanID, &rev.Revision, &rev.Content, &rev.Author, &rev.Source, &rev.Reason, &rev.CreatedAt)
+	return rev, err
+}
+
+func getPlanRevision(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+	if _, err := loadPlan(r.Header.Get("X-User-ID"), planID); err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	n, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+	rev, err := loadPlanRevision(planID, n)
+	if err != nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"revision": rev})
+}
+
+func diffPlanRevisions(w http.ResponseWriter, r *http.Request) {
+	planID := chi.URLParam(r, "id")
+	plan, err := loadPlan(r.Header.Get("X-User-ID"), planID)
+	if err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	// "to" defaults to the current revision and "from" to the one before it.
+	to := plan.Revision
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid 'to' revision", http.StatusBadRequest)
+			return
+		}
+	}
+	from := to - 1
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Invalid 'from' revision", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var contents [2]models.PlanContent
+	for i, n := range []int{from, to} {
+		rev, err := loadPlanRevision(planID, n)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Revision not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to load revision", http.StatusInternalServerError)
+			return
+		}
+		if err := json.Unmarshal([]byte(rev.Content), &contents[i]); err != nil {
+			http.Error(w, "Stored revision is not valid plan content", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":    from,
+		"to":      to,
+		"changes": planning.Diff(contents[0], contents[1]),
+	})
+}
+
+func rollbackPlan(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	planID := chi.URLParam(r, "id")
+	plan, err := loadPlan(userID, planID)
+	if err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Revision int    `json:"revision"`
+		Reason   string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	target, err := loadPlanRevision(planID, req.Revision)
+	if err != nil {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	// Rolling back to the current content changes nothing.
+	if target.Content == plan.Content {
+		getPlan(w, r)
+		return
+	}
+
+	// Rolling back never rewrites history: the old content is recorded
+	// again as a new revision.
+	reason := req.Reason
+	if reason == "" {
+		reason = "Rollback to revision " + strconv.Itoa(target.Revision)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to roll back plan", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := savePlanRevision(tx, planID, target.Content, userID, RevisionRollback, reason); err != nil {
+		http.Error(w, "Failed to roll back plan", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to roll back plan", http.StatusInternalServerError)
+		return
+	}
+	emitPlanUpdated(userID, planID)
+
+	getPlan(w, r)
+}
+
+func emitPlanUpdated(userID, planID string) {
+	if plan, err := loadPlan(userID, planID); err == nil {
+		webhooks.Emit(userID, webhooks.PlanUpdated, plan)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func doJSON(router http.Handler, method, path, token string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func createTestUser(t *testing.T, userID string) string {
+	t.Helper()
+	_, err := db.DB.Exec(`INSERT INTO users (id, email) VALUES (?, ?)`, userID, userID+"@example.com")
+	assert.NoError(t, err)
+	return generateTestJWT(userID)
+}
+
+func TestPlanRevisions(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	rr := doJSON(router, "POST", "/api/plans", token, map[string]interface{}{
+		"type": "workout",
+		"content": map[string]interface{}{"days": []interface{}{
+			map[string]interface{}{"day": 1, "exercises": []interface{}{
+				map[string]interface{}{"name": "Squat", "sets": 3, "reps": "5"},
+			}},
+		}},
+		"reason": "initial",
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var created struct {
+		Plan struct {
+			ID       string `json:"id"`
+			Revision int    `json:"revision"`
+		} `json:"plan"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, 1, created.Plan.Revision)
+	planPath := "/api/plans/" + created.Plan.ID
+
+	rr = doJSON(router, "PUT", planPath, token, map[string]interface{}{
+		"content": map[string]interface{}{"days": []interface{}{
+			map[string]interface{}{"day": 1, "exercises": []interface{}{
+				map[string]interface{}{"name": "Squat", "sets": 5, "reps": "5"},
+			}},
+		}},
+		"reason": "more volume",
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = doJSON(router, "GET", planPath+"/diff?from=1&to=2", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var diff struct {
+		Changes []map[string]interface{} `json:"changes"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &diff)
+	assert.Len(t, diff.Changes, 1)
+	assert.Equal(t, "sets_changed", diff.Changes[0]["kind"])
+
+	rr = doJSON(router, "POST", planPath+"/rollback", token, map[string]interface{}{"revision": 1})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = doJSON(router, "GET", planPath+"/revisions", token, nil)
+	var revs struct {
+		Revisions []map[string]interface{} `json:"revisions"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &revs)
+	assert.Len(t, revs.Revisions, 3)
+	assert.Equal(t, "rollback", revs.Revisions[0]["source"])
+	assert.Equal(t, "more volume", revs.Revisions[1]["reason"])
+
+	rr = doJSON(router, "GET", planPath+"/diff?from=1&to=3", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &diff)
+	assert.Empty(t, diff.Changes)
+
+	// Rolling back to the content the plan already has adds no revision.
+	rr = doJSON(router, "POST", planPath+"/rollback", token, map[string]interface{}{"revision": 1})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = doJSON(router, "GET", planPath+"/revisions", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &revs)
+	assert.Len(t, revs.Revisions, 3)
+}
+
+func TestPlanNotVisibleToOtherUsers(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	owner := createTestUser(t, "owner")
+	other := createTestUser(t, "other")
+
+	rr := doJSON(router, "POST", "/api/plans", owner, map[string]interface{}{"type": "diet"})
+	var created struct {
+		Plan struct {
+			ID string `json:"id"`
+		} `json:"plan"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	rr = doJSON(router, "GET", "/api/plans/"+created.Plan.ID+"/revisions", other, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/terr0r/fitness.ai/backend/recipeimport"
+)
+
+// Largest upload accepted by the recipe importer.
+const maxImportSize = 20 << 20
+
+// importRecipes reads recipes from the request body: schema.org JSON-LD
+// (raw or inside an HTML page), a Paprika export or CSV. The format is
+// detected unless given with ?format=. With ?dry_run=true nothing is saved.
+func importRecipes(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		http.Error(w, "Import file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = recipeimport.Detect(data)
+	}
+	results, err := recipeimport.Parse(format, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	imported := 0
+	for i, res := range results {
+		if res.Error != "" || dryRun {
+			continue
+		}
+		id, err := recipeimport.Save(r.Header.Get("X-User-ID"), res.Recipe)
+		if err != nil {
+			http.Error(w, "Failed to save recipe", http.StatusInternalServerError)
+			return
+		}
+		if saved, err := loadRecipe(id); err == nil {
+			results[i].Recipe = saved
+		}
+		imported++
+	}
+
+	status := http.StatusCreated
+	switch {
+	case dryRun:
+		status = http.StatusOK
+	case imported == 0:
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"format":   format,
+		"dry_run":  dryRun,
+		"imported": imported,
+		"results":  results,
+	})
+}
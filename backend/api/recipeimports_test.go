@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func postImport(router http.Handler, token, query, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/api/recipes/import"+query, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestImportRecipes(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	csv := "name,ingredients,servings,source\n" +
+		"Lentil Soup,200 g red lentils|1 onion,4,grandma\n" +
+		",1 egg,,\n"
+
+	var resp struct {
+		Format   string `json:"format"`
+		Imported int    `json:"imported"`
+		Results  []struct {
+			Recipe   models.Recipe `json:"recipe"`
+			Unmapped []string      `json:"unmapped"`
+			Error    string        `json:"error"`
+		} `json:"results"`
+	}
+
+	rr := postImport(router, token, "?dry_run=true", csv)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "csv", resp.Format)
+	assert.Equal(t, 0, resp.Imported)
+	names, _ := listRecipeNames(t, router, token, "")
+	assert.Empty(t, names)
+
+	rr = postImport(router, token, "", csv)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 1, resp.Imported)
+	assert.NotEmpty(t, resp.Results[0].Recipe.ID)
+	assert.Equal(t, 4, resp.Results[0].Recipe.Servings)
+	assert.Contains(t, resp.Results[0].Recipe.Tags, "vegan")
+	assert.Equal(t, []string{"source"}, resp.Results[0].Unmapped)
+	assert.Equal(t, "line 3: recipe has no name", resp.Results[1].Error)
+
+	names, _ = listRecipeNames(t, router, token, "?q=lentil")
+	assert.Equal(t, []string{"Lentil Soup"}, names)
+
+	rr = postImport(router, token, "?format=jsonld", `{"@type": "Recipe", "name": "Toast", "recipeIngredient": ["1 slice bread"]}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = postImport(router, token, "?format=jsonld", `<html><body>No recipe here</body></html>`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = postImport(router, token, "", "name\n\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
@@ -0,0 +1,655 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/dietary"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func SetupRecipeRoutes(r chi.Router) {
+	r.Route("/api/recipes", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listRecipes)
+		r.Post("/", createRecipe)
+		r.Post("/import", importRecipes)
+		r.Get("/{id}", getRecipe)
+		r.Put("/{id}", updateRecipe)
+		r.Delete("/{id}", deleteRecipe)
+		r.Get("/{id}/nutrition", getRecipeNutrition)
+		r.Post("/{id}/nutrition", recomputeRecipeNutrition)
+	})
+}
+
+const recipeColumns = `r.id, r.name, COALESCE(r.description, ''), COALESCE(r.ingredients, ''), COALESCE(r.instructions, ''), COALESCE(r.macros, ''), COALESCE(r.tags, ''),
+	COALESCE(r.servings, 1), COALESCE(r.nutrition, '')`
+
+func scanRecipe(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Recipe, error) {
+	var rec models.Recipe
+	var ingredients, instructions, macros, tags, nutrients string
+	dest := append([]interface{}{&rec.ID, &rec.Name, &rec.Description, &ingredients, &instructions, &macros, &tags, &rec.Servings, &nutrients}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return rec, err
+	}
+	rec.Ingredients = decodeStringList(ingredients)
+	rec.Instructions = decodeStringList(instructions)
+	rec.Tags = decodeStringList(tags)
+	rec.Allergens = []string{}
+	for _, a := range dietary.Analyze(rec.Ingredients).Allergens() {
+		rec.Allergens = append(rec.Allergens, string(a))
+	}
+	if macros != "" {
+		json.Unmarshal([]byte(macros), &rec.Macros)
+	}
+	if nutrients != "" {
+		rec.Nutrition = &models.Nutrients{}
+		json.Unmarshal([]byte(nutrients), rec.Nutrition)
+	}
+	return rec, nil
+}
+
+// decodeStringList reads a JSON array of strings, accepting the older
+// comma-separated form as well.
+func decodeStringList(s string) []string {
+	list := []string{}
+	if s == "" {
+		return list
+	}
+	if json.Unmarshal([]byte(s), &list) == nil {
+		return list
+	}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func encodeJSON(v interface{}) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func loadRecipe(id string) (models.Recipe, error) {
+	return scanRecipe(db.DB.QueryRow(`SELECT `+recipeColumns+` FROM recipes r WHERE r.id = ?`, id))
+}
+
+// recipeFilter holds the search and filter options shared by the recipe
+// listing and anything else that picks recipes from the catalog.
+type recipeFilter struct {
+	Query              string
+	Tags               []string
+	MaxCalories        float64
+	MinProtein         float64
+	ExcludeIngredients []string
+	// UserID, if set, limits recipes to the catalog and that user's own,
+	// such as the ones they imported.
+	UserID string
+}
+
+// queryList reads a parameter given either repeatedly or comma-separated.
+func queryList(r *http.Request, key string) []string {
+	var out []string
+	for _, v := range r.URL.Query()[key] {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+func parseRecipeFilter(r *http.Request) (recipeFilter, error) {
+	f := recipeFilter{
+		Query:              strings.TrimSpace(r.URL.Query().Get("q")),
+		Tags:               queryList(r, "tags"),
+		ExcludeIngredients: queryList(r, "exclude"),
+		UserID:             r.Header.Get("X-User-ID"),
+	}
+	var err error
+	if v := r.URL.Query().Get("max_calories"); v != "" {
+		if f.MaxCalories, err = strconv.ParseFloat(v, 64); err != nil {
+			return f, fmt.Errorf("invalid max_calories")
+		}
+	}
+	if v := r.URL.Query().Get("min_protein"); v != "" {
+		if f.MinProtein, err = strconv.ParseFloat(v, 64); err != nil {
+			return f, fmt.Errorf("invalid min_protein")
+		}
+	}
+	return f, nil
+}
+
+// where returns the SQL conditions (ANDed together) and their arguments for
+// every filter except the full-text query.
+func (f recipeFilter) where() ([]string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.UserID != "" {
+		conds = append(conds, `(r.user_id IS NULL OR r.user_id = ?)`)
+		args = append(args, f.UserID)
+	}
+	for _, tag := range f.Tags {
+		conds = append(conds, `EXISTS (SELECT 1 FROM json_each(CASE WHEN json_valid(r.tags) THEN r.tags ELSE '[]' END) WHERE lower(value) = lower(?))`)
+		args = append(args, tag)
+	}
+	if f.MaxCalories > 0 {
+		conds = append(conds, `json_extract(r.macros, '$.calories') <= ?`)
+		args = append(args, f.MaxCalories)
+	}
+	if f.MinProtein > 0 {
+		conds = append(conds, `json_extract(r.macros, '$.protein') >= ?`)
+		args = append(args, f.MinProtein)
+	}
+	for _, ing := range f.ExcludeIngredients {
+		conds = append(conds, `lower(COALESCE(r.ingredients, '')) NOT LIKE ? ESCAPE '\'`)
+		args = append(args, likeContains(strings.ToLower(ing)))
+	}
+	return conds, args
+}
+
+// likeContains is a LIKE pattern, used with ESCAPE '\', matching text
+// that contains s literally.
+func likeContains(s string) string {
+	return "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s) + "%"
+}
+
+// sampleRecipes draws up to limit recipes matching the filter's
+// structured conditions at random, the same ones for the same seed; any
+// full-text query is ignored. Taking the first by name would leave large
+// catalogs planned from the start of the alphabet.
+func sampleRecipes(f recipeFilter, limit int, seed int64) ([]models.Recipe, error) {
+	conds, args := f.where()
+	query := `SELECT r.id FROM recipes r`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	rows, err := db.DB.Query(query+` ORDER BY r.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	recipes := make([]models.Recipe, 0, len(ids))
+	for _, id := range ids {
+		rec, err := loadRecipe(id)
+		if err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, rec)
+	}
+	return recipes, nil
+}
+
+// ftsQuery turns free text into an FTS5 expression matching every word as a
+// prefix. Quoting each term keeps user input from being read as FTS syntax.
+func ftsQuery(q string) string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if word != "" {
+			terms = append(terms, `"`+word+`"*`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+type recipeCursor struct {
+	Name string  `json:"n,omitempty"`
+	Rank float64 `json:"r,omitempty"`
+	ID   string  `json:"i"`
+}
+
+func (c recipeCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeRecipeCursor(s string) (recipeCursor, error) {
+	var c recipeCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+func parseLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+func listRecipes(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRecipeFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cursor *recipeCursor
+	if v := r.URL.Query().Get("cursor"); v != "" {
+		c, err := decodeRecipeCursor(v)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		cursor = &c
+	}
+	limit := parseLimit(r)
+
+	restrictions, err := requestRestrictions(r)
+	if err != nil {
+		http.Error(w, "Failed to load dietary restrictions", http.StatusInternalServerError)
+		return
+	}
+
+	conds, args := filter.where()
+	var query string
+	search := ftsQuery(filter.Query)
+	if search != "" {
+		// Name matches weigh most, then description, then ingredients.
+		const rank = `bm25(recipes_fts, 0, 10.0, 4.0, 1.0)`
+		query = `SELECT ` + recipeColumns + `, ` + rank + ` FROM recipes_fts JOIN recipes r ON r.id = recipes_fts.recipe_id`
+		conds = append([]string{`recipes_fts MATCH ?`}, conds...)
+		args = append([]interface{}{search}, args...)
+		if cursor != nil {
+			conds = append(conds, `(`+rank+` > ? OR (`+rank+` = ? AND r.id > ?))`)
+			args = append(args, cursor.Rank, cursor.Rank, cursor.ID)
+		}
+		query += ` WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY ` + rank + `, r.id`
+	} else {
+		query = `SELECT ` + recipeColumns + `, 0 FROM recipes r`
+		if cursor != nil {
+			conds = append(conds, `(r.name > ? OR (r.name = ? AND r.id > ?))`)
+			args = append(args, cursor.Name, cursor.Name, cursor.ID)
+		}
+		if len(conds) > 0 {
+			query += ` WHERE ` + strings.Join(conds, " AND ")
+		}
+		query += ` ORDER BY r.name, r.id`
+	}
+	// Fetch one extra row to learn whether another page exists. Restricted
+	// searches read on until the page is full, since rows are dropped as
+	// they are checked.
+	if restrictions.Empty() {
+		query += ` LIMIT ?`
+		args = append(args, limit+1)
+	}
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to list recipes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	recipes := []models.Recipe{}
+	excluded := []excludedRecipe{}
+	var ranks []float64
+	for len(recipes) <= limit && rows.Next() {
+		var rank float64
+		rec, err := scanRecipe(rows, &rank)
+		if err != nil {
+			http.Error(w, "Failed to list recipes", http.StatusInternalServerError)
+			return
+		}
+		if reasons := checkRecipe(restrictions, rec); len(reasons) > 0 {
+			// Past the last row of this page, exclusions belong to the next.
+			if len(recipes) < limit {
+				excluded = append(excluded, excludedRecipe{ID: rec.ID, Name: rec.Name, Reasons: reasons})
+			}
+			continue
+		}
+		recipes = append(recipes, rec)
+		ranks = append(ranks, rank)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, "Failed to list recipes", http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]interface{}{}
+	if len(recipes) > limit {
+		recipes = recipes[:limit]
+		last := recipes[limit-1]
+		next := recipeCursor{ID: last.ID}
+		if search != "" {
+			next.Rank = ranks[limit-1]
+		} else {
+			next.Name = last.Name
+		}
+		resp["next_cursor"] = next.encode()
+	}
+	resp["recipes"] = recipes
+	if !restrictions.Empty() {
+		resp["restrictions"] = restrictions
+		resp["excluded"] = excluded
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func decodeRecipe(r *http.Request) (models.Recipe, error) {
+	var rec models.Recipe
+	if err := json.NewDecoder(r.Body).Decode(&rec); err != nil {
+		return rec, fmt.Errorf("Invalid payload")
+	}
+	rec.Name = strings.TrimSpace(rec.Name)
+	if rec.Name == "" {
+		return rec, fmt.Errorf("Recipe name is required")
+	}
+	if rec.Ingredients == nil {
+		rec.Ingredients = []string{}
+	}
+	if rec.Instructions == nil {
+		rec.Instructions = []string{}
+	}
+	if rec.Tags == nil {
+		rec.Tags = []string{}
+	}
+	if rec.Servings < 0 {
+		return rec, fmt.Errorf("Servings must be positive")
+	}
+	if rec.Servings == 0 {
+		rec.Servings = 1
+	}
+	rec.Tags = dietary.ApplyTags(rec.Tags, dietary.Analyze(rec.Ingredients))
+	return rec, nil
+}
+
+// canViewRecipe reports whether the user may see a recipe: a catalog
+// recipe, one of their own, or as an admin any recipe.
+func canViewRecipe(userID, id string) (bool, error) {
+	var owner *string
+	if err := db.DB.QueryRow(`SELECT user_id FROM recipes WHERE id = ?`, id).Scan(&owner); err != nil {
+		return false, err
+	}
+	return owner == nil || *owner == userID || isAdmin(userID), nil
+}
+
+// canEditRecipe reports whether the user may change a recipe: their own,
+// or as an admin any recipe, including catalog recipes that have no owner.
+func canEditRecipe(userID, id string) (bool, error) {
+	var owner *string
+	if err := db.DB.QueryRow(`SELECT user_id FROM recipes WHERE id = ?`, id).Scan(&owner); err != nil {
+		return false, err
+	}
+	return (owner != nil && *owner == userID) || isAdmin(userID), nil
+}
+
+func createRecipe(w http.ResponseWriter, r *http.Request) {
+	rec, err := decodeRecipe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec.ID = uuid.New().String()
+
+	_, err = db.DB.Exec(`INSERT INTO recipes (id, user_id, name, description, ingredients, instructions, macros, tags, servings) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, r.Header.Get("X-User-ID"), rec.Name, rec.Description, encodeJSON(rec.Ingredients), encodeJSON(rec.Instructions), encodeJSON(rec.Macros), encodeJSON(rec.Tags), rec.Servings)
+	if err != nil {
+		http.Error(w, "Failed to create recipe", http.StatusInternalServerError)
+		return
+	}
+
+	respondRecipeWithNutrition(w, http.StatusCreated, rec.ID)
+}
+
+func getRecipe(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if ok, err := canViewRecipe(r.Header.Get("X-User-ID"), id); err != nil || !ok {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	rec, err := loadRecipe(id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+
+	resp := map[string]interface{}{"recipe": rec}
+	if restrictions, err := requestRestrictions(r); err == nil {
+		if reasons := checkRecipe(restrictions, rec); len(reasons) > 0 {
+			resp["exclusions"] = reasons
+		}
+	}
+
+	q := r.URL.Query()
+	if q.Get("servings") != "" || q.Get("target_calories") != "" || q.Get("units") != "" {
+		scaled, err := scaleRecipe(rec, q.Get("servings"), q.Get("target_calories"), q.Get("units"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp["scaled"] = scaled
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type ScaledRecipe struct {
+	Servings    int                          `json:"servings"`
+	Factor      float64                      `json:"factor"`
+	Units       string                       `json:"units,omitempty"`
+	Ingredients []nutrition.ScaledIngredient `json:"ingredients"`
+	PerServing  models.Nutrients             `json:"per_serving"`
+	Total       models.Nutrients             `json:"total"`
+}
+
+// scaleRecipe rescales a recipe to a number of servings and/or so that one
+// serving provides targetCalories. Changing servings keeps the portion size;
+// a calorie target changes it.
+func scaleRecipe(rec models.Recipe, servingsParam, targetParam, units string) (ScaledRecipe, error) {
+	if units != "" && units != nutrition.Metric && units != nutrition.Imperial {
+		return ScaledRecipe{}, fmt.Errorf("units must be metric or imperial")
+	}
+
+	// Older rows may hold zero servings, which there is no scaling from.
+	if rec.Servings <= 0 {
+		return ScaledRecipe{}, fmt.Errorf("recipe has no servings to scale from")
+	}
+	servings := rec.Servings
+	if servingsParam != "" {
+		n, err := strconv.Atoi(servingsParam)
+		if err != nil || n <= 0 {
+			return ScaledRecipe{}, fmt.Errorf("servings must be a positive integer")
+		}
+		servings = n
+	}
+
+	// Hand-entered macros stand in when nothing could be computed.
+	m := rec.Macros
+	base := models.Nutrients{Calories: m.Calories, Protein: m.Protein, Carbs: m.Carbs, Fat: m.Fat, Fiber: m.Fiber}
+	if rec.Nutrition != nil {
+		base = *rec.Nutrition
+	}
+
+	portion := 1.0
+	if targetParam != "" {
+		target, err := strconv.ParseFloat(targetParam, 64)
+		if err != nil || target <= 0 {
+			return ScaledRecipe{}, fmt.Errorf("target_calories must be a positive number")
+		}
+		if base.Calories <= 0 {
+			return ScaledRecipe{}, fmt.Errorf("recipe has no calorie information to scale against")
+		}
+		portion = target / base.Calories
+	}
+
+	factor := float64(servings) / float64(rec.Servings) * portion
+	scaled := ScaledRecipe{
+		Servings:    servings,
+		Factor:      math.Round(factor*1000) / 1000,
+		Units:       units,
+		Ingredients: make([]nutrition.ScaledIngredient, 0, len(rec.Ingredients)),
+		PerServing:  nutrition.Round(nutrition.Scale(base, portion)),
+		Total:       nutrition.Round(nutrition.Scale(base, portion*float64(servings))),
+	}
+	for _, line := range rec.Ingredients {
+		scaled.Ingredients = append(scaled.Ingredients, nutrition.ScaleIngredient(line, factor, units))
+	}
+	return scaled, nil
+}
+
+func updateRecipe(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok, err := canEditRecipe(r.Header.Get("X-User-ID"), id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "Only the recipe's owner can change it", http.StatusForbidden)
+		return
+	}
+
+	rec, err := decodeRecipe(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rec.ID = id
+
+	_, err = db.DB.Exec(`UPDATE recipes SET name = ?, description = ?, ingredients = ?, instructions = ?, macros = ?, tags = ?, servings = ?, nutrition = NULL WHERE id = ?`,
+		rec.Name, rec.Description, encodeJSON(rec.Ingredients), encodeJSON(rec.Instructions), encodeJSON(rec.Macros), encodeJSON(rec.Tags), rec.Servings, id)
+	if err != nil {
+		http.Error(w, "Failed to update recipe", http.StatusInternalServerError)
+		return
+	}
+
+	respondRecipeWithNutrition(w, http.StatusOK, id)
+}
+
+func deleteRecipe(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok, err := canEditRecipe(r.Header.Get("X-User-ID"), id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "Only the recipe's owner can delete it", http.StatusForbidden)
+		return
+	}
+	if _, err := db.DB.Exec(`DELETE FROM recipe_ingredients WHERE recipe_id = ?`, id); err != nil {
+		http.Error(w, "Failed to delete recipe", http.StatusInternalServerError)
+		return
+	}
+	if _, err := db.DB.Exec(`DELETE FROM recipes WHERE id = ?`, id); err != nil {
+		http.Error(w, "Failed to delete recipe", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondRecipeWithNutrition recomputes a recipe's nutrition from its
+// ingredients and writes the recipe together with the lines that could not
+// be matched to the food database.
+func respondRecipeWithNutrition(w http.ResponseWriter, status int, id string) {
+	_, parsed, err := nutrition.RecomputeRecipe(id)
+	if err != nil {
+		http.Error(w, "Failed to compute recipe nutrition", http.StatusInternalServerError)
+		return
+	}
+	rec, err := loadRecipe(id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, status, map[string]interface{}{
+		"recipe":     rec,
+		"unresolved": unresolvedIngredients(parsed),
+	})
+}
+
+func unresolvedIngredients(parsed []models.RecipeIngredient) []string {
+	list := []string{}
+	for _, ri := range parsed {
+		if !ri.Resolved {
+			list = append(list, ri.Raw)
+		}
+	}
+	return list
+}
+
+func getRecipeNutrition(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if ok, err := canViewRecipe(r.Header.Get("X-User-ID"), id); err != nil || !ok {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	rec, err := loadRecipe(id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	ingredients, err := nutrition.LoadRecipeIngredients(rec.ID)
+	if err != nil {
+		http.Error(w, "Failed to load ingredients", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"servings":    rec.Servings,
+		"per_serving": rec.Nutrition,
+		"ingredients": ingredients,
+		"unresolved":  unresolvedIngredients(ingredients),
+	})
+}
+
+func recomputeRecipeNutrition(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok, err := canEditRecipe(r.Header.Get("X-User-ID"), id)
+	if err != nil {
+		http.Error(w, "Recipe not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "Only the recipe's owner can change it", http.StatusForbidden)
+		return
+	}
+	respondRecipeWithNutrition(w, http.StatusOK, id)
+}
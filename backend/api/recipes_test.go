@@ -0,0 +1,365 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+type recipeListResponse struct {
+	Recipes []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"recipes"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func seedRecipes(t *testing.T, router http.Handler, token string) {
+	t.Helper()
+	recipes := []map[string]interface{}{
+		{"name": "Chicken Rice Bowl", "description": "Simple meal prep", "ingredients": []string{"200 g chicken breast", "150 g rice"}, "macros": map[string]float64{"calories": 550, "protein": 48}, "tags": []string{"high-protein"}},
+		{"name": "Peanut Noodles", "description": "Quick weeknight noodles", "ingredients": []string{"100 g noodles", "2 tbsp peanut butter"}, "macros": map[string]float64{"calories": 650, "protein": 20}, "tags": []string{"vegan"}},
+		{"name": "Tofu Scramble", "description": "Breakfast with chicken-style seasoning", "ingredients": []string{"200 g tofu", "1 tsp turmeric"}, "macros": map[string]float64{"calories": 300, "protein": 25}, "tags": []string{"vegan", "high-protein"}},
+	}
+	for _, rec := range recipes {
+		rr := doJSON(router, "POST", "/api/recipes", token, rec)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	}
+}
+
+func listRecipeNames(t *testing.T, router http.Handler, token, query string) ([]string, string) {
+	t.Helper()
+	rr := doJSON(router, "GET", "/api/recipes"+query, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp recipeListResponse
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	names := []string{}
+	for _, r := range resp.Recipes {
+		names = append(names, r.Name)
+	}
+	return names, resp.NextCursor
+}
+
+func TestRecipeFilters(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedRecipes(t, router, token)
+
+	names, _ := listRecipeNames(t, router, token, "?tags=vegan,high-protein")
+	assert.Equal(t, []string{"Tofu Scramble"}, names)
+
+	names, _ = listRecipeNames(t, router, token, "?max_calories=600&min_protein=30")
+	assert.Equal(t, []string{"Chicken Rice Bowl"}, names)
+
+	names, _ = listRecipeNames(t, router, token, "?exclude=peanut")
+	assert.Equal(t, []string{"Chicken Rice Bowl", "Tofu Scramble"}, names)
+
+	// Wildcards are matched literally rather than excluding everything.
+	names, _ = listRecipeNames(t, router, token, "?exclude=%25")
+	assert.Len(t, names, 3)
+	names, _ = listRecipeNames(t, router, token, "?exclude=_")
+	assert.Len(t, names, 3)
+}
+
+func TestRecipeSearchRanksNameMatchesFirst(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedRecipes(t, router, token)
+
+	names, _ := listRecipeNames(t, router, token, "?q=chick")
+	assert.Equal(t, []string{"Chicken Rice Bowl", "Tofu Scramble"}, names)
+
+	names, _ = listRecipeNames(t, router, token, `?q="noodle`)
+	assert.Equal(t, []string{"Peanut Noodles"}, names)
+}
+
+func TestRecipeCursorPagination(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedRecipes(t, router, token)
+
+	names, cursor := listRecipeNames(t, router, token, "?limit=2")
+	assert.Equal(t, []string{"Chicken Rice Bowl", "Peanut Noodles"}, names)
+	assert.NotEmpty(t, cursor)
+
+	names, cursor = listRecipeNames(t, router, token, "?limit=2&cursor="+cursor)
+	assert.Equal(t, []string{"Tofu Scramble"}, names)
+	assert.Empty(t, cursor)
+}
+
+func TestRecipeCRUD(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+	adminToken := createTestUser(t, "admin-1")
+	t.Setenv("ADMIN_EMAILS", "admin-1@example.com")
+
+	rr := doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{"description": "no name"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{"name": "Porridge", "ingredients": []string{"50 g oats"}})
+	var created struct {
+		Recipe struct {
+			ID string `json:"id"`
+		} `json:"recipe"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	path := "/api/recipes/" + created.Recipe.ID
+
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{"name": "Overnight Oats", "ingredients": []string{"50 g oats", "100 ml milk"}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Only the owner, or an admin, can change a recipe.
+	rr = doJSON(router, "PUT", path, otherToken, map[string]interface{}{"name": "Mine Now"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = doJSON(router, "DELETE", path, otherToken, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = doJSON(router, "POST", path+"/nutrition", otherToken, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = doJSON(router, "PUT", path, adminToken, map[string]interface{}{"name": "Overnight Oats", "ingredients": []string{"50 g oats", "100 ml milk"}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	names, _ := listRecipeNames(t, router, token, "?q=milk")
+	assert.Equal(t, []string{"Overnight Oats"}, names)
+	// Other users see the catalog but not someone else's recipes.
+	db.DB.Exec(`INSERT INTO recipes (id, name, ingredients) VALUES ('catalog-1', 'Catalog Oats', '["50 g oats"]')`)
+	names, _ = listRecipeNames(t, router, otherToken, "?q=oats")
+	assert.Equal(t, []string{"Catalog Oats"}, names)
+	names, _ = listRecipeNames(t, router, otherToken, "")
+	assert.Equal(t, []string{"Catalog Oats"}, names)
+	rr = doJSON(router, "GET", path, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "GET", path+"/nutrition", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "GET", path, adminToken, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	names, _ = listRecipeNames(t, router, token, "?q=oats")
+	assert.Equal(t, []string{"Catalog Oats"}, names)
+}
+
+func seedFoods(t *testing.T) {
+	t.Helper()
+	tx, _ := db.DB.Begin()
+	foods := []models.Food{
+		{ID: "fdc:1", Name: "Chicken, broilers or fryers, breast, meat only, cooked, roasted", Per100g: models.Nutrients{Calories: 165, Protein: 31, Fat: 3.6}},
+		{ID: "fdc:2", Name: "Rice, white, long-grain, cooked", Per100g: models.Nutrients{Calories: 130, Protein: 2.7, Carbs: 28}, Portions: []models.FoodPortion{{Unit: "cup", Grams: 158}}},
+		{ID: "fdc:3", Name: "Egg, whole, raw, fresh", Per100g: models.Nutrients{Calories: 143, Protein: 12.6, Fat: 9.5}, Portions: []models.FoodPortion{{Unit: "large", Grams: 50}}},
+	}
+	for _, f := range foods {
+		assert.NoError(t, nutrition.SaveFood(tx, f))
+	}
+	assert.NoError(t, tx.Commit())
+}
+
+func TestRecipeNutritionIsComputed(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedFoods(t)
+
+	rr := doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+		"name":        "Egg Fried Rice",
+		"servings":    2,
+		"ingredients": []string{"200 g chicken breast", "1 cup cooked rice", "2 eggs", "1 tsp gochujang"},
+		"macros":      map[string]float64{"calories": 9999},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var resp struct {
+		Recipe struct {
+			ID        string             `json:"id"`
+			Macros    map[string]float64 `json:"macros"`
+			Nutrition map[string]float64 `json:"nutrition"`
+		} `json:"recipe"`
+		Unresolved []string `json:"unresolved"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	// With a line unresolved the hand-entered macros are kept.
+	assert.Nil(t, resp.Recipe.Nutrition)
+	assert.Equal(t, 9999.0, resp.Recipe.Macros["calories"])
+	assert.Equal(t, []string{"1 tsp gochujang"}, resp.Unresolved)
+
+	rr = doJSON(router, "PUT", "/api/recipes/"+resp.Recipe.ID, token, map[string]interface{}{
+		"name":        "Egg Fried Rice",
+		"servings":    2,
+		"ingredients": []string{"200 g chicken breast", "1 cup cooked rice", "2 eggs"},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	// (330 + 205.4 + 143) / 2 servings
+	assert.Equal(t, 339.0, resp.Recipe.Nutrition["calories"])
+	assert.Equal(t, 339.0, resp.Recipe.Macros["calories"])
+	assert.Empty(t, resp.Unresolved)
+
+	rr = doJSON(router, "GET", "/api/recipes/"+resp.Recipe.ID+"/nutrition", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var breakdown struct {
+		Ingredients []struct {
+			FoodID string  `json:"food_id"`
+			Grams  float64 `json:"grams"`
+		} `json:"ingredients"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &breakdown)
+	assert.Len(t, breakdown.Ingredients, 3)
+	assert.Equal(t, "fdc:3", breakdown.Ingredients[2].FoodID)
+	assert.Equal(t, 100.0, breakdown.Ingredients[2].Grams)
+}
+
+func TestRecipeScaling(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	rr := doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+		"name":        "Pancakes",
+		"servings":    2,
+		"ingredients": []string{"1 cup flour", "1 egg", "250 ml milk"},
+		"macros":      map[string]float64{"calories": 400, "protein": 15},
+	})
+	var created struct {
+		Recipe struct {
+			ID string `json:"id"`
+		} `json:"recipe"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	path := "/api/recipes/" + created.Recipe.ID
+
+	var resp struct {
+		Scaled ScaledRecipe `json:"scaled"`
+	}
+
+	rr = doJSON(router, "GET", path+"?servings=4", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 2.0, resp.Scaled.Factor)
+	assert.Equal(t, "2 cup flour", resp.Scaled.Ingredients[0].Display)
+	assert.Equal(t, "2 eggs", resp.Scaled.Ingredients[1].Display)
+	assert.True(t, resp.Scaled.Ingredients[1].NonLinear)
+	assert.Equal(t, 400.0, resp.Scaled.PerServing.Calories)
+	assert.Equal(t, 1600.0, resp.Scaled.Total.Calories)
+
+	rr = doJSON(router, "GET", path+"?servings=1&target_calories=600&units=metric", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, 0.75, resp.Scaled.Factor)
+	assert.Equal(t, "175 ml flour", resp.Scaled.Ingredients[0].Display)
+	assert.True(t, resp.Scaled.Ingredients[1].NonLinear)
+	assert.Equal(t, 600.0, resp.Scaled.PerServing.Calories)
+	assert.Equal(t, 22.5, resp.Scaled.PerServing.Protein)
+
+	rr = doJSON(router, "GET", path+"?servings=0", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// A stored recipe without servings cannot be scaled.
+	_, err := db.DB.Exec(`UPDATE recipes SET servings = 0 WHERE id = ?`, created.Recipe.ID)
+	require.NoError(t, err)
+	rr = doJSON(router, "GET", path+"?servings=4", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{"name": "Pancakes", "servings": -2})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestRecipeDietaryRestrictions(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupUserRoutes(router)
+	SetupRecipeRoutes(router)
+	token := createTestUser(t, "user-123")
+	seedRecipes(t, router, token)
+
+	// Tags are derived from the ingredients, replacing wrong hand-set ones.
+	rr := doJSON(router, "GET", "/api/recipes?tags=gluten-free", token, nil)
+	var list struct {
+		Recipes []models.Recipe `json:"recipes"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Recipes, 2)
+	assert.Equal(t, []string{"high-protein", "gluten-free", "dairy-free"}, list.Recipes[0].Tags)
+	assert.Equal(t, []string{"soy"}, list.Recipes[1].Allergens)
+
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"allergens": []string{"kryptonite"}})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{
+		"diets": []string{"Vegetarian"}, "allergens": []string{"peanut"},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"diets":["vegetarian"],"allergens":["peanuts"]`)
+
+	// Omitting the fields leaves them alone.
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam"})
+	assert.Contains(t, rr.Body.String(), `"allergens":["peanuts"]`)
+
+	rr = doJSON(router, "GET", "/api/recipes", token, nil)
+	var resp struct {
+		Recipes  []models.Recipe `json:"recipes"`
+		Excluded []struct {
+			ID      string `json:"id"`
+			Name    string `json:"name"`
+			Reasons []struct {
+				Rule   string `json:"rule"`
+				Reason string `json:"reason"`
+			} `json:"reasons"`
+		} `json:"excluded"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Len(t, resp.Recipes, 1)
+	assert.Equal(t, "Tofu Scramble", resp.Recipes[0].Name)
+	assert.Len(t, resp.Excluded, 2)
+	assert.Equal(t, "Chicken Rice Bowl", resp.Excluded[0].Name)
+	assert.Equal(t, "diet:vegetarian", resp.Excluded[0].Reasons[0].Rule)
+	assert.Equal(t, `not vegetarian: "200 g chicken breast" contains meat (chicken)`, resp.Excluded[0].Reasons[0].Reason)
+	assert.Equal(t, "allergen:peanuts", resp.Excluded[1].Reasons[0].Rule)
+
+	// Paging fills each page with allowed recipes only.
+	names, next := listRecipeNames(t, router, token, "?limit=1")
+	assert.Equal(t, []string{"Tofu Scramble"}, names)
+	assert.Empty(t, next)
+
+	names, _ = listRecipeNames(t, router, token, "?restrictions=off")
+	assert.Len(t, names, 3)
+
+	rr = doJSON(router, "GET", "/api/recipes/"+resp.Excluded[1].ID, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"exclusions":[{"rule":"allergen:peanuts"`)
+}
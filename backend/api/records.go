@@ -0,0 +1,255 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupRecordRoutes(r chi.Router) {
+	r.Route("/api/records", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", getRecords)
+		r.Get("/events", listRecordEvents)
+	})
+}
+
+func userFormula(userID string) string {
+	var formula string
+	db.DB.QueryRow(`SELECT COALESCE(e1rm_formula, '') FROM users WHERE id = ?`, userID).Scan(&formula)
+	if formula == "" {
+		return training.DefaultFormula
+	}
+	return formula
+}
+
+// inClause returns "col IN (?, ...)" with its arguments, or "1" for an
+// empty list meaning no restriction.
+func inClause(column string, values []string) (string, []interface{}) {
+	if len(values) == 0 {
+		return "1", nil
+	}
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return column + ` IN (` + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + `)`, args
+}
+
+// loadHistory returns every set the user logged for the given exercises,
+// or for all exercises when none are given.
+func loadHistory(userID string, exerciseIDs []string) ([]training.HistorySet, error) {
+	cond, args := inClause("t.exercise_id", exerciseIDs)
+	rows, err := db.DB.Query(`SELECT t.id, t.exercise_id, t.position, COALESCE(t.reps, 0), COALESCE(t.load, 0), t.warmup, s.id, s.started_at
+		FROM training_sets t JOIN training_sessions s ON s.id = t.session_id
+		WHERE s.user_id = ? AND `+cond, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []training.HistorySet
+	for rows.Next() {
+		var h training.HistorySet
+		var started string
+		if err := rows.Scan(&h.ID, &h.ExerciseID, &h.Position, &h.Reps, &h.Load, &h.Warmup, &h.SessionID, &started); err != nil {
+			return nil, err
+		}
+		h.At, _ = time.Parse(time.RFC3339, started)
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+func recordEventID(userID string, e models.RecordEvent) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(userID+"|"+e.Kind+"|"+e.SetID)).String()
+}
+
+// refreshRecords rebuilds the stored PR events for the given exercises (all
+// when none are given) from the logged sets and returns the events that
+// did not exist before, i.e. the records just set. The first results for
+// an exercise are stored as its records but not returned, as they beat
+// nothing.
+func refreshRecords(userID string, exerciseIDs []string) ([]models.RecordEvent, error) {
+	history, err := loadHistory(userID, exerciseIDs)
+	if err != nil {
+		return nil, err
+	}
+	events := training.Records(history, userFormula(userID))
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	cond, args := inClause("exercise_id", exerciseIDs)
+	rows, err := tx.Query(`SELECT id FROM pr_events WHERE user_id = ? AND `+cond, append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	stale := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stale[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	created := []models.RecordEvent{}
+	for _, e := range events {
+		e.ID = recordEventID(userID, e)
+		if stale[e.ID] {
+			delete(stale, e.ID)
+		} else if e.Previous > 0 {
+			created = append(created, e)
+		}
+		_, err := tx.Exec(`INSERT INTO pr_events (id, user_id, exercise_id, kind, value, previous, reps, load, session_id, set_id, achieved_at, formula)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET value = excluded.value, previous = excluded.previous, reps = excluded.reps, load = excluded.load,
+				achieved_at = excluded.achieved_at, formula = excluded.formula`,
+			e.ID, userID, e.ExerciseID, e.Kind, e.Value, e.Previous, e.Reps, e.Load, e.SessionID, e.SetID, utils.FormatTime(e.AchievedAt), utils.NullIfEmpty(e.Formula))
+		if err != nil {
+			return nil, err
+		}
+	}
+	for id := range stale {
+		if _, err := tx.Exec(`DELETE FROM pr_events WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	return created, tx.Commit()
+}
+
+const recordEventColumns = `p.id, p.exercise_id, e.name, p.kind, p.value, p.previous, p.reps, p.load, p.session_id, p.set_id, p.achieved_at, COALESCE(p.formula, '')`
+
+func scanRecordEvent(row interface{ Scan(...interface{}) error }) (models.RecordEvent, error) {
+	var e models.RecordEvent
+	var achieved string
+	err := row.Scan(&e.ID, &e.ExerciseID, &e.ExerciseName, &e.Kind, &e.Value, &e.Previous, &e.Reps, &e.Load, &e.SessionID, &e.SetID, &achieved, &e.Formula)
+	e.AchievedAt, _ = time.Parse(time.RFC3339, achieved)
+	return e, err
+}
+
+type exerciseRecords struct {
+	ExerciseID   string                        `json:"exercise_id"`
+	ExerciseName string                        `json:"exercise_name"`
+	Best         map[string]models.RecordEvent `json:"best"`
+	History      []models.RecordEvent          `json:"history"`
+}
+
+// getRecords returns the current bests and record history per exercise.
+// Stored events use the user's e1RM formula; ?formula= recomputes them with
+// another one without storing anything.
+func getRecords(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	exerciseIDs := queryList(r, "exercise_id")
+
+	formula := userFormula(userID)
+	var events []models.RecordEvent
+	if v := r.URL.Query().Get("formula"); v != "" && training.Key(v) != formula {
+		f, err := training.ParseFormula(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		formula = f
+		history, err := loadHistory(userID, exerciseIDs)
+		if err != nil {
+			http.Error(w, "Failed to load records", http.StatusInternalServerError)
+			return
+		}
+		events = training.Records(history, formula)
+		names := map[string]string{}
+		for i := range events {
+			id := events[i].ExerciseID
+			if _, ok := names[id]; !ok {
+				ex, _ := loadExercise(id)
+				names[id] = ex.Name
+			}
+			events[i].ExerciseName = names[id]
+		}
+	} else {
+		cond, args := inClause("p.exercise_id", exerciseIDs)
+		rows, err := db.DB.Query(`SELECT `+recordEventColumns+` FROM pr_events p JOIN exercises e ON e.id = p.exercise_id
+			WHERE p.user_id = ? AND `+cond+` ORDER BY p.achieved_at`, append([]interface{}{userID}, args...)...)
+		if err != nil {
+			http.Error(w, "Failed to load records", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			e, err := scanRecordEvent(rows)
+			if err != nil {
+				http.Error(w, "Failed to load records", http.StatusInternalServerError)
+				return
+			}
+			events = append(events, e)
+		}
+	}
+
+	byExercise := map[string]*exerciseRecords{}
+	list := []*exerciseRecords{}
+	for _, e := range events {
+		rec, ok := byExercise[e.ExerciseID]
+		if !ok {
+			rec = &exerciseRecords{ExerciseID: e.ExerciseID, ExerciseName: e.ExerciseName, Best: map[string]models.RecordEvent{}, History: []models.RecordEvent{}}
+			byExercise[e.ExerciseID] = rec
+			list = append(list, rec)
+		}
+		rec.History = append(rec.History, e)
+		// Events come in order, so the last of each kind is the best.
+		rec.Best[e.Kind] = e
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ExerciseName < list[j].ExerciseName })
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"formula":   formula,
+		"exercises": list,
+	})
+}
+
+// listRecordEvents returns the most recent records first, for feeds such
+// as the dashboard.
+func listRecordEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := loadRecentRecords(r.Context(), r.Header.Get("X-User-ID"), parseLimit(r))
+	if err != nil {
+		http.Error(w, "Failed to load records", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"events": events})
+}
+
+func loadRecentRecords(ctx context.Context, userID string, limit int) ([]models.RecordEvent, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+recordEventColumns+` FROM pr_events p JOIN exercises e ON e.id = p.exercise_id
+		WHERE p.user_id = ? ORDER BY p.achieved_at DESC, p.kind LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.RecordEvent{}
+	for rows.Next() {
+		e, err := scanRecordEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
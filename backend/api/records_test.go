@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestPersonalRecords(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupUserRoutes(router)
+	SetupSessionRoutes(router)
+	SetupRecordRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	logSession := func(started string, sets ...map[string]interface{}) (string, []models.RecordEvent) {
+		rr := doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{"started_at": started})
+		id := decodeSession(t, rr.Body.Bytes()).Session.ID
+		var records []models.RecordEvent
+		for _, set := range sets {
+			rr = doJSON(router, "POST", "/api/sessions/"+id+"/sets", token, set)
+			var resp struct {
+				NewRecords []models.RecordEvent `json:"new_records"`
+			}
+			json.Unmarshal(rr.Body.Bytes(), &resp)
+			records = append(records, resp.NewRecords...)
+		}
+		return id, records
+	}
+
+	_, first := logSession("2026-10-01T07:00:00Z",
+		map[string]interface{}{"exercise_id": "bench-press", "reps": 5, "load": 80},
+	)
+	// The first set is a baseline, not a new record.
+	assert.Empty(t, first)
+
+	second, records := logSession("2026-10-08T07:00:00Z",
+		map[string]interface{}{"exercise_id": "bench-press", "reps": 5, "load": 75},
+		map[string]interface{}{"exercise_id": "bench-press", "reps": 1, "load": 90},
+	)
+	// A heavier single only beats the 1RM; its estimate stays below 93.3.
+	assert.Len(t, records, 1)
+	assert.Equal(t, "1rm", records[0].Kind)
+	assert.Equal(t, 90.0, records[0].Value)
+	assert.Equal(t, 80.0, records[0].Previous)
+
+	var resp struct {
+		Formula   string `json:"formula"`
+		Exercises []struct {
+			ExerciseName string                        `json:"exercise_name"`
+			Best         map[string]models.RecordEvent `json:"best"`
+			History      []models.RecordEvent          `json:"history"`
+		} `json:"exercises"`
+	}
+	rr := doJSON(router, "GET", "/api/records", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "epley", resp.Formula)
+	assert.Len(t, resp.Exercises, 1)
+	assert.Equal(t, "Barbell Bench Press", resp.Exercises[0].ExerciseName)
+	assert.Len(t, resp.Exercises[0].History, 6)
+	assert.Equal(t, 90.0, resp.Exercises[0].Best["1rm"].Value)
+	assert.Equal(t, 93.3, resp.Exercises[0].Best["e1rm"].Value)
+
+	rr = doJSON(router, "GET", "/api/records?formula=brzycki", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "brzycki", resp.Formula)
+	assert.Equal(t, 90.0, resp.Exercises[0].Best["e1rm"].Value)
+
+	rr = doJSON(router, "GET", "/api/records?formula=guess", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Switching the profile formula rewrites the stored estimates.
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"e1rm_formula": "Brzycki"})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/records", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "brzycki", resp.Formula)
+	assert.Equal(t, 90.0, resp.Exercises[0].Best["e1rm"].Value)
+	assert.Equal(t, "brzycki", resp.Exercises[0].Best["e1rm"].Formula)
+
+	// Deleting the session that held the 1RM drops its record.
+	rr = doJSON(router, "DELETE", "/api/sessions/"+second, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	var events struct {
+		Events []models.RecordEvent `json:"events"`
+	}
+	rr = doJSON(router, "GET", "/api/records/events", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &events)
+	assert.Len(t, events.Events, 5)
+	for _, e := range events.Events {
+		assert.Equal(t, 80.0, e.Load)
+	}
+}
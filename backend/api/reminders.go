@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/notify"
+	"github.com/terr0r/fitness.ai/backend/reminders"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Reminders a user can set up.
+const maxReminders = 50
+
+func init() {
+	reminders.Register(reminders.Kind{
+		Name: "weigh_in", Title: "Log your weight", Message: "Step on the scale and log today's weight.", URL: "/journal",
+		Check: func(userID, date string) (bool, string, error) {
+			var n int
+			err := db.DB.QueryRow(`SELECT COUNT(*) FROM journals WHERE user_id = ? AND type = ? AND date = ?`,
+				userID, journal.Weight, date).Scan(&n)
+			return n == 0, "", err
+		},
+	})
+	reminders.Register(reminders.Kind{
+		Name: "workout", Title: "Time to work out", URL: "/schedule",
+		Check: checkWorkoutReminder,
+	})
+	reminders.Register(reminders.Kind{
+		Name: "water", Title: "Drink some water", Message: "Have a glass of water and log it.", URL: "/journal",
+		Check: func(userID, date string) (bool, string, error) {
+			// Quiet once a water goal is met for the day; without a goal
+			// the reminder is always sent.
+			return len(metHabits(context.Background(), userID, journal.Water, date)) == 0, "", nil
+		},
+	})
+}
+
+// checkWorkoutReminder sends the reminder only on days with a planned
+// session still to do, naming the sessions.
+func checkWorkoutReminder(userID, date string) (bool, string, error) {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return false, "", err
+	}
+	if err := materializeSchedule(userID, day, day); err != nil {
+		return false, "", err
+	}
+	sessions, err := loadSchedule(context.Background(), userID, day, day)
+	if err != nil {
+		return false, "", err
+	}
+	var planned []string
+	for _, s := range sessions {
+		if s.Status != "scheduled" {
+			continue
+		}
+		if s.StartTime != "" {
+			planned = append(planned, s.Title+" at "+s.StartTime)
+		} else {
+			planned = append(planned, s.Title)
+		}
+	}
+	if len(planned) == 0 {
+		return false, "", nil
+	}
+	return true, "Today: " + strings.Join(planned, ", "), nil
+}
+
+func SetupReminderRoutes(r chi.Router) {
+	r.Route("/api/reminders", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listReminders)
+		r.Post("/", createReminder)
+		r.Get("/kinds", listReminderKinds)
+		r.Get("/{id}", getReminder)
+		r.Put("/{id}", updateReminder)
+		r.Delete("/{id}", deleteReminder)
+	})
+}
+
+func listReminderKinds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"kinds": reminders.Kinds(), "channels": notify.Channels()})
+}
+
+func listReminders(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.DB.Query(`SELECT `+reminders.Columns+` FROM reminders r WHERE r.user_id = ? ORDER BY r.created_at`, r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to list reminders", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Reminder{}
+	for rows.Next() {
+		rem, _, err := reminders.Scan(rows)
+		if err != nil {
+			http.Error(w, "Failed to list reminders", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, rem)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reminders": list})
+}
+
+func getReminder(w http.ResponseWriter, r *http.Request) {
+	rem, err := reminders.Get(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Reminder not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reminder": rem})
+}
+
+// reminderRequest changes only the fields it contains.
+type reminderRequest struct {
+	Kind     *string   `json:"kind"`
+	Title    *string   `json:"title"`
+	Message  *string   `json:"message"`
+	Times    *[]string `json:"times"`
+	Days     *[]string `json:"days"`
+	Channels *[]string `json:"channels"`
+	Active   *bool     `json:"active"`
+}
+
+// apply validates the request onto rem.
+func (req reminderRequest) apply(rem *models.Reminder) error {
+	if req.Kind != nil {
+		rem.Kind = *req.Kind
+	}
+	if req.Title != nil {
+		rem.Title = *req.Title
+	}
+	if req.Message != nil {
+		rem.Message = strings.TrimSpace(*req.Message)
+	}
+	if req.Times != nil {
+		rem.Times = *req.Times
+	}
+	if req.Days != nil {
+		rem.Days = *req.Days
+	}
+	if req.Channels != nil {
+		rem.Channels = *req.Channels
+	}
+	if req.Active != nil {
+		rem.Active = *req.Active
+	}
+	return reminders.Validate(rem)
+}
+
+// createReminder sets up a rule. It sends in-app notifications only
+// unless channels are given, and defaults to a custom reminder.
+func createReminder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	var req reminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	rem := models.Reminder{ID: uuid.New().String(), Kind: reminders.Custom, Active: true}
+	if err := req.apply(&rem); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var count int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM reminders WHERE user_id = ?`, userID).Scan(&count)
+	if count >= maxReminders {
+		http.Error(w, fmt.Sprintf("at most %d reminders can be set up", maxReminders), http.StatusConflict)
+		return
+	}
+
+	reminders.Schedule(userID, &rem)
+	now := clock.Now()
+	_, err := db.DB.Exec(`INSERT INTO reminders (id, user_id, kind, title, message, times, days, channels, active, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rem.ID, userID, rem.Kind, rem.Title, utils.NullIfEmpty(rem.Message), encodeJSON(rem.Times), encodeJSON(rem.Days), encodeJSON(rem.Channels),
+		rem.Active, nextRunAt(rem), now, now)
+	if err != nil {
+		http.Error(w, "Failed to create reminder", http.StatusInternalServerError)
+		return
+	}
+	saved, err := reminders.Get(userID, rem.ID)
+	if err != nil {
+		http.Error(w, "Reminder not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"reminder": saved})
+}
+
+// updateReminder changes a rule and schedules it afresh, so a changed
+// time applies from the next match.
+func updateReminder(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	rem, err := reminders.Get(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Reminder not found", http.StatusNotFound)
+		return
+	}
+	var req reminderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(&rem); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reminders.Schedule(userID, &rem)
+	_, err = db.DB.Exec(`UPDATE reminders SET kind = ?, title = ?, message = ?, times = ?, days = ?, channels = ?, active = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		rem.Kind, rem.Title, utils.NullIfEmpty(rem.Message), encodeJSON(rem.Times), encodeJSON(rem.Days), encodeJSON(rem.Channels),
+		rem.Active, nextRunAt(rem), clock.Now(), rem.ID, userID)
+	if err != nil {
+		http.Error(w, "Failed to update reminder", http.StatusInternalServerError)
+		return
+	}
+	saved, err := reminders.Get(userID, rem.ID)
+	if err != nil {
+		http.Error(w, "Reminder not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"reminder": saved})
+}
+
+// deleteReminder removes a rule; notifications it already sent stay in
+// the inbox.
+func deleteReminder(w http.ResponseWriter, r *http.Request) {
+	res, err := db.DB.Exec(`DELETE FROM reminders WHERE id = ? AND user_id = ?`, chi.URLParam(r, "id"), r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to delete reminder", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Reminder not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// nextRunAt is the stored form of a reminder's next run, as the scheduler
+// compares it.
+func nextRunAt(rem models.Reminder) interface{} {
+	if rem.NextRunAt == nil {
+		return nil
+	}
+	return utils.FormatTime(*rem.NextRunAt)
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/reminders"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+	"github.com/terr0r/fitness.ai/backend/webpush"
+)
+
+func TestReminders(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	// Monday 2025-03-03, 06:00 in Berlin.
+	now := clock.NewFake(time.Date(2025, 3, 3, 5, 0, 0, 0, time.UTC))
+	defer now.Install()()
+
+	router := chi.NewRouter()
+	SetupReminderRoutes(router)
+	SetupNotificationRoutes(router)
+	SetupJournalRoutes(router)
+	SetupUserRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+	rr := doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam", "timezone": "Europe/Berlin"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var resp struct {
+		Reminder models.Reminder `json:"reminder"`
+	}
+	rr = doJSON(router, "POST", "/api/reminders", token, map[string]interface{}{
+		"kind": "weigh_in", "times": []string{"07:30"}, "days": []string{"mon", "tue"},
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	weighIn := resp.Reminder
+	assert.Equal(t, "Log your weight", weighIn.Title)
+	assert.Equal(t, []string{}, weighIn.Channels)
+	require.NotNil(t, weighIn.NextRunAt)
+	assert.Equal(t, time.Date(2025, 3, 3, 6, 30, 0, 0, time.UTC), weighIn.NextRunAt.UTC())
+
+	for _, bad := range []map[string]interface{}{
+		{"kind": "nap", "times": []string{"07:30"}},
+		{"times": []string{"7.30"}},
+		{"times": []string{"07:30"}, "channels": []string{"fax"}},
+	} {
+		rr = doJSON(router, "POST", "/api/reminders", token, bad)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "%v", bad)
+	}
+	rr = doJSON(router, "GET", "/api/reminders/"+weighIn.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "GET", "/api/reminders/kinds", token, nil)
+	assert.Contains(t, rr.Body.String(), `"workout"`)
+
+	// A paused reminder has no next run; resuming schedules it again.
+	rr = doJSON(router, "PUT", "/api/reminders/"+weighIn.ID, token, map[string]interface{}{"active": false})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	resp.Reminder = models.Reminder{}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Nil(t, resp.Reminder.NextRunAt)
+	rr = doJSON(router, "PUT", "/api/reminders/"+weighIn.ID, token, map[string]interface{}{"active": true, "channels": []string{"push"}})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, time.Date(2025, 3, 3, 6, 30, 0, 0, time.UTC), resp.Reminder.NextRunAt.UTC())
+
+	// Push goes to the user's browser through the push service.
+	public, private, err := webpush.GenerateKeys()
+	require.NoError(t, err)
+	t.Setenv("VAPID_PUBLIC_KEY", public)
+	t.Setenv("VAPID_PRIVATE_KEY", private)
+	var mu sync.Mutex
+	var pushed []string
+	push := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pushed = append(pushed, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer push.Close()
+	oldClient := webpush.Client
+	webpush.Client = localClient(push)
+	defer func() { webpush.Client = oldClient }()
+
+	rr = doJSON(router, "GET", "/api/notifications/push/key", token, nil)
+	assert.Contains(t, rr.Body.String(), public)
+	browserKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	secret := make([]byte, 16)
+	rand.Read(secret)
+	keys := map[string]string{
+		"p256dh": base64.RawURLEncoding.EncodeToString(browserKey.PublicKey().Bytes()),
+		"auth":   base64.RawURLEncoding.EncodeToString(secret),
+	}
+	for _, path := range []string{"/live", "/gone"} {
+		rr = doJSON(router, "POST", "/api/notifications/push/subscriptions", token, map[string]interface{}{"endpoint": "https://example.com" + path, "keys": keys})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		assert.NotContains(t, rr.Body.String(), keys["auth"])
+	}
+	for _, endpoint := range []string{"http://push.example.com/x", push.URL + "/live"} {
+		rr = doJSON(router, "POST", "/api/notifications/push/subscriptions", token, map[string]interface{}{"endpoint": endpoint, "keys": keys})
+		assert.Equal(t, http.StatusBadRequest, rr.Code, endpoint)
+	}
+
+	// At 07:30 the reminder is sent, to the inbox and by push.
+	now.Set(time.Date(2025, 3, 3, 6, 30, 0, 0, time.UTC))
+	sent, err := reminders.SendDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	_, err = jobs.RunDue(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/live", "/gone"}, pushed)
+	var subs struct {
+		Subscriptions []models.PushSubscription `json:"subscriptions"`
+	}
+	rr = doJSON(router, "GET", "/api/notifications/push/subscriptions", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &subs)
+	require.Len(t, subs.Subscriptions, 1)
+	assert.True(t, strings.HasSuffix(subs.Subscriptions[0].Endpoint, "/live"))
+
+	// On Tuesday the weight is already logged, so there is no reminder.
+	now.Set(time.Date(2025, 3, 4, 5, 0, 0, 0, time.UTC))
+	rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+		"date": "2025-03-04", "type": "weight", "data": map[string]float64{"weight": 80},
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	now.Set(time.Date(2025, 3, 4, 6, 30, 0, 0, time.UTC))
+	sent, _ = reminders.SendDue(ctx)
+	assert.Zero(t, sent)
+	// The next run is the following Monday.
+	rr = doJSON(router, "GET", "/api/reminders/"+weighIn.ID, token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, time.Date(2025, 3, 10, 6, 30, 0, 0, time.UTC), resp.Reminder.NextRunAt.UTC())
+
+	// The inbox keeps read and unread state.
+	var inbox struct {
+		Notifications []models.Notification `json:"notifications"`
+		Unread        int                   `json:"unread"`
+	}
+	rr = doJSON(router, "GET", "/api/notifications", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &inbox)
+	require.Len(t, inbox.Notifications, 1)
+	assert.Equal(t, 1, inbox.Unread)
+	n := inbox.Notifications[0]
+	assert.Equal(t, "weigh_in", n.Kind)
+	assert.Equal(t, weighIn.ID, n.ReminderID)
+	assert.False(t, n.Read)
+
+	rr = doJSON(router, "POST", "/api/notifications/"+n.ID+"/read", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "POST", "/api/notifications/"+n.ID+"/read", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"read":true`)
+	rr = doJSON(router, "GET", "/api/notifications?unread=true", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &inbox)
+	assert.Empty(t, inbox.Notifications)
+	assert.Zero(t, inbox.Unread)
+	rr = doJSON(router, "POST", "/api/notifications/"+n.ID+"/unread", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"unread":1`)
+	rr = doJSON(router, "POST", "/api/notifications/read-all", token, nil)
+	assert.Contains(t, rr.Body.String(), `"marked":1`)
+
+	// Quiet hours are validated.
+	rr = doJSON(router, "PUT", "/api/notifications/settings", token, map[string]string{"quiet_start": "22:00"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "PUT", "/api/notifications/settings", token, map[string]string{"quiet_start": "22:00", "quiet_end": "07:00"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.JSONEq(t, `{"settings": {"quiet_start": "22:00", "quiet_end": "07:00"}}`, rr.Body.String())
+
+	// Moving timezone moves the reminder to 07:30 there, still Tuesday
+	// morning in New York.
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam", "timezone": "America/New_York"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "GET", "/api/reminders/"+weighIn.ID, token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, time.Date(2025, 3, 4, 12, 30, 0, 0, time.UTC), resp.Reminder.NextRunAt.UTC())
+
+	rr = doJSON(router, "DELETE", "/api/notifications/"+n.ID, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/reminders/"+weighIn.ID, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/reminders/"+weighIn.ID, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/terr0r/fitness.ai/backend/dietary"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// excludedRecipe is a recipe left out because of the user's restrictions,
+// with the reasons why.
+type excludedRecipe struct {
+	ID      string              `json:"id"`
+	Name    string              `json:"name"`
+	Reasons []dietary.Exclusion `json:"reasons"`
+}
+
+func userRestrictions(u models.User) (dietary.Restrictions, error) {
+	return dietary.NewRestrictions(u.Diets, u.Allergens)
+}
+
+// requestRestrictions loads the caller's restrictions unless the request
+// opts out with ?restrictions=off, e.g. to browse the whole catalog.
+func requestRestrictions(r *http.Request) (dietary.Restrictions, error) {
+	if r.URL.Query().Get("restrictions") == "off" {
+		return dietary.Restrictions{}, nil
+	}
+	user, err := loadUser(r.Header.Get("X-User-ID"))
+	if err != nil {
+		return dietary.Restrictions{}, err
+	}
+	return userRestrictions(user)
+}
+
+// checkRecipe returns why rec breaks the restrictions, if it does.
+func checkRecipe(rs dietary.Restrictions, rec models.Recipe) []dietary.Exclusion {
+	if rs.Empty() {
+		return nil
+	}
+	return dietary.Check(rs, dietary.Analyze(rec.Ingredients))
+}
@@ -0,0 +1,461 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/ical"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/planning"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const (
+	dateLayout             = "2006-01-02"
+	maxScheduleRangeDays   = 366
+	defaultSessionDuration = 60
+)
+
+func SetupScheduleRoutes(r chi.Router) {
+	r.Route("/api/schedule", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", getSchedule)
+		r.Get("/calendar", getCalendarFeed)
+		r.Post("/calendar/rotate", rotateCalendarFeed)
+		r.Post("/{id}/move", moveSession)
+		r.Post("/{id}/skip", skipSession)
+		r.Post("/{id}/reschedule", rescheduleSession)
+	})
+
+	// The feed is authenticated by the secret token in its URL so calendar
+	// apps, which cannot send bearer tokens, can subscribe to it.
+	r.Get("/api/calendar/{file}", serveCalendarFeed)
+}
+
+// localToday is midnight of the current date in loc, expressed as a UTC
+// calendar date so it can be compared with stored DATE values.
+func localToday(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// parseDateRange reads from/to query parameters, defaulting to the coming week.
+func parseDateRange(r *http.Request, loc *time.Location) (time.Time, time.Time, error) {
+	from := localToday(loc)
+	to := from.AddDate(0, 0, 6)
+
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = time.Parse(dateLayout, v); err != nil {
+			return from, to, fmt.Errorf("invalid 'from' date")
+		}
+		if r.URL.Query().Get("to") == "" {
+			to = from.AddDate(0, 0, 6)
+		}
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = time.Parse(dateLayout, v); err != nil {
+			return from, to, fmt.Errorf("invalid 'to' date")
+		}
+	}
+	if to.Before(from) {
+		return from, to, fmt.Errorf("'to' must not be before 'from'")
+	}
+	if to.Sub(from) > maxScheduleRangeDays*24*time.Hour {
+		return from, to, fmt.Errorf("date range may span at most %d days", maxScheduleRangeDays)
+	}
+	return from, to, nil
+}
+
+// materializeSchedule makes sure every active plan's sessions between from
+// and to exist as rows. Sessions the user has touched (moved, skipped or
+// completed) are kept as they are; untouched ones that the current plan
+// content no longer produces are dropped.
+func materializeSchedule(userID string, from, to time.Time) error {
+	rows, err := db.DB.Query(`SELECT `+planColumns+` FROM plans p WHERE p.user_id = ? AND p.status = 'active'`, userID)
+	if err != nil {
+		return err
+	}
+	var plans []models.Plan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		plans = append(plans, p)
+	}
+	rows.Close()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, p := range plans {
+		var content models.PlanContent
+		if p.Content == "" || json.Unmarshal([]byte(p.Content), &content) != nil {
+			continue
+		}
+		start, _ := time.Parse(dateLayout, p.StartDate)
+		end, _ := time.Parse(dateLayout, p.EndDate)
+
+		occurrences := planning.Expand(content, start, end, from, to)
+		keep := make([]string, 0, len(occurrences))
+		for _, o := range occurrences {
+			date := o.Date.Format(dateLayout)
+			keep = append(keep, fmt.Sprintf("%d|%s", o.Day, date))
+			_, err := tx.Exec(`INSERT INTO scheduled_sessions (id, user_id, plan_id, plan_day, original_date, scheduled_date, title, duration_minutes)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (plan_id, plan_day, original_date) DO UPDATE SET title = excluded.title`,
+				uuid.New().String(), userID, p.ID, o.Day, date, date, o.Title, defaultSessionDuration)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `DELETE FROM scheduled_sessions
+			WHERE plan_id = ? AND status = 'scheduled' AND scheduled_date = original_date
+			AND original_date BETWEEN ? AND ?`
+		args := []interface{}{p.ID, from.Format(dateLayout), to.Format(dateLayout)}
+		if len(keep) > 0 {
+			query += ` AND (plan_day || '|' || original_date) NOT IN (?` + strings.Repeat(", ?", len(keep)-1) + `)`
+			for _, k := range keep {
+				args = append(args, k)
+			}
+		}
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const scheduledSessionColumns = `s.id, s.plan_id, s.plan_day, COALESCE(s.title, ''), s.original_date, s.scheduled_date, COALESCE(s.start_time, ''), COALESCE(s.duration_minutes, 0), s.status`
+
+func scanScheduledSession(row interface{ Scan(...interface{}) error }) (models.ScheduledSession, error) {
+	var s models.ScheduledSession
+	err := row.Scan(&s.ID, &s.PlanID, &s.PlanDay, &s.Title, &s.OriginalDate, &s.Date, &s.StartTime, &s.DurationMinutes, &s.Status)
+	s.OriginalDate = trimDate(s.OriginalDate)
+	s.Date = trimDate(s.Date)
+	return s, err
+}
+
+func loadSchedule(ctx context.Context, userID string, from, to time.Time) ([]models.ScheduledSession, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+scheduledSessionColumns+`
+		FROM scheduled_sessions s JOIN plans p ON p.id = s.plan_id
+		WHERE s.user_id = ? AND p.status = 'active' AND s.scheduled_date BETWEEN ? AND ?
+		ORDER BY s.scheduled_date, COALESCE(s.start_time, ''), s.plan_day`,
+		userID, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.ScheduledSession{}
+	for rows.Next() {
+		s, err := scanScheduledSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func getSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	loc := utils.UserLocation(userID)
+
+	from, to, err := parseDateRange(r, loc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := materializeSchedule(userID, from, to); err != nil {
+		http.Error(w, "Failed to build schedule", http.StatusInternalServerError)
+		return
+	}
+	sessions, err := loadSchedule(r.Context(), userID, from, to)
+	if err != nil {
+		http.Error(w, "Failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"from":     from.Format(dateLayout),
+		"to":       to.Format(dateLayout),
+		"timezone": loc.String(),
+		"sessions": sessions,
+	})
+}
+
+func loadScheduledSession(userID, id string) (models.ScheduledSession, error) {
+	return scanScheduledSession(db.DB.QueryRow(`SELECT `+scheduledSessionColumns+` FROM scheduled_sessions s WHERE s.id = ? AND s.user_id = ?`, id, userID))
+}
+
+type moveRequest struct {
+	Date            string `json:"date"`
+	StartTime       string `json:"start_time"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+func (req moveRequest) validate() error {
+	if _, err := time.Parse(dateLayout, req.Date); err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	if req.StartTime != "" {
+		if _, err := time.Parse("15:04", req.StartTime); err != nil {
+			return fmt.Errorf("start_time must be HH:MM")
+		}
+	}
+	if req.DurationMinutes < 0 {
+		return fmt.Errorf("duration_minutes must not be negative")
+	}
+	return nil
+}
+
+func moveSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadScheduledSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.DB.Exec(`UPDATE scheduled_sessions
+		SET scheduled_date = ?, start_time = ?, duration_minutes = COALESCE(NULLIF(?, 0), duration_minutes), status = 'scheduled', updated_at = ?
+		WHERE id = ?`, req.Date, utils.NullIfEmpty(req.StartTime), req.DurationMinutes, time.Now(), id)
+	if err != nil {
+		http.Error(w, "Failed to move session", http.StatusInternalServerError)
+		return
+	}
+
+	respondScheduledSession(w, userID, id)
+}
+
+func skipSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadScheduledSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if _, err := db.DB.Exec(`UPDATE scheduled_sessions SET status = 'skipped', updated_at = ? WHERE id = ?`, time.Now(), id); err != nil {
+		http.Error(w, "Failed to skip session", http.StatusInternalServerError)
+		return
+	}
+
+	respondScheduledSession(w, userID, id)
+}
+
+// rescheduleSession moves a session to a new date and shifts every later
+// pending session of the same plan by the same number of days, so the rest
+// of the programme keeps its spacing.
+func rescheduleSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	session, err := loadScheduledSession(userID, id)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req moveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	oldDate, _ := time.Parse(dateLayout, session.Date)
+	newDate, _ := time.Parse(dateLayout, req.Date)
+	shift := int(newDate.Sub(oldDate).Hours() / 24)
+
+	// Sessions of the plan that have not been generated yet must exist before
+	// they can be shifted; a year ahead is as far as a client can look.
+	if err := materializeSchedule(userID, oldDate, oldDate.AddDate(0, 0, maxScheduleRangeDays)); err != nil {
+		http.Error(w, "Failed to reschedule session", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to reschedule session", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`UPDATE scheduled_sessions
+		SET scheduled_date = date(scheduled_date, ? || ' days'), status = 'scheduled', updated_at = ?
+		WHERE plan_id = ? AND user_id = ? AND status = 'scheduled' AND (id = ? OR scheduled_date > ?)`,
+		fmt.Sprintf("%+d", shift), time.Now(), session.PlanID, userID, id, session.Date)
+	if err != nil {
+		http.Error(w, "Failed to reschedule session", http.StatusInternalServerError)
+		return
+	}
+	if req.StartTime != "" {
+		if _, err := tx.Exec(`UPDATE scheduled_sessions SET start_time = ? WHERE id = ?`, req.StartTime, id); err != nil {
+			http.Error(w, "Failed to reschedule session", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to reschedule session", http.StatusInternalServerError)
+		return
+	}
+
+	respondScheduledSession(w, userID, id)
+}
+
+func respondScheduledSession(w http.ResponseWriter, userID, id string) {
+	session, err := loadScheduledSession(userID, id)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"session": session})
+}
+
+func newCalendarToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func calendarFeedURL(r *http.Request, token string) string {
+	base := os.Getenv("PUBLIC_URL")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimRight(base, "/") + "/api/calendar/" + token + ".ics"
+}
+
+func getCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	var token string
+	err := db.DB.QueryRow(`SELECT token FROM calendar_tokens WHERE user_id = ?`, userID).Scan(&token)
+	if err != nil {
+		if token, err = newCalendarToken(); err != nil {
+			http.Error(w, "Failed to create calendar token", http.StatusInternalServerError)
+			return
+		}
+		if _, err := db.DB.Exec(`INSERT INTO calendar_tokens (user_id, token) VALUES (?, ?)`, userID, token); err != nil {
+			http.Error(w, "Failed to create calendar token", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"url": calendarFeedURL(r, token)})
+}
+
+// rotateCalendarFeed replaces the feed token, invalidating the old URL.
+func rotateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	token, err := newCalendarToken()
+	if err != nil {
+		http.Error(w, "Failed to create calendar token", http.StatusInternalServerError)
+		return
+	}
+	_, err = db.DB.Exec(`INSERT INTO calendar_tokens (user_id, token, created_at) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET token = excluded.token, created_at = excluded.created_at`, userID, token, time.Now())
+	if err != nil {
+		http.Error(w, "Failed to rotate calendar token", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"url": calendarFeedURL(r, token)})
+}
+
+func serveCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(chi.URLParam(r, "file"), ".ics")
+	if !ok || token == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var userID string
+	if err := db.DB.QueryRow(`SELECT user_id FROM calendar_tokens WHERE token = ?`, token).Scan(&userID); err != nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	loc := utils.UserLocation(userID)
+	today := localToday(loc)
+	from, to := today.AddDate(0, 0, -30), today.AddDate(0, 0, 180)
+
+	if err := materializeSchedule(userID, from, to); err != nil {
+		http.Error(w, "Failed to build schedule", http.StatusInternalServerError)
+		return
+	}
+	sessions, err := loadSchedule(r.Context(), userID, from, to)
+	if err != nil {
+		http.Error(w, "Failed to load schedule", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{ProdID: "-//Fitness.ai//Training Schedule//EN", Name: "Fitness.ai Training"}
+	for _, s := range sessions {
+		cal.Events = append(cal.Events, sessionEvent(s, loc, r.Host))
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="fitness-ai.ics"`)
+	cal.Write(w)
+}
+
+func sessionEvent(s models.ScheduledSession, loc *time.Location, host string) ical.Event {
+	date, _ := time.Parse(dateLayout, s.Date)
+	e := ical.Event{
+		UID:     s.ID + "@" + host,
+		Summary: s.Title,
+		Start:   date,
+		AllDay:  true,
+		Status:  "CONFIRMED",
+	}
+	if s.Status == "skipped" {
+		e.Status = "CANCELLED"
+	}
+	if s.StartTime != "" {
+		if t, err := time.ParseInLocation(dateLayout+" 15:04", s.Date+" "+s.StartTime, loc); err == nil {
+			e.Start = t
+			e.AllDay = false
+			e.Duration = time.Duration(s.DurationMinutes) * time.Minute
+		}
+	}
+	return e
+}
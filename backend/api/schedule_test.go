@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+type scheduleResponse struct {
+	Sessions []struct {
+		ID     string `json:"id"`
+		Date   string `json:"date"`
+		Title  string `json:"title"`
+		Status string `json:"status"`
+	} `json:"sessions"`
+}
+
+func TestSchedule(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	SetupScheduleRoutes(router)
+	token := createTestUser(t, "user-123")
+	db.DB.Exec(`UPDATE users SET timezone = 'Europe/Berlin' WHERE id = 'user-123'`)
+
+	rr := doJSON(router, "POST", "/api/plans", token, map[string]interface{}{
+		"type":       "workout",
+		"start_date": "2026-10-19",
+		"content": map[string]interface{}{"days": []interface{}{
+			map[string]interface{}{"day": 1, "weekday": "monday", "title": "Upper", "exercises": []interface{}{map[string]interface{}{"name": "Bench"}}},
+			map[string]interface{}{"day": 2, "weekday": "wednesday", "title": "Lower", "exercises": []interface{}{map[string]interface{}{"name": "Squat"}}},
+		}},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/schedule?from=2026-10-19&to=2026-10-28", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var sched scheduleResponse
+	json.Unmarshal(rr.Body.Bytes(), &sched)
+	assert.Len(t, sched.Sessions, 4)
+	assert.Equal(t, "2026-10-19", sched.Sessions[0].Date)
+	assert.Equal(t, "Upper", sched.Sessions[0].Title)
+
+	// Reschedule the first session by two days; the later ones follow.
+	rr = doJSON(router, "POST", "/api/schedule/"+sched.Sessions[0].ID+"/reschedule", token, map[string]interface{}{"date": "2026-10-21"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = doJSON(router, "POST", "/api/schedule/"+sched.Sessions[1].ID+"/skip", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = doJSON(router, "GET", "/api/schedule?from=2026-10-19&to=2026-10-30", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &sched)
+	var dates []string
+	for _, s := range sched.Sessions {
+		dates = append(dates, s.Date+" "+s.Status)
+	}
+	assert.Equal(t, []string{"2026-10-21 scheduled", "2026-10-23 skipped", "2026-10-28 scheduled", "2026-10-30 scheduled"}, dates)
+
+	rr = doJSON(router, "GET", "/api/schedule/calendar", token, nil)
+	var feed struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &feed)
+	assert.Contains(t, feed.URL, "/api/calendar/")
+
+	path := feed.URL[strings.Index(feed.URL, "/api/calendar/"):]
+	req, _ := http.NewRequest("GET", path, nil)
+	ics := httptest.NewRecorder()
+	router.ServeHTTP(ics, req)
+	assert.Equal(t, http.StatusOK, ics.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", ics.Header().Get("Content-Type"))
+	assert.Contains(t, ics.Body.String(), "BEGIN:VCALENDAR")
+
+	req, _ = http.NewRequest("GET", "/api/calendar/not-a-token.ics", nil)
+	ics = httptest.NewRecorder()
+	router.ServeHTTP(ics, req)
+	assert.Equal(t, http.StatusNotFound, ics.Code)
+}
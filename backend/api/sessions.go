@@ -0,0 +1,562 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+func SetupSessionRoutes(r chi.Router) {
+	r.Route("/api/sessions", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listSessions)
+		r.Post("/", startSession)
+		r.Get("/{id}", getSession)
+		r.Put("/{id}", updateSession)
+		r.Delete("/{id}", deleteSession)
+		r.Post("/{id}/finish", finishSession)
+		r.Post("/{id}/sets", addSet)
+		r.Put("/{id}/sets/{setID}", updateSet)
+		r.Delete("/{id}/sets/{setID}", deleteSet)
+	})
+}
+
+const sessionColumns = `s.id, COALESCE(s.workout_id, ''), COALESCE(s.scheduled_session_id, ''), COALESCE(s.name, ''), s.started_at, COALESCE(s.finished_at, ''), COALESCE(s.notes, '')`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (models.TrainingSession, error) {
+	var s models.TrainingSession
+	var started, finished string
+	if err := row.Scan(&s.ID, &s.WorkoutID, &s.ScheduledSessionID, &s.Name, &started, &finished, &s.Notes); err != nil {
+		return s, err
+	}
+	s.StartedAt, _ = time.Parse(time.RFC3339, started)
+	if finished != "" {
+		t, _ := time.Parse(time.RFC3339, finished)
+		s.FinishedAt = &t
+	}
+	return s, nil
+}
+
+// loadSession returns a user's session with its sets and totals.
+func loadSession(userID, id string) (models.TrainingSession, error) {
+	s, err := scanSession(db.DB.QueryRow(`SELECT `+sessionColumns+` FROM training_sessions s WHERE s.id = ? AND s.user_id = ?`, id, userID))
+	if err != nil {
+		return s, err
+	}
+
+	rows, err := db.DB.Query(`SELECT t.id, t.exercise_id, e.name, t.position, COALESCE(t.reps, 0), COALESCE(t.load, 0), COALESCE(t.rpe, 0),
+		COALESCE(t.rest_seconds, 0), COALESCE(t.tempo, ''), COALESCE(t.duration_seconds, 0), t.warmup, COALESCE(t.notes, ''),
+		e.primary_muscles, e.secondary_muscles
+		FROM training_sets t JOIN exercises e ON e.id = t.exercise_id
+		WHERE t.session_id = ? ORDER BY t.position`, id)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	s.Sets = []models.LoggedSet{}
+	exercises := map[string]models.Exercise{}
+	for rows.Next() {
+		var set models.LoggedSet
+		var primary, secondary string
+		if err := rows.Scan(&set.ID, &set.ExerciseID, &set.ExerciseName, &set.Position, &set.Reps, &set.Load, &set.RPE,
+			&set.RestSeconds, &set.Tempo, &set.DurationSeconds, &set.Warmup, &set.Notes, &primary, &secondary); err != nil {
+			return s, err
+		}
+		s.Sets = append(s.Sets, set)
+		exercises[set.ExerciseID] = models.Exercise{
+			ID:               set.ExerciseID,
+			PrimaryMuscles:   decodeStringList(primary),
+			SecondaryMuscles: decodeStringList(secondary),
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	totals := training.Totals(s, exercises)
+	s.Totals = &totals
+	return s, nil
+}
+
+// respondSession writes a session together with any personal records the
+// request produced, which also go out to webhooks.
+func respondSession(w http.ResponseWriter, status int, userID, id string, records []models.RecordEvent) {
+	s, err := loadSession(userID, id)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	for _, e := range records {
+		webhooks.Emit(userID, webhooks.RecordAchieved, e)
+	}
+	resp := map[string]interface{}{"session": s}
+	if len(records) > 0 {
+		resp["new_records"] = records
+	}
+	writeJSON(w, status, resp)
+}
+
+// sessionExerciseIDs lists the exercises logged in a session.
+func sessionExerciseIDs(id string) ([]string, error) {
+	var ids []string
+	rows, err := db.DB.Query(`SELECT DISTINCT exercise_id FROM training_sets WHERE session_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ex string
+		if err := rows.Scan(&ex); err != nil {
+			return nil, err
+		}
+		ids = append(ids, ex)
+	}
+	return ids, rows.Err()
+}
+
+// updateSessionRecords recomputes the records of every exercise a session
+// touched before or after a change.
+func updateSessionRecords(userID, id string, before []string) ([]models.RecordEvent, error) {
+	after, err := sessionExerciseIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	ids := append([]string{}, before...)
+	for _, ex := range after {
+		if !containsString(ids, ex) {
+			ids = append(ids, ex)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return refreshRecords(userID, ids)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// listSessions returns the user's sessions, newest first, optionally
+// limited to those started between the from and to dates (inclusive, in
+// the user's time zone).
+func listSessions(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	loc := utils.UserLocation(userID)
+
+	conds := []string{`s.user_id = ?`}
+	args := []interface{}{userID}
+	for _, p := range []struct {
+		param, cond string
+		days        int
+	}{{"from", `s.started_at >= ?`, 0}, {"to", `s.started_at < ?`, 1}} {
+		v := r.URL.Query().Get(p.param)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseInLocation(dateLayout, v, loc)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid '%s' date", p.param), http.StatusBadRequest)
+			return
+		}
+		conds = append(conds, p.cond)
+		args = append(args, utils.FormatTime(d.AddDate(0, 0, p.days)))
+	}
+	args = append(args, parseLimit(r))
+
+	rows, err := db.DB.Query(`SELECT s.id FROM training_sessions s WHERE `+strings.Join(conds, " AND ")+` ORDER BY s.started_at DESC LIMIT ?`, args...)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	sessions := []models.TrainingSession{}
+	for _, id := range ids {
+		s, err := loadSession(userID, id)
+		if err != nil {
+			http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+		sessions = append(sessions, s)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": sessions})
+}
+
+type sessionRequest struct {
+	WorkoutID          string     `json:"workout_id"`
+	ScheduledSessionID string     `json:"scheduled_session_id"`
+	Name               *string    `json:"name"`
+	Notes              *string    `json:"notes"`
+	StartedAt          *time.Time `json:"started_at"`
+	FinishedAt         *time.Time `json:"finished_at"`
+}
+
+// startSession opens a session, by default starting now. Starting from a
+// workout or a scheduled plan session links the two and borrows its name;
+// the workout's prescription is returned for reference.
+func startSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	var req sessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	s := models.TrainingSession{ID: uuid.New().String(), StartedAt: time.Now().UTC()}
+	if req.StartedAt != nil {
+		s.StartedAt = *req.StartedAt
+	}
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+
+	var workout *models.Workout
+	if req.WorkoutID != "" {
+		wo, err := loadWorkout(req.WorkoutID)
+		if err != nil {
+			http.Error(w, "Workout not found", http.StatusBadRequest)
+			return
+		}
+		workout = &wo
+		s.WorkoutID = wo.ID
+		s.Name = wo.Name
+	}
+	if req.ScheduledSessionID != "" {
+		scheduled, err := loadScheduledSession(userID, req.ScheduledSessionID)
+		if err != nil {
+			http.Error(w, "Scheduled session not found", http.StatusBadRequest)
+			return
+		}
+		s.ScheduledSessionID = scheduled.ID
+		if s.Name == "" {
+			s.Name = scheduled.Title
+		}
+	}
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+
+	_, err := db.DB.Exec(`INSERT INTO training_sessions (id, user_id, workout_id, scheduled_session_id, name, started_at, notes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		s.ID, userID, utils.NullIfEmpty(s.WorkoutID), utils.NullIfEmpty(s.ScheduledSessionID), utils.NullIfEmpty(s.Name), utils.FormatTime(s.StartedAt), utils.NullIfEmpty(s.Notes))
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+
+	s, err = loadSession(userID, s.ID)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	resp := map[string]interface{}{"session": s}
+	if workout != nil {
+		resp["workout"] = workout
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func getSession(w http.ResponseWriter, r *http.Request) {
+	respondSession(w, http.StatusOK, r.Header.Get("X-User-ID"), chi.URLParam(r, "id"), nil)
+}
+
+// updateSession edits a session, including finished ones. Only the fields
+// present in the payload change.
+func updateSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	s, err := loadSession(userID, id)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req sessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+	if req.StartedAt != nil {
+		s.StartedAt = *req.StartedAt
+	}
+	completed := s.FinishedAt == nil && req.FinishedAt != nil
+	if req.FinishedAt != nil {
+		s.FinishedAt = req.FinishedAt
+	}
+	if s.FinishedAt != nil && s.FinishedAt.Before(s.StartedAt) {
+		http.Error(w, "finished_at must not be before started_at", http.StatusBadRequest)
+		return
+	}
+
+	if err := saveSession(s); err != nil {
+		http.Error(w, "Failed to update session", http.StatusInternalServerError)
+		return
+	}
+	// A new start time can reorder the history.
+	records, err := updateSessionRecords(userID, id, nil)
+	if err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	if completed {
+		emitSessionCompleted(userID, id)
+	}
+	respondSession(w, http.StatusOK, userID, id, records)
+}
+
+func saveSession(s models.TrainingSession) error {
+	var finished interface{}
+	if s.FinishedAt != nil {
+		finished = utils.FormatTime(*s.FinishedAt)
+	}
+	_, err := db.DB.Exec(`UPDATE training_sessions SET name = ?, notes = ?, started_at = ?, finished_at = ? WHERE id = ?`,
+		utils.NullIfEmpty(s.Name), utils.NullIfEmpty(s.Notes), utils.FormatTime(s.StartedAt), finished, s.ID)
+	return err
+}
+
+// finishSession closes a session, by default now, and marks the scheduled
+// plan session it came from as completed.
+func finishSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	s, err := loadSession(userID, id)
+	if err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	var req sessionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	completed := s.FinishedAt == nil
+	finished := time.Now().UTC()
+	if req.FinishedAt != nil {
+		finished = *req.FinishedAt
+	}
+	if finished.Before(s.StartedAt) {
+		http.Error(w, "finished_at must not be before started_at", http.StatusBadRequest)
+		return
+	}
+	s.FinishedAt = &finished
+	if req.Notes != nil {
+		s.Notes = *req.Notes
+	}
+
+	if err := saveSession(s); err != nil {
+		http.Error(w, "Failed to finish session", http.StatusInternalServerError)
+		return
+	}
+	if s.ScheduledSessionID != "" {
+		_, err := db.DB.Exec(`UPDATE scheduled_sessions SET status = 'completed', updated_at = CURRENT_TIMESTAMP WHERE id = ? AND user_id = ?`,
+			s.ScheduledSessionID, userID)
+		if err != nil {
+			http.Error(w, "Failed to finish session", http.StatusInternalServerError)
+			return
+		}
+	}
+	records, err := updateSessionRecords(userID, id, nil)
+	if err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	if completed {
+		emitSessionCompleted(userID, id)
+	}
+	respondSession(w, http.StatusOK, userID, id, records)
+}
+
+// emitSessionCompleted tells webhooks a session has been finished; closing
+// it again later is not a new completion.
+func emitSessionCompleted(userID, id string) {
+	if s, err := loadSession(userID, id); err == nil {
+		webhooks.Emit(userID, webhooks.SessionCompleted, s)
+	}
+}
+
+func deleteSession(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	before, err := sessionExerciseIDs(id)
+	if err != nil {
+		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+		return
+	}
+	if _, err := db.DB.Exec(`DELETE FROM training_sets WHERE session_id = ?`, id); err != nil {
+		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+		return
+	}
+	if _, err := db.DB.Exec(`DELETE FROM training_sessions WHERE id = ?`, id); err != nil {
+		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+		return
+	}
+	if _, err := updateSessionRecords(userID, id, before); err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Tempo is four phases (eccentric, pause, concentric, pause) in seconds, X
+// meaning explosive, written "3-1-X-0" or "31X0".
+var tempoPattern = regexp.MustCompile(`^([0-9x]-){3}[0-9x]$|^[0-9x]{4}$`)
+
+func decodeSet(r *http.Request) (models.LoggedSet, error) {
+	var set models.LoggedSet
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+		return set, fmt.Errorf("Invalid payload")
+	}
+	if _, err := loadExercise(set.ExerciseID); err != nil {
+		return set, fmt.Errorf("unknown exercise_id %q", set.ExerciseID)
+	}
+	set.Tempo = strings.ToLower(strings.ReplaceAll(set.Tempo, " ", ""))
+	switch {
+	case set.Reps <= 0 && set.DurationSeconds <= 0:
+		return set, fmt.Errorf("reps or duration_seconds is required")
+	case set.Reps < 0 || set.Load < 0 || set.RestSeconds < 0 || set.DurationSeconds < 0:
+		return set, fmt.Errorf("values cannot be negative")
+	case set.RPE < 0 || set.RPE > 10:
+		return set, fmt.Errorf("rpe must be between 0 and 10")
+	case set.Tempo != "" && !tempoPattern.MatchString(set.Tempo):
+		return set, fmt.Errorf("tempo must look like 3-1-X-0")
+	}
+	return set, nil
+}
+
+// addSet appends a set to the session.
+func addSet(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	set, err := decodeSet(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	set.ID = uuid.New().String()
+
+	_, err = db.DB.Exec(`INSERT INTO training_sets (id, session_id, position, exercise_id, reps, load, rpe, rest_seconds, tempo, duration_seconds, warmup, notes)
+		VALUES (?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM training_sets WHERE session_id = ?), ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		set.ID, id, id, set.ExerciseID, set.Reps, set.Load, set.RPE, set.RestSeconds, utils.NullIfEmpty(set.Tempo), set.DurationSeconds, set.Warmup, utils.NullIfEmpty(set.Notes))
+	if err != nil {
+		http.Error(w, "Failed to log set", http.StatusInternalServerError)
+		return
+	}
+	records, err := updateSessionRecords(userID, id, nil)
+	if err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	respondSession(w, http.StatusCreated, userID, id, records)
+}
+
+func updateSet(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	set, err := decodeSet(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	before, err := sessionExerciseIDs(id)
+	if err != nil {
+		http.Error(w, "Failed to update set", http.StatusInternalServerError)
+		return
+	}
+
+	res, err := db.DB.Exec(`UPDATE training_sets SET exercise_id = ?, reps = ?, load = ?, rpe = ?, rest_seconds = ?, tempo = ?, duration_seconds = ?, warmup = ?, notes = ?
+		WHERE id = ? AND session_id = ?`,
+		set.ExerciseID, set.Reps, set.Load, set.RPE, set.RestSeconds, utils.NullIfEmpty(set.Tempo), set.DurationSeconds, set.Warmup, utils.NullIfEmpty(set.Notes),
+		chi.URLParam(r, "setID"), id)
+	if err != nil {
+		http.Error(w, "Failed to update set", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Set not found", http.StatusNotFound)
+		return
+	}
+	records, err := updateSessionRecords(userID, id, before)
+	if err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	respondSession(w, http.StatusOK, userID, id, records)
+}
+
+func deleteSet(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadSession(userID, id); err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	before, err := sessionExerciseIDs(id)
+	if err != nil {
+		http.Error(w, "Failed to delete set", http.StatusInternalServerError)
+		return
+	}
+	res, err := db.DB.Exec(`DELETE FROM training_sets WHERE id = ? AND session_id = ?`, chi.URLParam(r, "setID"), id)
+	if err != nil {
+		http.Error(w, "Failed to delete set", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "Set not found", http.StatusNotFound)
+		return
+	}
+	records, err := updateSessionRecords(userID, id, before)
+	if err != nil {
+		http.Error(w, "Failed to update records", http.StatusInternalServerError)
+		return
+	}
+	respondSession(w, http.StatusOK, userID, id, records)
+}
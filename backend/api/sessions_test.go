@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+type sessionResponse struct {
+	Session models.TrainingSession `json:"session"`
+	Workout *models.Workout        `json:"workout"`
+}
+
+func decodeSession(t *testing.T, body []byte) sessionResponse {
+	t.Helper()
+	var resp sessionResponse
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	return resp
+}
+
+func TestTrainingSessions(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupExerciseRoutes(router)
+	SetupWorkoutRoutes(router)
+	SetupSessionRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+
+	rr := doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{
+		"name": "Squat Day", "exercises": []interface{}{
+			map[string]interface{}{"exercise_id": "back-squat", "sets": []interface{}{map[string]interface{}{"reps": "5", "weight": 100}}},
+		},
+	})
+	var wo struct {
+		Workout models.Workout `json:"workout"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &wo)
+
+	rr = doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{
+		"workout_id": wo.Workout.ID, "started_at": "2026-10-19T07:00:00+02:00",
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	started := decodeSession(t, rr.Body.Bytes())
+	assert.Equal(t, "Squat Day", started.Session.Name)
+	assert.Equal(t, "2026-10-19T05:00:00Z", utils.FormatTime(started.Session.StartedAt))
+	assert.Equal(t, "back-squat", started.Workout.Exercises[0].ExerciseID)
+	path := "/api/sessions/" + started.Session.ID
+
+	sets := []map[string]interface{}{
+		{"exercise_id": "back-squat", "reps": 5, "load": 60, "warmup": true},
+		{"exercise_id": "back-squat", "reps": 5, "load": 100, "rpe": 8, "rest_seconds": 180, "tempo": "3-1-X-0"},
+		{"exercise_id": "back-squat", "reps": 5, "load": 100, "rpe": 9},
+		{"exercise_id": "romanian-deadlift", "reps": 8, "load": 80},
+	}
+	for _, set := range sets {
+		rr = doJSON(router, "POST", path+"/sets", token, set)
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	logged := decodeSession(t, rr.Body.Bytes()).Session
+	assert.Len(t, logged.Sets, 4)
+	assert.Equal(t, "Barbell Back Squat", logged.Sets[1].ExerciseName)
+	assert.Equal(t, "3-1-x-0", logged.Sets[1].Tempo)
+	assert.Equal(t, 4, logged.Sets[3].Position)
+
+	for _, bad := range []map[string]interface{}{
+		{"exercise_id": "nope", "reps": 5},
+		{"exercise_id": "back-squat"},
+		{"exercise_id": "back-squat", "reps": 5, "rpe": 11},
+		{"exercise_id": "back-squat", "reps": 5, "tempo": "slow"},
+	} {
+		rr = doJSON(router, "POST", path+"/sets", token, bad)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, bad)
+	}
+
+	// Fix a typo in the last set.
+	rr = doJSON(router, "PUT", path+"/sets/"+logged.Sets[3].ID, token, map[string]interface{}{"exercise_id": "romanian-deadlift", "reps": 8, "load": 90})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = doJSON(router, "POST", path+"/finish", token, map[string]interface{}{"finished_at": "2026-10-19T05:55:00Z", "notes": "Felt strong"})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	finished := decodeSession(t, rr.Body.Bytes()).Session
+	totals := finished.Totals
+	assert.Equal(t, "Felt strong", finished.Notes)
+	assert.Equal(t, 55.0, *totals.DurationMinutes)
+	assert.Equal(t, 3, totals.WorkingSets)
+	assert.Equal(t, 1720.0, totals.Volume)
+	assert.Equal(t, 3.0, totals.SetsPerMuscle["glutes"])
+	assert.Equal(t, 1.0, totals.SetsPerMuscle["hamstrings"])
+	assert.Equal(t, 1.5, totals.SetsPerMuscle["lower_back"])
+
+	// Edit the past session: it actually started later.
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{"started_at": "2026-10-19T05:15:00Z"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 40.0, *decodeSession(t, rr.Body.Bytes()).Session.Totals.DurationMinutes)
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{"started_at": "2026-10-19T06:00:00Z"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = doJSON(router, "DELETE", path+"/sets/"+logged.Sets[0].ID, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 3, decodeSession(t, rr.Body.Bytes()).Session.Totals.Sets)
+
+	rr = doJSON(router, "GET", path, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{"name": "Run", "started_at": "2026-10-21T17:00:00Z"})
+	var list struct {
+		Sessions []models.TrainingSession `json:"sessions"`
+	}
+	rr = doJSON(router, "GET", "/api/sessions?from=2026-10-19&to=2026-10-20", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Len(t, list.Sessions, 1)
+	rr = doJSON(router, "GET", "/api/sessions", token, nil)
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Equal(t, "Run", list.Sessions[0].Name)
+	assert.Len(t, list.Sessions, 2)
+
+	// Exercises with logged sets cannot be deleted.
+	rr = doJSON(router, "DELETE", "/api/exercises/romanian-deadlift", token, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	var remaining int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM training_sets`).Scan(&remaining)
+	assert.Equal(t, 0, remaining)
+	rr = doJSON(router, "DELETE", "/api/exercises/romanian-deadlift", token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/planning"
+	"github.com/terr0r/fitness.ai/backend/shopping"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupPantryRoutes(r chi.Router) {
+	r.Route("/api/pantry", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listPantry)
+		r.Post("/", addPantryItem)
+		r.Delete("/{name}", removePantryItem)
+	})
+}
+
+func loadPantry(userID string) ([]string, error) {
+	rows, err := db.DB.Query(`SELECT name FROM pantry_items WHERE user_id = ? ORDER BY name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	return items, rows.Err()
+}
+
+func listPantry(w http.ResponseWriter, r *http.Request) {
+	items, err := loadPantry(r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to load pantry", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": items})
+}
+
+func addPantryItem(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	name := shopping.NormalizeName(req.Name)
+	if name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := db.DB.Exec(`INSERT OR IGNORE INTO pantry_items (user_id, name) VALUES (?, ?)`, userID, name); err != nil {
+		http.Error(w, "Failed to add pantry item", http.StatusInternalServerError)
+		return
+	}
+	listPantry(w, r)
+}
+
+func removePantryItem(w http.ResponseWriter, r *http.Request) {
+	name := shopping.NormalizeName(chi.URLParam(r, "name"))
+	if _, err := db.DB.Exec(`DELETE FROM pantry_items WHERE user_id = ? AND name = ?`, r.Header.Get("X-User-ID"), name); err != nil {
+		http.Error(w, "Failed to remove pantry item", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getShoppingList aggregates the ingredients of every meal an active diet
+// plan puts between from and to. Each recipe's lines are scaled by the planned
+// servings relative to the servings the recipe makes.
+func getShoppingList(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	plan, err := loadPlan(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+	if plan.Type != "diet" {
+		http.Error(w, "Shopping lists are only available for diet plans", http.StatusBadRequest)
+		return
+	}
+	if plan.Status != "active" {
+		http.Error(w, "Plan is not active", http.StatusConflict)
+		return
+	}
+
+	var content models.PlanContent
+	if plan.Content != "" {
+		if err := json.Unmarshal([]byte(plan.Content), &content); err != nil {
+			http.Error(w, "Plan content is not valid", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	planStart, _ := time.Parse(dateLayout, plan.StartDate)
+	planEnd, _ := time.Parse(dateLayout, plan.EndDate)
+
+	// Without an explicit range the list covers the whole plan, or its first
+	// week when the plan is open-ended.
+	q := r.URL.Query()
+	if q.Get("from") == "" && !planStart.IsZero() {
+		q.Set("from", plan.StartDate)
+		if !planEnd.IsZero() && q.Get("to") == "" {
+			q.Set("to", plan.EndDate)
+		}
+		r.URL.RawQuery = q.Encode()
+	}
+	from, to, err := parseDateRange(r, utils.UserLocation(userID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	builder := shopping.NewBuilder()
+	recipes := map[string]*models.Recipe{}
+	for _, day := range planning.MealDays(content, planStart, planEnd, from, to) {
+		for _, meal := range day.Day.Meals {
+			if meal.RecipeID == "" {
+				continue
+			}
+			rec, ok := recipes[meal.RecipeID]
+			if !ok {
+				loaded, err := loadRecipe(meal.RecipeID)
+				if err == nil {
+					rec = &loaded
+				}
+				recipes[meal.RecipeID] = rec
+			}
+			if rec == nil {
+				continue
+			}
+
+			servings := meal.Servings
+			if servings == 0 {
+				servings = 1
+			}
+			// Like nutrition, a recipe without servings counts as one.
+			made := float64(rec.Servings)
+			if made <= 0 {
+				made = 1
+			}
+			factor := servings / made
+			for _, line := range rec.Ingredients {
+				builder.Add(line, factor, rec.Name)
+			}
+		}
+	}
+
+	pantry, err := loadPantry(userID)
+	if err != nil {
+		http.Error(w, "Failed to load pantry", http.StatusInternalServerError)
+		return
+	}
+	list := builder.Build(pantry)
+
+	title := "Shopping list " + from.Format(dateLayout) + " to " + to.Format(dateLayout)
+	switch strings.ToLower(q.Get("format")) {
+	case "", "json":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"plan_id": plan.ID,
+			"from":    from.Format(dateLayout),
+			"to":      to.Format(dateLayout),
+			"aisles":  list.Aisles,
+			"pantry":  list.Pantry,
+		})
+	case "markdown", "md":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		list.WriteMarkdown(w, title)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="shopping-list.csv"`)
+		list.WriteCSV(w)
+	default:
+		http.Error(w, "format must be json, markdown or csv", http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestShoppingList(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupPlanRoutes(router)
+	SetupRecipeRoutes(router)
+	SetupPantryRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	rr := doJSON(router, "POST", "/api/recipes", token, map[string]interface{}{
+		"name": "Chicken Bowl", "servings": 2,
+		"ingredients": []string{"400 g chicken breast", "2 tbsp olive oil", "1 cup rice"},
+	})
+	var created struct {
+		Recipe struct {
+			ID string `json:"id"`
+		} `json:"recipe"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+
+	meal := map[string]interface{}{"slot": "lunch", "recipe_id": created.Recipe.ID, "name": "Chicken Bowl", "servings": 1}
+	rr = doJSON(router, "POST", "/api/plans", token, map[string]interface{}{
+		"type": "diet", "start_date": "2026-10-19", "end_date": "2026-10-25",
+		"content": map[string]interface{}{"days": []interface{}{
+			map[string]interface{}{"day": 1, "meals": []interface{}{meal}},
+			map[string]interface{}{"day": 2, "meals": []interface{}{meal}},
+		}},
+	})
+	var plan struct {
+		Plan struct {
+			ID string `json:"id"`
+		} `json:"plan"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &plan)
+	path := "/api/plans/" + plan.Plan.ID + "/shopping-list"
+
+	rr = doJSON(router, "POST", "/api/pantry", token, map[string]string{"name": "Olive oil"})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// Two days in range: each day eats one of two servings.
+	rr = doJSON(router, "GET", path+"?from=2026-10-19&to=2026-10-20", token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var list struct {
+		Aisles []struct {
+			Name  string `json:"name"`
+			Items []struct {
+				Display string `json:"display"`
+			} `json:"items"`
+		} `json:"aisles"`
+		Pantry []string `json:"pantry"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	assert.Equal(t, "Meat & Seafood", list.Aisles[0].Name)
+	assert.Equal(t, "400 g chicken breast", list.Aisles[0].Items[0].Display)
+	assert.Equal(t, "237 ml rice", list.Aisles[1].Items[0].Display)
+	assert.Equal(t, []string{"olive oil"}, list.Pantry)
+
+	rr = doJSON(router, "GET", path+"?from=2026-10-19&to=2026-10-19&format=csv", token, nil)
+	assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "aisle,item,quantity,unit,recipes\nMeat & Seafood,chicken breast,200,g,Chicken Bowl\n"))
+
+	// Without a range the whole plan is covered, cycling its two days.
+	rr = doJSON(router, "GET", path+"?format=markdown", token, nil)
+	assert.Contains(t, rr.Body.String(), "# Shopping list 2026-10-19 to 2026-10-25")
+	assert.Contains(t, rr.Body.String(), "- [ ] 1.4 kg chicken breast")
+
+	// Only active diet plans have shopping lists.
+	_, err := db.DB.Exec(`UPDATE plans SET status = 'archived' WHERE id = ?`, plan.Plan.ID)
+	require.NoError(t, err)
+	rr = doJSON(router, "GET", path, token, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = doJSON(router, "POST", "/api/plans", token, map[string]interface{}{
+		"type": "workout", "start_date": "2026-10-19", "content": map[string]interface{}{"days": []interface{}{}},
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &plan)
+	rr = doJSON(router, "GET", "/api/plans/"+plan.Plan.ID+"/shopping-list", token, nil)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
@@ -3,12 +3,17 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/dietary"
 	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/reminders"
+	"github.com/terr0r/fitness.ai/backend/training"
 	"github.com/terr0r/fitness.ai/backend/utils"
 )
 
@@ -51,28 +56,54 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// isAdmin reports whether the user's email is listed in ADMIN_EMAILS
+// (comma separated).
+func isAdmin(userID string) bool {
+	var email string
+	if err := db.DB.QueryRow(`SELECT email FROM users WHERE id = ?`, userID).Scan(&email); err != nil {
+		return false
+	}
+	for _, admin := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
 func getMe(w http.ResponseWriter, r *http.Request) {
-	userID := r.Header.Get("X-User-ID")
+	user, err := loadUser(r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user": user,
+	})
+}
+
+func loadUser(userID string) (models.User, error) {
 	var user models.User
-	query := `SELECT id, email, name, age, gender, height, weight, activity_level, country, goals, created_at, updated_at FROM users WHERE id = ?`
+	query := `SELECT id, email, name, age, gender, height, weight, activity_level, country, goals, timezone, COALESCE(diets, ''), COALESCE(allergens, ''), COALESCE(e1rm_formula, ''), COALESCE(max_heart_rate, 0), COALESCE(resting_heart_rate, 0), created_at, updated_at FROM users WHERE id = ?`
 
 	// We'll map NULL to default empty values using sql.Null* types if needed,
 	// but standard Scan usually works if columns are properly handled or we default them in struct.
 	// Since SQLite driver might return nil/null for these text/real columns, we should handle gracefully.
 	// For brevity, assuming user struct with omitempty and direct scan works if they aren't completely null but empty.
 	// Actually, SQLite might return NULL so we should use pointer fallbacks.
-	var name, gender, activity, country, goals *string
+	var name, gender, activity, country, goals, timezone *string
 	var age *int
 	var height, weight *float64
+	var diets, allergens string
 
 	err := db.DB.QueryRow(query, userID).Scan(
-		&user.ID, &user.Email, &name, &age, &gender, &height, &weight, &activity, &country, &goals, &user.CreatedAt, &user.UpdatedAt,
+		&user.ID, &user.Email, &name, &age, &gender, &height, &weight, &activity, &country, &goals, &timezone, &diets, &allergens, &user.E1RMFormula, &user.MaxHeartRate, &user.RestingHeartRate, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
+		return user, err
 	}
 
 	if name != nil {
@@ -99,11 +130,13 @@ func getMe(w http.ResponseWriter, r *http.Request) {
 	if goals != nil {
 		user.Goals = *goals
 	}
+	if timezone != nil {
+		user.Timezone = *timezone
+	}
+	user.Diets = decodeStringList(diets)
+	user.Allergens = decodeStringList(allergens)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"user": user,
-	})
+	return user, nil
 }
 
 func updateMe(w http.ResponseWriter, r *http.Request) {
@@ -118,6 +151,17 @@ func updateMe(w http.ResponseWriter, r *http.Request) {
 		ActivityLevel string  `json:"activity_level"`
 		Country       string  `json:"country"`
 		Goals         string  `json:"goals"`
+		// Left unchanged when omitted, as the profile page does not send
+		// it; "" clears it.
+		Timezone *string `json:"timezone"`
+		// Left unchanged when omitted, so an older client cannot silently
+		// clear someone's allergies.
+		Diets       *[]string `json:"diets"`
+		Allergens   *[]string `json:"allergens"`
+		E1RMFormula *string   `json:"e1rm_formula"`
+		// 0 clears a measured heart rate.
+		MaxHeartRate     *int `json:"max_heart_rate"`
+		RestingHeartRate *int `json:"resting_heart_rate"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
@@ -125,19 +169,100 @@ func updateMe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if updates.Timezone != nil && *updates.Timezone != "" {
+		if _, err := time.LoadLocation(*updates.Timezone); err != nil {
+			http.Error(w, "Unknown timezone", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var diets, allergens interface{}
+	if updates.Diets != nil || updates.Allergens != nil {
+		var dietNames, allergenNames []string
+		if updates.Diets != nil {
+			dietNames = *updates.Diets
+		}
+		if updates.Allergens != nil {
+			allergenNames = *updates.Allergens
+		}
+		rs, err := dietary.NewRestrictions(dietNames, allergenNames)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if updates.Diets != nil {
+			diets = encodeJSON(rs.Diets)
+		}
+		if updates.Allergens != nil {
+			allergens = encodeJSON(rs.AllergenNames())
+		}
+	}
+
+	var formula interface{}
+	if updates.E1RMFormula != nil {
+		f, err := training.ParseFormula(*updates.E1RMFormula)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		formula = f
+	}
+
+	before, err := loadUser(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	maxHR, restingHR := before.MaxHeartRate, before.RestingHeartRate
+	if updates.MaxHeartRate != nil {
+		maxHR = *updates.MaxHeartRate
+	}
+	if updates.RestingHeartRate != nil {
+		restingHR = *updates.RestingHeartRate
+	}
+	if err := training.ValidateHeartRates(maxHR, restingHR); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	query := `
 		UPDATE users 
-		SET name = ?, age = ?, gender = ?, height = ?, weight = ?, activity_level = ?, country = ?, goals = ?, updated_at = ?
+		SET name = ?, age = ?, gender = ?, height = ?, weight = ?, activity_level = ?, country = ?, goals = ?, timezone = NULLIF(COALESCE(?, timezone), ''),
+			diets = COALESCE(?, diets), allergens = COALESCE(?, allergens),
+			e1rm_formula = COALESCE(?, e1rm_formula), max_heart_rate = NULLIF(?, 0), resting_heart_rate = NULLIF(?, 0), updated_at = ?
 		WHERE id = ?
 	`
-	_, err := db.DB.Exec(query,
+	_, err = db.DB.Exec(query,
 		updates.Name, updates.Age, updates.Gender, updates.Height, updates.Weight,
-		updates.ActivityLevel, updates.Country, updates.Goals, time.Now(), userID)
+		updates.ActivityLevel, updates.Country, updates.Goals, updates.Timezone, diets, allergens, formula, maxHR, restingHR, time.Now(), userID)
 
 	if err != nil {
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
+	if formula != nil {
+		// Estimated maxes depend on the formula.
+		if _, err := refreshRecords(userID, nil); err != nil {
+			http.Error(w, "Failed to update records", http.StatusInternalServerError)
+			return
+		}
+	}
+	if after, err := loadUser(userID); err == nil && userHRProfile(after) != userHRProfile(before) {
+		// Zones and training loads of cardio sessions depend on the
+		// heart rate profile.
+		if err := refreshCardioLoads(userID); err != nil {
+			http.Error(w, "Failed to update training loads", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if updates.Timezone != nil && *updates.Timezone != before.Timezone {
+		// Reminders are set in local time.
+		if err := reminders.Reschedule(userID); err != nil {
+			http.Error(w, "Failed to reschedule reminders", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	// Fetch updated user to return
 	getMe(w, r)
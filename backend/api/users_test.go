@@ -96,3 +96,31 @@ func TestUpdateMe(t *testing.T) {
 	assert.Equal(t, float64(180), userMap["height"])
 	assert.Equal(t, "UK", userMap["country"])
 }
+
+func TestUpdateMeKeepsTimezone(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := setupTestRouter()
+	SetupUserRoutes(router)
+	token := createTestUser(t, "user-123")
+	timezone := func() string {
+		var tz *string
+		db.DB.QueryRow(`SELECT timezone FROM users WHERE id = 'user-123'`).Scan(&tz)
+		if tz == nil {
+			return ""
+		}
+		return *tz
+	}
+
+	rr := doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam", "timezone": "Europe/Berlin"})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	// The profile page saves without a timezone.
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam", "goals": "run a 10k"})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "Europe/Berlin", timezone())
+
+	rr = doJSON(router, "PUT", "/api/users/me", token, map[string]interface{}{"name": "Sam", "timezone": ""})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "", timezone())
+}
@@ -0,0 +1,370 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+const (
+	// Webhooks a user can register.
+	maxWebhooks = 20
+	// Shortest secret a user can choose instead of a generated one.
+	minWebhookSecret = 16
+)
+
+func SetupWebhookRoutes(r chi.Router) {
+	r.Route("/api/webhooks", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listWebhooks)
+		r.Post("/", createWebhook)
+		r.Get("/events", listWebhookEvents)
+		r.Get("/{id}", getWebhook)
+		r.Put("/{id}", updateWebhook)
+		r.Delete("/{id}", deleteWebhook)
+		r.Get("/{id}/deliveries", listWebhookDeliveries)
+		r.Get("/{id}/deliveries/{deliveryID}", getWebhookDelivery)
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", redeliverWebhook)
+	})
+}
+
+const webhookColumns = `w.id, w.url, COALESCE(w.description, ''), w.events, w.all_users, w.active, w.created_at, w.updated_at`
+
+func scanWebhook(row interface{ Scan(...interface{}) error }) (models.Webhook, error) {
+	var h models.Webhook
+	var events string
+	err := row.Scan(&h.ID, &h.URL, &h.Description, &events, &h.AllUsers, &h.Active, &h.CreatedAt, &h.UpdatedAt)
+	json.Unmarshal([]byte(events), &h.Events)
+	return h, err
+}
+
+func loadWebhook(userID, id string) (models.Webhook, error) {
+	return scanWebhook(db.DB.QueryRow(`SELECT `+webhookColumns+` FROM webhooks w WHERE w.id = ? AND w.user_id = ?`, id, userID))
+}
+
+func listWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"events": webhooks.Events})
+}
+
+func listWebhooks(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.DB.Query(`SELECT `+webhookColumns+` FROM webhooks w WHERE w.user_id = ? ORDER BY w.created_at`, r.Header.Get("X-User-ID"))
+	if err != nil {
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.Webhook{}
+	for rows.Next() {
+		h, err := scanWebhook(rows)
+		if err != nil {
+			http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, h)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"webhooks": list})
+}
+
+func getWebhook(w http.ResponseWriter, r *http.Request) {
+	h, err := loadWebhook(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"webhook": h})
+}
+
+// webhookRequest changes only the fields it contains.
+type webhookRequest struct {
+	URL         *string   `json:"url"`
+	Description *string   `json:"description"`
+	Events      *[]string `json:"events"`
+	AllUsers    *bool     `json:"all_users"`
+	Active      *bool     `json:"active"`
+	Secret      *string   `json:"secret"`
+}
+
+// apply validates the request onto h and returns the new secret, if any.
+func (req webhookRequest) apply(h *models.Webhook, userID string) (string, error) {
+	if req.URL != nil {
+		h.URL = strings.TrimSpace(*req.URL)
+	}
+	if err := webhooks.ValidateURL(h.URL); err != nil {
+		return "", err
+	}
+	if req.Description != nil {
+		h.Description = strings.TrimSpace(*req.Description)
+		if len(h.Description) > 200 {
+			return "", fmt.Errorf("description must be at most 200 characters")
+		}
+	}
+	if req.Events != nil {
+		h.Events = []string{}
+		for _, e := range *req.Events {
+			if !webhooks.KnownEvent(e) {
+				return "", fmt.Errorf("unknown event %q", e)
+			}
+			if !containsString(h.Events, e) {
+				h.Events = append(h.Events, e)
+			}
+		}
+	}
+	if len(h.Events) == 0 {
+		return "", fmt.Errorf("events must list at least one event")
+	}
+	if req.AllUsers != nil {
+		if *req.AllUsers && !isAdmin(userID) {
+			return "", fmt.Errorf("only admins can subscribe to all users' events")
+		}
+		h.AllUsers = *req.AllUsers
+	}
+	if req.Active != nil {
+		h.Active = *req.Active
+	}
+	if req.Secret != nil {
+		if len(*req.Secret) < minWebhookSecret {
+			return "", fmt.Errorf("secret must be at least %d characters", minWebhookSecret)
+		}
+		return *req.Secret, nil
+	}
+	return "", nil
+}
+
+// createWebhook registers an endpoint. The response carries the signing
+// secret, generated unless one is given; it is not shown again.
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	h := models.Webhook{ID: uuid.New().String(), Active: true}
+	secret, err := req.apply(&h, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if secret == "" {
+		secret = webhooks.NewSecret()
+	}
+
+	var count int
+	if err := db.DB.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`, userID).Scan(&count); err != nil {
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+	if count >= maxWebhooks {
+		http.Error(w, fmt.Sprintf("at most %d webhooks can be registered", maxWebhooks), http.StatusConflict)
+		return
+	}
+
+	now := time.Now()
+	_, err = db.DB.Exec(`INSERT INTO webhooks (id, user_id, url, description, secret, events, all_users, active, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.ID, userID, h.URL, utils.NullIfEmpty(h.Description), secret, encodeJSON(h.Events), h.AllUsers, h.Active, now, now)
+	if err != nil {
+		http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+		return
+	}
+	saved, err := loadWebhook(userID, h.ID)
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	saved.Secret = secret
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"webhook": saved})
+}
+
+func updateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	h, err := loadWebhook(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	secret, err := req.apply(&h, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	_, err = db.DB.Exec(`UPDATE webhooks SET url = ?, description = ?, secret = COALESCE(?, secret), events = ?, all_users = ?, active = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?`,
+		h.URL, utils.NullIfEmpty(h.Description), utils.NullIfEmpty(secret), encodeJSON(h.Events), h.AllUsers, h.Active, time.Now(), h.ID, userID)
+	if err != nil {
+		http.Error(w, "Failed to update webhook", http.StatusInternalServerError)
+		return
+	}
+	saved, err := loadWebhook(userID, h.ID)
+	if err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	saved.Secret = secret
+	writeJSON(w, http.StatusOK, map[string]interface{}{"webhook": saved})
+}
+
+// deleteWebhook removes an endpoint with its delivery log.
+func deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadWebhook(userID, id); err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+	for _, query := range []string{
+		`DELETE FROM webhook_attempts WHERE delivery_id IN (SELECT id FROM webhook_deliveries WHERE webhook_id = ?)`,
+		`DELETE FROM webhook_deliveries WHERE webhook_id = ?`,
+		`DELETE FROM webhooks WHERE id = ?`,
+	} {
+		if _, err := tx.Exec(query, id); err != nil {
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+const webhookDeliveryColumns = `d.id, d.webhook_id, d.event_id, d.event, d.status, d.attempts, d.next_attempt_at, COALESCE(d.response_status, 0),
+	COALESCE(d.error, ''), COALESCE(d.redelivery_of, ''), d.created_at, d.updated_at, d.delivered_at`
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.ResponseStatus,
+		&d.Error, &d.RedeliveryOf, &d.CreatedAt, &d.UpdatedAt, &d.DeliveredAt)
+	return d, err
+}
+
+// loadWebhookDelivery returns a delivery of the user's webhook with its
+// payload and attempt log.
+func loadWebhookDelivery(userID, webhookID, id string) (models.WebhookDelivery, error) {
+	var payload string
+	var d models.WebhookDelivery
+	err := db.DB.QueryRow(`SELECT d.payload FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE d.id = ? AND d.webhook_id = ? AND w.user_id = ?`, id, webhookID, userID).Scan(&payload)
+	if err != nil {
+		return d, err
+	}
+	d, err = scanWebhookDelivery(db.DB.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries d WHERE d.id = ?`, id))
+	if err != nil {
+		return d, err
+	}
+	d.Payload = json.RawMessage(payload)
+
+	rows, err := db.DB.Query(`SELECT attempt, COALESCE(status_code, 0), COALESCE(error, ''), COALESCE(response_body, ''), duration_ms, attempted_at
+		FROM webhook_attempts WHERE delivery_id = ? ORDER BY attempt`, id)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	d.Log = []models.WebhookAttempt{}
+	for rows.Next() {
+		var a models.WebhookAttempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.ResponseBody, &a.DurationMS, &a.AttemptedAt); err != nil {
+			return d, err
+		}
+		d.Log = append(d.Log, a)
+	}
+	return d, rows.Err()
+}
+
+// listWebhookDeliveries returns a webhook's deliveries, newest first,
+// optionally only those with ?status=.
+func listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	id := chi.URLParam(r, "id")
+	if _, err := loadWebhook(userID, id); err != nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries d WHERE d.webhook_id = ?`
+	args := []interface{}{id}
+	switch status := r.URL.Query().Get("status"); status {
+	case "":
+	case "pending", "succeeded", "failed":
+		query += ` AND d.status = ?`
+		args = append(args, status)
+	default:
+		http.Error(w, "status must be pending, succeeded or failed", http.StatusBadRequest)
+		return
+	}
+	rows, err := db.DB.Query(query+` ORDER BY d.created_at DESC LIMIT ?`, append(args, parseLimit(r))...)
+	if err != nil {
+		http.Error(w, "Failed to list deliveries", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	list := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			http.Error(w, "Failed to list deliveries", http.StatusInternalServerError)
+			return
+		}
+		list = append(list, d)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deliveries": list})
+}
+
+func getWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	d, err := loadWebhookDelivery(r.Header.Get("X-User-ID"), chi.URLParam(r, "id"), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"delivery": d})
+}
+
+// redeliverWebhook sends a delivery's event again, as a new delivery, and
+// responds once the first attempt is made. A failed redelivery is retried
+// like any other.
+func redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("X-User-ID")
+	webhookID := chi.URLParam(r, "id")
+	if _, err := loadWebhookDelivery(userID, webhookID, chi.URLParam(r, "deliveryID")); err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	id, err := webhooks.Redeliver(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		http.Error(w, "Failed to redeliver", http.StatusInternalServerError)
+		return
+	}
+	if err := webhooks.Deliver(r.Context(), id); err != nil {
+		http.Error(w, "Failed to redeliver", http.StatusInternalServerError)
+		return
+	}
+	d, err := loadWebhookDelivery(userID, webhookID, id)
+	if err != nil {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"delivery": d})
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+	"github.com/terr0r/fitness.ai/backend/webhooks"
+)
+
+// webhookReceiver collects the events delivered to a local HTTPS server.
+type webhookReceiver struct {
+	mu     sync.Mutex
+	status int
+	events []map[string]interface{}
+	// signatures are checked with the secret of the webhook in the path.
+	secrets map[string]string
+	invalid int
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if webhooks.Verify(rc.secrets[r.URL.Path], r.Header.Get(webhooks.SignatureHeader), body, time.Now(), time.Minute) != nil {
+		rc.invalid++
+	}
+	var e map[string]interface{}
+	json.Unmarshal(body, &e)
+	rc.events = append(rc.events, e)
+	w.WriteHeader(rc.status)
+}
+
+// types returns the types of the events received since the last call.
+func (rc *webhookReceiver) types() []string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	var types []string
+	for _, e := range rc.events {
+		types = append(types, e["type"].(string))
+	}
+	rc.events = nil
+	return types
+}
+
+// localClient returns a client that sends requests for any host to
+// server, so tests can subscribe public URLs.
+func localClient(server *httptest.Server) *http.Client {
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	return &http.Client{Transport: transport}
+}
+
+func TestWebhooks(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+	t.Setenv("ADMIN_EMAILS", "admin-1@example.com")
+
+	receiver := &webhookReceiver{status: http.StatusOK, secrets: map[string]string{}}
+	server := httptest.NewTLSServer(receiver)
+	defer server.Close()
+	oldClient := webhooks.Client
+	defer func() { webhooks.Client = oldClient }()
+	webhooks.Client = localClient(server)
+	// The test server's certificate is also for example.com.
+	hookURL := "https://example.com"
+	deliver := func() {
+		_, err := jobs.RunDue(context.Background())
+		require.NoError(t, err)
+	}
+
+	router := chi.NewRouter()
+	SetupWebhookRoutes(router)
+	SetupJournalRoutes(router)
+	SetupHabitRoutes(router)
+	SetupSessionRoutes(router)
+	SetupPlanRoutes(router)
+	token := createTestUser(t, "user-123")
+	otherToken := createTestUser(t, "user-456")
+	adminToken := createTestUser(t, "admin-1")
+
+	rr := doJSON(router, "GET", "/api/webhooks/events", token, nil)
+	assert.Contains(t, rr.Body.String(), `"pr.achieved"`)
+
+	for _, bad := range []map[string]interface{}{
+		{"url": "http://example.com", "events": []string{"journal.created"}},
+		{"url": server.URL, "events": []string{"journal.created"}},
+		{"url": hookURL, "events": []string{"journal.deleted"}},
+		{"url": hookURL, "events": []string{}},
+		{"url": hookURL, "events": []string{"journal.created"}, "all_users": true},
+		{"url": hookURL, "events": []string{"journal.created"}, "secret": "short"},
+	} {
+		rr = doJSON(router, "POST", "/api/webhooks", token, bad)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, bad)
+	}
+
+	var resp struct {
+		Webhook models.Webhook `json:"webhook"`
+	}
+	rr = doJSON(router, "POST", "/api/webhooks", token, map[string]interface{}{
+		"url": hookURL + "/user", "description": "Automations", "events": webhooks.Events,
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	hook := resp.Webhook
+	assert.True(t, hook.Active)
+	assert.Len(t, hook.Events, 5)
+	assert.Regexp(t, `^whsec_[0-9a-f]{64}$`, hook.Secret)
+	receiver.secrets["/user"] = hook.Secret
+
+	rr = doJSON(router, "GET", "/api/webhooks/"+hook.ID, token, nil)
+	assert.NotContains(t, rr.Body.String(), "secret")
+	rr = doJSON(router, "GET", "/api/webhooks/"+hook.ID, otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// An admin can hear about everyone.
+	resp.Webhook = models.Webhook{}
+	rr = doJSON(router, "POST", "/api/webhooks", adminToken, map[string]interface{}{
+		"url": hookURL + "/admin", "events": []string{"journal.created"}, "all_users": true, "secret": "admin-secret-0123456789",
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.True(t, resp.Webhook.AllUsers)
+	receiver.secrets["/admin"] = "admin-secret-0123456789"
+
+	// Journal entries, and the habit they complete.
+	rr = doJSON(router, "POST", "/api/habits", token, map[string]interface{}{
+		"name": "10k steps", "entry_type": "steps", "field": "count", "target": 10000,
+	})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	for _, count := range []int{6000, 5000, 1000} {
+		rr = doJSON(router, "POST", "/api/journals", token, map[string]interface{}{
+			"date": "2025-03-03", "type": "steps", "data": map[string]interface{}{"count": count},
+		})
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	deliver()
+	assert.ElementsMatch(t, []string{
+		"journal.created", "journal.created", "journal.created", "goal.completed",
+		"journal.created", "journal.created", "journal.created",
+	}, receiver.types())
+
+	// Other users' events reach only the admin's webhook.
+	rr = doJSON(router, "POST", "/api/journals", otherToken, map[string]interface{}{
+		"date": "2025-03-03", "type": "mood", "data": map[string]interface{}{"score": 4},
+	})
+	require.Equal(t, http.StatusCreated, rr.Code)
+	deliver()
+	assert.Equal(t, []string{"journal.created"}, receiver.types())
+
+	// A first squat sets no record; beating it in the next session does.
+	rr = doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{"started_at": "2025-03-01T07:00:00Z"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	session := decodeSession(t, rr.Body.Bytes()).Session
+	rr = doJSON(router, "POST", "/api/sessions/"+session.ID+"/sets", token, map[string]interface{}{"exercise_id": "back-squat", "reps": 5, "load": 100})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	deliver()
+	assert.Empty(t, receiver.types())
+
+	// Finishing a session with a new record.
+	rr = doJSON(router, "POST", "/api/sessions", token, map[string]interface{}{"started_at": "2025-03-03T07:00:00Z"})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	session = decodeSession(t, rr.Body.Bytes()).Session
+	rr = doJSON(router, "POST", "/api/sessions/"+session.ID+"/sets", token, map[string]interface{}{"exercise_id": "back-squat", "reps": 5, "load": 105})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	deliver()
+	types := receiver.types()
+	assert.Contains(t, types, "pr.achieved")
+	assert.NotContains(t, types, "session.completed")
+	rr = doJSON(router, "POST", "/api/sessions/"+session.ID+"/finish", token, map[string]interface{}{"finished_at": "2025-03-03T08:00:00Z"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "POST", "/api/sessions/"+session.ID+"/finish", token, map[string]interface{}{"finished_at": "2025-03-03T08:05:00Z"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	deliver()
+	assert.Equal(t, []string{"session.completed"}, receiver.types())
+
+	rr = doJSON(router, "POST", "/api/plans", token, map[string]interface{}{"type": "workout", "content": map[string]interface{}{"days": []interface{}{}}})
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var plan struct {
+		Plan models.Plan `json:"plan"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &plan)
+	rr = doJSON(router, "PUT", "/api/plans/"+plan.Plan.ID, token, map[string]interface{}{"status": "active"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	deliver()
+	assert.Equal(t, []string{"plan.updated"}, receiver.types())
+	assert.Zero(t, receiver.invalid)
+
+	// Failed deliveries stay pending with the error until they are retried.
+	receiver.status = http.StatusServiceUnavailable
+	rr = doJSON(router, "PUT", "/api/plans/"+plan.Plan.ID, token, map[string]interface{}{"status": "paused"})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	deliver()
+	receiver.types()
+	var deliveries struct {
+		Deliveries []models.WebhookDelivery `json:"deliveries"`
+	}
+	rr = doJSON(router, "GET", "/api/webhooks/"+hook.ID+"/deliveries?status=pending", token, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &deliveries)
+	require.Len(t, deliveries.Deliveries, 1)
+	failed := deliveries.Deliveries[0]
+	assert.Equal(t, "plan.updated", failed.Event)
+	assert.Equal(t, 1, failed.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, failed.ResponseStatus)
+	require.NotNil(t, failed.NextAttemptAt)
+	assert.True(t, failed.NextAttemptAt.After(time.Now()))
+
+	var one struct {
+		Delivery models.WebhookDelivery `json:"delivery"`
+	}
+	rr = doJSON(router, "GET", "/api/webhooks/"+hook.ID+"/deliveries/"+failed.ID, token, nil)
+	require.Equal(t, http.StatusOK, rr.Code)
+	json.Unmarshal(rr.Body.Bytes(), &one)
+	require.Len(t, one.Delivery.Log, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, one.Delivery.Log[0].StatusCode)
+	assert.Contains(t, string(one.Delivery.Payload), `"type":"plan.updated"`)
+
+	// Redelivering sends the same event straight away.
+	receiver.status = http.StatusAccepted
+	one.Delivery = models.WebhookDelivery{}
+	rr = doJSON(router, "POST", "/api/webhooks/"+hook.ID+"/deliveries/"+failed.ID+"/redeliver", token, nil)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	json.Unmarshal(rr.Body.Bytes(), &one)
+	assert.Equal(t, "succeeded", one.Delivery.Status)
+	assert.Equal(t, failed.EventID, one.Delivery.EventID)
+	assert.Equal(t, failed.ID, one.Delivery.RedeliveryOf)
+	assert.Equal(t, []string{"plan.updated"}, receiver.types())
+	rr = doJSON(router, "POST", "/api/webhooks/"+hook.ID+"/deliveries/"+failed.ID+"/redeliver", otherToken, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Paused webhooks get nothing.
+	rr = doJSON(router, "PUT", "/api/webhooks/"+hook.ID, token, map[string]interface{}{"active": false})
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = doJSON(router, "PUT", "/api/plans/"+plan.Plan.ID, token, map[string]interface{}{"status": "active"})
+	require.Equal(t, http.StatusOK, rr.Code)
+	deliver()
+	assert.Empty(t, receiver.types())
+
+	rr = doJSON(router, "DELETE", "/api/webhooks/"+hook.ID, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "GET", "/api/webhooks/"+hook.ID+"/deliveries", token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
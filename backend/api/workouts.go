@@ -0,0 +1,329 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/training"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+func SetupWorkoutRoutes(r chi.Router) {
+	r.Route("/api/workouts", func(r chi.Router) {
+		r.Use(AuthMiddleware)
+		r.Get("/", listWorkouts)
+		r.Post("/", createWorkout)
+		r.Get("/{id}", getWorkout)
+		r.Put("/{id}", updateWorkout)
+		r.Delete("/{id}", deleteWorkout)
+	})
+}
+
+// loadWorkoutExercises returns the exercises of the given workouts, in
+// order and with their library entries, keyed by workout ID.
+func loadWorkoutExercises(workoutIDs []string) (map[string][]models.WorkoutExercise, error) {
+	out := map[string][]models.WorkoutExercise{}
+	if len(workoutIDs) == 0 {
+		return out, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(workoutIDs)), ", ")
+	args := make([]interface{}, len(workoutIDs))
+	for i, id := range workoutIDs {
+		args[i] = id
+	}
+
+	rows, err := db.DB.Query(`SELECT `+exerciseColumns+`, we.workout_id, COALESCE(we.notes, ''), we.sets
+		FROM workout_exercises we JOIN exercises e ON e.id = we.exercise_id
+		WHERE we.workout_id IN (`+placeholders+`) ORDER BY we.workout_id, we.position`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID, notes, sets string
+		ex, err := scanExercise(rows, &workoutID, &notes, &sets)
+		if err != nil {
+			return nil, err
+		}
+
+		we := models.WorkoutExercise{ExerciseID: ex.ID, Exercise: &ex, Notes: notes, Sets: []models.SetPrescription{}}
+		json.Unmarshal([]byte(sets), &we.Sets)
+		out[workoutID] = append(out[workoutID], we)
+	}
+	return out, rows.Err()
+}
+
+func loadWorkout(id string) (models.Workout, error) {
+	var wo models.Workout
+	err := db.DB.QueryRow(`SELECT id, name, COALESCE(description, ''), COALESCE(difficulty, '') FROM workouts WHERE id = ?`, id).
+		Scan(&wo.ID, &wo.Name, &wo.Description, &wo.Difficulty)
+	if err != nil {
+		return wo, err
+	}
+	exercises, err := loadWorkoutExercises([]string{id})
+	if err != nil {
+		return wo, err
+	}
+	wo.Exercises = exercises[id]
+	if wo.Exercises == nil {
+		wo.Exercises = []models.WorkoutExercise{}
+	}
+	return wo, nil
+}
+
+// listWorkouts filters by difficulty and by the primary muscle groups and
+// equipment of the workouts' exercises.
+func listWorkouts(w http.ResponseWriter, r *http.Request) {
+	var conds []string
+	var args []interface{}
+	for _, m := range queryList(r, "muscle") {
+		conds = append(conds, `EXISTS (SELECT 1 FROM workout_exercises we JOIN exercises e ON e.id = we.exercise_id
+			WHERE we.workout_id = w.id AND `+jsonListHas("e.primary_muscles")+`)`)
+		args = append(args, training.Key(m))
+	}
+	for _, eq := range queryList(r, "equipment") {
+		conds = append(conds, `EXISTS (SELECT 1 FROM workout_exercises we JOIN exercises e ON e.id = we.exercise_id
+			WHERE we.workout_id = w.id AND `+jsonListHas("e.equipment")+`)`)
+		args = append(args, training.Key(eq))
+	}
+	if d := r.URL.Query().Get("difficulty"); d != "" {
+		conds = append(conds, `w.difficulty = ?`)
+		args = append(args, training.Key(d))
+	}
+
+	query := `SELECT w.id, w.name, COALESCE(w.description, ''), COALESCE(w.difficulty, '') FROM workouts w`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY w.name, w.id`
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		http.Error(w, "Failed to list workouts", http.StatusInternalServerError)
+		return
+	}
+	workouts := []models.Workout{}
+	var ids []string
+	for rows.Next() {
+		var wo models.Workout
+		if err := rows.Scan(&wo.ID, &wo.Name, &wo.Description, &wo.Difficulty); err != nil {
+			rows.Close()
+			http.Error(w, "Failed to list workouts", http.StatusInternalServerError)
+			return
+		}
+		workouts = append(workouts, wo)
+		ids = append(ids, wo.ID)
+	}
+	rows.Close()
+
+	exercises, err := loadWorkoutExercises(ids)
+	if err != nil {
+		http.Error(w, "Failed to list workouts", http.StatusInternalServerError)
+		return
+	}
+	for i := range workouts {
+		workouts[i].Exercises = exercises[workouts[i].ID]
+		if workouts[i].Exercises == nil {
+			workouts[i].Exercises = []models.WorkoutExercise{}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"workouts": workouts})
+}
+
+// Reps are a count, a range like "8-12", or AMRAP.
+var repsPattern = regexp.MustCompile(`^(\d+(-\d+)?|amrap)$`)
+
+func decodeWorkout(r *http.Request) (models.Workout, error) {
+	var wo models.Workout
+	if err := json.NewDecoder(r.Body).Decode(&wo); err != nil {
+		return wo, fmt.Errorf("Invalid payload")
+	}
+	wo.Name = strings.TrimSpace(wo.Name)
+	if wo.Name == "" {
+		return wo, fmt.Errorf("Workout name is required")
+	}
+	var err error
+	if wo.Difficulty, err = training.NormalizeOne("difficulty", training.Difficulties, wo.Difficulty); err != nil {
+		return wo, err
+	}
+	if wo.Exercises == nil {
+		wo.Exercises = []models.WorkoutExercise{}
+	}
+
+	for i := range wo.Exercises {
+		we := &wo.Exercises[i]
+		ex, err := loadExercise(we.ExerciseID)
+		if err != nil {
+			return wo, fmt.Errorf("exercise %d: unknown exercise_id %q", i+1, we.ExerciseID)
+		}
+		we.Exercise = &ex
+		if we.Sets == nil {
+			we.Sets = []models.SetPrescription{}
+		}
+		for j := range we.Sets {
+			set := &we.Sets[j]
+			set.Reps = strings.ToLower(strings.ReplaceAll(set.Reps, " ", ""))
+			switch {
+			case set.Reps == "" && set.DurationSeconds <= 0:
+				return wo, fmt.Errorf("exercise %d set %d: reps or duration_seconds is required", i+1, j+1)
+			case set.Reps != "" && !repsPattern.MatchString(set.Reps):
+				return wo, fmt.Errorf("exercise %d set %d: reps must be a number, a range like 8-12 or AMRAP", i+1, j+1)
+			case set.Weight < 0 || set.RestSeconds < 0 || set.DurationSeconds < 0:
+				return wo, fmt.Errorf("exercise %d set %d: values cannot be negative", i+1, j+1)
+			case set.RPE < 0 || set.RPE > 10:
+				return wo, fmt.Errorf("exercise %d set %d: rpe must be between 0 and 10", i+1, j+1)
+			}
+		}
+	}
+	return wo, nil
+}
+
+// saveWorkoutExercises replaces the exercise list of a workout.
+func saveWorkoutExercises(tx *sql.Tx, wo models.Workout) error {
+	if _, err := tx.Exec(`DELETE FROM workout_exercises WHERE workout_id = ?`, wo.ID); err != nil {
+		return err
+	}
+	for i, we := range wo.Exercises {
+		_, err := tx.Exec(`INSERT INTO workout_exercises (workout_id, position, exercise_id, notes, sets) VALUES (?, ?, ?, ?, ?)`,
+			wo.ID, i, we.ExerciseID, utils.NullIfEmpty(we.Notes), encodeJSON(we.Sets))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canEditWorkout reports whether the user may change a workout: their own,
+// or as an admin any workout, including catalog workouts that have no owner.
+func canEditWorkout(userID, id string) (bool, error) {
+	var owner *string
+	if err := db.DB.QueryRow(`SELECT user_id FROM workouts WHERE id = ?`, id).Scan(&owner); err != nil {
+		return false, err
+	}
+	return (owner != nil && *owner == userID) || isAdmin(userID), nil
+}
+
+func createWorkout(w http.ResponseWriter, r *http.Request) {
+	wo, err := decodeWorkout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wo.ID = uuid.New().String()
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to create workout", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO workouts (id, user_id, name, description, difficulty) VALUES (?, ?, ?, ?, ?)`,
+		wo.ID, r.Header.Get("X-User-ID"), wo.Name, wo.Description, utils.NullIfEmpty(wo.Difficulty))
+	if err == nil {
+		err = saveWorkoutExercises(tx, wo)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		http.Error(w, "Failed to create workout", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"workout": wo})
+}
+
+func getWorkout(w http.ResponseWriter, r *http.Request) {
+	wo, err := loadWorkout(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Workout not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"workout": wo})
+}
+
+func updateWorkout(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok, err := canEditWorkout(r.Header.Get("X-User-ID"), id)
+	if err != nil {
+		http.Error(w, "Workout not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "Only the workout's owner can change it", http.StatusForbidden)
+		return
+	}
+
+	wo, err := decodeWorkout(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	wo.ID = id
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to update workout", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE workouts SET name = ?, description = ?, difficulty = ? WHERE id = ?`,
+		wo.Name, wo.Description, utils.NullIfEmpty(wo.Difficulty), id)
+	if err == nil {
+		err = saveWorkoutExercises(tx, wo)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		http.Error(w, "Failed to update workout", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"workout": wo})
+}
+
+func deleteWorkout(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	ok, err := canEditWorkout(r.Header.Get("X-User-ID"), id)
+	if err != nil {
+		http.Error(w, "Workout not found", http.StatusNotFound)
+		return
+	}
+	if !ok {
+		http.Error(w, "Only the workout's owner can delete it", http.StatusForbidden)
+		return
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		http.Error(w, "Failed to delete workout", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM workout_exercises WHERE workout_id = ?`, id)
+	if err == nil {
+		_, err = tx.Exec(`DELETE FROM workouts WHERE id = ?`, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete workout", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
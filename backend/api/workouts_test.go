@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func listWorkoutNames(t *testing.T, router http.Handler, token, query string) []string {
+	t.Helper()
+	rr := doJSON(router, "GET", "/api/workouts"+query, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Workouts []models.Workout `json:"workouts"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &resp)
+	names := []string{}
+	for _, wo := range resp.Workouts {
+		names = append(names, wo.Name)
+	}
+	return names
+}
+
+func TestWorkouts(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	router := chi.NewRouter()
+	SetupExerciseRoutes(router)
+	SetupWorkoutRoutes(router)
+	token := createTestUser(t, "user-123")
+
+	rr := doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{
+		"name": "Lower A", "difficulty": "Intermediate",
+		"exercises": []interface{}{
+			map[string]interface{}{"exercise_id": "back-squat", "sets": []interface{}{
+				map[string]interface{}{"reps": "5", "weight": 60, "warmup": true},
+				map[string]interface{}{"reps": "5", "weight": 100, "rest_seconds": 180},
+				map[string]interface{}{"reps": "5", "weight": 100, "rest_seconds": 180},
+			}},
+			map[string]interface{}{"exercise_id": "romanian-deadlift", "notes": "Slow eccentric", "sets": []interface{}{
+				map[string]interface{}{"reps": "8 - 10", "rpe": 8},
+			}},
+		},
+	})
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var created struct {
+		Workout models.Workout `json:"workout"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	assert.Equal(t, "intermediate", created.Workout.Difficulty)
+
+	doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{
+		"name": "Upper A", "difficulty": "beginner",
+		"exercises": []interface{}{
+			map[string]interface{}{"exercise_id": "push-up", "sets": []interface{}{map[string]interface{}{"reps": "AMRAP"}}},
+			map[string]interface{}{"exercise_id": "plank", "sets": []interface{}{map[string]interface{}{"duration_seconds": 60}}},
+		},
+	})
+
+	rr = doJSON(router, "GET", "/api/workouts/"+created.Workout.ID, token, nil)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var got struct {
+		Workout models.Workout `json:"workout"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &got)
+	assert.Len(t, got.Workout.Exercises, 2)
+	assert.Equal(t, "back-squat", got.Workout.Exercises[0].ExerciseID)
+	assert.Equal(t, "Barbell Back Squat", got.Workout.Exercises[0].Exercise.Name)
+	assert.Len(t, got.Workout.Exercises[0].Sets, 3)
+	assert.True(t, got.Workout.Exercises[0].Sets[0].Warmup)
+	assert.Equal(t, "8-10", got.Workout.Exercises[1].Sets[0].Reps)
+	assert.Equal(t, "Slow eccentric", got.Workout.Exercises[1].Notes)
+
+	assert.Equal(t, []string{"Lower A"}, listWorkoutNames(t, router, token, "?muscle=hamstrings"))
+	assert.Equal(t, []string{"Upper A"}, listWorkoutNames(t, router, token, "?equipment=bodyweight"))
+	assert.Equal(t, []string{"Upper A"}, listWorkoutNames(t, router, token, "?difficulty=beginner"))
+	assert.Equal(t, []string{"Lower A", "Upper A"}, listWorkoutNames(t, router, token, ""))
+
+	rr = doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{
+		"name": "Bad", "exercises": []interface{}{map[string]interface{}{"exercise_id": "nope"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{
+		"name": "Bad", "exercises": []interface{}{map[string]interface{}{"exercise_id": "plank", "sets": []interface{}{map[string]interface{}{"reps": "lots"}}}},
+	})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = doJSON(router, "POST", "/api/workouts", token, map[string]interface{}{"name": "Bad", "difficulty": "elite"})
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Exercises in use cannot be deleted.
+	rr = doJSON(router, "DELETE", "/api/exercises/back-squat", token, nil)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	path := "/api/workouts/" + created.Workout.ID
+	rr = doJSON(router, "PUT", path, token, map[string]interface{}{
+		"name": "Lower A", "exercises": []interface{}{
+			map[string]interface{}{"exercise_id": "deadlift", "sets": []interface{}{map[string]interface{}{"reps": "3"}}},
+		},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Empty(t, listWorkoutNames(t, router, token, "?muscle=quads"))
+
+	// Only the owner, or an admin, can change or delete a workout.
+	otherToken := createTestUser(t, "user-456")
+	rr = doJSON(router, "PUT", path, otherToken, map[string]interface{}{"name": "Mine now"})
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = doJSON(router, "DELETE", path, otherToken, nil)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	t.Setenv("ADMIN_EMAILS", "user-456@example.com")
+	rr = doJSON(router, "PUT", path, otherToken, map[string]interface{}{"name": "Lower A"})
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = doJSON(router, "DELETE", path, token, nil)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	rr = doJSON(router, "DELETE", "/api/exercises/back-squat", token, nil)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
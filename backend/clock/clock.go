@@ -0,0 +1,52 @@
+// Package clock is the time source for code that schedules work, such as
+// jobs and reminders, so tests can fix the time and move it along.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Now returns the current time. Tests replace it with a Fake's Now.
+var Now = time.Now
+
+// Fake is a clock that only moves when told to. It is safe to use from
+// several goroutines.
+type Fake struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFake returns a fake clock reading t.
+func NewFake(t time.Time) *Fake {
+	return &Fake{now: t}
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set moves the clock to t.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Advance moves the clock on by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Install makes f the clock read through Now, until the returned function
+// restores the previous one.
+func (f *Fake) Install() func() {
+	old := Now
+	Now = f.Now
+	return func() { Now = old }
+}
@@ -0,0 +1,190 @@
+// Command import loads bulk data into the Fitness.ai database.
+//
+// Usage:
+//
+//	go run ./cmd/import foods -format json FoodData_Central_foundation_food_json.json
+//	go run ./cmd/import foods -format csv  FoodData_Central_csv_2024-04-18/
+//	go run ./cmd/import products en.openfoodfacts.org.products.csv.gz
+//	go run ./cmd/import products -format jsonl openfoodfacts-products.jsonl.gz
+//	go run ./cmd/import recipes My\ Recipes.paprikarecipes
+//	go run ./cmd/import recipes -format csv -dry-run recipes.csv
+//
+// It uses the same DATABASE_URL as the server and must be run from the
+// backend directory so db/schema.sql can be found.
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+	"github.com/terr0r/fitness.ai/backend/recipeimport"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: import <foods|products|recipes> [flags] <path>")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+	if err := db.InitDB(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.CloseDB()
+	if err := db.RunSchema("db/schema.sql"); err != nil {
+		log.Fatalf("Failed to run schema migration: %v", err)
+	}
+
+	switch os.Args[1] {
+	case "foods":
+		importFoods(os.Args[2:])
+	case "products":
+		importProducts(os.Args[2:])
+	case "recipes":
+		importRecipes(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func importFoods(args []string) {
+	fs := flag.NewFlagSet("foods", flag.ExitOnError)
+	format := fs.String("format", "json", "dump format: json (single file) or csv (unpacked directory)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	path := fs.Arg(0)
+
+	var read func(fn func(models.Food) error) (int, error)
+	switch *format {
+	case "json":
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", path, err)
+		}
+		defer f.Close()
+		read = func(fn func(models.Food) error) (int, error) { return nutrition.ReadFDCJSON(f, fn) }
+	case "csv":
+		read = func(fn func(models.Food) error) (int, error) { return nutrition.ReadFDCCSV(path, fn) }
+	default:
+		log.Fatalf("Unknown format %q", *format)
+	}
+
+	n, err := nutrition.ImportFoods(read)
+	if err != nil {
+		log.Fatalf("Import failed after %d foods: %v", n, err)
+	}
+	fmt.Printf("Imported %d foods\n", n)
+
+	// Recipes saved before the import may now resolve more ingredients.
+	recipes, err := nutrition.RecomputeAllRecipes()
+	if err != nil {
+		log.Fatalf("Failed to recompute recipe nutrition: %v", err)
+	}
+	fmt.Printf("Recomputed nutrition for %d recipes\n", recipes)
+}
+
+// importProducts loads an Open Food Facts export, gzipped or not, into the
+// barcode lookup table.
+func importProducts(args []string) {
+	fs := flag.NewFlagSet("products", flag.ExitOnError)
+	format := fs.String("format", "", "csv or jsonl; detected from the file name when empty")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	path := fs.Arg(0)
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	name := strings.TrimSuffix(path, ".gz")
+	if name != path {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	if *format == "" {
+		*format = "csv"
+		if strings.HasSuffix(name, ".jsonl") || strings.HasSuffix(name, ".json") {
+			*format = "jsonl"
+		}
+	}
+
+	var read func(fn func(models.Product) error) (int, error)
+	switch *format {
+	case "csv":
+		read = func(fn func(models.Product) error) (int, error) { return nutrition.ReadOFFCSV(r, fn) }
+	case "jsonl":
+		read = func(fn func(models.Product) error) (int, error) { return nutrition.ReadOFFJSONL(r, fn) }
+	default:
+		log.Fatalf("Unknown format %q", *format)
+	}
+
+	n, err := nutrition.ImportProducts(read)
+	if err != nil {
+		log.Fatalf("Import failed after %d products: %v", n, err)
+	}
+	fmt.Printf("Imported %d products\n", n)
+}
+
+func importRecipes(args []string) {
+	fs := flag.NewFlagSet("recipes", flag.ExitOnError)
+	format := fs.String("format", "", "jsonld, paprika or csv; detected from the file when empty")
+	dryRun := fs.Bool("dry-run", false, "parse and report without saving")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	path := fs.Arg(0)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+	results, err := recipeimport.Parse(*format, data)
+	if err != nil {
+		log.Fatalf("Failed to parse %s: %v", path, err)
+	}
+
+	imported := 0
+	for _, res := range results {
+		if res.Error != "" {
+			fmt.Printf("skipped: %s\n", res.Error)
+			continue
+		}
+		if len(res.Unmapped) > 0 {
+			fmt.Printf("%s: unmapped fields %s\n", res.Recipe.Name, strings.Join(res.Unmapped, ", "))
+		}
+		if *dryRun {
+			continue
+		}
+		if _, err := recipeimport.Save("", res.Recipe); err != nil {
+			log.Fatalf("Failed to save %q: %v", res.Recipe.Name, err)
+		}
+		imported++
+	}
+	fmt.Printf("Imported %d of %d recipes\n", imported, len(results))
+}
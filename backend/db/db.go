@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -12,6 +13,19 @@ import (
 
 var DB *sql.DB
 
+// WithBusyTimeout adds a busy timeout to a database path, so a query made
+// while a background job is writing waits for it instead of failing.
+func WithBusyTimeout(dbPath string) string {
+	if strings.Contains(dbPath, "busy_timeout") {
+		return dbPath
+	}
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return dbPath + sep + "_pragma=busy_timeout(5000)"
+}
+
 func InitDB() error {
 	dbPath := os.Getenv("DATABASE_URL")
 	if dbPath == "" {
@@ -19,7 +33,7 @@ func InitDB() error {
 	}
 
 	var err error
-	DB, err = sql.Open("sqlite", dbPath)
+	DB, err = sql.Open("sqlite", WithBusyTimeout(dbPath))
 	if err != nil {
 		return fmt.Errorf("unable to connect to database: %v", err)
 	}
@@ -51,6 +65,12 @@ func RunSchema(schemaPath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to execute schema: %v", err)
 	}
+	if err := addColumns(ctx); err != nil {
+		return fmt.Errorf("failed to migrate schema: %v", err)
+	}
+	if err := migrateJobQueue(ctx); err != nil {
+		return fmt.Errorf("failed to migrate to the job queue: %v", err)
+	}
 
 	fmt.Println("Database schema applied successfully")
 	return nil
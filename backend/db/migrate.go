@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// column is a column added to a table after the table was first created.
+// CREATE TABLE IF NOT EXISTS leaves existing tables as they are, so such
+// columns are added here instead of in schema.sql.
+type column struct {
+	table      string
+	name       string
+	definition string
+	// backfill, if set, runs once after the column is added.
+	backfill string
+}
+
+var columns = []column{
+	{table: "users", name: "timezone", definition: "TEXT"}, // IANA name, e.g. Europe/London
+	// NULL for catalog recipes, which only admins can change.
+	{table: "recipes", name: "user_id", definition: "TEXT REFERENCES users(id) ON DELETE SET NULL"},
+	{table: "recipes", name: "servings", definition: "INTEGER DEFAULT 1"},
+	{table: "recipes", name: "nutrition", definition: "TEXT"}, // JSON stored as text, computed per serving
+	{table: "users", name: "diets", definition: "TEXT"},       // JSON array, e.g. ["vegan"]
+	{table: "users", name: "allergens", definition: "TEXT"},   // JSON array of allergen groups
+	// NULL for catalog workouts, which only admins can change.
+	{table: "workouts", name: "user_id", definition: "TEXT REFERENCES users(id) ON DELETE SET NULL"},
+	{table: "users", name: "e1rm_formula", definition: "TEXT"},          // epley, brzycki, ...; NULL for the default
+	{table: "users", name: "max_heart_rate", definition: "INTEGER"},     // measured, bpm; NULL to estimate from age
+	{table: "users", name: "resting_heart_rate", definition: "INTEGER"}, // bpm
+	// JSON time in zone, TRIMP and load; NULL without heart rate.
+	{table: "cardio_sessions", name: "hr_load", definition: "TEXT"},
+	// Copy of the hr_load score for the fitness curve.
+	{table: "cardio_sessions", name: "load", definition: "REAL NOT NULL DEFAULT 0"},
+	// ALTER TABLE cannot default to CURRENT_TIMESTAMP, so writers set it.
+	{table: "journals", name: "updated_at", definition: "DATETIME",
+		backfill: `UPDATE journals SET updated_at = created_at WHERE updated_at IS NULL`},
+}
+
+// addColumns adds the columns a database is missing.
+func addColumns(ctx context.Context) error {
+	for _, c := range columns {
+		var n int
+		err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.name).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := DB.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.name, c.definition)); err != nil {
+			return fmt.Errorf("adding %s.%s: %v", c.table, c.name, err)
+		}
+		if c.backfill != "" {
+			if _, err := DB.ExecContext(ctx, c.backfill); err != nil {
+				return fmt.Errorf("filling in %s.%s: %v", c.table, c.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// migrateJobQueue moves work from before the job queue onto it. Imports
+// are copied into jobs under their new kinds, and pending webhook
+// deliveries, which used to be polled for, get a job each. It runs while
+// the old import_jobs table is there, and drops it.
+func migrateJobQueue(ctx context.Context) error {
+	var n int
+	err := DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'import_jobs'`).Scan(&n)
+	if err != nil || n == 0 {
+		return err
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := copyImportJobs(ctx, tx); err != nil {
+		return fmt.Errorf("copying import_jobs: %v", err)
+	}
+	if err := queuePendingDeliveries(ctx, tx); err != nil {
+		return fmt.Errorf("queueing webhook deliveries: %v", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DROP TABLE import_jobs`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// copyImportJobs copies imports into jobs, keeping their IDs. Imports that
+// had not finished have lost their uploads, so they fail, as they would
+// have on a restart.
+func copyImportJobs(ctx context.Context, tx *sql.Tx) error {
+	type importJob struct {
+		id, userID, kind, status       string
+		stage, summary, errText        sql.NullString
+		progress                       float64
+		createdAt, updatedAt, finished sql.NullTime
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT id, user_id, kind, status, stage, progress, summary, error, created_at, updated_at, finished_at
+		FROM import_jobs`)
+	if err != nil {
+		return err
+	}
+	var list []importJob
+	for rows.Next() {
+		var j importJob
+		err := rows.Scan(&j.id, &j.userID, &j.kind, &j.status, &j.stage, &j.progress, &j.summary, &j.errText, &j.createdAt, &j.updatedAt, &j.finished)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, j := range list {
+		if j.status != "completed" && j.status != "failed" {
+			j.status = "failed"
+			j.errText = sql.NullString{String: "interrupted by a server restart", Valid: true}
+			j.finished = sql.NullTime{Time: now, Valid: true}
+		}
+		// run_at is in the form utils.FormatTime writes, which cannot be
+		// called from here as utils uses this package.
+		runAt := now
+		if j.createdAt.Valid {
+			runAt = j.createdAt.Time
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO jobs (id, user_id, kind, status, stage, progress, attempts, max_attempts, run_at, result, error,
+			created_at, updated_at, finished_at) VALUES (?, ?, ?, ?, ?, ?, 1, 1, ?, ?, ?, ?, ?, ?)`,
+			j.id, j.userID, "import."+j.kind, j.status, j.stage, j.progress, runAt.UTC().Format(time.RFC3339), j.summary, j.errText,
+			j.createdAt, j.updatedAt, j.finished)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queuePendingDeliveries gives each pending webhook delivery without one a
+// job, due when its next attempt was.
+func queuePendingDeliveries(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, next_attempt_at FROM webhook_deliveries d
+		WHERE status = 'pending' AND next_attempt_at IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM jobs WHERE kind = 'webhook.deliver' AND json_extract(payload, '$.delivery_id') = d.id)`)
+	if err != nil {
+		return err
+	}
+	due := map[string]time.Time{}
+	for rows.Next() {
+		var id string
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			rows.Close()
+			return err
+		}
+		due[id] = at
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for id, at := range due {
+		payload, err := json.Marshal(map[string]string{"delivery_id": id})
+		if err != nil {
+			return err
+		}
+		// The jobs package's default attempts; a failed send is retried by
+		// the delivery, not the job.
+		_, err = tx.ExecContext(ctx, `INSERT INTO jobs (id, kind, payload, max_attempts, run_at, created_at, updated_at)
+			VALUES (?, 'webhook.deliver', ?, 5, ?, ?, ?)`, uuid.New().String(), string(payload), at.UTC().Format(time.RFC3339), now, now)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
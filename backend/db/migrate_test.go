@@ -0,0 +1,137 @@
+package db
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// oldSchema is a database created before columns were added to its tables,
+// and before the job queue.
+const oldSchema = `
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL,
+    password_hash TEXT,
+    name TEXT,
+    age INTEGER,
+    gender TEXT,
+    height REAL,
+    weight REAL,
+    activity_level TEXT,
+    country TEXT,
+    goals TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE recipes (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    ingredients TEXT,
+    instructions TEXT,
+    macros TEXT,
+    tags TEXT
+);
+CREATE TABLE journals (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    type TEXT NOT NULL,
+    entry_data TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE import_jobs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'queued',
+    stage TEXT,
+    progress REAL NOT NULL DEFAULT 0,
+    summary TEXT,
+    error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    finished_at DATETIME
+);
+CREATE TABLE webhooks (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL
+);
+CREATE TABLE webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO users (id, email) VALUES ('u1', 'u1@example.com');
+INSERT INTO import_jobs (id, user_id, kind, status, summary, created_at, finished_at)
+    VALUES ('i1', 'u1', 'apple_health', 'completed', '{"workouts":3}', '2025-01-01 08:00:00', '2025-01-01 08:05:00');
+INSERT INTO import_jobs (id, user_id, kind, status, created_at) VALUES ('i2', 'u1', 'apple_health', 'running', '2025-01-02 08:00:00');
+INSERT INTO webhooks (id, user_id, url, secret, events) VALUES ('h1', 'u1', 'https://hooks.example.com', 'secret', '["journal.created"]');
+INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, payload, status, next_attempt_at)
+    VALUES ('d1', 'h1', 'e1', 'journal.created', '{}', 'pending', '2025-01-03T09:00:00Z'),
+           ('d2', 'h1', 'e2', 'journal.created', '{}', 'succeeded', NULL);
+INSERT INTO journals (id, user_id, date, type, created_at) VALUES ('j1', 'u1', '2025-01-01', 'weight', '2025-01-01 08:00:00');
+INSERT INTO recipes (id, name) VALUES ('r1', 'Porridge');
+`
+
+func TestRunSchemaUpgrades(t *testing.T) {
+	var err error
+	DB, err = sql.Open("sqlite", WithBusyTimeout(filepath.Join(t.TempDir(), "old.db")))
+	require.NoError(t, err)
+	defer func() { CloseDB(); DB = nil }()
+
+	_, err = DB.Exec(oldSchema)
+	require.NoError(t, err)
+	require.NoError(t, RunSchema("schema.sql"))
+	// Running it again finds nothing to add.
+	require.NoError(t, RunSchema("schema.sql"))
+
+	for _, c := range columns {
+		var n int
+		DB.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.name).Scan(&n)
+		assert.Equal(t, 1, n, "%s.%s", c.table, c.name)
+	}
+	_, err = DB.Exec(`UPDATE users SET timezone = 'Europe/London' WHERE id = 'u1'`)
+	assert.NoError(t, err)
+	var servings int
+	require.NoError(t, DB.QueryRow(`SELECT servings FROM recipes WHERE id = 'r1'`).Scan(&servings))
+	assert.Equal(t, 1, servings)
+	// Existing entries were last updated when they were created.
+	var updated time.Time
+	require.NoError(t, DB.QueryRow(`SELECT updated_at FROM journals WHERE id = 'j1'`).Scan(&updated))
+	assert.Equal(t, time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC), updated)
+
+	// Imports moved to the job queue, and unfinished ones failed.
+	var n int
+	DB.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'import_jobs'`).Scan(&n)
+	assert.Equal(t, 0, n)
+	var kind, status, result, runAt string
+	require.NoError(t, DB.QueryRow(`SELECT kind, status, result, run_at FROM jobs WHERE id = 'i1'`).Scan(&kind, &status, &result, &runAt))
+	assert.Equal(t, "import.apple_health", kind)
+	assert.Equal(t, "completed", status)
+	assert.JSONEq(t, `{"workouts":3}`, result)
+	assert.Equal(t, "2025-01-01T08:00:00Z", runAt)
+	require.NoError(t, DB.QueryRow(`SELECT status FROM jobs WHERE id = 'i2'`).Scan(&status))
+	assert.Equal(t, "failed", status)
+	// The pending delivery has a job, once.
+	var payload string
+	require.NoError(t, DB.QueryRow(`SELECT payload, run_at FROM jobs WHERE kind = 'webhook.deliver'`).Scan(&payload, &runAt))
+	assert.JSONEq(t, `{"delivery_id":"d1"}`, payload)
+	assert.Equal(t, "2025-01-03T09:00:00Z", runAt)
+	DB.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&n)
+	assert.Equal(t, 3, n)
+}
@@ -39,14 +39,476 @@ CREATE TABLE IF NOT EXISTS workouts (
     name TEXT NOT NULL,
     description TEXT,
     difficulty TEXT,
-    exercises TEXT -- JSON stored as text
+    exercises TEXT -- JSON stored as text; superseded by workout_exercises
 );
 
 CREATE TABLE IF NOT EXISTS journals (
     id TEXT PRIMARY KEY,
     user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     date DATE NOT NULL,
-    type TEXT NOT NULL,
-    entry_data TEXT, -- JSON stored as text
+    type TEXT NOT NULL, -- a registered journal entry type: food, weight, ...
+    entry_data TEXT, -- JSON stored as text, validated against the type
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_journals_user_date ON journals(user_id, date, type);
+
+CREATE TABLE IF NOT EXISTS habits (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    entry_type TEXT NOT NULL, -- journal entry type the habit reads
+    field TEXT, -- NULL when entries are counted
+    aggregate TEXT NOT NULL, -- sum, max, min, avg or count
+    comparison TEXT NOT NULL, -- at_least or at_most
+    target REAL NOT NULL,
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 );
+
+CREATE INDEX IF NOT EXISTS idx_habits_user ON habits(user_id);
+
+CREATE TABLE IF NOT EXISTS plan_revisions (
+    id TEXT PRIMARY KEY,
+    plan_id TEXT NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL, -- JSON stored as text
+    author TEXT NOT NULL,
+    source TEXT NOT NULL, -- manual, generator, adaptive or rollback
+    reason TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (plan_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id TEXT NOT NULL REFERENCES plans(id) ON DELETE CASCADE,
+    plan_day INTEGER NOT NULL,
+    original_date DATE NOT NULL, -- date the plan put it on; never changes
+    scheduled_date DATE NOT NULL,
+    start_time TEXT, -- HH:MM local time, NULL for all-day
+    duration_minutes INTEGER,
+    title TEXT,
+    status TEXT NOT NULL DEFAULT 'scheduled', -- scheduled, skipped or completed
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (plan_id, plan_day, original_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_sessions_user_date ON scheduled_sessions(user_id, scheduled_date);
+
+CREATE TABLE IF NOT EXISTS calendar_tokens (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT UNIQUE NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Full-text index over recipes, kept in sync by the triggers below.
+CREATE VIRTUAL TABLE IF NOT EXISTS recipes_fts USING fts5(
+    recipe_id UNINDEXED,
+    name,
+    description,
+    ingredients,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS recipes_fts_insert AFTER INSERT ON recipes BEGIN
+    INSERT INTO recipes_fts (recipe_id, name, description, ingredients) VALUES (new.id, new.name, new.description, new.ingredients);
+END;
+
+CREATE TRIGGER IF NOT EXISTS recipes_fts_update AFTER UPDATE ON recipes BEGIN
+    DELETE FROM recipes_fts WHERE recipe_id = old.id;
+    INSERT INTO recipes_fts (recipe_id, name, description, ingredients) VALUES (new.id, new.name, new.description, new.ingredients);
+END;
+
+CREATE TRIGGER IF NOT EXISTS recipes_fts_delete AFTER DELETE ON recipes BEGIN
+    DELETE FROM recipes_fts WHERE recipe_id = old.id;
+END;
+
+-- Index recipes that existed before the FTS table did.
+INSERT INTO recipes_fts (recipe_id, name, description, ingredients)
+SELECT id, name, description, ingredients FROM recipes
+WHERE id NOT IN (SELECT recipe_id FROM recipes_fts);
+
+CREATE TABLE IF NOT EXISTS foods (
+    id TEXT PRIMARY KEY,
+    fdc_id INTEGER UNIQUE,
+    name TEXT NOT NULL,
+    category TEXT,
+    -- Nutrients per 100g; micronutrients in mg
+    calories REAL DEFAULT 0,
+    protein REAL DEFAULT 0,
+    carbs REAL DEFAULT 0,
+    fat REAL DEFAULT 0,
+    fiber REAL DEFAULT 0,
+    sugar REAL DEFAULT 0,
+    sodium_mg REAL DEFAULT 0,
+    potassium_mg REAL DEFAULT 0,
+    calcium_mg REAL DEFAULT 0,
+    iron_mg REAL DEFAULT 0,
+    vitamin_c_mg REAL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS food_portions (
+    food_id TEXT NOT NULL REFERENCES foods(id) ON DELETE CASCADE,
+    unit TEXT NOT NULL,
+    grams REAL NOT NULL,
+    PRIMARY KEY (food_id, unit)
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS foods_fts USING fts5(
+    food_id UNINDEXED,
+    name,
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS foods_fts_insert AFTER INSERT ON foods BEGIN
+    INSERT INTO foods_fts (food_id, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS foods_fts_update AFTER UPDATE OF name ON foods BEGIN
+    DELETE FROM foods_fts WHERE food_id = old.id;
+    INSERT INTO foods_fts (food_id, name) VALUES (new.id, new.name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS foods_fts_delete AFTER DELETE ON foods BEGIN
+    DELETE FROM foods_fts WHERE food_id = old.id;
+END;
+
+CREATE TABLE IF NOT EXISTS products (
+    barcode TEXT PRIMARY KEY, -- normalised EAN-13 or EAN-8
+    name TEXT NOT NULL,
+    brand TEXT,
+    quantity TEXT,
+    serving_size TEXT,
+    serving_grams REAL,
+    -- Nutrients per 100g; micronutrients in mg
+    calories REAL DEFAULT 0,
+    protein REAL DEFAULT 0,
+    carbs REAL DEFAULT 0,
+    fat REAL DEFAULT 0,
+    fiber REAL DEFAULT 0,
+    sugar REAL DEFAULT 0,
+    sodium_mg REAL DEFAULT 0,
+    potassium_mg REAL DEFAULT 0,
+    calcium_mg REAL DEFAULT 0,
+    iron_mg REAL DEFAULT 0,
+    vitamin_c_mg REAL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS product_overrides (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    barcode TEXT NOT NULL,
+    data TEXT NOT NULL, -- JSON stored as text: the fields replaced
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, barcode)
+);
+
+CREATE TABLE IF NOT EXISTS recipe_ingredients (
+    recipe_id TEXT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    raw TEXT NOT NULL,
+    quantity REAL,
+    unit TEXT,
+    name TEXT,
+    food_id TEXT REFERENCES foods(id) ON DELETE SET NULL,
+    grams REAL,
+    resolved INTEGER NOT NULL DEFAULT 0, -- 1 when grams could be worked out
+    PRIMARY KEY (recipe_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS pantry_items (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL, -- normalized ingredient name, e.g. "olive oil"
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, name)
+);
+
+-- Exercise library. Muscle, equipment and instruction lists are JSON arrays.
+CREATE TABLE IF NOT EXISTS exercises (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    primary_muscles TEXT NOT NULL DEFAULT '[]',
+    secondary_muscles TEXT NOT NULL DEFAULT '[]',
+    equipment TEXT NOT NULL DEFAULT '[]',
+    movement_pattern TEXT,
+    unilateral INTEGER NOT NULL DEFAULT 0,
+    instructions TEXT NOT NULL DEFAULT '[]'
+);
+
+-- Ordered exercises of a workout; sets is a JSON array of prescriptions.
+CREATE TABLE IF NOT EXISTS workout_exercises (
+    workout_id TEXT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    exercise_id TEXT NOT NULL REFERENCES exercises(id),
+    notes TEXT,
+    sets TEXT NOT NULL DEFAULT '[]',
+    PRIMARY KEY (workout_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_exercises_exercise ON workout_exercises(exercise_id);
+
+-- Starter library of common exercises.
+INSERT OR IGNORE INTO exercises (id, name, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral, instructions) VALUES
+    ('back-squat', 'Barbell Back Squat', '["quads","glutes"]', '["adductors","lower_back"]', '["barbell"]', 'squat', 0,
+        '["Set the bar on your upper back and brace.","Sit down between your heels until the hips pass the knees.","Drive up through the whole foot."]'),
+    ('front-squat', 'Front Squat', '["quads"]', '["glutes","upper_back"]', '["barbell"]', 'squat', 0,
+        '["Rack the bar on the front delts with elbows high.","Squat down keeping the torso upright.","Stand up without letting the elbows drop."]'),
+    ('goblet-squat', 'Goblet Squat', '["quads","glutes"]', '["abs"]', '["dumbbell"]', 'squat', 0,
+        '["Hold a dumbbell at your chest.","Squat down between your knees.","Stand back up."]'),
+    ('deadlift', 'Deadlift', '["hamstrings","glutes","lower_back"]', '["traps","forearms","quads"]', '["barbell"]', 'hinge', 0,
+        '["Stand with the bar over mid-foot.","Grip the bar, flatten the back and pull the slack out.","Push the floor away and lock out the hips."]'),
+    ('romanian-deadlift', 'Romanian Deadlift', '["hamstrings","glutes"]', '["lower_back"]', '["barbell"]', 'hinge', 0,
+        '["Start standing with the bar.","Push the hips back with soft knees until the hamstrings stretch.","Return by driving the hips forward."]'),
+    ('hip-thrust', 'Barbell Hip Thrust', '["glutes"]', '["hamstrings"]', '["barbell","bench"]', 'hinge', 0,
+        '["Sit with your upper back against a bench and the bar over your hips.","Drive the hips up until the torso is level.","Lower under control."]'),
+    ('bulgarian-split-squat', 'Bulgarian Split Squat', '["quads","glutes"]', '["adductors"]', '["dumbbell","bench"]', 'lunge', 1,
+        '["Rest the rear foot on a bench.","Lower until the front thigh is parallel.","Drive up through the front foot."]'),
+    ('walking-lunge', 'Walking Lunge', '["quads","glutes"]', '["hamstrings"]', '["dumbbell"]', 'lunge', 1,
+        '["Step forward and lower the back knee towards the floor.","Push through the front foot into the next step."]'),
+    ('bench-press', 'Barbell Bench Press', '["chest"]', '["front_delts","triceps"]', '["barbell","bench"]', 'horizontal_push', 0,
+        '["Lie on the bench with eyes under the bar and shoulder blades pinched.","Lower the bar to the lower chest.","Press back up over the shoulders."]'),
+    ('incline-dumbbell-press', 'Incline Dumbbell Press', '["chest","front_delts"]', '["triceps"]', '["dumbbell","bench"]', 'horizontal_push', 0,
+        '["Set the bench to 30 degrees.","Lower the dumbbells to the sides of the chest.","Press up and slightly in."]'),
+    ('push-up', 'Push-up', '["chest"]', '["triceps","front_delts","abs"]', '["bodyweight"]', 'horizontal_push', 0,
+        '["Hold a plank with hands under the shoulders.","Lower the chest to the floor.","Push back up keeping the body straight."]'),
+    ('dip', 'Dip', '["chest","triceps"]', '["front_delts"]', '["bodyweight"]', 'vertical_push', 0,
+        '["Support yourself on the bars with straight arms.","Lower until the shoulders are below the elbows.","Press back up."]'),
+    ('overhead-press', 'Overhead Press', '["front_delts"]', '["triceps","side_delts","upper_back"]', '["barbell"]', 'vertical_push', 0,
+        '["Hold the bar at the collarbones.","Press it overhead, moving the head out of the way.","Lock out with the bar over mid-foot."]'),
+    ('lateral-raise', 'Dumbbell Lateral Raise', '["side_delts"]', '["traps"]', '["dumbbell"]', 'isolation', 0,
+        '["Hold dumbbells at your sides.","Raise them out to shoulder height.","Lower slowly."]'),
+    ('barbell-row', 'Barbell Row', '["upper_back","lats"]', '["rear_delts","biceps","lower_back"]', '["barbell"]', 'horizontal_pull', 0,
+        '["Hinge forward with a flat back.","Row the bar to the lower ribs.","Lower with control."]'),
+    ('one-arm-dumbbell-row', 'One-arm Dumbbell Row', '["lats","upper_back"]', '["biceps","rear_delts"]', '["dumbbell","bench"]', 'horizontal_pull', 1,
+        '["Brace one hand and knee on a bench.","Row the dumbbell towards the hip.","Lower until the arm is straight."]'),
+    ('pull-up', 'Pull-up', '["lats"]', '["biceps","upper_back"]', '["pull_up_bar"]', 'vertical_pull', 0,
+        '["Hang from the bar with an overhand grip.","Pull until the chin clears the bar.","Lower to a full hang."]'),
+    ('lat-pulldown', 'Lat Pulldown', '["lats"]', '["biceps","upper_back"]', '["cable","machine"]', 'vertical_pull', 0,
+        '["Grip the bar wider than the shoulders.","Pull it to the upper chest.","Let it rise until the arms are straight."]'),
+    ('face-pull', 'Face Pull', '["rear_delts"]', '["upper_back","traps"]', '["cable"]', 'horizontal_pull', 0,
+        '["Set a rope at face height.","Pull it towards your face, elbows high.","Return slowly."]'),
+    ('barbell-curl', 'Barbell Curl', '["biceps"]', '["forearms"]', '["barbell"]', 'isolation', 0,
+        '["Hold the bar with an underhand grip.","Curl it up without swinging.","Lower fully."]'),
+    ('triceps-pushdown', 'Triceps Pushdown', '["triceps"]', '[]', '["cable"]', 'isolation', 0,
+        '["Hold the attachment with elbows at your sides.","Push down until the arms are straight.","Let it back up under control."]'),
+    ('leg-press', 'Leg Press', '["quads","glutes"]', '["hamstrings"]', '["machine"]', 'squat', 0,
+        '["Place feet shoulder-width on the platform.","Lower until the knees are at 90 degrees.","Press back up without locking the knees."]'),
+    ('leg-curl', 'Lying Leg Curl', '["hamstrings"]', '["calves"]', '["machine"]', 'isolation', 0,
+        '["Lie face down with the pad above the heels.","Curl the heels towards the glutes.","Lower slowly."]'),
+    ('calf-raise', 'Standing Calf Raise', '["calves"]', '[]', '["machine"]', 'isolation', 0,
+        '["Stand on the platform edge with heels hanging.","Rise onto the toes.","Lower into a deep stretch."]'),
+    ('plank', 'Plank', '["abs"]', '["obliques","lower_back"]', '["bodyweight"]', 'anti_rotation', 0,
+        '["Rest on forearms and toes.","Hold the body in a straight line."]'),
+    ('farmers-carry', 'Farmer''s Carry', '["forearms","traps"]', '["abs","glutes"]', '["dumbbell"]', 'carry', 0,
+        '["Pick up heavy weights at your sides.","Walk with short steps and a tall posture."]'),
+    ('kettlebell-swing', 'Kettlebell Swing', '["glutes","hamstrings"]', '["lower_back","abs"]', '["kettlebell"]', 'hinge', 0,
+        '["Hike the bell back between your legs.","Snap the hips forward to float it to chest height."]'),
+    ('running', 'Running', '["quads","calves"]', '["hamstrings","glutes"]', '["bodyweight"]', 'locomotion', 0,
+        '["Run at the prescribed pace or heart rate."]'),
+    ('rowing-machine', 'Rowing Machine', '["upper_back","quads"]', '["lats","hamstrings","biceps"]', '["cardio_machine"]', 'locomotion', 0,
+        '["Push with the legs, then swing back and pull the handle to the ribs.","Return arms, body, then legs."]');
+
+-- Logged training sessions. Times are RFC 3339 UTC text.
+CREATE TABLE IF NOT EXISTS training_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    workout_id TEXT REFERENCES workouts(id) ON DELETE SET NULL, -- template it was started from
+    scheduled_session_id TEXT REFERENCES scheduled_sessions(id) ON DELETE SET NULL,
+    name TEXT,
+    started_at TEXT NOT NULL,
+    finished_at TEXT,
+    notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_sessions_user_started ON training_sessions(user_id, started_at);
+
+CREATE TABLE IF NOT EXISTS training_sets (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES training_sessions(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    exercise_id TEXT NOT NULL REFERENCES exercises(id),
+    reps INTEGER,
+    load REAL, -- kg
+    rpe REAL,
+    rest_seconds INTEGER,
+    tempo TEXT, -- e.g. 3-1-X-0
+    duration_seconds INTEGER,
+    warmup INTEGER NOT NULL DEFAULT 0,
+    notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_sets_session ON training_sets(session_id, position);
+
+-- Personal records, one row per record-setting set and kind. Rebuilt from
+-- the logged sets whenever a session changes; the id is derived from the
+-- set and kind so unchanged records keep their row.
+CREATE TABLE IF NOT EXISTS pr_events (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    exercise_id TEXT NOT NULL REFERENCES exercises(id),
+    kind TEXT NOT NULL, -- 1rm, 3rm, 5rm, volume or e1rm
+    value REAL NOT NULL,
+    previous REAL NOT NULL DEFAULT 0,
+    reps INTEGER NOT NULL,
+    load REAL NOT NULL,
+    session_id TEXT NOT NULL,
+    set_id TEXT NOT NULL,
+    achieved_at TEXT NOT NULL,
+    formula TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_events_user_exercise ON pr_events(user_id, exercise_id, achieved_at);
+
+-- Runs, rides and other cardio activities, mostly imported from device
+-- files. Splits and the heart rate series are JSON arrays.
+CREATE TABLE IF NOT EXISTS cardio_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sport TEXT NOT NULL,
+    name TEXT,
+    source TEXT NOT NULL, -- gpx, tcx, fit or apple_health
+    started_at TEXT NOT NULL,
+    duration_seconds REAL NOT NULL,
+    moving_seconds REAL NOT NULL,
+    distance REAL NOT NULL, -- metres
+    elevation_gain REAL NOT NULL DEFAULT 0,
+    avg_heart_rate INTEGER,
+    max_heart_rate INTEGER,
+    splits TEXT NOT NULL DEFAULT '[]',
+    heart_rate TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_cardio_sessions_user_started ON cardio_sessions(user_id, started_at);
+
+-- Work queued to run outside a request, such as imports and webhook
+-- deliveries. Failed jobs have run out of attempts and wait to be retried
+-- by hand.
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT REFERENCES users(id) ON DELETE CASCADE, -- NULL for system jobs
+    kind TEXT NOT NULL,
+    payload TEXT NOT NULL DEFAULT 'null', -- JSON handler input
+    status TEXT NOT NULL DEFAULT 'queued', -- queued, running, completed or failed
+    stage TEXT,
+    progress REAL NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TEXT NOT NULL, -- RFC3339 UTC; not claimed before then
+    result TEXT, -- JSON, set on completion
+    error TEXT, -- of the last failed attempt
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    started_at DATETIME,
+    finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_jobs_user_created ON jobs(user_id, created_at);
+
+-- HTTPS endpoints that receive signed event deliveries. all_users
+-- subscriptions, which only admins can create, receive every user's events.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    description TEXT,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL, -- JSON array of event names
+    all_users BOOLEAN NOT NULL DEFAULT 0,
+    active BOOLEAN NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id);
+
+-- One event sent to one webhook, retried until it succeeds or runs out of
+-- attempts.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL, -- shared by every delivery of the same event
+    event TEXT NOT NULL,
+    payload TEXT NOT NULL, -- the signed JSON body
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, succeeded or failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME, -- RFC 3339 UTC; NULL once finished
+    response_status INTEGER, -- of the last attempt
+    error TEXT, -- of the last attempt
+    redelivery_of TEXT, -- the delivery this one repeats
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    delivered_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_created ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    delivery_id TEXT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER, -- NULL when no response arrived
+    error TEXT,
+    response_body TEXT, -- truncated
+    duration_ms INTEGER NOT NULL,
+    attempted_at DATETIME NOT NULL,
+    PRIMARY KEY (delivery_id, attempt)
+);
+
+-- Per-user reminder rules. next_run_at is the next local time the rule
+-- matches, converted to UTC, or NULL while the reminder is paused.
+CREATE TABLE IF NOT EXISTS reminders (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL, -- weigh_in, workout, water or custom
+    title TEXT NOT NULL,
+    message TEXT,
+    times TEXT NOT NULL, -- JSON array of HH:MM local times
+    days TEXT NOT NULL DEFAULT '[]', -- JSON array of mon ... sun; empty for every day
+    channels TEXT NOT NULL DEFAULT '[]', -- JSON array of email and push; the inbox always gets it
+    active BOOLEAN NOT NULL DEFAULT 1,
+    next_run_at TEXT, -- RFC3339 UTC
+    last_run_at TEXT, -- RFC3339 UTC
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_due ON reminders(active, next_run_at);
+CREATE INDEX IF NOT EXISTS idx_reminders_user ON reminders(user_id);
+
+-- The in-app inbox.
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL, -- reminder kind, or another source
+    title TEXT NOT NULL,
+    body TEXT,
+    url TEXT,
+    data TEXT, -- JSON
+    reminder_id TEXT,
+    read_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created ON notifications(user_id, created_at);
+
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    quiet_start TEXT, -- HH:MM local time
+    quiet_end TEXT,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS push_subscriptions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    endpoint TEXT UNIQUE NOT NULL,
+    p256dh TEXT NOT NULL, -- base64url client public key
+    auth TEXT NOT NULL, -- base64url auth secret
+    user_agent TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_push_subscriptions_user ON push_subscriptions(user_id);
@@ -0,0 +1,373 @@
+// Package dietary knows which ingredients carry which allergens and animal
+// products, and uses that to derive recipe tags and enforce a user's
+// dietary restrictions.
+package dietary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Allergen is one of the major allergen groups (the EU list of fourteen).
+type Allergen string
+
+const (
+	Gluten      Allergen = "gluten"
+	Crustaceans Allergen = "crustaceans"
+	Eggs        Allergen = "eggs"
+	Fish        Allergen = "fish"
+	Peanuts     Allergen = "peanuts"
+	Soy         Allergen = "soy"
+	Milk        Allergen = "milk"
+	TreeNuts    Allergen = "tree_nuts"
+	Celery      Allergen = "celery"
+	Mustard     Allergen = "mustard"
+	Sesame      Allergen = "sesame"
+	Sulphites   Allergen = "sulphites"
+	Lupin       Allergen = "lupin"
+	Molluscs    Allergen = "molluscs"
+)
+
+// AllAllergens lists every allergen group in display order.
+var AllAllergens = []Allergen{Gluten, Crustaceans, Eggs, Fish, Peanuts, Soy, Milk, TreeNuts, Celery, Mustard, Sesame, Sulphites, Lupin, Molluscs}
+
+// Label is the allergen's human readable name.
+func (a Allergen) Label() string {
+	return strings.ReplaceAll(string(a), "_", " ")
+}
+
+var allergenAliases = map[string][]Allergen{
+	"dairy":     {Milk},
+	"lactose":   {Milk},
+	"egg":       {Eggs},
+	"peanut":    {Peanuts},
+	"nuts":      {TreeNuts},
+	"tree_nut":  {TreeNuts},
+	"shellfish": {Crustaceans, Molluscs},
+	"wheat":     {Gluten},
+	"soya":      {Soy},
+	"sulfites":  {Sulphites},
+	"mollusks":  {Molluscs},
+}
+
+// ParseAllergen maps user input such as "Tree nuts" or "shellfish" to the
+// allergen groups it names.
+func ParseAllergen(s string) ([]Allergen, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	for _, a := range AllAllergens {
+		if string(a) == key {
+			return []Allergen{a}, nil
+		}
+	}
+	if list, ok := allergenAliases[key]; ok {
+		return list, nil
+	}
+	return nil, fmt.Errorf("unknown allergen %q", s)
+}
+
+// Animal products, used for the vegan, vegetarian and pescatarian diets.
+const (
+	Meat    = "meat"
+	Seafood = "fish or seafood"
+	Dairy   = "dairy"
+	Egg     = "egg"
+	Honey   = "honey"
+)
+
+// term is what a recognised ingredient phrase contributes.
+type term struct {
+	allergens []Allergen
+	source    string
+}
+
+// terms maps normalised ingredient phrases (singular, space separated) to
+// what they contain. Longer phrases win over shorter ones, so entries such as
+// "peanut butter" or "oyster mushroom" keep "butter" and "oyster" from
+// matching; an entry with nothing in it just hides its words.
+var terms = map[string]term{}
+
+func add(source string, allergens []Allergen, phrases ...string) {
+	for _, p := range phrases {
+		terms[p] = term{allergens: allergens, source: source}
+	}
+}
+
+func init() {
+	none := []Allergen(nil)
+
+	add("", []Allergen{Gluten}, "wheat", "flour", "bread", "breadcrumb", "panko", "pasta", "spaghetti", "penne", "macaroni",
+		"noodle", "couscous", "bulgur", "semolina", "spelt", "rye", "barley", "seitan", "oat", "tortilla", "pita", "bagel",
+		"cracker", "beer", "malt", "crouton", "gluten", "farro", "orzo", "bun", "baguette", "croissant", "pastry")
+	add("", none, "buckwheat", "corn flour", "cornflour", "rice flour", "coconut flour", "chickpea flour", "rice noodle",
+		"corn tortilla", "potato flour", "tapioca flour", "gluten free")
+	add("", []Allergen{TreeNuts}, "almond", "walnut", "cashew", "pecan", "pistachio", "hazelnut", "macadamia", "brazil nut",
+		"marzipan", "praline", "almond milk", "almond flour", "almond butter", "cashew butter", "cashew milk", "hazelnut milk")
+	add("", []Allergen{Peanuts}, "peanut", "peanut butter", "groundnut", "peanut oil")
+	add("", []Allergen{Soy}, "soy", "soya", "tofu", "tempeh", "edamame", "miso", "tamari", "soy milk", "soya milk", "soybean")
+	add("", []Allergen{Soy, Gluten}, "soy sauce", "soya sauce")
+	add("", []Allergen{Sesame}, "sesame", "tahini", "sesame oil", "sesame seed", "hummus")
+	add("", []Allergen{Celery}, "celery", "celeriac", "celery salt")
+	add("", []Allergen{Mustard}, "mustard", "dijon", "mustard seed")
+	add("", []Allergen{Sulphites}, "wine", "wine vinegar", "dried apricot")
+	add("", []Allergen{Lupin}, "lupin", "lupine")
+
+	add(Dairy, []Allergen{Milk}, "milk", "butter", "cheese", "cream", "yogurt", "yoghurt", "ghee", "whey", "casein", "parmesan",
+		"mozzarella", "cheddar", "feta", "ricotta", "mascarpone", "paneer", "halloumi", "buttermilk", "sour cream",
+		"creme fraiche", "cream cheese", "ice cream", "custard", "brie", "gouda", "skyr", "kefir", "quark")
+	add("", none, "coconut milk", "coconut cream", "coconut yogurt", "rice milk", "cocoa butter", "cream of tartar",
+		"nut butter", "shea butter", "dairy free")
+	add("", []Allergen{Gluten}, "oat milk")
+	add(Egg, []Allergen{Eggs}, "egg", "egg white", "egg yolk", "mayonnaise", "mayo", "meringue", "aioli")
+	add(Seafood, []Allergen{Fish}, "fish", "salmon", "tuna", "cod", "haddock", "trout", "sardine", "anchovy", "mackerel",
+		"tilapia", "halibut", "sea bass", "pollock", "fish sauce", "worcestershire", "worcestershire sauce")
+	add(Seafood, []Allergen{Crustaceans}, "shrimp", "prawn", "crab", "lobster", "crayfish", "langoustine")
+	add(Seafood, []Allergen{Molluscs}, "mussel", "clam", "oyster", "scallop", "squid", "calamari", "octopus", "snail",
+		"oyster sauce")
+	add("", none, "oyster mushroom", "crab apple")
+	add(Meat, none, "chicken", "beef", "pork", "lamb", "turkey", "bacon", "ham", "sausage", "duck", "veal", "venison", "steak",
+		"prosciutto", "salami", "chorizo", "pepperoni", "gelatin", "gelatine", "lard", "mince", "meat", "meatball", "goat",
+		"rabbit", "pancetta")
+	add(Honey, none, "honey")
+}
+
+// maxPhrase is the longest phrase in terms, in words.
+const maxPhrase = 3
+
+// Finding is one ingredient line that contains an allergen or animal
+// product.
+type Finding struct {
+	Ingredient string     `json:"ingredient"`
+	Term       string     `json:"term"`
+	Allergens  []Allergen `json:"allergens,omitempty"`
+	Source     string     `json:"source,omitempty"`
+}
+
+// Analysis is what a recipe's ingredients contain.
+type Analysis struct {
+	// Ingredients is the number of lines analysed; with none, nothing can
+	// be derived about the recipe.
+	Ingredients int
+	Findings    []Finding
+}
+
+// Analyze scans ingredient lines for allergens and animal products.
+func Analyze(ingredients []string) Analysis {
+	a := Analysis{}
+	for _, line := range ingredients {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		a.Ingredients++
+		a.Findings = append(a.Findings, scanLine(line)...)
+	}
+	return a
+}
+
+// qualifier is what a "vegan" or "X free" in an ingredient line rules out.
+type qualifier struct {
+	allergens []Allergen
+	sources   []string
+}
+
+// qualifierAt reads a qualifier ending at words[j] and returns it with the
+// index of its first word. "Not vegan" and "non dairy free" qualify nothing.
+func qualifierAt(words []string, j int) (q qualifier, start int, ok bool) {
+	switch {
+	case words[j] == "vegan":
+		q = qualifier{allergens: []Allergen{Milk, Eggs}, sources: []string{Meat, Seafood, Dairy, Egg, Honey}}
+		start = j
+	case words[j] == "free" && j > 0:
+		list, err := ParseAllergen(words[j-1])
+		if err == nil {
+			q.allergens = list
+		}
+		if words[j-1] == "dairy" {
+			q.sources = []string{Dairy}
+		}
+		if q.allergens == nil && q.sources == nil {
+			return q, 0, false
+		}
+		start = j - 1
+	default:
+		return q, 0, false
+	}
+	if start > 0 && (words[start-1] == "not" || words[start-1] == "non") {
+		return q, 0, false
+	}
+	return q, start, true
+}
+
+// scanLine finds every known phrase in one ingredient line. "Vegan" and
+// "X-free" qualifiers apply only to what they describe: the rest of their
+// part of the line ("vegan mayo", "gluten-free pasta"), or, when they come
+// last, what precedes them ("mayo (vegan)"). Parts are separated by commas,
+// brackets and words such as "and", so "vegan cheese and 1 egg" still
+// contains eggs.
+func scanLine(line string) []Finding {
+	parts := segments(line)
+	// The qualifiers that apply to each word.
+	scopes := make([][][]qualifier, len(parts))
+	for p, words := range parts {
+		scopes[p] = make([][]qualifier, len(words))
+	}
+	for p, words := range parts {
+		for j := range words {
+			q, start, ok := qualifierAt(words, j)
+			if !ok {
+				continue
+			}
+			switch {
+			case j+1 < len(words):
+				for k := j + 1; k < len(words); k++ {
+					scopes[p][k] = append(scopes[p][k], q)
+				}
+			case start > 0:
+				for k := 0; k < start; k++ {
+					scopes[p][k] = append(scopes[p][k], q)
+				}
+			case p > 0:
+				for k := range parts[p-1] {
+					scopes[p-1][k] = append(scopes[p-1][k], q)
+				}
+			}
+		}
+	}
+
+	var findings []Finding
+	for p, words := range parts {
+		for i := 0; i < len(words); {
+			n := maxPhrase
+			if i+n > len(words) {
+				n = len(words) - i
+			}
+			matched := false
+			for ; n > 0; n-- {
+				phrase := strings.Join(words[i:i+n], " ")
+				t, ok := terms[phrase]
+				if !ok {
+					continue
+				}
+				f := Finding{Ingredient: line, Term: phrase}
+				for _, al := range t.allergens {
+					if !suppresses(scopes[p][i], al, "") {
+						f.Allergens = append(f.Allergens, al)
+					}
+				}
+				if t.source != "" && !suppresses(scopes[p][i], "", t.source) {
+					f.Source = t.source
+				}
+				if len(f.Allergens) > 0 || f.Source != "" {
+					findings = append(findings, f)
+				}
+				i += n
+				matched = true
+				break
+			}
+			if !matched {
+				i++
+			}
+		}
+	}
+	return findings
+}
+
+func suppresses(qs []qualifier, al Allergen, source string) bool {
+	for _, q := range qs {
+		if (al != "" && contains(q.allergens, al)) || (source != "" && containsString(q.sources, source)) {
+			return true
+		}
+	}
+	return false
+}
+
+// segments splits an ingredient line into parts that a qualifier can
+// describe, each tokenized.
+func segments(line string) [][]string {
+	var parts [][]string
+	for _, piece := range strings.FieldsFunc(line, func(r rune) bool { return strings.ContainsRune(",;()[]/", r) }) {
+		var words []string
+		for _, w := range tokenize(piece) {
+			if w == "and" || w == "or" || w == "with" || w == "plus" {
+				if len(words) > 0 {
+					parts = append(parts, words)
+				}
+				words = nil
+				continue
+			}
+			words = append(words, w)
+		}
+		if len(words) > 0 {
+			parts = append(parts, words)
+		}
+	}
+	return parts
+}
+
+// tokenize lowercases a line, splits it into words and reduces simple
+// plurals.
+func tokenize(line string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(line), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for i, w := range fields {
+		fields[i] = singular(w)
+	}
+	return fields
+}
+
+func singular(w string) string {
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		return w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "oes") && len(w) > 4:
+		return w[:len(w)-2]
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && len(w) > 3:
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func contains(list []Allergen, a Allergen) bool {
+	for _, v := range list {
+		if v == a {
+			return true
+		}
+	}
+	return false
+}
+
+// Allergens lists the allergen groups found, in display order.
+func (a Analysis) Allergens() []Allergen {
+	found := map[Allergen]bool{}
+	for _, f := range a.Findings {
+		for _, al := range f.Allergens {
+			found[al] = true
+		}
+	}
+	list := []Allergen{}
+	for _, al := range AllAllergens {
+		if found[al] {
+			list = append(list, al)
+		}
+	}
+	return list
+}
+
+// Sources lists the animal products found, sorted.
+func (a Analysis) Sources() []string {
+	found := map[string]bool{}
+	for _, f := range a.Findings {
+		if f.Source != "" {
+			found[f.Source] = true
+		}
+	}
+	list := []string{}
+	for s := range found {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return list
+}
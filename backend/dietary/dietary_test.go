@@ -0,0 +1,88 @@
+package dietary
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		line      string
+		allergens []Allergen
+		sources   []string
+	}{
+		{"2 tbsp peanut butter", []Allergen{Peanuts}, []string{}},
+		{"400 ml coconut milk", []Allergen{}, []string{}},
+		{"1 cup oat milk", []Allergen{Gluten}, []string{}},
+		{"200 g oyster mushrooms", []Allergen{}, []string{}},
+		{"1 tbsp oyster sauce", []Allergen{Molluscs}, []string{Seafood}},
+		{"2 eggs, beaten", []Allergen{Eggs}, []string{Egg}},
+		{"1 eggplant", []Allergen{}, []string{}},
+		{"200 g gluten-free pasta", []Allergen{}, []string{}},
+		{"2 tbsp soy sauce", []Allergen{Gluten, Soy}, []string{}},
+		{"3 tbsp vegan mayo", []Allergen{}, []string{}},
+		{"50 g Parmesan cheese", []Allergen{Milk}, []string{Dairy}},
+		{"1 tsp Dijon mustard", []Allergen{Mustard}, []string{}},
+		{"300 g chicken thighs", []Allergen{}, []string{Meat}},
+		{"1 tbsp honey", []Allergen{}, []string{Honey}},
+		{"100 g cashews", []Allergen{TreeNuts}, []string{}},
+		{"1 tsp nutmeg", []Allergen{}, []string{}},
+		// Qualifiers only describe their own part of the line.
+		{"vegan cheese and 1 egg", []Allergen{Eggs}, []string{Egg}},
+		{"2 eggs (not vegan)", []Allergen{Eggs}, []string{Egg}},
+		{"dairy free chocolate, milk to serve", []Allergen{Milk}, []string{Dairy}},
+		{"3 tbsp mayo (vegan)", []Allergen{}, []string{}},
+		{"200 g pasta, gluten-free", []Allergen{}, []string{}},
+	}
+	for _, tt := range tests {
+		a := Analyze([]string{tt.line})
+		assert.Equal(t, tt.allergens, a.Allergens(), tt.line)
+		assert.Equal(t, tt.sources, a.Sources(), tt.line)
+	}
+}
+
+func TestTags(t *testing.T) {
+	assert.Equal(t, []string{Vegan, Vegetarian, GlutenFree, DairyFree},
+		Analyze([]string{"200 g chickpeas", "1 tbsp olive oil", "1 lemon"}).Tags())
+	assert.Equal(t, []string{Vegetarian},
+		Analyze([]string{"200 g spaghetti", "50 g butter", "2 eggs"}).Tags())
+	assert.Equal(t, []string{GlutenFree, DairyFree},
+		Analyze([]string{"1 salmon fillet", "200 g rice"}).Tags())
+	assert.Empty(t, Analyze(nil).Tags())
+
+	tags := ApplyTags([]string{"dinner", "Vegan"}, Analyze([]string{"1 tbsp honey", "200 g rice"}))
+	assert.Equal(t, []string{"dinner", Vegetarian, GlutenFree, DairyFree}, tags)
+	assert.Equal(t, []string{"vegan"}, ApplyTags([]string{"vegan"}, Analyze(nil)))
+}
+
+func TestCheck(t *testing.T) {
+	rs, err := NewRestrictions([]string{"Vegan", "coeliac"}, []string{"Peanut", "shellfish"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{Vegan, GlutenFree}, rs.Diets)
+	assert.Equal(t, []Allergen{Peanuts, Crustaceans, Molluscs}, rs.Allergens)
+
+	ex := Check(rs, Analyze([]string{"2 tbsp peanut butter", "1 tbsp honey", "200 g bread", "1 cup rice"}))
+	var rules []string
+	for _, e := range ex {
+		rules = append(rules, e.Rule)
+	}
+	assert.Equal(t, []string{"allergen:peanuts", "diet:vegan", "diet:gluten-free"}, rules)
+	assert.Equal(t, `contains peanuts (peanut butter in "2 tbsp peanut butter")`, ex[0].Reason)
+	assert.Equal(t, `not vegan: "1 tbsp honey" contains honey (honey)`, ex[1].Reason)
+
+	_, err = NewRestrictions([]string{"carnivore"}, nil)
+	assert.EqualError(t, err, `unknown diet "carnivore"`)
+	_, err = NewRestrictions(nil, []string{"kryptonite"})
+	assert.EqualError(t, err, `unknown allergen "kryptonite"`)
+}
+
+func TestPromptConstraints(t *testing.T) {
+	assert.Equal(t, "", PromptConstraints(Restrictions{}))
+
+	p := PromptConstraints(Restrictions{Diets: []string{Vegetarian}, Allergens: []Allergen{TreeNuts, Sesame}})
+	assert.True(t, strings.HasPrefix(p, "Dietary restrictions"))
+	assert.Contains(t, p, "- The user follows a vegetarian diet: no meat, fish or seafood.\n")
+	assert.Contains(t, p, "allergic to tree nuts, sesame")
+}
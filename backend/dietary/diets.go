@@ -0,0 +1,187 @@
+package dietary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diets the engine can enforce and derive as recipe tags.
+const (
+	Vegan       = "vegan"
+	Vegetarian  = "vegetarian"
+	Pescatarian = "pescatarian"
+	GlutenFree  = "gluten-free"
+	DairyFree   = "dairy-free"
+)
+
+// diet describes what a diet rules out.
+type diet struct {
+	sources   []string
+	allergens []Allergen
+}
+
+var diets = map[string]diet{
+	Vegan:       {sources: []string{Meat, Seafood, Dairy, Egg, Honey}},
+	Vegetarian:  {sources: []string{Meat, Seafood}},
+	Pescatarian: {sources: []string{Meat}},
+	GlutenFree:  {allergens: []Allergen{Gluten}},
+	DairyFree:   {sources: []string{Dairy}, allergens: []Allergen{Milk}},
+}
+
+// DerivedTags are the diet tags set on recipes from their ingredients.
+var DerivedTags = []string{Vegan, Vegetarian, GlutenFree, DairyFree}
+
+var dietAliases = map[string]string{
+	"vegetarian":  Vegetarian,
+	"veggie":      Vegetarian,
+	"pescetarian": Pescatarian,
+	"coeliac":     GlutenFree,
+	"celiac":      GlutenFree,
+	"lactose":     DairyFree,
+}
+
+// ParseDiet normalises a diet name such as "Gluten free" or "coeliac".
+func ParseDiet(s string) (string, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer(" ", "-", "_", "-").Replace(key)
+	if _, ok := diets[key]; ok {
+		return key, nil
+	}
+	if d, ok := dietAliases[key]; ok {
+		return d, nil
+	}
+	return "", fmt.Errorf("unknown diet %q", s)
+}
+
+// Restrictions are a user's diets and allergies.
+type Restrictions struct {
+	Diets     []string   `json:"diets"`
+	Allergens []Allergen `json:"allergens"`
+}
+
+// NewRestrictions parses stored or user supplied names, failing on the
+// first one it does not recognise.
+func NewRestrictions(dietNames, allergenNames []string) (Restrictions, error) {
+	rs := Restrictions{Diets: []string{}, Allergens: []Allergen{}}
+	for _, name := range dietNames {
+		d, err := ParseDiet(name)
+		if err != nil {
+			return rs, err
+		}
+		if !containsString(rs.Diets, d) {
+			rs.Diets = append(rs.Diets, d)
+		}
+	}
+	for _, name := range allergenNames {
+		list, err := ParseAllergen(name)
+		if err != nil {
+			return rs, err
+		}
+		for _, a := range list {
+			if !contains(rs.Allergens, a) {
+				rs.Allergens = append(rs.Allergens, a)
+			}
+		}
+	}
+	return rs, nil
+}
+
+// Empty reports whether there is nothing to enforce.
+func (rs Restrictions) Empty() bool {
+	return len(rs.Diets) == 0 && len(rs.Allergens) == 0
+}
+
+// AllergenNames returns the allergens as plain strings, e.g. for storage.
+func (rs Restrictions) AllergenNames() []string {
+	names := make([]string, len(rs.Allergens))
+	for i, a := range rs.Allergens {
+		names[i] = string(a)
+	}
+	return names
+}
+
+// Exclusion explains why a recipe breaks a restriction.
+type Exclusion struct {
+	// Rule is "allergen:<group>" or "diet:<name>".
+	Rule       string `json:"rule"`
+	Ingredient string `json:"ingredient"`
+	Reason     string `json:"reason"`
+}
+
+// Check returns every way the analysed ingredients break the restrictions,
+// one exclusion per rule and ingredient line.
+func Check(rs Restrictions, a Analysis) []Exclusion {
+	var out []Exclusion
+	seen := map[string]bool{}
+	record := func(rule, ingredient, reason string) {
+		if key := rule + "|" + ingredient; !seen[key] {
+			seen[key] = true
+			out = append(out, Exclusion{Rule: rule, Ingredient: ingredient, Reason: reason})
+		}
+	}
+
+	for _, f := range a.Findings {
+		for _, al := range rs.Allergens {
+			if contains(f.Allergens, al) {
+				record("allergen:"+string(al), f.Ingredient,
+					fmt.Sprintf("contains %s (%s in %q)", al.Label(), f.Term, f.Ingredient))
+			}
+		}
+		for _, name := range rs.Diets {
+			d := diets[name]
+			if f.Source != "" && containsString(d.sources, f.Source) {
+				record("diet:"+name, f.Ingredient,
+					fmt.Sprintf("not %s: %q contains %s (%s)", name, f.Ingredient, f.Source, f.Term))
+				continue
+			}
+			for _, al := range d.allergens {
+				if contains(f.Allergens, al) {
+					record("diet:"+name, f.Ingredient,
+						fmt.Sprintf("not %s: %q contains %s (%s)", name, f.Ingredient, al.Label(), f.Term))
+					break
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Tags returns the diet tags the analysed ingredients qualify for. Nothing
+// is derived for a recipe without ingredients.
+func (a Analysis) Tags() []string {
+	tags := []string{}
+	if a.Ingredients == 0 {
+		return tags
+	}
+	for _, name := range DerivedTags {
+		if len(Check(Restrictions{Diets: []string{name}}, a)) == 0 {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// ApplyTags replaces any derived diet tags in tags with the ones the
+// ingredients actually qualify for, keeping every other tag. A recipe
+// without ingredients keeps its tags as given.
+func ApplyTags(tags []string, a Analysis) []string {
+	if a.Ingredients == 0 {
+		return tags
+	}
+	out := []string{}
+	for _, t := range tags {
+		if !containsString(DerivedTags, strings.ToLower(t)) {
+			out = append(out, t)
+		}
+	}
+	return append(out, a.Tags()...)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package dietary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PromptConstraints renders restrictions as instructions for a language
+// model that suggests or adapts meals. It returns "" when there is nothing
+// to enforce.
+func PromptConstraints(rs Restrictions) string {
+	if rs.Empty() {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Dietary restrictions (mandatory, never suggest anything that breaks them):\n")
+	for _, name := range rs.Diets {
+		d := diets[name]
+		var excluded []string
+		excluded = append(excluded, d.sources...)
+		for _, a := range d.allergens {
+			excluded = append(excluded, a.Label())
+		}
+		fmt.Fprintf(&b, "- The user follows a %s diet: no %s.\n", name, strings.Join(excluded, ", "))
+	}
+	if len(rs.Allergens) > 0 {
+		labels := make([]string, len(rs.Allergens))
+		for i, a := range rs.Allergens {
+			labels[i] = a.Label()
+		}
+		fmt.Fprintf(&b, "- The user is allergic to %s: exclude these and any ingredient that contains or is derived from them.\n", strings.Join(labels, ", "))
+	}
+	return b.String()
+}
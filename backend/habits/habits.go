@@ -0,0 +1,190 @@
+// Package habits computes logging streaks, user-defined habit outcomes and
+// plan adherence scores from journal entries and training sessions.
+package habits
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// How a habit combines a day's entries.
+const (
+	Sum     = "sum"
+	Max     = "max"
+	Min     = "min"
+	Average = "avg"
+	// Count counts the day's entries and ignores their fields.
+	Count = "count"
+)
+
+// How a day's value is compared with the target.
+const (
+	AtLeast = "at_least"
+	AtMost  = "at_most"
+)
+
+// Validate checks a habit's rule against the journal types and fills in
+// defaults: entries are counted when no field is given and summed when one
+// is, and the value must reach the target.
+func Validate(h *models.Habit) error {
+	h.Name = strings.TrimSpace(h.Name)
+	if h.Name == "" || len(h.Name) > 100 {
+		return fmt.Errorf("name is required and must be at most 100 characters")
+	}
+	t, ok := journal.Lookup(h.EntryType)
+	if !ok {
+		return fmt.Errorf("unknown entry_type %q", h.EntryType)
+	}
+	h.EntryType = t.Name
+
+	h.Aggregate = strings.ToLower(strings.TrimSpace(h.Aggregate))
+	if h.Aggregate == "" {
+		h.Aggregate = Sum
+		if h.Field == "" {
+			h.Aggregate = Count
+		}
+	}
+	switch h.Aggregate {
+	case Count:
+		h.Field = ""
+		if h.Target == 0 {
+			h.Target = 1
+		}
+	case Sum, Max, Min, Average:
+		f, ok := t.Field(h.Field)
+		if !ok {
+			return fmt.Errorf("%s entries have no field %q", t.Name, h.Field)
+		}
+		if f.Kind != journal.Number && f.Kind != journal.Integer {
+			return fmt.Errorf("field %q is not numeric", h.Field)
+		}
+	default:
+		return fmt.Errorf("aggregate must be one of sum, max, min, avg or count")
+	}
+
+	h.Comparison = strings.ToLower(strings.TrimSpace(h.Comparison))
+	if h.Comparison == "" {
+		h.Comparison = AtLeast
+	}
+	if h.Comparison != AtLeast && h.Comparison != AtMost {
+		return fmt.Errorf("comparison must be at_least or at_most")
+	}
+	if h.Target < 0 || math.IsNaN(h.Target) || math.IsInf(h.Target, 0) {
+		return fmt.Errorf("target must be a non-negative number")
+	}
+	return nil
+}
+
+// Met reports whether a day's value meets the habit.
+func Met(h models.Habit, value float64) bool {
+	if h.Comparison == AtMost {
+		return value <= h.Target
+	}
+	return value >= h.Target
+}
+
+// Evaluate turns daily values, keyed by date, into a day-by-day record of
+// the habit from..to and its streaks as of today. history holds every
+// date the habit was met, so streaks can reach back before from.
+func Evaluate(h models.Habit, values map[string]float64, history []time.Time, from, to, today time.Time) models.HabitProgress {
+	p := models.HabitProgress{From: from.Format(dateLayout), To: to.Format(dateLayout), Days: []models.HabitDay{}}
+	s := Streaks(history, today)
+	p.CurrentStreak, p.LongestStreak = s.Current, s.Longest
+
+	counted, met := 0, 0
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		day := models.HabitDay{Date: d.Format(dateLayout)}
+		if v, ok := values[day.Date]; ok {
+			day.Value = &v
+			day.Met = Met(h, v)
+		}
+		p.Days = append(p.Days, day)
+		// Today still counts as open until it is met.
+		if d.After(today) || (d.Equal(today) && !day.Met) {
+			continue
+		}
+		counted++
+		if day.Met {
+			met++
+		}
+	}
+	if counted > 0 {
+		p.CompletionRate = math.Round(float64(met)/float64(counted)*1000) / 1000
+	}
+	return p
+}
+
+// Streaks measures runs of consecutive dates. dates must be ascending and
+// distinct; dates after today are ignored. The current streak survives a
+// today with nothing logged yet, ending at yesterday.
+func Streaks(dates []time.Time, today time.Time) models.Streak {
+	var s models.Streak
+	var prev time.Time
+	run := 0
+	for _, d := range dates {
+		if d.After(today) {
+			break
+		}
+		if run > 0 && d.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > s.Longest {
+			s.Longest = run
+		}
+		prev = d
+	}
+	if run > 0 {
+		s.Last = prev.Format(dateLayout)
+		if !prev.Before(today.AddDate(0, 0, -1)) {
+			s.Current = run
+		}
+	}
+	return s
+}
+
+// Eating within CalorieTolerance of the calorie target counts as on
+// target; the nutrition score then falls linearly to zero at
+// calorieCutoff.
+const (
+	CalorieTolerance = 0.1
+	calorieCutoff    = 0.5
+)
+
+// CalorieScore rates a day's calories against the target from 0 to 1.
+func CalorieScore(eaten, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	off := math.Abs(eaten/target - 1)
+	if off <= CalorieTolerance {
+		return 1
+	}
+	score := math.Max(0, 1-(off-CalorieTolerance)/(calorieCutoff-CalorieTolerance))
+	return math.Round(score*1000) / 1000
+}
+
+// Score averages the parts that apply (non-nil, each 0..1) into a score
+// out of 100, or nil when none apply.
+func Score(parts ...*float64) *float64 {
+	sum, n := 0.0, 0
+	for _, p := range parts {
+		if p != nil {
+			sum += *p
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	score := math.Round(sum/float64(n)*1000) / 10
+	return &score
+}
@@ -0,0 +1,93 @@
+package habits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse(dateLayout, s)
+	return t
+}
+
+func days(dates ...string) []time.Time {
+	out := make([]time.Time, len(dates))
+	for i, d := range dates {
+		out[i] = day(d)
+	}
+	return out
+}
+
+func TestStreaks(t *testing.T) {
+	today := day("2025-03-10")
+	assert.Equal(t, models.Streak{}, Streaks(nil, today))
+
+	s := Streaks(days("2025-03-01", "2025-03-02", "2025-03-03", "2025-03-08", "2025-03-09", "2025-03-10"), today)
+	assert.Equal(t, models.Streak{Current: 3, Longest: 3, Last: "2025-03-10"}, s)
+
+	// Nothing logged yet today keeps yesterday's streak alive; a gap ends it.
+	s = Streaks(days("2025-03-08", "2025-03-09"), today)
+	assert.Equal(t, 2, s.Current)
+	s = Streaks(days("2025-03-07", "2025-03-08"), today)
+	assert.Equal(t, 0, s.Current)
+	assert.Equal(t, 2, s.Longest)
+
+	// Future-dated entries do not count.
+	s = Streaks(days("2025-03-10", "2025-03-11"), today)
+	assert.Equal(t, models.Streak{Current: 1, Longest: 1, Last: "2025-03-10"}, s)
+}
+
+func TestValidate(t *testing.T) {
+	h := models.Habit{Name: " 10k steps ", EntryType: "Steps", Field: "count", Target: 10000}
+	assert.NoError(t, Validate(&h))
+	assert.Equal(t, models.Habit{Name: "10k steps", EntryType: "steps", Field: "count", Aggregate: Sum, Comparison: AtLeast, Target: 10000}, h)
+
+	h = models.Habit{Name: "Log mood", EntryType: "mood", Field: "score", Aggregate: "count"}
+	assert.NoError(t, Validate(&h))
+	assert.Equal(t, "", h.Field)
+	assert.Equal(t, 1.0, h.Target)
+
+	for _, h := range []models.Habit{
+		{Name: "", EntryType: "steps", Field: "count"},
+		{Name: "x", EntryType: "dreams"},
+		{Name: "x", EntryType: "sleep", Field: "bedtime"},
+		{Name: "x", EntryType: "sleep", Field: "naps"},
+		{Name: "x", EntryType: "sleep", Field: "hours", Aggregate: "median"},
+		{Name: "x", EntryType: "sleep", Field: "hours", Comparison: "about"},
+		{Name: "x", EntryType: "sleep", Field: "hours", Target: -1},
+	} {
+		assert.Error(t, Validate(&h), h)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	h := models.Habit{Aggregate: Max, Field: "hours", Comparison: AtLeast, Target: 8}
+	values := map[string]float64{"2025-03-01": 8.5, "2025-03-02": 6, "2025-03-04": 9}
+	p := Evaluate(h, values, days("2025-03-01", "2025-03-04"), day("2025-03-01"), day("2025-03-05"), day("2025-03-05"))
+
+	assert.Len(t, p.Days, 5)
+	assert.True(t, p.Days[0].Met)
+	assert.False(t, p.Days[1].Met)
+	assert.Equal(t, 6.0, *p.Days[1].Value)
+	assert.Nil(t, p.Days[2].Value)
+	// Today is still open, so two of four days were met.
+	assert.Equal(t, 0.5, p.CompletionRate)
+	// Yesterday was met, so the streak is still alive.
+	assert.Equal(t, 1, p.CurrentStreak)
+	assert.Equal(t, 1, p.LongestStreak)
+
+	assert.True(t, Met(models.Habit{Comparison: AtMost, Target: 2000}, 1800))
+}
+
+func TestScores(t *testing.T) {
+	assert.Equal(t, 1.0, CalorieScore(2100, 2000))
+	assert.Equal(t, 0.5, CalorieScore(2600, 2000))
+	assert.Equal(t, 0.0, CalorieScore(500, 2000))
+
+	one, half := 1.0, 0.5
+	assert.Nil(t, Score(nil, nil))
+	assert.Equal(t, 75.0, *Score(&one, nil, &half))
+}
@@ -0,0 +1,498 @@
+// Package healthimport reads the export of Apple's Health app: the
+// export.xml inside export.zip. The file can hold years of samples, so it
+// is decoded record by record and only daily totals and workouts are kept
+// in memory.
+package healthimport
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/terr0r/fitness.ai/backend/activityimport"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Source marks sessions imported from Apple Health.
+const Source = "apple_health"
+
+// Record and workout types that are imported.
+const (
+	BodyMass         = "HKQuantityTypeIdentifierBodyMass"
+	StepCount        = "HKQuantityTypeIdentifierStepCount"
+	ActiveEnergy     = "HKQuantityTypeIdentifierActiveEnergyBurned"
+	HeartRate        = "HKQuantityTypeIdentifierHeartRate"
+	RestingHeartRate = "HKQuantityTypeIdentifierRestingHeartRate"
+	SleepAnalysis    = "HKCategoryTypeIdentifierSleepAnalysis"
+
+	workoutPrefix = "HKWorkoutActivityType"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// Apple Health writes local times with their UTC offset.
+	timeLayout = "2006-01-02 15:04:05 -0700"
+)
+
+// Factors converting Apple Health units to ours: kg, kcal, metres and
+// seconds.
+var (
+	massUnits     = map[string]float64{"kg": 1, "g": 0.001, "lb": 0.45359237, "st": 6.35029318}
+	energyUnits   = map[string]float64{"kcal": 1, "Cal": 1, "kJ": 1 / 4.184}
+	distanceUnits = map[string]float64{"m": 1, "km": 1000, "cm": 0.01, "mi": 1609.344, "yd": 0.9144, "ft": 0.3048}
+	durationUnits = map[string]float64{"s": 1, "sec": 1, "min": 60, "hr": 3600, "h": 3600}
+)
+
+// Workouts of these types are strength sessions; all others are cardio.
+var strengthWorkouts = map[string]bool{
+	"TraditionalStrengthTraining": true,
+	"FunctionalStrengthTraining":  true,
+	"CoreTraining":                true,
+}
+
+func convert(value, unit string, units map[string]float64) (float64, bool) {
+	factor, ok := units[unit]
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+		return 0, false
+	}
+	return v * factor, true
+}
+
+func parseTime(s string) (time.Time, bool) {
+	t, err := time.Parse(timeLayout, strings.TrimSpace(s))
+	return t, err == nil
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
+
+// Workout is a workout read from the export. Times keep the offset they
+// were recorded with; distances are in metres and energy in kcal.
+type Workout struct {
+	// Activity is Apple's type without its prefix, e.g. "Running".
+	Activity        string
+	Name            string
+	Sport           string
+	Strength        bool
+	Source          string
+	Start, End      time.Time
+	DurationSeconds float64
+	DistanceMeters  float64
+	Calories        float64
+	AvgHeartRate    int
+	MaxHeartRate    int
+}
+
+// CardioSession turns a cardio workout into a session to store.
+func (w Workout) CardioSession() models.CardioSession {
+	return models.CardioSession{
+		Sport:           w.Sport,
+		Name:            w.Name,
+		Source:          Source,
+		StartedAt:       w.Start,
+		DurationSeconds: w.DurationSeconds,
+		MovingSeconds:   w.DurationSeconds,
+		DistanceMeters:  w.DistanceMeters,
+		AvgHeartRate:    w.AvgHeartRate,
+		MaxHeartRate:    w.MaxHeartRate,
+		Splits:          []models.Split{},
+	}
+}
+
+// activityWords splits "TraditionalStrengthTraining" into its words.
+func activityWords(activity string) []string {
+	var words []string
+	start := 0
+	for i, r := range activity {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, activity[start:i])
+			start = i
+		}
+	}
+	if start < len(activity) {
+		words = append(words, activity[start:])
+	}
+	return words
+}
+
+type sleepNight struct {
+	asleep     float64
+	start, end time.Time
+}
+
+type heartDay struct {
+	min, max, sum float64
+	count         int
+	resting       float64
+	restingCount  int
+}
+
+// Export is what was read from an export. Apple Health keeps samples from
+// every device that recorded them, so steps, energy and sleep are totalled
+// per source and the source with the most is taken for each day; adding
+// them up would count a walk once for the phone and again for the watch.
+type Export struct {
+	// Records counts the records read; Ignored those of other types or
+	// with values that could not be read.
+	Records  int
+	Ignored  int
+	Workouts []Workout
+
+	weights []models.JournalEntry
+	steps   map[string]map[string]float64
+	energy  map[string]map[string]float64
+	sleep   map[string]map[string]*sleepNight
+	heart   map[string]*heartDay
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func addTo(m map[string]map[string]float64, date, source string, v float64) {
+	if m[date] == nil {
+		m[date] = map[string]float64{}
+	}
+	m[date][source] += v
+}
+
+// Open opens an export: export.zip as downloaded from the Health app or
+// the export.xml inside it. It returns the XML and its size, for
+// reporting progress.
+func Open(name string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	head := make([]byte, 4)
+	n, _ := io.ReadFull(f, head)
+	if n == 4 && string(head) == "PK\x03\x04" {
+		f.Close()
+		return openZip(name)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+type zipEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z zipEntry) Close() error {
+	z.ReadCloser.Close()
+	return z.archive.Close()
+}
+
+func openZip(name string) (io.ReadCloser, int64, error) {
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid zip file: %w", err)
+	}
+	for _, f := range archive.File {
+		if path.Base(f.Name) != "export.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			archive.Close()
+			return nil, 0, fmt.Errorf("invalid zip file: %w", err)
+		}
+		return zipEntry{rc, archive}, int64(f.UncompressedSize64), nil
+	}
+	archive.Close()
+	return nil, 0, fmt.Errorf("export.xml not found in the zip file")
+}
+
+// Parse reads an export.xml.
+func Parse(r io.Reader) (*Export, error) {
+	e := &Export{
+		steps:  map[string]map[string]float64{},
+		energy: map[string]map[string]float64{},
+		sleep:  map[string]map[string]*sleepNight{},
+		heart:  map[string]*heartDay{},
+	}
+	dec := xml.NewDecoder(bufio.NewReaderSize(r, 64<<10))
+	root := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return e, fmt.Errorf("invalid export.xml: %w", err)
+		}
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "HealthData":
+			root = true
+		case "Record":
+			e.Records++
+			if !e.addRecord(t) {
+				e.Ignored++
+			}
+			if err := dec.Skip(); err != nil {
+				return e, fmt.Errorf("invalid export.xml: %w", err)
+			}
+		case "Correlation":
+			// The records grouped in a correlation, such as a blood
+			// pressure reading, are listed on their own as well.
+			if err := dec.Skip(); err != nil {
+				return e, fmt.Errorf("invalid export.xml: %w", err)
+			}
+		case "Workout":
+			var w workoutXML
+			if err := dec.DecodeElement(&w, &t); err != nil {
+				return e, fmt.Errorf("invalid export.xml: %w", err)
+			}
+			e.Records++
+			if workout, ok := w.workout(); ok {
+				e.Workouts = append(e.Workouts, workout)
+			} else {
+				e.Ignored++
+			}
+		default:
+			if !root {
+				return e, fmt.Errorf("not an Apple Health export: unexpected <%s>", t.Name.Local)
+			}
+		}
+	}
+	if !root {
+		return e, fmt.Errorf("not an Apple Health export")
+	}
+	return e, nil
+}
+
+// addRecord folds a record into the daily totals and reports whether it
+// was used.
+func (e *Export) addRecord(t xml.StartElement) bool {
+	start, ok1 := parseTime(attr(t, "startDate"))
+	end, ok2 := parseTime(attr(t, "endDate"))
+	if !ok1 || !ok2 {
+		return false
+	}
+	value, unit, source := attr(t, "value"), attr(t, "unit"), attr(t, "sourceName")
+	date := start.Format(dateLayout)
+
+	switch attr(t, "type") {
+	case BodyMass:
+		kg, ok := convert(value, unit, massUnits)
+		if !ok {
+			return false
+		}
+		e.weights = append(e.weights, models.JournalEntry{Date: date, Type: journal.Weight,
+			Data: map[string]interface{}{"weight": round(kg, 2), "time": start.Format("15:04")}})
+	case StepCount:
+		v, ok := convert(value, unit, map[string]float64{"count": 1})
+		if !ok {
+			return false
+		}
+		addTo(e.steps, date, source, v)
+	case ActiveEnergy:
+		kcal, ok := convert(value, unit, energyUnits)
+		if !ok {
+			return false
+		}
+		addTo(e.energy, date, source, kcal)
+	case HeartRate, RestingHeartRate:
+		bpm, ok := convert(value, unit, map[string]float64{"count/min": 1})
+		if !ok || bpm == 0 {
+			return false
+		}
+		d := e.heart[date]
+		if d == nil {
+			d = &heartDay{}
+			e.heart[date] = d
+		}
+		if attr(t, "type") == RestingHeartRate {
+			d.resting += bpm
+			d.restingCount++
+			break
+		}
+		if d.count == 0 || bpm < d.min {
+			d.min = bpm
+		}
+		d.max = math.Max(d.max, bpm)
+		d.sum += bpm
+		d.count++
+	case SleepAnalysis:
+		// In bed and awake time are left out; the asleep values are
+		// "Asleep" in older exports and the sleep stages in newer ones.
+		if !strings.HasPrefix(value, "HKCategoryValueSleepAnalysisAsleep") || !end.After(start) {
+			return false
+		}
+		night := end.Format(dateLayout)
+		if e.sleep[night] == nil {
+			e.sleep[night] = map[string]*sleepNight{}
+		}
+		n := e.sleep[night][source]
+		if n == nil {
+			n = &sleepNight{start: start, end: end}
+			e.sleep[night][source] = n
+		}
+		n.asleep += end.Sub(start).Seconds()
+		if start.Before(n.start) {
+			n.start = start
+		}
+		if end.After(n.end) {
+			n.end = end
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+type workoutXML struct {
+	Activity     string `xml:"workoutActivityType,attr"`
+	Duration     string `xml:"duration,attr"`
+	DurationUnit string `xml:"durationUnit,attr"`
+	Distance     string `xml:"totalDistance,attr"`
+	DistanceUnit string `xml:"totalDistanceUnit,attr"`
+	Energy       string `xml:"totalEnergyBurned,attr"`
+	EnergyUnit   string `xml:"totalEnergyBurnedUnit,attr"`
+	Source       string `xml:"sourceName,attr"`
+	Start        string `xml:"startDate,attr"`
+	End          string `xml:"endDate,attr"`
+	// Newer exports move the totals into statistics elements.
+	Statistics []struct {
+		Type    string `xml:"type,attr"`
+		Sum     string `xml:"sum,attr"`
+		Average string `xml:"average,attr"`
+		Maximum string `xml:"maximum,attr"`
+		Unit    string `xml:"unit,attr"`
+	} `xml:"WorkoutStatistics"`
+}
+
+func (x workoutXML) workout() (Workout, bool) {
+	start, ok1 := parseTime(x.Start)
+	end, ok2 := parseTime(x.End)
+	if !ok1 || !ok2 || !strings.HasPrefix(x.Activity, workoutPrefix) {
+		return Workout{}, false
+	}
+	activity := strings.TrimPrefix(x.Activity, workoutPrefix)
+	words := activityWords(activity)
+	w := Workout{
+		Activity: activity,
+		Name:     strings.Join(words, " "),
+		Sport:    activityimport.ParseSport(strings.Join(words, "_")),
+		Strength: strengthWorkouts[activity],
+		Source:   x.Source,
+		Start:    start,
+		End:      end,
+	}
+	if v, ok := convert(x.Duration, x.DurationUnit, durationUnits); ok {
+		w.DurationSeconds = v
+	} else {
+		w.DurationSeconds = end.Sub(start).Seconds()
+	}
+	w.DistanceMeters, _ = convert(x.Distance, x.DistanceUnit, distanceUnits)
+	w.Calories, _ = convert(x.Energy, x.EnergyUnit, energyUnits)
+	for _, s := range x.Statistics {
+		switch {
+		case strings.HasPrefix(s.Type, "HKQuantityTypeIdentifierDistance") && w.DistanceMeters == 0:
+			w.DistanceMeters, _ = convert(s.Sum, s.Unit, distanceUnits)
+		case s.Type == ActiveEnergy && w.Calories == 0:
+			w.Calories, _ = convert(s.Sum, s.Unit, energyUnits)
+		case s.Type == HeartRate:
+			avg, _ := convert(s.Average, s.Unit, map[string]float64{"count/min": 1})
+			max, _ := convert(s.Maximum, s.Unit, map[string]float64{"count/min": 1})
+			w.AvgHeartRate, w.MaxHeartRate = int(math.Round(avg)), int(math.Round(max))
+		}
+	}
+	w.DistanceMeters = round(w.DistanceMeters, 1)
+	w.Calories = math.Round(w.Calories)
+	if w.DurationSeconds <= 0 {
+		return Workout{}, false
+	}
+	return w, true
+}
+
+// bestSource returns the largest per-source total.
+func bestSource(totals map[string]float64) float64 {
+	best := 0.0
+	for _, v := range totals {
+		best = math.Max(best, v)
+	}
+	return best
+}
+
+// Entries returns the journal entries the export maps to, ordered by date
+// and type: each weighing, and a steps, active energy, sleep and heart
+// rate entry per day.
+func (e *Export) Entries() []models.JournalEntry {
+	entries := append([]models.JournalEntry{}, e.weights...)
+	for date, totals := range e.steps {
+		entries = append(entries, models.JournalEntry{Date: date, Type: journal.Steps,
+			Data: map[string]interface{}{"count": math.Round(bestSource(totals))}})
+	}
+	for date, totals := range e.energy {
+		entries = append(entries, models.JournalEntry{Date: date, Type: journal.ActiveEnergy,
+			Data: map[string]interface{}{"calories": math.Round(bestSource(totals))}})
+	}
+	for date, sources := range e.sleep {
+		var best *sleepNight
+		for _, n := range sources {
+			if best == nil || n.asleep > best.asleep {
+				best = n
+			}
+		}
+		entries = append(entries, models.JournalEntry{Date: date, Type: journal.Sleep,
+			Data: map[string]interface{}{
+				"hours":     round(best.asleep/3600, 2),
+				"bedtime":   best.start.Format("15:04"),
+				"wake_time": best.end.Format("15:04"),
+			}})
+	}
+	for date, d := range e.heart {
+		data := map[string]interface{}{}
+		if d.count > 0 {
+			data["min"], data["max"] = math.Round(d.min), math.Round(d.max)
+			data["avg"] = math.Round(d.sum / float64(d.count))
+		}
+		if d.restingCount > 0 {
+			data["resting"] = math.Round(d.resting / float64(d.restingCount))
+		}
+		entries = append(entries, models.JournalEntry{Date: date, Type: journal.HeartRate, Data: data})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return entries[i].Type < entries[j].Type
+	})
+	return entries
+}
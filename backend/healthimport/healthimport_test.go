@@ -0,0 +1,158 @@
+package healthimport
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const sampleExport = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE HealthData [
+<!ELEMENT HealthData (ExportDate,Me,(Record|Correlation|Workout|ActivitySummary)*)>
+]>
+<HealthData locale="en_GB">
+ <ExportDate value="2025-03-10 09:00:00 +0100"/>
+ <Me HKCharacteristicTypeIdentifierBiologicalSex="HKBiologicalSexMale"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="lb" value="176.4" startDate="2025-03-03 07:15:00 +0100" endDate="2025-03-03 07:15:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="79.6" startDate="2025-03-05 07:05:00 +0100" endDate="2025-03-05 07:05:00 +0100">
+  <MetadataEntry key="HKWasUserEntered" value="1"/>
+ </Record>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="iPhone" unit="count" value="3000" startDate="2025-03-03 08:00:00 +0100" endDate="2025-03-03 09:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="iPhone" unit="count" value="2000" startDate="2025-03-03 17:00:00 +0100" endDate="2025-03-03 18:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="Watch" unit="count" value="5600" startDate="2025-03-03 08:00:00 +0100" endDate="2025-03-03 18:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierActiveEnergyBurned" sourceName="Watch" unit="kJ" value="2092" startDate="2025-03-03 08:00:00 +0100" endDate="2025-03-03 18:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierHeartRate" sourceName="Watch" unit="count/min" value="58" startDate="2025-03-03 06:00:00 +0100" endDate="2025-03-03 06:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierHeartRate" sourceName="Watch" unit="count/min" value="142" startDate="2025-03-03 18:30:00 +0100" endDate="2025-03-03 18:30:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierHeartRate" sourceName="Watch" unit="count/min" value="75" startDate="2025-03-03 12:00:00 +0100" endDate="2025-03-03 12:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierRestingHeartRate" sourceName="Watch" unit="count/min" value="52" startDate="2025-03-03 00:00:00 +0100" endDate="2025-03-03 23:59:00 +0100"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" value="HKCategoryValueSleepAnalysisInBed" startDate="2025-03-03 22:30:00 +0100" endDate="2025-03-04 07:00:00 +0100"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" value="HKCategoryValueSleepAnalysisAsleepCore" startDate="2025-03-03 23:00:00 +0100" endDate="2025-03-04 02:00:00 +0100"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" value="HKCategoryValueSleepAnalysisAwake" startDate="2025-03-04 02:00:00 +0100" endDate="2025-03-04 02:30:00 +0100"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="Watch" value="HKCategoryValueSleepAnalysisAsleepDeep" startDate="2025-03-04 02:30:00 +0100" endDate="2025-03-04 06:45:00 +0100"/>
+ <Record type="HKCategoryTypeIdentifierSleepAnalysis" sourceName="iPhone" value="HKCategoryValueSleepAnalysisAsleep" startDate="2025-03-04 00:00:00 +0100" endDate="2025-03-04 06:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierDietaryWater" sourceName="App" unit="mL" value="250" startDate="2025-03-03 10:00:00 +0100" endDate="2025-03-03 10:00:00 +0100"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Scale" unit="kg" value="heavy" startDate="2025-03-06 07:00:00 +0100" endDate="2025-03-06 07:00:00 +0100"/>
+ <Correlation type="HKCorrelationTypeIdentifierBloodPressure" startDate="2025-03-03 09:00:00 +0100" endDate="2025-03-03 09:00:00 +0100">
+  <Record type="HKQuantityTypeIdentifierStepCount" sourceName="Watch" unit="count" value="99999" startDate="2025-03-03 09:00:00 +0100" endDate="2025-03-03 09:00:00 +0100"/>
+ </Correlation>
+ <Workout workoutActivityType="HKWorkoutActivityTypeRunning" duration="30.5" durationUnit="min" totalDistance="5.2" totalDistanceUnit="km" totalEnergyBurned="410" totalEnergyBurnedUnit="kcal" sourceName="Watch" startDate="2025-03-03 18:00:00 +0100" endDate="2025-03-03 18:30:30 +0100">
+  <WorkoutStatistics type="HKQuantityTypeIdentifierHeartRate" startDate="2025-03-03 18:00:00 +0100" endDate="2025-03-03 18:30:30 +0100" average="151.4" minimum="98" maximum="176" unit="count/min"/>
+  <WorkoutRoute sourceName="Watch"><FileReference path="/workout-routes/route_1.gpx"/></WorkoutRoute>
+ </Workout>
+ <Workout workoutActivityType="HKWorkoutActivityTypeTraditionalStrengthTraining" duration="45" durationUnit="min" sourceName="Watch" startDate="2025-03-04 18:00:00 +0100" endDate="2025-03-04 18:45:00 +0100">
+  <WorkoutStatistics type="HKQuantityTypeIdentifierActiveEnergyBurned" sum="210" unit="kcal"/>
+ </Workout>
+ <Workout workoutActivityType="HKWorkoutActivityTypeCycling" duration="1" durationUnit="hr" sourceName="Watch" startDate="2025-03-05 17:00:00 +0100" endDate="2025-03-05 18:00:00 +0100">
+  <WorkoutStatistics type="HKQuantityTypeIdentifierDistanceCycling" sum="15" unit="mi"/>
+ </Workout>
+ <ActivitySummary dateComponents="2025-03-03" activeEnergyBurned="500"/>
+</HealthData>
+`
+
+func TestParse(t *testing.T) {
+	e, err := Parse(strings.NewReader(sampleExport))
+	require.NoError(t, err)
+	assert.Equal(t, 20, e.Records)
+	// Water and the unreadable weight; in bed and awake time.
+	assert.Equal(t, 4, e.Ignored)
+
+	entries := e.Entries()
+	byKey := map[string]map[string]interface{}{}
+	for _, entry := range entries {
+		byKey[entry.Date+" "+entry.Type] = entry.Data
+	}
+	assert.Len(t, entries, 6)
+	assert.Equal(t, map[string]interface{}{"weight": 80.01, "time": "07:15"}, byKey["2025-03-03 weight"])
+	assert.Equal(t, map[string]interface{}{"weight": 79.6, "time": "07:05"}, byKey["2025-03-05 weight"])
+	// The watch counted more than the phone; the two are not added up.
+	assert.Equal(t, map[string]interface{}{"count": 5600.0}, byKey["2025-03-03 steps"])
+	assert.Equal(t, map[string]interface{}{"calories": 500.0}, byKey["2025-03-03 active_energy"])
+	assert.Equal(t, map[string]interface{}{"resting": 52.0, "min": 58.0, "avg": 92.0, "max": 142.0}, byKey["2025-03-03 heart_rate"])
+	assert.Equal(t, map[string]interface{}{"hours": 7.25, "bedtime": "23:00", "wake_time": "06:45"}, byKey["2025-03-04 sleep"])
+	assert.Equal(t, "2025-03-03", entries[0].Date)
+
+	require.Len(t, e.Workouts, 3)
+	run := e.Workouts[0]
+	assert.Equal(t, "running", run.Sport)
+	assert.False(t, run.Strength)
+	assert.Equal(t, 1830.0, run.DurationSeconds)
+	assert.Equal(t, 5200.0, run.DistanceMeters)
+	assert.Equal(t, 410.0, run.Calories)
+	assert.Equal(t, 151, run.AvgHeartRate)
+	assert.Equal(t, 176, run.MaxHeartRate)
+	assert.Equal(t, "2025-03-03T17:00:00Z", utils.FormatTime(run.Start))
+
+	lift := e.Workouts[1]
+	assert.True(t, lift.Strength)
+	assert.Equal(t, "Traditional Strength Training", lift.Name)
+	assert.Equal(t, "other", lift.Sport)
+	assert.Equal(t, 210.0, lift.Calories)
+
+	ride := e.Workouts[2]
+	assert.Equal(t, "cycling", ride.Sport)
+	assert.Equal(t, 3600.0, ride.DurationSeconds)
+	assert.Equal(t, 24140.2, ride.DistanceMeters)
+	s := ride.CardioSession()
+	assert.Equal(t, Source, s.Source)
+	assert.Equal(t, 3600.0, s.MovingSeconds)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(strings.NewReader(`<gpx><trk/></gpx>`))
+	assert.ErrorContains(t, err, "not an Apple Health export")
+	_, err = Parse(strings.NewReader(`<HealthData><Record type="x"`))
+	assert.ErrorContains(t, err, "invalid export.xml")
+	_, err = Parse(strings.NewReader(``))
+	assert.Error(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	writeZip := func(name string, files map[string]string) string {
+		p := filepath.Join(dir, name)
+		f, err := os.Create(p)
+		require.NoError(t, err)
+		zw := zip.NewWriter(f)
+		for name, content := range files {
+			w, _ := zw.Create(name)
+			w.Write([]byte(content))
+		}
+		zw.Close()
+		f.Close()
+		return p
+	}
+
+	path := writeZip("export.zip", map[string]string{
+		"apple_health_export/export_cda.xml": "<ClinicalDocument/>",
+		"apple_health_export/export.xml":     sampleExport,
+	})
+	r, size, err := Open(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(sampleExport)), size)
+	e, err := Parse(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Len(t, e.Workouts, 3)
+
+	_, _, err = Open(writeZip("empty.zip", map[string]string{"notes.txt": "hi"}))
+	assert.ErrorContains(t, err, "export.xml not found")
+
+	xmlPath := filepath.Join(dir, "export.xml")
+	os.WriteFile(xmlPath, []byte(sampleExport), 0o600)
+	r, size, err = Open(xmlPath)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, int64(len(sampleExport)), size)
+}
+
+func TestActivityWords(t *testing.T) {
+	assert.Equal(t, []string{"Traditional", "Strength", "Training"}, activityWords("TraditionalStrengthTraining"))
+	assert.Equal(t, []string{"Running"}, activityWords("Running"))
+	assert.Empty(t, activityWords(""))
+}
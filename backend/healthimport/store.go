@@ -0,0 +1,187 @@
+package healthimport
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/activityimport"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Keys the summary counts workouts under, next to the journal types.
+const (
+	CardioKey   = "cardio"
+	StrengthKey = "strength"
+)
+
+// Strength workouts starting within this window of a stored session are
+// taken to be the same one, as for cardio.
+const duplicateWindow = 2 * time.Minute
+
+// entryKey identifies a journal entry for deduplication: weighings by
+// date and time, the daily totals by date alone.
+func entryKey(date, entryType, clock string) string {
+	if entryType != journal.Weight {
+		clock = ""
+	}
+	return date + "|" + entryType + "|" + clock
+}
+
+// existingEntries returns the keys of the user's stored entries of the
+// imported types.
+func existingEntries(userID string) (map[string]bool, error) {
+	rows, err := db.DB.Query(`SELECT date, type, COALESCE(json_extract(entry_data, '$.time'), '') FROM journals
+		WHERE user_id = ? AND type IN (?, ?, ?, ?, ?)`,
+		userID, journal.Weight, journal.Steps, journal.ActiveEnergy, journal.Sleep, journal.HeartRate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := map[string]bool{}
+	for rows.Next() {
+		var date, entryType, clock string
+		if err := rows.Scan(&date, &entryType, &clock); err != nil {
+			return nil, err
+		}
+		if len(date) > 10 {
+			date = date[:10]
+		}
+		keys[entryKey(date, entryType, clock)] = true
+	}
+	return keys, rows.Err()
+}
+
+func findStrengthDuplicate(userID string, start time.Time) (string, error) {
+	var id string
+	err := db.DB.QueryRow(`SELECT id FROM training_sessions WHERE user_id = ? AND started_at BETWEEN ? AND ? ORDER BY started_at LIMIT 1`,
+		userID, utils.FormatTime(start.Add(-duplicateWindow)), utils.FormatTime(start.Add(duplicateWindow))).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// Entries are written in batches of this many, each in a transaction;
+// progress is reported between them, when nothing holds the write lock.
+const batchSize = 500
+
+// saveEntries stores a batch of journal entries, skipping and counting
+// duplicates and entries the journal types reject.
+func saveEntries(userID string, entries []models.JournalEntry, existing map[string]bool, summary *models.HealthImportSummary) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	insert, err := tx.Prepare(`INSERT INTO journals (id, user_id, date, type, entry_data, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	now := time.Now()
+	for _, entry := range entries {
+		clock, _ := entry.Data["time"].(string)
+		key := entryKey(entry.Date, entry.Type, clock)
+		if existing[key] {
+			summary.Duplicates[entry.Type]++
+			continue
+		}
+		t, _ := journal.Lookup(entry.Type)
+		raw, _ := json.Marshal(entry.Data)
+		data, err := t.Validate(raw)
+		if err != nil {
+			summary.Invalid[entry.Type]++
+			continue
+		}
+		encoded, _ := json.Marshal(data)
+		if _, err := insert.Exec(uuid.New().String(), userID, entry.Date, entry.Type, string(encoded), now, now); err != nil {
+			return err
+		}
+		existing[key] = true
+		summary.Imported[entry.Type]++
+	}
+	return tx.Commit()
+}
+
+// saveWorkout stores a workout as a cardio or strength session unless one
+// starting at the same time is stored already.
+func saveWorkout(userID string, w Workout, summary *models.HealthImportSummary) error {
+	key := CardioKey
+	if w.Strength {
+		key = StrengthKey
+	}
+	var dup string
+	var err error
+	if w.Strength {
+		dup, err = findStrengthDuplicate(userID, w.Start)
+	} else {
+		dup, err = activityimport.FindDuplicate(userID, w.CardioSession())
+	}
+	if err != nil {
+		return err
+	}
+	if dup != "" {
+		summary.Duplicates[key]++
+		return nil
+	}
+	if w.Strength {
+		_, err = db.DB.Exec(`INSERT INTO training_sessions (id, user_id, name, started_at, finished_at, notes) VALUES (?, ?, ?, ?, ?, ?)`,
+			uuid.New().String(), userID, w.Name, utils.FormatTime(w.Start), utils.FormatTime(w.End), "Imported from Apple Health")
+	} else {
+		_, err = activityimport.Save(userID, w.CardioSession())
+	}
+	if err != nil {
+		return err
+	}
+	summary.Imported[key]++
+	return nil
+}
+
+// Save stores an export for the user. Entries and workouts that are
+// already stored, from an earlier import or logged by hand, are skipped
+// and counted as duplicates; entries our journal types reject are counted
+// as invalid. progress, if set, is called with the number of items done.
+func Save(userID string, e *Export, progress func(done, total int)) (models.HealthImportSummary, error) {
+	summary := models.HealthImportSummary{
+		Records:    e.Records,
+		Ignored:    e.Ignored,
+		Imported:   map[string]int{},
+		Duplicates: map[string]int{},
+		Invalid:    map[string]int{},
+	}
+	existing, err := existingEntries(userID)
+	if err != nil {
+		return summary, err
+	}
+	entries := e.Entries()
+	total := len(entries) + len(e.Workouts)
+	report := func(done int) {
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	for i := 0; i < len(entries); i += batchSize {
+		end := min(i+batchSize, len(entries))
+		if err := saveEntries(userID, entries[i:end], existing, &summary); err != nil {
+			return summary, err
+		}
+		report(end)
+	}
+	for i, w := range e.Workouts {
+		if err := saveWorkout(userID, w, &summary); err != nil {
+			return summary, err
+		}
+		if done := len(entries) + i + 1; done%batchSize == 0 || done == total {
+			report(done)
+		}
+	}
+	return summary, nil
+}
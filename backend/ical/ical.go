@@ -0,0 +1,117 @@
+// Package ical writes the subset of RFC 5545 iCalendar needed to publish a
+// read-only training calendar feed.
+package ical
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	// Start is either a date (AllDay) or an instant, which is written in UTC.
+	Start    time.Time
+	Duration time.Duration
+	AllDay   bool
+	Status   string // TENTATIVE, CONFIRMED or CANCELLED
+	Modified time.Time
+}
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Write encodes the calendar with CRLF line endings and folds long lines at
+// 75 octets as the RFC requires.
+func (c Calendar) Write(w io.Writer) error {
+	lw := &lineWriter{w: w}
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + c.ProdID)
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + Escape(c.Name))
+	}
+	for _, e := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + e.UID)
+		stamp := e.Modified
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+		lw.line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		if e.AllDay {
+			lw.line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+			lw.line("DTEND;VALUE=DATE:" + e.Start.AddDate(0, 0, 1).Format("20060102"))
+		} else {
+			lw.line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			if e.Duration > 0 {
+				lw.line("DTEND:" + e.Start.Add(e.Duration).UTC().Format("20060102T150405Z"))
+			}
+		}
+		lw.line("SUMMARY:" + Escape(e.Summary))
+		if e.Description != "" {
+			lw.line("DESCRIPTION:" + Escape(e.Description))
+		}
+		if e.Status != "" {
+			lw.line("STATUS:" + e.Status)
+		}
+		lw.line("END:VEVENT")
+	}
+	lw.line("END:VCALENDAR")
+	return lw.err
+}
+
+// Escape escapes TEXT property values.
+func Escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	_, lw.err = io.WriteString(lw.w, fold(s)+"\r\n")
+}
+
+// fold splits a content line into 75-octet chunks without breaking UTF-8
+// sequences; continuation lines start with a single space.
+func fold(s string) string {
+	if len(s) <= 75 {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
+
+// String is a convenience for tests and debugging.
+func (c Calendar) String() string {
+	var b strings.Builder
+	if err := c.Write(&b); err != nil {
+		return fmt.Sprintf("ical: %v", err)
+	}
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarWrite(t *testing.T) {
+	cal := Calendar{ProdID: "-//Test//EN", Events: []Event{
+		{UID: "1@test", Summary: "Legs, core; cardio", Start: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), AllDay: true, Modified: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{UID: "2@test", Summary: strings.Repeat("x", 100), Start: time.Date(2026, 10, 20, 7, 30, 0, 0, time.UTC), Duration: time.Hour, Modified: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	out := cal.String()
+
+	assert.Contains(t, out, "DTSTART;VALUE=DATE:20261019\r\nDTEND;VALUE=DATE:20261020\r\n")
+	assert.Contains(t, out, `SUMMARY:Legs\, core\; cardio`)
+	assert.Contains(t, out, "DTSTART:20261020T073000Z\r\nDTEND:20261020T083000Z\r\n")
+	for _, line := range strings.Split(out, "\r\n") {
+		assert.LessOrEqual(t, len(line), 75)
+	}
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+}
@@ -0,0 +1,375 @@
+// Package jobs runs work outside the request path from a queue kept in the
+// jobs table, so it survives restarts.
+//
+// Each kind of job has a handler, registered at start-up. Workers started
+// with Start claim due jobs one at a time; an attempt that fails is tried
+// again after a backoff until the kind's MaxAttempts, and then the job is
+// left failed (the dead letter) until it is retried by hand. Handlers that
+// know retrying cannot help return Permanent errors to fail at once.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Job statuses.
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Completed = "completed"
+	Failed    = "failed"
+)
+
+const (
+	// DefaultMaxAttempts applies to kinds registered without one.
+	DefaultMaxAttempts = 5
+	baseDelay          = 10 * time.Second
+	maxDelay           = time.Hour
+)
+
+// Handler runs one attempt at a job and returns its result, which is
+// stored as JSON. ctx is cancelled when the attempt times out or the
+// workers are stopped.
+type Handler func(ctx context.Context, job models.Job) (interface{}, error)
+
+// Options tune how a kind of job is run.
+type Options struct {
+	// MaxAttempts is how often a job is tried before it fails.
+	MaxAttempts int
+	// Timeout bounds a single attempt; zero means no limit.
+	Timeout time.Duration
+	// KeepCompleted stops completed jobs of the kind from being pruned,
+	// for kinds whose jobs are shown to users as a history.
+	KeepCompleted bool
+}
+
+type kind struct {
+	handler Handler
+	opts    Options
+}
+
+var registry = map[string]kind{}
+
+// Register adds a kind of job, usually from an init function of the
+// package that handles it. Enqueueing a kind nobody registered fails, and
+// registering a name twice panics.
+func Register(name string, handler Handler, opts Options) {
+	if _, ok := registry[name]; ok {
+		panic("jobs: duplicate kind " + name)
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	registry[name] = kind{handler: handler, opts: opts}
+}
+
+// Handle adapts a function taking a decoded payload to a Handler. A
+// payload that does not decode fails the job permanently.
+func Handle[T any](fn func(ctx context.Context, job models.Job, payload T) (interface{}, error)) Handler {
+	return func(ctx context.Context, job models.Job) (interface{}, error) {
+		var payload T
+		if err := json.Unmarshal(job.Payload, &payload); err != nil {
+			return nil, Permanent(fmt.Errorf("invalid payload: %w", err))
+		}
+		return fn(ctx, job, payload)
+	}
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as one that retrying will not fix.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Backoff is the wait after the given failed attempt: 10s, 20s, 40s, ...
+// up to an hour.
+func Backoff(attempt int) time.Duration {
+	d := baseDelay
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
+
+// Enqueue queues a job to run as soon as a worker is free. userID is empty
+// for jobs that belong to no user.
+func Enqueue(userID, kind string, payload interface{}) (models.Job, error) {
+	return EnqueueAt(userID, kind, payload, clock.Now())
+}
+
+// EnqueueAt queues a job that is not run before at.
+func EnqueueAt(userID, kind string, payload interface{}, at time.Time) (models.Job, error) {
+	id, err := enqueue(db.DB, userID, kind, payload, at)
+	if err != nil {
+		return models.Job{}, err
+	}
+	return Get(id)
+}
+
+// EnqueueTx queues a job that is not run before at within tx, so it only
+// exists if tx commits, and returns its ID.
+func EnqueueTx(tx *sql.Tx, userID, kind string, payload interface{}, at time.Time) (string, error) {
+	return enqueue(tx, userID, kind, payload, at)
+}
+
+func enqueue(exec interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+}, userID, name string, payload interface{}, at time.Time) (string, error) {
+	k, ok := registry[name]
+	if !ok {
+		return "", fmt.Errorf("jobs: unknown kind %q", name)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	var user interface{}
+	if userID != "" {
+		user = userID
+	}
+	id := uuid.New().String()
+	now := time.Now()
+	_, err = exec.Exec(`INSERT INTO jobs (id, user_id, kind, payload, max_attempts, run_at, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, user, name, string(data), k.opts.MaxAttempts, utils.FormatTime(at), now, now)
+	if err != nil {
+		return "", err
+	}
+	notify()
+	return id, nil
+}
+
+const columns = `id, COALESCE(user_id, ''), kind, payload, status, COALESCE(stage, ''), progress, attempts, max_attempts, run_at,
+	result, COALESCE(error, ''), created_at, updated_at, started_at, finished_at`
+
+func scan(row interface{ Scan(...interface{}) error }) (models.Job, error) {
+	var j models.Job
+	var payload, runAt string
+	var result *string
+	err := row.Scan(&j.ID, &j.UserID, &j.Kind, &payload, &j.Status, &j.Stage, &j.Progress, &j.Attempts, &j.MaxAttempts, &runAt,
+		&result, &j.Error, &j.CreatedAt, &j.UpdatedAt, &j.StartedAt, &j.FinishedAt)
+	if err != nil {
+		return j, err
+	}
+	j.Payload = json.RawMessage(payload)
+	j.RunAt, _ = time.Parse(time.RFC3339, runAt)
+	if result != nil {
+		j.Result = json.RawMessage(*result)
+	}
+	return j, nil
+}
+
+// Get loads a job.
+func Get(id string) (models.Job, error) {
+	return scan(db.DB.QueryRow(`SELECT `+columns+` FROM jobs WHERE id = ?`, id))
+}
+
+// Filter selects jobs for List. Empty fields match everything.
+type Filter struct {
+	UserID string
+	Status string
+	// Kind matches a kind, or every kind under a prefix ending in '.'.
+	Kind  string
+	Limit int
+}
+
+// List returns jobs matching f, newest first.
+func List(f Filter) ([]models.Job, error) {
+	conds := []string{`1 = 1`}
+	var args []interface{}
+	if f.UserID != "" {
+		conds = append(conds, `user_id = ?`)
+		args = append(args, f.UserID)
+	}
+	if f.Status != "" {
+		conds = append(conds, `status = ?`)
+		args = append(args, f.Status)
+	}
+	if strings.HasSuffix(f.Kind, ".") {
+		conds = append(conds, `substr(kind, 1, ?) = ?`)
+		args = append(args, len(f.Kind), f.Kind)
+	} else if f.Kind != "" {
+		conds = append(conds, `kind = ?`)
+		args = append(args, f.Kind)
+	}
+	if f.Limit <= 0 {
+		f.Limit = 100
+	}
+	args = append(args, f.Limit)
+
+	rows, err := db.DB.Query(`SELECT `+columns+` FROM jobs WHERE `+strings.Join(conds, " AND ")+` ORDER BY created_at DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	jobs := []models.Job{}
+	for rows.Next() {
+		j, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// SetProgress records how far a running job has got, from 0 to 1, and
+// the stage it is in. Progress is only shown to users, so a failure is
+// logged and the job carries on.
+func SetProgress(id, stage string, progress float64) {
+	_, err := db.DB.Exec(`UPDATE jobs SET stage = ?, progress = ?, updated_at = ? WHERE id = ? AND status = 'running'`,
+		stage, math.Round(progress*1000)/1000, time.Now(), id)
+	if err != nil {
+		log.Printf("jobs: failed to record progress of job %s: %v", id, err)
+	}
+}
+
+// ErrNotFailed is returned by Retry for jobs that have not failed.
+var ErrNotFailed = errors.New("jobs: only failed jobs can be retried")
+
+// Retry queues a failed job again with a fresh set of attempts.
+func Retry(id string) (models.Job, error) {
+	now := time.Now()
+	res, err := db.DB.Exec(`UPDATE jobs SET status = 'queued', attempts = 0, stage = NULL, progress = 0, error = NULL, run_at = ?,
+		started_at = NULL, finished_at = NULL, updated_at = ? WHERE id = ? AND status = 'failed'`, utils.FormatTime(clock.Now()), now, id)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := Get(id); err != nil {
+			return models.Job{}, err
+		}
+		return models.Job{}, ErrNotFailed
+	}
+	notify()
+	return Get(id)
+}
+
+// RequeueInterrupted queues again the jobs that were running when the
+// process last stopped without shutting its workers down. The interrupted
+// attempt counts, so a job that keeps crashing the server still fails.
+func RequeueInterrupted() error {
+	now := time.Now()
+	_, err := db.DB.Exec(`UPDATE jobs SET status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'queued' END,
+		error = 'interrupted by a server restart', run_at = ?, updated_at = ?,
+		finished_at = CASE WHEN attempts >= max_attempts THEN ? END
+		WHERE status = 'running'`, utils.FormatTime(clock.Now()), now, now)
+	return err
+}
+
+// claim marks the next due job as running and returns it, or
+// sql.ErrNoRows when nothing is due.
+func claim(ctx context.Context) (models.Job, error) {
+	now := time.Now()
+	var id string
+	err := db.DB.QueryRowContext(ctx, `UPDATE jobs SET status = 'running', attempts = attempts + 1, stage = NULL, started_at = ?, updated_at = ?
+		WHERE id = (SELECT id FROM jobs WHERE status = 'queued' AND run_at <= ? ORDER BY run_at, created_at LIMIT 1) AND status = 'queued'
+		RETURNING id`, now, now, utils.FormatTime(clock.Now())).Scan(&id)
+	if err != nil {
+		return models.Job{}, err
+	}
+	return Get(id)
+}
+
+// execute runs one attempt at a claimed job and records the outcome. When
+// interrupted reports true afterwards, a failed attempt is put back
+// without counting it. The error is from recording the outcome; a job
+// left running is only run again by RequeueInterrupted.
+func execute(ctx context.Context, job models.Job, interrupted func() bool) error {
+	k, ok := registry[job.Kind]
+	var result interface{}
+	var err error
+	if !ok {
+		err = Permanent(fmt.Errorf("no handler for %q", job.Kind))
+	} else {
+		result, err = attempt(ctx, k, job)
+	}
+	var data []byte
+	if err == nil {
+		if data, err = json.Marshal(result); err != nil {
+			err = Permanent(err)
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		_, err = db.DB.Exec(`UPDATE jobs SET status = 'completed', stage = NULL, progress = 1, result = ?, error = NULL, updated_at = ?, finished_at = ?
+			WHERE id = ?`, string(data), now, now, job.ID)
+	case interrupted():
+		_, err = db.DB.Exec(`UPDATE jobs SET status = 'queued', attempts = attempts - 1, run_at = ?, updated_at = ? WHERE id = ?`,
+			utils.FormatTime(clock.Now()), now, job.ID)
+	case IsPermanent(err) || job.Attempts >= job.MaxAttempts:
+		_, err = db.DB.Exec(`UPDATE jobs SET status = 'failed', error = ?, updated_at = ?, finished_at = ? WHERE id = ?`, err.Error(), now, now, job.ID)
+	default:
+		_, err = db.DB.Exec(`UPDATE jobs SET status = 'queued', error = ?, run_at = ?, updated_at = ? WHERE id = ?`,
+			err.Error(), utils.FormatTime(clock.Now().Add(Backoff(job.Attempts))), now, job.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("jobs: failed to record the outcome of job %s: %w", job.ID, err)
+	}
+	return nil
+}
+
+// attempt calls the handler within the kind's timeout, turning a panic
+// into an error.
+func attempt(ctx context.Context, k kind, job models.Job) (result interface{}, err error) {
+	if k.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.opts.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return k.handler(ctx, job)
+}
+
+// RunDue runs every job that is due, one after another, until none is
+// left, and returns how many it ran. Jobs that fall due meanwhile, such
+// as immediate follow-ups, are run too.
+func RunDue(ctx context.Context) (int, error) {
+	n := 0
+	for ctx.Err() == nil {
+		job, err := claim(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+		if err := execute(ctx, job, func() bool { return ctx.Err() != nil }); err != nil {
+			return n, err
+		}
+	}
+	return n, ctx.Err()
+}
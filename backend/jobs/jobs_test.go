@@ -0,0 +1,225 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+type testPayload struct {
+	Fail int    `json:"fail"` // attempts that fail before one succeeds
+	Mode string `json:"mode"`
+}
+
+// blocked hears from jobs of mode "block" once they are running.
+var blocked = make(chan struct{}, 10)
+
+func init() {
+	Register("test.run", Handle(func(ctx context.Context, job models.Job, p testPayload) (interface{}, error) {
+		switch {
+		case p.Mode == "panic":
+			panic("boom")
+		case p.Mode == "permanent":
+			return nil, Permanent(errors.New("cannot work"))
+		case p.Mode == "vanish":
+			// The outcome then has nowhere to go.
+			_, err := db.DB.Exec(`ALTER TABLE jobs RENAME TO jobs_away`)
+			return nil, err
+		case p.Mode == "block":
+			blocked <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		case job.Attempts <= p.Fail:
+			return nil, errors.New("try again")
+		}
+		return map[string]int{"attempts": job.Attempts}, nil
+	}), Options{MaxAttempts: 3})
+	Register("test.kept", Handle(func(ctx context.Context, job models.Job, p testPayload) (interface{}, error) {
+		return nil, nil
+	}), Options{KeepCompleted: true})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 40*time.Second, Backoff(3))
+	assert.Equal(t, time.Hour, Backoff(20))
+}
+
+func TestRunDue(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	now := clock.NewFake(time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC))
+	defer now.Install()()
+	ctx := context.Background()
+
+	_, err := Enqueue("", "test.missing", nil)
+	assert.ErrorContains(t, err, "unknown kind")
+
+	// Failed attempts are retried after the backoff.
+	job, err := Enqueue("u1", "test.run", testPayload{Fail: 1})
+	require.NoError(t, err)
+	assert.Equal(t, Queued, job.Status)
+	assert.Equal(t, 3, job.MaxAttempts)
+	n, err := RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	job, _ = Get(job.ID)
+	assert.Equal(t, Queued, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "try again", job.Error)
+	assert.Equal(t, now.Now().Add(Backoff(1)), job.RunAt)
+
+	n, _ = RunDue(ctx)
+	assert.Equal(t, 0, n)
+	now.Advance(Backoff(1))
+	n, _ = RunDue(ctx)
+	assert.Equal(t, 1, n)
+	job, _ = Get(job.ID)
+	assert.Equal(t, Completed, job.Status)
+	assert.Equal(t, 1.0, job.Progress)
+	assert.Empty(t, job.Error)
+	assert.NotNil(t, job.FinishedAt)
+	assert.JSONEq(t, `{"attempts":2}`, string(job.Result))
+
+	// Jobs that run out of attempts or cannot succeed are failed; a panic
+	// is a failed attempt like any other.
+	exhausted, _ := Enqueue("u1", "test.run", testPayload{Fail: 5})
+	for i := 0; i < 5; i++ {
+		RunDue(ctx)
+		now.Advance(time.Hour)
+	}
+	exhausted, _ = Get(exhausted.ID)
+	assert.Equal(t, Failed, exhausted.Status)
+	assert.Equal(t, 3, exhausted.Attempts)
+
+	permanent, _ := Enqueue("u1", "test.run", testPayload{Mode: "permanent"})
+	panicked, _ := Enqueue("u1", "test.run", testPayload{Mode: "panic"})
+	broken, _ := Enqueue("u1", "test.run", "not an object")
+	RunDue(ctx)
+	permanent, _ = Get(permanent.ID)
+	assert.Equal(t, Failed, permanent.Status)
+	assert.Equal(t, 1, permanent.Attempts)
+	assert.Equal(t, "cannot work", permanent.Error)
+	panicked, _ = Get(panicked.ID)
+	assert.Equal(t, Queued, panicked.Status)
+	assert.Equal(t, "panic: boom", panicked.Error)
+	broken, _ = Get(broken.ID)
+	assert.Equal(t, Failed, broken.Status)
+	assert.Contains(t, broken.Error, "invalid payload")
+
+	failed, err := List(Filter{UserID: "u1", Status: Failed, Kind: "test."})
+	require.NoError(t, err)
+	assert.Len(t, failed, 3)
+
+	// A failed job can be retried by hand with fresh attempts.
+	_, err = Retry(job.ID)
+	assert.ErrorIs(t, err, ErrNotFailed)
+	db.DB.Exec(`UPDATE jobs SET payload = '{"fail":3}' WHERE id = ?`, exhausted.ID)
+	exhausted, err = Retry(exhausted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Queued, exhausted.Status)
+	assert.Equal(t, 0, exhausted.Attempts)
+	assert.Empty(t, exhausted.Error)
+}
+
+func TestRunDueReportsUnrecordedOutcome(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	_, err := Enqueue("", "test.run", testPayload{Mode: "vanish"})
+	require.NoError(t, err)
+	n, err := RunDue(context.Background())
+	assert.Equal(t, 1, n)
+	assert.ErrorContains(t, err, "failed to record the outcome")
+	db.DB.Exec(`ALTER TABLE jobs_away RENAME TO jobs`)
+}
+
+func TestRequeueInterrupted(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	fresh, _ := Enqueue("", "test.run", testPayload{})
+	spent, _ := Enqueue("", "test.run", testPayload{})
+	db.DB.Exec(`UPDATE jobs SET status = 'running', attempts = 1 WHERE id = ?`, fresh.ID)
+	db.DB.Exec(`UPDATE jobs SET status = 'running', attempts = 3 WHERE id = ?`, spent.ID)
+	require.NoError(t, RequeueInterrupted())
+
+	fresh, _ = Get(fresh.ID)
+	assert.Equal(t, Queued, fresh.Status)
+	assert.Equal(t, 1, fresh.Attempts)
+	spent, _ = Get(spent.ID)
+	assert.Equal(t, Failed, spent.Status)
+	assert.Equal(t, "interrupted by a server restart", spent.Error)
+}
+
+func TestPruneCompleted(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	now := clock.NewFake(time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC))
+	defer now.Install()()
+	old, _ := Enqueue("", "test.run", testPayload{})
+	kept, _ := Enqueue("", "test.kept", testPayload{})
+	failed, _ := Enqueue("", "test.run", testPayload{})
+	now.Advance(time.Hour)
+	recent, _ := Enqueue("", "test.run", testPayload{})
+	db.DB.Exec(`UPDATE jobs SET status = 'completed' WHERE id != ?`, failed.ID)
+	db.DB.Exec(`UPDATE jobs SET status = 'failed' WHERE id = ?`, failed.ID)
+
+	now.Advance(retention)
+	require.NoError(t, pruneCompleted())
+	for _, id := range []string{kept.ID, failed.ID, recent.ID} {
+		_, err := Get(id)
+		assert.NoError(t, err)
+	}
+	_, err := Get(old.ID)
+	assert.Error(t, err)
+}
+
+func TestPool(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		job, err := Enqueue("", "test.run", testPayload{})
+		require.NoError(t, err)
+		ids = append(ids, job.ID)
+	}
+	pool := Start(3)
+	require.Eventually(t, func() bool {
+		done := 0
+		for _, id := range ids {
+			if j, _ := Get(id); j.Status == Completed {
+				done++
+			}
+		}
+		return done == len(ids)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Shutting down cancels jobs still running at the deadline and puts
+	// them back without using up an attempt.
+	job, _ := Enqueue("", "test.run", testPayload{Mode: "block"})
+	<-blocked
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pool.Shutdown(ctx), context.DeadlineExceeded)
+	job, _ = Get(job.ID)
+	assert.Equal(t, Queued, job.Status)
+	assert.Equal(t, 0, job.Attempts)
+
+	var result map[string]int
+	j, _ := Get(ids[0])
+	json.Unmarshal(j.Result, &result)
+	assert.Equal(t, 1, result["attempts"])
+}
@@ -0,0 +1,146 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const (
+	// How often idle workers look for jobs when nothing wakes them, such
+	// as retries falling due.
+	pollInterval = 5 * time.Second
+	// Completed jobs are kept this long after they were due, then
+	// deleted, unless their kind has KeepCompleted; failed ones stay until
+	// they are retried.
+	retention = 7 * 24 * time.Hour
+)
+
+// wake tells an idle worker there may be a job to claim.
+var wake = make(chan struct{}, 1)
+
+func notify() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pool is a set of workers running queued jobs.
+type Pool struct {
+	stop     chan struct{}
+	stopOnce sync.Once
+	// ctx is passed to handlers and cancelled when shutdown runs out of
+	// time.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start starts concurrency workers, at least one.
+func Start(concurrency int) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{stop: make(chan struct{}), ctx: ctx, cancel: cancel}
+	for i := 0; i < max(concurrency, 1); i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	p.wg.Add(1)
+	go p.prune()
+	return p
+}
+
+func (p *Pool) stopping() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for !p.stopping() {
+		job, err := claim(p.ctx)
+		if err == nil {
+			// Another worker may be able to take the next one.
+			notify()
+			if err := execute(p.ctx, job, func() bool { return p.ctx.Err() != nil }); err != nil {
+				log.Print(err)
+			}
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) && p.ctx.Err() == nil {
+			log.Printf("jobs: failed to claim a job: %v", err)
+		}
+		select {
+		case <-p.stop:
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneCompleted deletes completed jobs older than the retention, other
+// than those of kinds registered with KeepCompleted.
+func pruneCompleted() error {
+	kept := []string{}
+	for name, k := range registry {
+		if k.opts.KeepCompleted {
+			kept = append(kept, name)
+		}
+	}
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Exec(`DELETE FROM jobs WHERE status = 'completed' AND run_at < ? AND kind NOT IN (SELECT value FROM json_each(?))`,
+		utils.FormatTime(clock.Now().Add(-retention)), string(data))
+	return err
+}
+
+func (p *Pool) prune() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		pruneCompleted()
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops the workers from claiming jobs and waits for the running
+// ones to finish. If ctx ends first their contexts are cancelled, and
+// jobs that then fail are queued again without counting the attempt.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,290 @@
+// Package journal defines the entry types users can log in their journal
+// and validates entries against them. Each type is described once, as a
+// list of fields; the same description validates writes and is rendered as
+// a JSON Schema for clients building forms.
+package journal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Field kinds.
+const (
+	Number  = "number"
+	Integer = "integer"
+	String  = "string"
+	Boolean = "boolean"
+	// Clock is a local time of day written "HH:MM".
+	Clock = "time"
+)
+
+// Field describes one property of an entry. Min and Max bound numbers;
+// MaxLength bounds strings.
+type Field struct {
+	Name        string
+	Kind        string
+	Title       string
+	Description string
+	Unit        string
+	Required    bool
+	Min, Max    *float64
+	MaxLength   int
+	Enum        []string
+}
+
+// Type is a registered entry type. Check, when set, validates rules that
+// span fields once each field is valid; it sees the normalised data and
+// may fill in defaults.
+type Type struct {
+	Name        string
+	Title       string
+	Description string
+	Fields      []Field
+	Check       func(data map[string]interface{}) []FieldError
+}
+
+func bound(v float64) *float64 { return &v }
+
+var registry = map[string]Type{}
+
+// Register adds an entry type. Two types with the same name would make
+// stored entries ambiguous, so that panics.
+func Register(t Type) {
+	if _, ok := registry[t.Name]; ok {
+		panic("journal: duplicate type " + t.Name)
+	}
+	registry[t.Name] = t
+}
+
+// Lookup returns a registered type.
+func Lookup(name string) (Type, bool) {
+	t, ok := registry[strings.ToLower(strings.TrimSpace(name))]
+	return t, ok
+}
+
+// Types lists the registered types by name.
+func Types() []Type {
+	types := make([]Type, 0, len(registry))
+	for _, t := range registry {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Name < types[j].Name })
+	return types
+}
+
+// Field returns the type's field called name.
+func (t Type) Field(name string) (Field, bool) {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// FieldError is a problem with one field of an entry; Field is empty for
+// problems with the entry as a whole.
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists everything wrong with an entry.
+type ValidationError struct {
+	Type   string
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		if fe.Field != "" {
+			msgs[i] = fe.Field + ": " + fe.Message
+		} else {
+			msgs[i] = fe.Message
+		}
+	}
+	return fmt.Sprintf("invalid %s entry: %s", e.Type, strings.Join(msgs, "; "))
+}
+
+var clockPattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// Validate checks raw entry data against the type and returns it
+// normalised: unknown fields are rejected, strings trimmed and empty
+// optional strings dropped.
+func (t Type) Validate(raw json.RawMessage) (map[string]interface{}, error) {
+	verr := &ValidationError{Type: t.Name}
+	var data map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if len(bytes.TrimSpace(raw)) == 0 || dec.Decode(&data) != nil || data == nil {
+		verr.Errors = append(verr.Errors, FieldError{Message: "data must be a JSON object"})
+		return nil, verr
+	}
+
+	known := map[string]bool{}
+	out := map[string]interface{}{}
+	for _, f := range t.Fields {
+		known[f.Name] = true
+		v, ok := data[f.Name]
+		if !ok || v == nil {
+			if f.Required {
+				verr.Errors = append(verr.Errors, FieldError{Field: f.Name, Message: "is required"})
+			}
+			continue
+		}
+		value, msg := f.check(v)
+		if msg != "" {
+			verr.Errors = append(verr.Errors, FieldError{Field: f.Name, Message: msg})
+			continue
+		}
+		if s, ok := value.(string); ok && s == "" {
+			if f.Required {
+				verr.Errors = append(verr.Errors, FieldError{Field: f.Name, Message: "is required"})
+			}
+			continue
+		}
+		out[f.Name] = value
+	}
+
+	var unknown []string
+	for name := range data {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.Errors = append(verr.Errors, FieldError{Field: name, Message: "is not a field of " + t.Name + " entries"})
+	}
+	if t.Check != nil && len(verr.Errors) == 0 {
+		verr.Errors = t.Check(out)
+	}
+
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+	return out, nil
+}
+
+// check validates one value and returns it in its stored form, or a
+// message saying what is wrong.
+func (f Field) check(v interface{}) (interface{}, string) {
+	switch f.Kind {
+	case Number, Integer:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, "must be a number"
+		}
+		x, err := n.Float64()
+		if err != nil || math.IsInf(x, 0) {
+			return nil, "must be a number"
+		}
+		if f.Kind == Integer && x != math.Trunc(x) {
+			return nil, "must be a whole number"
+		}
+		if f.Min != nil && x < *f.Min {
+			return nil, fmt.Sprintf("must be at least %g", *f.Min)
+		}
+		if f.Max != nil && x > *f.Max {
+			return nil, fmt.Sprintf("must be at most %g", *f.Max)
+		}
+		if f.Kind == Integer {
+			return int64(x), ""
+		}
+		return x, ""
+	case Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, "must be true or false"
+		}
+		return b, ""
+	case String, Clock:
+		s, ok := v.(string)
+		if !ok {
+			return nil, "must be a string"
+		}
+		s = strings.TrimSpace(s)
+		if f.MaxLength > 0 && len([]rune(s)) > f.MaxLength {
+			return nil, fmt.Sprintf("must be at most %d characters", f.MaxLength)
+		}
+		if f.Kind == Clock && s != "" && !clockPattern.MatchString(s) {
+			return nil, "must be a time of day as HH:MM"
+		}
+		if len(f.Enum) > 0 && s != "" {
+			s = strings.ToLower(s)
+			if !containsString(f.Enum, s) {
+				return nil, "must be one of " + strings.Join(f.Enum, ", ")
+			}
+		}
+		return s, ""
+	}
+	return nil, "has an unsupported kind"
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Schema renders the type as a JSON Schema (draft 2020-12) object.
+func (t Type) Schema() map[string]interface{} {
+	props := map[string]interface{}{}
+	required := []string{}
+	order := []string{}
+	for _, f := range t.Fields {
+		order = append(order, f.Name)
+		p := map[string]interface{}{"type": f.Kind}
+		if f.Kind == Clock {
+			p["type"] = "string"
+			p["pattern"] = clockPattern.String()
+		}
+		if f.Title != "" {
+			p["title"] = f.Title
+		}
+		if f.Description != "" {
+			p["description"] = f.Description
+		}
+		if f.Unit != "" {
+			p["x-unit"] = f.Unit
+		}
+		if f.Min != nil {
+			p["minimum"] = *f.Min
+		}
+		if f.Max != nil {
+			p["maximum"] = *f.Max
+		}
+		if f.MaxLength > 0 {
+			p["maxLength"] = f.MaxLength
+		}
+		if len(f.Enum) > 0 {
+			p["enum"] = f.Enum
+		}
+		props[f.Name] = p
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  "journal/" + t.Name,
+		"title":                t.Title,
+		"description":          t.Description,
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+		// Properties are unordered in JSON; forms follow this order.
+		"x-order": order,
+	}
+}
@@ -0,0 +1,67 @@
+package journal
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	food, ok := Lookup(" Food ")
+	assert.True(t, ok)
+
+	data, err := food.Validate(json.RawMessage(`{"name":" Oats ","meal":"Breakfast","calories":350,"notes":""}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Oats", "meal": "breakfast", "calories": 350.0}, data)
+
+	_, err = food.Validate(json.RawMessage(`{"meal":"brunch","calories":-1,"sugar":5}`))
+	verr, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, []FieldError{
+		{Field: "meal", Message: "must be one of breakfast, lunch, dinner, snack"},
+		{Field: "calories", Message: "must be at least 0"},
+		{Field: "sugar", Message: "is not a field of food entries"},
+	}, verr.Errors)
+
+	// Food entries come from a recipe, a food or quick-add macros.
+	data, err = food.Validate(json.RawMessage(`{"recipe_id":"r1","meal":"lunch"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, data["servings"])
+	_, err = food.Validate(json.RawMessage(`{"food_id":"f1"}`))
+	assert.EqualError(t, err, "invalid food entry: grams: is required with food_id")
+	_, err = food.Validate(json.RawMessage(`{"recipe_id":"r1","food_id":"f1"}`))
+	assert.EqualError(t, err, "invalid food entry: give either recipe_id or food_id, not both")
+	_, err = food.Validate(json.RawMessage(`{"calories":200,"grams":50}`))
+	assert.EqualError(t, err, "invalid food entry: name: is required for quick-add entries; grams: needs recipe_id or food_id")
+
+	_, err = food.Validate(json.RawMessage(`[1]`))
+	assert.EqualError(t, err, "invalid food entry: data must be a JSON object")
+
+	sleep, _ := Lookup("sleep")
+	data, err = sleep.Validate(json.RawMessage(`{"hours":7.5,"quality":4,"bedtime":"23:15"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), data["quality"])
+	_, err = sleep.Validate(json.RawMessage(`{"hours":7,"quality":3.5,"bedtime":"25:00"}`))
+	assert.EqualError(t, err, "invalid sleep entry: quality: must be a whole number; bedtime: must be a time of day as HH:MM")
+
+	_, ok = Lookup("unknown")
+	assert.False(t, ok)
+}
+
+func TestSchema(t *testing.T) {
+	weight, _ := Lookup("weight")
+	s := weight.Schema()
+	assert.Equal(t, "object", s["type"])
+	assert.Equal(t, []string{"weight"}, s["required"])
+	assert.Equal(t, []string{"weight", "body_fat", "time"}, s["x-order"])
+	props := s["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "number", "title": "Weight", "x-unit": "kg", "minimum": 20.0, "maximum": 500.0}, props["weight"])
+	assert.Equal(t, "string", props["time"].(map[string]interface{})["type"])
+
+	names := []string{}
+	for _, t := range Types() {
+		names = append(names, t.Name)
+	}
+	assert.Equal(t, []string{"active_energy", "food", "heart_rate", "mood", "note", "sleep", "steps", "water", "weight"}, names)
+}
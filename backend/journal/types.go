@@ -0,0 +1,138 @@
+package journal
+
+// Built-in entry types.
+const (
+	Food         = "food"
+	Weight       = "weight"
+	Sleep        = "sleep"
+	Mood         = "mood"
+	Water        = "water"
+	Steps        = "steps"
+	HeartRate    = "heart_rate"
+	ActiveEnergy = "active_energy"
+	Note         = "note"
+)
+
+// Meals a food entry can belong to.
+var Meals = []string{"breakfast", "lunch", "dinner", "snack"}
+
+func init() {
+	Register(Type{
+		Name: Food, Title: "Food",
+		Description: "Something eaten: a recipe by servings, a food by grams, or quick-add macros.",
+		Fields: []Field{
+			{Name: "name", Kind: String, Title: "Food", MaxLength: 200},
+			{Name: "meal", Kind: String, Title: "Meal", Enum: Meals},
+			{Name: "recipe_id", Kind: String, Title: "Recipe", MaxLength: 100},
+			{Name: "servings", Kind: Number, Title: "Servings", Min: bound(0.1), Max: bound(50)},
+			{Name: "food_id", Kind: String, Title: "Food", Description: "A food from the nutrition database", MaxLength: 100},
+			{Name: "grams", Kind: Number, Title: "Amount", Unit: "g", Min: bound(1), Max: bound(5000)},
+			{Name: "calories", Kind: Number, Title: "Calories", Unit: "kcal", Min: bound(0), Max: bound(10000)},
+			{Name: "protein", Kind: Number, Title: "Protein", Unit: "g", Min: bound(0), Max: bound(1000)},
+			{Name: "carbs", Kind: Number, Title: "Carbs", Unit: "g", Min: bound(0), Max: bound(1000)},
+			{Name: "fat", Kind: Number, Title: "Fat", Unit: "g", Min: bound(0), Max: bound(1000)},
+			{Name: "fiber", Kind: Number, Title: "Fibre", Unit: "g", Min: bound(0), Max: bound(500)},
+			{Name: "notes", Kind: String, Title: "Notes", MaxLength: 1000},
+		},
+		Check: checkFood,
+	})
+	Register(Type{
+		Name: Weight, Title: "Weight", Description: "A body weight measurement.",
+		Fields: []Field{
+			{Name: "weight", Kind: Number, Title: "Weight", Unit: "kg", Required: true, Min: bound(20), Max: bound(500)},
+			{Name: "body_fat", Kind: Number, Title: "Body fat", Unit: "%", Min: bound(2), Max: bound(75)},
+			{Name: "time", Kind: Clock, Title: "Time"},
+		},
+	})
+	Register(Type{
+		Name: Sleep, Title: "Sleep", Description: "The night's sleep ending on the entry date.",
+		Fields: []Field{
+			{Name: "hours", Kind: Number, Title: "Hours asleep", Unit: "h", Required: true, Min: bound(0), Max: bound(24)},
+			{Name: "quality", Kind: Integer, Title: "Quality", Description: "1 (poor) to 5 (great)", Min: bound(1), Max: bound(5)},
+			{Name: "bedtime", Kind: Clock, Title: "Bedtime"},
+			{Name: "wake_time", Kind: Clock, Title: "Wake time"},
+		},
+	})
+	Register(Type{
+		Name: Mood, Title: "Mood", Description: "How the day felt.",
+		Fields: []Field{
+			{Name: "score", Kind: Integer, Title: "Mood", Description: "1 (low) to 5 (great)", Required: true, Min: bound(1), Max: bound(5)},
+			{Name: "energy", Kind: Integer, Title: "Energy", Description: "1 (drained) to 5 (energetic)", Min: bound(1), Max: bound(5)},
+			{Name: "stress", Kind: Integer, Title: "Stress", Description: "1 (calm) to 5 (very stressed)", Min: bound(1), Max: bound(5)},
+			{Name: "notes", Kind: String, Title: "Notes", MaxLength: 1000},
+		},
+	})
+	Register(Type{
+		Name: Water, Title: "Water", Description: "Water drunk; several entries a day add up.",
+		Fields: []Field{
+			{Name: "ml", Kind: Number, Title: "Amount", Unit: "ml", Required: true, Min: bound(1), Max: bound(10000)},
+		},
+	})
+	Register(Type{
+		Name: Steps, Title: "Steps", Description: "The day's step count.",
+		Fields: []Field{
+			{Name: "count", Kind: Integer, Title: "Steps", Required: true, Min: bound(0), Max: bound(200000)},
+		},
+	})
+	Register(Type{
+		Name: HeartRate, Title: "Heart rate", Description: "The day's heart rate, usually from a watch.",
+		Fields: []Field{
+			{Name: "resting", Kind: Integer, Title: "Resting", Unit: "bpm", Min: bound(20), Max: bound(250)},
+			{Name: "min", Kind: Integer, Title: "Lowest", Unit: "bpm", Min: bound(20), Max: bound(250)},
+			{Name: "avg", Kind: Integer, Title: "Average", Unit: "bpm", Min: bound(20), Max: bound(250)},
+			{Name: "max", Kind: Integer, Title: "Highest", Unit: "bpm", Min: bound(20), Max: bound(250)},
+		},
+	})
+	Register(Type{
+		Name: ActiveEnergy, Title: "Active energy", Description: "Calories burned by activity over the day.",
+		Fields: []Field{
+			{Name: "calories", Kind: Number, Title: "Active energy", Unit: "kcal", Required: true, Min: bound(0), Max: bound(20000)},
+		},
+	})
+	Register(Type{
+		Name: Note, Title: "Note", Description: "Free text.",
+		Fields: []Field{
+			{Name: "text", Kind: String, Title: "Note", Required: true, MaxLength: 5000},
+		},
+	})
+}
+
+// checkFood makes sure a food entry says what was eaten in exactly one
+// way. Recipe servings default to one; the nutrients of recipe and food
+// entries are worked out when the entry is saved.
+func checkFood(data map[string]interface{}) []FieldError {
+	_, recipe := data["recipe_id"]
+	_, food := data["food_id"]
+	var errs []FieldError
+	switch {
+	case recipe && food:
+		errs = append(errs, FieldError{Message: "give either recipe_id or food_id, not both"})
+	case recipe:
+		if _, ok := data["servings"]; !ok {
+			data["servings"] = 1.0
+		}
+		if _, ok := data["grams"]; ok {
+			errs = append(errs, FieldError{Field: "grams", Message: "is only used with food_id"})
+		}
+	case food:
+		if _, ok := data["grams"]; !ok {
+			errs = append(errs, FieldError{Field: "grams", Message: "is required with food_id"})
+		}
+		if _, ok := data["servings"]; ok {
+			errs = append(errs, FieldError{Field: "servings", Message: "is only used with recipe_id"})
+		}
+	default:
+		if _, ok := data["name"]; !ok {
+			errs = append(errs, FieldError{Field: "name", Message: "is required for quick-add entries"})
+		}
+		if _, ok := data["calories"]; !ok {
+			errs = append(errs, FieldError{Field: "calories", Message: "is required for quick-add entries"})
+		}
+		for _, f := range []string{"servings", "grams"} {
+			if _, ok := data[f]; ok {
+				errs = append(errs, FieldError{Field: f, Message: "needs recipe_id or food_id"})
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,386 @@
+// Package journalcsv reads journal entries from CSV files, our own exports
+// and those of other trackers, and writes them back out.
+//
+// Exports have one file per entry type. The columns are "date"
+// (YYYY-MM-DD), then every field of the type in the order of its schema
+// (see /api/journals/types), then "id". Numbers use a decimal point, times
+// are HH:MM and empty cells are fields that were not logged. New fields
+// are only ever added before "id", so spreadsheets that look columns up by
+// name keep working.
+package journalcsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// DateFormats are the named formats dates can be read in. A Go layout
+// such as "02 Jan 2006" is accepted as well.
+var DateFormats = map[string][]string{
+	"iso": {"2006-01-02", "2006/01/02"},
+	"us":  {"01/02/2006", "1/2/2006", "01/02/06", "1/2/06"},
+	"eu":  {"02/01/2006", "2/1/2006", "02.01.2006", "2.1.2006", "02-01-2006", "02/01/06"},
+}
+
+const poundsToKg = 0.45359237
+
+// Options configure an import.
+type Options struct {
+	Preset Preset
+	// Type is the journal type rows become; it is the preset's when the
+	// preset has one.
+	Type journal.Type
+	// DateFormat overrides the preset's: a name from DateFormats or a Go
+	// layout.
+	DateFormat string
+	// DecimalComma reads "1.234,5" as 1234.5; otherwise it is "1,234.5".
+	DecimalComma bool
+	// Pounds reads weights in pounds.
+	Pounds bool
+}
+
+// Row is one line of the file as an entry, or the reasons it cannot be
+// one. Warnings note values that were left out, such as an unknown meal.
+type Row struct {
+	Line     int                    `json:"line"`
+	Date     string                 `json:"date,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Errors   []journal.FieldError   `json:"errors,omitempty"`
+	Warnings []string               `json:"warnings,omitempty"`
+	// ID is set once the entry has been saved.
+	ID string `json:"id,omitempty"`
+}
+
+// Parsed is a file read for import. Unmapped lists the columns that were
+// not imported.
+type Parsed struct {
+	Columns  map[string]string `json:"columns"`
+	Unmapped []string          `json:"unmapped"`
+	Rows     []Row             `json:"rows"`
+}
+
+// Columns returns the export header for entries of t.
+func Columns(t journal.Type) []string {
+	cols := []string{DateColumn}
+	for _, f := range t.Fields {
+		cols = append(cols, f.Name)
+	}
+	return append(cols, "id")
+}
+
+// Write exports entries of t, which must all be of that type. Text that
+// looks like a spreadsheet formula is escaped.
+func Write(w io.Writer, t journal.Type, entries []models.JournalEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns(t)); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		row := []string{e.Date}
+		for _, f := range t.Fields {
+			row = append(row, formatValue(e.Data[f.Name]))
+		}
+		if err := cw.Write(append(row, e.ID)); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return escapeFormula(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// formulaPrefixes start a cell that spreadsheets would run as a formula.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula quotes text that a spreadsheet would otherwise read as a
+// formula, such as a food named "=HYPERLINK(...)", with a leading
+// apostrophe. Parse removes it again.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune(formulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func unescapeFormula(s string) string {
+	if len(s) > 1 && s[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(s[1])) {
+		return s[1:]
+	}
+	return s
+}
+
+// detectDelimiter picks the separator the header line uses most: comma,
+// semicolon (common where the comma is the decimal mark) or tab.
+func detectDelimiter(header []byte) rune {
+	best, count := ',', bytes.Count(header, []byte(","))
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(header, []byte(string(d))); n > count {
+			best, count = d, n
+		}
+	}
+	return best
+}
+
+// Parse reads a CSV file for import. Every row is validated against the
+// journal type; the file as a whole is only rejected when it cannot be
+// read or has no date column.
+func Parse(r io.Reader, opts Options) (Parsed, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(4096)
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	cr := csv.NewReader(br)
+	cr.Comma = detectDelimiter(head)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return Parsed{}, fmt.Errorf("invalid CSV: %v", err)
+	}
+	layouts, err := dateLayouts(opts)
+	if err != nil {
+		return Parsed{}, err
+	}
+
+	p := Parsed{Columns: map[string]string{}, Unmapped: []string{}, Rows: []Row{}}
+	fields := make([]*journal.Field, len(header))
+	dateCol := -1
+	for i, h := range header {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff"))
+		key := strings.ToLower(h)
+		name := key
+		if opts.Preset.Columns != nil {
+			name = opts.Preset.Columns[key]
+		}
+		if name == DateColumn && dateCol < 0 {
+			dateCol = i
+			p.Columns[h] = DateColumn
+			continue
+		}
+		if f, ok := opts.Type.Field(name); ok {
+			fields[i] = &f
+			p.Columns[h] = f.Name
+			continue
+		}
+		// Our own exports carry the entry ID, which is not imported.
+		if opts.Preset.Name != Native || key != "id" {
+			p.Unmapped = append(p.Unmapped, h)
+		}
+	}
+	if dateCol < 0 {
+		return Parsed{}, fmt.Errorf("CSV needs a date column")
+	}
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Parsed{}, fmt.Errorf("invalid CSV: %v", err)
+		}
+		if blank(record) {
+			continue
+		}
+		p.Rows = append(p.Rows, parseRow(line, record, dateCol, fields, layouts, opts))
+	}
+	return p, nil
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func dateLayouts(opts Options) ([]string, error) {
+	format := opts.DateFormat
+	if format == "" {
+		format = opts.Preset.DateFormat
+	}
+	if layouts, ok := DateFormats[strings.ToLower(format)]; ok {
+		return layouts, nil
+	}
+	if strings.Contains(format, "2006") || strings.Contains(format, "06") {
+		return []string{format}, nil
+	}
+	return nil, fmt.Errorf("unknown date format %q; use iso, us, eu or a Go layout", format)
+}
+
+func parseDate(s string, layouts []string) (string, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(dateLayout), true
+		}
+		// Dates with a time after them, e.g. "2025-03-03 07:15:00".
+		if len(s) > len(layout) && (s[len(layout)] == ' ' || s[len(layout)] == 'T') {
+			if t, err := time.Parse(layout, s[:len(layout)]); err == nil {
+				return t.Format(dateLayout), true
+			}
+		}
+	}
+	return "", false
+}
+
+// parseNumber reads a number written with thousands separators and units,
+// e.g. "1,234.5 kcal" or, with a decimal comma, "1.234,5".
+func parseNumber(s string, decimalComma bool) (float64, bool) {
+	s = strings.TrimRightFunc(strings.TrimSpace(s), func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsSpace(r) || r == '%'
+	})
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, s)
+	if decimalComma {
+		s = strings.ReplaceAll(strings.ReplaceAll(s, ".", ""), ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+var clockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04 pm", "3:04pm"}
+
+func parseClock(s string) (string, bool) {
+	for _, layout := range clockLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.Format("15:04"), true
+		}
+	}
+	return "", false
+}
+
+// matchEnum finds the allowed value s stands for, ignoring case and a
+// plural "s", e.g. "Snacks" for "snack".
+func matchEnum(s string, enum []string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, v := range enum {
+		if s == v || s == v+"s" {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func parseRow(line int, record []string, dateCol int, fields []*journal.Field, layouts []string, opts Options) Row {
+	row := Row{Line: line, Data: map[string]interface{}{}}
+	if dateCol < len(record) {
+		if date, ok := parseDate(record[dateCol], layouts); ok {
+			row.Date = date
+		} else {
+			row.Errors = append(row.Errors, journal.FieldError{Field: DateColumn, Message: fmt.Sprintf("%q is not a date", strings.TrimSpace(record[dateCol]))})
+		}
+	}
+
+	var mealLabel string
+	for i, value := range record {
+		value = strings.TrimSpace(unescapeFormula(value))
+		if i >= len(fields) || fields[i] == nil || value == "" {
+			continue
+		}
+		f := fields[i]
+		switch f.Kind {
+		case journal.Number, journal.Integer:
+			v, ok := parseNumber(value, opts.DecimalComma)
+			if !ok {
+				row.Errors = append(row.Errors, journal.FieldError{Field: f.Name, Message: fmt.Sprintf("%q is not a number", value)})
+				continue
+			}
+			if opts.Pounds && f.Unit == "kg" {
+				v = float64(int64(v*poundsToKg*100+0.5)) / 100
+			}
+			row.Data[f.Name] = v
+		case journal.Clock:
+			v, ok := parseClock(value)
+			if !ok {
+				row.Errors = append(row.Errors, journal.FieldError{Field: f.Name, Message: fmt.Sprintf("%q is not a time of day", value)})
+				continue
+			}
+			row.Data[f.Name] = v
+		case journal.Boolean:
+			v, err := strconv.ParseBool(strings.ToLower(value))
+			if err != nil {
+				row.Errors = append(row.Errors, journal.FieldError{Field: f.Name, Message: fmt.Sprintf("%q is not true or false", value)})
+				continue
+			}
+			row.Data[f.Name] = v
+		default:
+			if len(f.Enum) > 0 {
+				if f.Name == "meal" {
+					mealLabel = value
+				}
+				v, ok := matchEnum(value, f.Enum)
+				if !ok && !f.Required {
+					row.Warnings = append(row.Warnings, fmt.Sprintf("%s %q is not one of %s and was left out", f.Name, value, strings.Join(f.Enum, ", ")))
+					continue
+				}
+				if ok {
+					value = v
+				}
+			}
+			row.Data[f.Name] = value
+		}
+	}
+
+	// Trackers that export daily meal totals give them no name.
+	if opts.Type.Name == journal.Food && row.Data["name"] == nil && row.Data["recipe_id"] == nil && row.Data["food_id"] == nil {
+		name := opts.Preset.Title
+		if mealLabel != "" {
+			name += " " + strings.ToLower(mealLabel)
+		}
+		row.Data["name"] = name
+	}
+
+	if len(row.Errors) > 0 {
+		return row
+	}
+	raw, _ := json.Marshal(row.Data)
+	data, err := opts.Type.Validate(raw)
+	var verr *journal.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		row.Errors = verr.Errors
+	case err != nil:
+		row.Errors = []journal.FieldError{{Message: err.Error()}}
+	default:
+		row.Data = data
+	}
+	return row
+}
@@ -0,0 +1,162 @@
+package journalcsv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/journal"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func lookup(t *testing.T, name string) journal.Type {
+	typ, ok := journal.Lookup(name)
+	require.True(t, ok)
+	return typ
+}
+
+func TestParseMyFitnessPal(t *testing.T) {
+	preset, _ := LookupPreset("myfitnesspal")
+	csv := "\ufeffDate,Meal,Calories,Fat (g),Carbohydrates (g),Fiber,Protein (g),Sodium (mg),Note\n" +
+		"2025-03-03,Breakfast,412.5,12,48,6,22,300,\n" +
+		"2025-03-03,Snacks,\"1,050\",40,120,10,30,900,movie night\n" +
+		",,,,,,,,\n" +
+		"2025-03-04,Brunch,300,,,,,,\n" +
+		"03/05/2025,Dinner,abc,,,,,,\n"
+	p, err := Parse(strings.NewReader(csv), Options{Preset: preset, Type: lookup(t, journal.Food)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Sodium (mg)"}, p.Unmapped)
+	assert.Equal(t, "calories", p.Columns["Calories"])
+	require.Len(t, p.Rows, 4)
+
+	breakfast := p.Rows[0]
+	assert.Equal(t, 2, breakfast.Line)
+	assert.Equal(t, "2025-03-03", breakfast.Date)
+	assert.Empty(t, breakfast.Errors)
+	assert.Equal(t, "MyFitnessPal nutrition breakfast", breakfast.Data["name"])
+	assert.Equal(t, "breakfast", breakfast.Data["meal"])
+	assert.Equal(t, 412.5, breakfast.Data["calories"])
+
+	snack := p.Rows[1]
+	assert.Equal(t, "snack", snack.Data["meal"])
+	assert.Equal(t, 1050.0, snack.Data["calories"])
+	assert.Equal(t, "movie night", snack.Data["notes"])
+
+	// The blank line is skipped but still counted.
+	brunch := p.Rows[2]
+	assert.Equal(t, 5, brunch.Line)
+	assert.Empty(t, brunch.Errors)
+	assert.Nil(t, brunch.Data["meal"])
+	assert.Len(t, brunch.Warnings, 1)
+
+	bad := p.Rows[3]
+	require.Len(t, bad.Errors, 2)
+	assert.Equal(t, "date", bad.Errors[0].Field)
+	assert.Equal(t, "calories", bad.Errors[1].Field)
+}
+
+func TestParseOptions(t *testing.T) {
+	preset, _ := LookupPreset(Native)
+	weight := lookup(t, journal.Weight)
+
+	// Semicolons and decimal commas, European dates and pounds.
+	csv := "date;weight;body_fat;time;id\n05.03.2025;176,4;18,5;7:15 AM;abc\n06.03.2025 07:00;1.200;;;\n"
+	p, err := Parse(strings.NewReader(csv), Options{Preset: preset, Type: weight, DateFormat: "eu", DecimalComma: true, Pounds: true})
+	require.NoError(t, err)
+	assert.Empty(t, p.Unmapped)
+	require.Len(t, p.Rows, 2)
+	assert.Equal(t, "2025-03-05", p.Rows[0].Date)
+	assert.Equal(t, map[string]interface{}{"weight": 80.01, "body_fat": 18.5, "time": "07:15"}, p.Rows[0].Data)
+	// 1200 lb is more than the journal allows.
+	assert.Equal(t, "2025-03-06", p.Rows[1].Date)
+	require.Len(t, p.Rows[1].Errors, 1)
+	assert.Equal(t, "weight", p.Rows[1].Errors[0].Field)
+
+	_, err = Parse(strings.NewReader("day,weight\n"), Options{Preset: preset, Type: weight})
+	assert.ErrorContains(t, err, "date column")
+	_, err = Parse(strings.NewReader("date,weight\n"), Options{Preset: preset, Type: weight, DateFormat: "someday"})
+	assert.ErrorContains(t, err, "unknown date format")
+	p, err = Parse(strings.NewReader("date,weight\n3 Mar 2025,80\n"), Options{Preset: preset, Type: weight, DateFormat: "2 Jan 2006"})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-03", p.Rows[0].Date)
+}
+
+func TestParseNumber(t *testing.T) {
+	for _, c := range []struct {
+		in    string
+		comma bool
+		want  float64
+	}{
+		{"1,234.5", false, 1234.5},
+		{"1.234,5", true, 1234.5},
+		{"412 kcal", false, 412},
+		{"18.5 %", false, 18.5},
+		{"-3", false, -3},
+	} {
+		got, ok := parseNumber(c.in, c.comma)
+		assert.True(t, ok, c.in)
+		assert.Equal(t, c.want, got, c.in)
+	}
+	_, ok := parseNumber("n/a", false)
+	assert.False(t, ok)
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	sleep := lookup(t, journal.Sleep)
+	assert.Equal(t, []string{"date", "hours", "quality", "bedtime", "wake_time", "id"}, Columns(sleep))
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, sleep, []models.JournalEntry{
+		{ID: "e1", Date: "2025-03-03", Data: map[string]interface{}{"hours": 7.25, "quality": 4.0, "bedtime": "23:00"}},
+		{ID: "e2", Date: "2025-03-04", Data: map[string]interface{}{"hours": 8.0}},
+	}))
+	assert.Equal(t, "date,hours,quality,bedtime,wake_time,id\n2025-03-03,7.25,4,23:00,,e1\n2025-03-04,8,,,,e2\n", buf.String())
+
+	preset, _ := LookupPreset(Native)
+	p, err := Parse(&buf, Options{Preset: preset, Type: sleep})
+	require.NoError(t, err)
+	require.Len(t, p.Rows, 2)
+	assert.Equal(t, map[string]interface{}{"hours": 7.25, "quality": int64(4), "bedtime": "23:00"}, p.Rows[0].Data)
+	assert.Empty(t, p.Rows[1].Errors)
+}
+
+func TestWriteEscapesFormulas(t *testing.T) {
+	food := lookup(t, journal.Food)
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, food, []models.JournalEntry{
+		{ID: "e1", Date: "2025-03-03", Data: map[string]interface{}{"name": `=HYPERLINK("http://evil.example")`, "notes": "-lots", "calories": -0.0}},
+		{ID: "e2", Date: "2025-03-03", Data: map[string]interface{}{"name": "@SUM(A1)", "notes": "\tcmd", "calories": 100.0}},
+	}))
+	assert.Contains(t, buf.String(), `"'=HYPERLINK(""http://evil.example"")"`)
+	assert.Contains(t, buf.String(), "'@SUM(A1)")
+	assert.NotContains(t, buf.String(), ",@SUM")
+
+	preset, _ := LookupPreset(Native)
+	p, err := Parse(&buf, Options{Preset: preset, Type: food})
+	require.NoError(t, err)
+	require.Len(t, p.Rows, 2)
+	assert.Equal(t, `=HYPERLINK("http://evil.example")`, p.Rows[0].Data["name"])
+	assert.Equal(t, "-lots", p.Rows[0].Data["notes"])
+	assert.Equal(t, "@SUM(A1)", p.Rows[1].Data["name"])
+}
+
+func TestPresets(t *testing.T) {
+	list := Presets()
+	assert.Equal(t, Native, list[0].Name)
+	for _, p := range list[1:] {
+		_, ok := journal.Lookup(p.Type)
+		assert.True(t, ok, p.Name)
+		_, ok = DateFormats[p.DateFormat]
+		assert.True(t, ok, p.Name)
+		typ, _ := journal.Lookup(p.Type)
+		for col, field := range p.Columns {
+			if field == DateColumn {
+				continue
+			}
+			_, ok := typ.Field(field)
+			assert.True(t, ok, "%s: %s", p.Name, col)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package journalcsv
+
+import (
+	"sort"
+
+	"github.com/terr0r/fitness.ai/backend/journal"
+)
+
+// Native is the preset for files in our own export format.
+const Native = "native"
+
+// Preset maps the columns of another app's CSV export to journal fields.
+type Preset struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	// Type is the journal type every row becomes. The native preset has
+	// none; its type is given with the import.
+	Type string `json:"type,omitempty"`
+	// DateFormat is the named format (see DateFormats) dates are read in
+	// unless the import gives another.
+	DateFormat string `json:"date_format"`
+	// Columns maps lowercased header names to fields, or to "date". The
+	// native preset maps each field from the column of the same name.
+	Columns map[string]string `json:"columns,omitempty"`
+}
+
+// DateColumn is the field name a preset maps the entry date to.
+const DateColumn = "date"
+
+var presets = map[string]Preset{
+	Native: {
+		Name: Native, Title: "Fitness.ai export", DateFormat: "iso",
+	},
+	"myfitnesspal": {
+		Name: "myfitnesspal", Title: "MyFitnessPal nutrition", Type: journal.Food, DateFormat: "iso",
+		Columns: map[string]string{
+			"date": DateColumn, "meal": "meal", "calories": "calories",
+			"fat (g)": "fat", "carbohydrates (g)": "carbs", "fiber": "fiber", "protein (g)": "protein", "note": "notes",
+		},
+	},
+	"myfitnesspal_weight": {
+		Name: "myfitnesspal_weight", Title: "MyFitnessPal weight", Type: journal.Weight, DateFormat: "iso",
+		Columns: map[string]string{"date": DateColumn, "weight": "weight", "body fat %": "body_fat"},
+	},
+	"loseit": {
+		Name: "loseit", Title: "Lose It!", Type: journal.Food, DateFormat: "us",
+		Columns: map[string]string{
+			"date": DateColumn, "name": "name", "type": "meal", "calories": "calories",
+			"fat (g)": "fat", "protein (g)": "protein", "carbohydrates (g)": "carbs", "fiber (g)": "fiber",
+		},
+	},
+	"cronometer": {
+		Name: "cronometer", Title: "Cronometer servings", Type: journal.Food, DateFormat: "iso",
+		Columns: map[string]string{
+			"day": DateColumn, "group": "meal", "food name": "name", "energy (kcal)": "calories",
+			"protein (g)": "protein", "carbs (g)": "carbs", "fat (g)": "fat", "fiber (g)": "fiber",
+		},
+	},
+}
+
+// LookupPreset returns a preset by name.
+func LookupPreset(name string) (Preset, bool) {
+	p, ok := presets[name]
+	return p, ok
+}
+
+// Presets returns every preset, the native one first and the others by
+// name.
+func Presets() []Preset {
+	list := []Preset{presets[Native]}
+	var names []string
+	for name := range presets {
+		if name != Native {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list = append(list, presets[name])
+	}
+	return list
+}
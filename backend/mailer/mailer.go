@@ -0,0 +1,104 @@
+// Package mailer sends plain-text email through an SMTP relay configured
+// with SMTP_ADDR (host:port), SMTP_FROM and, for relays that need them,
+// SMTP_USERNAME and SMTP_PASSWORD.
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is one email to one recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email.
+type Mailer interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// ErrNotConfigured is returned when no relay is set up.
+var ErrNotConfigured = errors.New("mailer: SMTP_ADDR is not set")
+
+// Default is the mailer the app sends with; tests replace it.
+var Default Mailer = SMTP{}
+
+// Send sends m with Default.
+func Send(ctx context.Context, m Message) error {
+	return Default.Send(ctx, m)
+}
+
+// SMTP sends through the relay in the environment, read on every send.
+type SMTP struct{}
+
+func (SMTP) Send(ctx context.Context, m Message) error {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return ErrNotConfigured
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "Fitness.ai <no-reply@localhost>"
+	}
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid SMTP_FROM: %w", err)
+	}
+	to, err := mail.ParseAddress(m.To)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid recipient: %w", err)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("mailer: invalid SMTP_ADDR: %w", err)
+	}
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	// net/smtp takes no context; honour it at least before connecting.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(addr, auth, sender.Address, []string{to.Address}, compose(sender, to, m))
+}
+
+// compose writes m as a UTF-8 plain-text message.
+func compose(from, to *mail.Address, m Message) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+uuid.New().String()+"@"+domain(from.Address)+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func domain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
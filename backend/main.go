@@ -1,16 +1,24 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/joho/godotenv"
 	"github.com/terr0r/fitness.ai/backend/api"
 	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/reminders"
 )
 
 func main() {
@@ -29,6 +37,9 @@ func main() {
 	if err := db.RunSchema("db/schema.sql"); err != nil {
 		log.Fatalf("Failed to run schema migration: %v", err)
 	}
+	if err := jobs.RequeueInterrupted(); err != nil {
+		log.Printf("Failed to requeue interrupted jobs: %v", err)
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
@@ -38,6 +49,27 @@ func main() {
 	// API Routes
 	api.SetupAuthRoutes(r)
 	api.SetupUserRoutes(r)
+	api.SetupPlanRoutes(r)
+	api.SetupScheduleRoutes(r)
+	api.SetupRecipeRoutes(r)
+	api.SetupFoodRoutes(r)
+	api.SetupPantryRoutes(r)
+	api.SetupExerciseRoutes(r)
+	api.SetupWorkoutRoutes(r)
+	api.SetupSessionRoutes(r)
+	api.SetupRecordRoutes(r)
+	api.SetupAnalyticsRoutes(r)
+	api.SetupCardioRoutes(r)
+	api.SetupJournalRoutes(r)
+	api.SetupNutritionRoutes(r)
+	api.SetupHabitRoutes(r)
+	api.SetupAdherenceRoutes(r)
+	api.SetupDashboardRoutes(r)
+	api.SetupImportRoutes(r)
+	api.SetupWebhookRoutes(r)
+	api.SetupJobRoutes(r)
+	api.SetupReminderRoutes(r)
+	api.SetupNotificationRoutes(r)
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Fitness.ai API is running"))
@@ -48,8 +80,39 @@ func main() {
 		port = "8080"
 	}
 
-	fmt.Printf("Server starting on port %s\n", port)
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		log.Fatal(err)
+	// Background jobs
+	workers, err := strconv.Atoi(os.Getenv("JOB_WORKERS"))
+	if err != nil || workers < 1 {
+		workers = 4
+	}
+	pool := jobs.Start(workers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	remindersDone := reminders.Start(ctx)
+
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		fmt.Printf("Server starting on port %s\n", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop taking requests, then let running jobs and reminders finish.
+	<-ctx.Done()
+	log.Println("Shutting down")
+	shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		log.Printf("Failed to close HTTP connections: %v", err)
+	}
+	if err := pool.Shutdown(shutdown); err != nil {
+		log.Printf("Interrupted running jobs: %v", err)
+	}
+	select {
+	case <-remindersDone:
+	case <-shutdown.Done():
+		log.Printf("Interrupted sending reminders: %v", shutdown.Err())
 	}
 }
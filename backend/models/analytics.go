@@ -0,0 +1,46 @@
+package models
+
+// TrainingBucket aggregates the working sets logged in one day, week or
+// month. Start and End are inclusive local dates.
+type TrainingBucket struct {
+	Start    string  `json:"start"`
+	End      string  `json:"end"`
+	Sessions int     `json:"sessions"`
+	Sets     int     `json:"sets"`
+	HardSets int     `json:"hard_sets"`
+	Reps     int     `json:"reps"`
+	Tonnage  float64 `json:"tonnage"`
+	// AvgIntensity is the mean load of the loaded sets as a percentage of
+	// the exercise's best e1RM at the time; nil when none could be rated.
+	AvgIntensity *float64 `json:"avg_intensity"`
+	// HardSetsPerMuscle counts a hard set fully for its exercise's primary
+	// muscles and half for the secondary ones.
+	HardSetsPerMuscle map[string]float64 `json:"hard_sets_per_muscle"`
+	// Frequency counts the sessions that trained each muscle as a primary
+	// mover.
+	Frequency map[string]int `json:"frequency"`
+}
+
+// Workload compares the last 7 days of training load with the 28-day
+// weekly average ending on the same date.
+type Workload struct {
+	Date    string   `json:"date"`
+	Metric  string   `json:"metric"`
+	Acute   float64  `json:"acute"`
+	Chronic float64  `json:"chronic"`
+	Ratio   *float64 `json:"ratio"`
+	Status  string   `json:"status"`
+	Warning string   `json:"warning,omitempty"`
+}
+
+// FitnessDay is the state of the fitness/fatigue model on a date: CTL
+// (fitness) and ATL (fatigue) are exponentially weighted averages of the
+// daily load over 42 and 7 days, TSB (form) is the difference between the
+// two going into the day.
+type FitnessDay struct {
+	Date string  `json:"date"`
+	Load float64 `json:"load"`
+	CTL  float64 `json:"ctl"`
+	ATL  float64 `json:"atl"`
+	TSB  float64 `json:"tsb"`
+}
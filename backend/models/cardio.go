@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// Split covers one kilometre of a cardio session; the last one may be
+// shorter. Durations are moving time.
+type Split struct {
+	Index            int     `json:"index"`
+	DistanceMeters   float64 `json:"distance_m"`
+	DurationSeconds  float64 `json:"duration_s"`
+	PaceSecondsPerKm float64 `json:"pace_s_per_km"`
+	SpeedKmh         float64 `json:"speed_kmh"`
+	ElevationGain    float64 `json:"elevation_gain_m"`
+	AvgHeartRate     int     `json:"avg_heart_rate,omitempty"`
+}
+
+// HeartRateSample is a heart rate reading Offset seconds into a session.
+type HeartRateSample struct {
+	Offset int `json:"t"`
+	BPM    int `json:"bpm"`
+}
+
+// CardioSession is a run, ride or other distance activity, usually
+// imported from a device file. Distances and elevation are in metres.
+type CardioSession struct {
+	ID               string    `json:"id"`
+	Sport            string    `json:"sport"`
+	Name             string    `json:"name,omitempty"`
+	Source           string    `json:"source"`
+	StartedAt        time.Time `json:"started_at"`
+	DurationSeconds  float64   `json:"duration_s"`
+	MovingSeconds    float64   `json:"moving_s"`
+	DistanceMeters   float64   `json:"distance_m"`
+	ElevationGain    float64   `json:"elevation_gain_m"`
+	PaceSecondsPerKm float64   `json:"pace_s_per_km,omitempty"`
+	SpeedKmh         float64   `json:"speed_kmh,omitempty"`
+	AvgHeartRate     int       `json:"avg_heart_rate,omitempty"`
+	MaxHeartRate     int       `json:"max_heart_rate,omitempty"`
+	Splits           []Split   `json:"splits"`
+	// HRLoad is nil for sessions without heart rate data.
+	HRLoad *HeartRateLoad `json:"hr_load,omitempty"`
+	// HeartRate is only returned when a single session is fetched.
+	HeartRate []HeartRateSample `json:"heart_rate,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// HRProfile holds the heart rates the zones are derived from. Estimated values
+// stand in for ones the user has not measured.
+type HRProfile struct {
+	Max              int  `json:"max"`
+	Resting          int  `json:"resting"`
+	MaxEstimated     bool `json:"max_estimated"`
+	RestingEstimated bool `json:"resting_estimated"`
+	Female           bool `json:"-"`
+}
+
+// HRZone is a heart rate training zone; MaxBPM is exclusive.
+type HRZone struct {
+	Zone   int    `json:"zone"`
+	Name   string `json:"name"`
+	MinBPM int    `json:"min_bpm"`
+	MaxBPM int    `json:"max_bpm"`
+}
+
+// HeartRateLoad rates a session from its heart rate series. TRIMP is
+// Banister's training impulse; Load scales it so an hour at threshold
+// scores 100, like a training stress score.
+type HeartRateLoad struct {
+	TimeInZones []float64 `json:"time_in_zones_s"`
+	TRIMP       float64   `json:"trimp"`
+	Load        float64   `json:"load"`
+}
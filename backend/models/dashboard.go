@@ -0,0 +1,27 @@
+package models
+
+// ProfileCompleteness reports how much of the profile is filled in; the
+// missing fields use their JSON names.
+type ProfileCompleteness struct {
+	Percent int      `json:"percent"`
+	Missing []string `json:"missing"`
+}
+
+// WeightPoint is the body weight logged on a date, averaged when there are
+// several measurements.
+type WeightPoint struct {
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"`
+}
+
+// WeightTrend summarises recent weigh-ins. Changes compare the latest
+// weight with the last one on or before 7 and 30 days earlier, and
+// WeeklyRate is the fitted slope over Points; each is nil without enough
+// data.
+type WeightTrend struct {
+	Latest     *WeightPoint  `json:"latest"`
+	Change7    *float64      `json:"change_7d"`
+	Change30   *float64      `json:"change_30d"`
+	WeeklyRate *float64      `json:"weekly_rate"`
+	Points     []WeightPoint `json:"points"`
+}
@@ -0,0 +1,110 @@
+package models
+
+// Nutrients holds energy, macronutrients and the key micronutrients we
+// track. Micronutrients are in milligrams.
+type Nutrients struct {
+	Calories    float64 `json:"calories"`
+	Protein     float64 `json:"protein"`
+	Carbs       float64 `json:"carbs"`
+	Fat         float64 `json:"fat"`
+	Fiber       float64 `json:"fiber"`
+	Sugar       float64 `json:"sugar"`
+	SodiumMg    float64 `json:"sodium_mg"`
+	PotassiumMg float64 `json:"potassium_mg"`
+	CalciumMg   float64 `json:"calcium_mg"`
+	IronMg      float64 `json:"iron_mg"`
+	VitaminCMg  float64 `json:"vitamin_c_mg"`
+}
+
+// Macros returns the macronutrient subset.
+func (n Nutrients) Macros() Macros {
+	return Macros{Calories: n.Calories, Protein: n.Protein, Carbs: n.Carbs, Fat: n.Fat, Fiber: n.Fiber}
+}
+
+// Food is an entry in the local food-composition database. Nutrients are
+// per 100g.
+type Food struct {
+	ID       string        `json:"id"`
+	FdcID    int           `json:"fdc_id,omitempty"`
+	Name     string        `json:"name"`
+	Category string        `json:"category,omitempty"`
+	Per100g  Nutrients     `json:"per_100g"`
+	Portions []FoodPortion `json:"portions,omitempty"`
+}
+
+// FoodPortion converts one household unit of a food ("cup", "large",
+// "slice") to grams.
+type FoodPortion struct {
+	Unit  string  `json:"unit"`
+	Grams float64 `json:"grams"`
+}
+
+// RecipeIngredient is one parsed ingredient line of a recipe.
+type RecipeIngredient struct {
+	Position int     `json:"position"`
+	Raw      string  `json:"raw"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+	Name     string  `json:"name"`
+	FoodID   string  `json:"food_id,omitempty"`
+	FoodName string  `json:"food_name,omitempty"`
+	Grams    float64 `json:"grams"`
+	Resolved bool    `json:"resolved"`
+}
+
+// MealNutrition is one meal of a day's food log.
+type MealNutrition struct {
+	Meal    string         `json:"meal"`
+	Totals  Macros         `json:"totals"`
+	Entries []JournalEntry `json:"entries"`
+}
+
+// WeeklyNutrition averages the days with food logged in the week ending on
+// To.
+type WeeklyNutrition struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	DaysLogged int    `json:"days_logged"`
+	Average    Macros `json:"average"`
+}
+
+// DailyNutrition is a day's food log against the user's targets. Targets
+// and Remaining are nil when the profile is too incomplete to compute
+// targets.
+type DailyNutrition struct {
+	Date      string          `json:"date"`
+	Totals    Macros          `json:"totals"`
+	Targets   *Macros         `json:"targets"`
+	Remaining *Macros         `json:"remaining"`
+	Meals     []MealNutrition `json:"meals"`
+	Week      WeeklyNutrition `json:"week"`
+}
+
+// Product is a packaged food from the Open Food Facts dump, found by
+// barcode. Nutrients are per 100g (100ml for drinks); PerServing is set
+// when the serving size is known in grams.
+type Product struct {
+	Barcode      string     `json:"barcode"`
+	Name         string     `json:"name"`
+	Brand        string     `json:"brand,omitempty"`
+	Quantity     string     `json:"quantity,omitempty"`
+	ServingSize  string     `json:"serving_size,omitempty"`
+	ServingGrams float64    `json:"serving_grams,omitempty"`
+	Per100g      Nutrients  `json:"per_100g"`
+	PerServing   *Nutrients `json:"per_serving,omitempty"`
+	// Source is openfoodfacts, or user for products only known from an
+	// override; Overridden lists the fields a user override replaced.
+	Source     string   `json:"source"`
+	Overridden []string `json:"overridden,omitempty"`
+}
+
+// ProductOverride is a user's correction to a product, or the whole
+// product when the dump lacks it. Only the fields given replace the
+// dump's; Per100g is keyed like Nutrients.
+type ProductOverride struct {
+	Name         *string            `json:"name,omitempty"`
+	Brand        *string            `json:"brand,omitempty"`
+	ServingSize  *string            `json:"serving_size,omitempty"`
+	ServingGrams *float64           `json:"serving_grams,omitempty"`
+	Per100g      map[string]float64 `json:"per_100g,omitempty"`
+}
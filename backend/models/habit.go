@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// Habit is a user-defined daily target evaluated from journal entries of
+// one type: the day's entries are aggregated over Field and compared with
+// Target, e.g. the sum of steps "count" at least 10000.
+type Habit struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	EntryType  string         `json:"entry_type"`
+	Field      string         `json:"field,omitempty"`
+	Aggregate  string         `json:"aggregate"`
+	Comparison string         `json:"comparison"`
+	Target     float64        `json:"target"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Progress   *HabitProgress `json:"progress,omitempty"`
+}
+
+// HabitDay is a habit's outcome on one date; Value is nil when nothing
+// was logged.
+type HabitDay struct {
+	Date  string   `json:"date"`
+	Value *float64 `json:"value"`
+	Met   bool     `json:"met"`
+}
+
+// HabitProgress summarises a habit over a window of days ending today.
+type HabitProgress struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	CurrentStreak  int        `json:"current_streak"`
+	LongestStreak  int        `json:"longest_streak"`
+	CompletionRate float64    `json:"completion_rate"`
+	Days           []HabitDay `json:"days,omitempty"`
+}
+
+// Streak is a run of consecutive days something was logged or achieved.
+// Current counts back from today, or from yesterday while today is still
+// open.
+type Streak struct {
+	EntryType string `json:"entry_type,omitempty"`
+	Current   int    `json:"current"`
+	Longest   int    `json:"longest"`
+	Last      string `json:"last,omitempty"`
+}
+
+// AdherenceDay scores one day from 0 to 100 across the parts of the plan
+// that applied to it; parts that did not apply are nil, and so is Score
+// when none did.
+type AdherenceDay struct {
+	Date      string   `json:"date"`
+	Score     *float64 `json:"score"`
+	Training  *float64 `json:"training"`
+	Nutrition *float64 `json:"nutrition"`
+	Habits    *float64 `json:"habits"`
+}
+
+// AdherenceWeek compares a week's training and eating with the plan.
+// Sessions after today are not counted.
+type AdherenceWeek struct {
+	Start               string   `json:"start"`
+	End                 string   `json:"end"`
+	SessionsScheduled   int      `json:"sessions_scheduled"`
+	SessionsCompleted   int      `json:"sessions_completed"`
+	SessionRate         *float64 `json:"session_rate"`
+	DaysFoodLogged      int      `json:"days_food_logged"`
+	DaysOnCalorieTarget int      `json:"days_on_calorie_target"`
+	AverageScore        *float64 `json:"average_score"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// HealthImportSummary counts what an Apple Health import read and what it
+// stored, per journal type and per "cardio" or "strength" workout.
+// Duplicates were already stored; Invalid entries failed validation.
+type HealthImportSummary struct {
+	Records    int            `json:"records"`
+	Ignored    int            `json:"ignored"`
+	Imported   map[string]int `json:"imported"`
+	Duplicates map[string]int `json:"duplicates"`
+	Invalid    map[string]int `json:"invalid"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is work queued to run outside a request. Progress runs from 0 to 1
+// across its stages; Result is set once it has completed. A failed job has
+// used up its attempts, or could not succeed, and waits to be retried by
+// hand; Error is that of the last attempt.
+type Job struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Status      string          `json:"status"` // queued, running, completed or failed
+	Stage       string          `json:"stage,omitempty"`
+	Progress    float64         `json:"progress"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	StartedAt   *time.Time      `json:"started_at,omitempty"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+	// UserID is empty for system jobs such as webhook deliveries.
+	UserID  string          `json:"-"`
+	Payload json.RawMessage `json:"-"`
+}
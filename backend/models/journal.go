@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// JournalEntry is one logged item of a registered type; Data holds the
+// type's fields.
+type JournalEntry struct {
+	ID        string                 `json:"id"`
+	Date      string                 `json:"date"`
+	Type      string                 `json:"type"`
+	Data      map[string]interface{} `json:"data"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Reminder is a rule that notifies its user at local times on chosen
+// weekdays. Times are HH:MM in the user's timezone; no Days means every
+// day. Kinds other than "custom" check first whether the reminder is
+// still needed, such as a weigh-in on a day with a weight entry.
+type Reminder struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"` // weigh_in, workout, water or custom
+	Title     string     `json:"title"`
+	Message   string     `json:"message,omitempty"`
+	Times     []string   `json:"times"`
+	Days      []string   `json:"days"` // mon ... sun
+	Channels  []string   `json:"channels"`
+	Active    bool       `json:"active"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Notification is a message to a user, kept in their in-app inbox and
+// possibly sent by email or Web Push too.
+type Notification struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Title      string          `json:"title"`
+	Body       string          `json:"body,omitempty"`
+	URL        string          `json:"url,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	ReminderID string          `json:"reminder_id,omitempty"`
+	Read       bool            `json:"read"`
+	ReadAt     *time.Time      `json:"read_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UserID     string          `json:"-"`
+}
+
+// NotificationSettings hold a user's quiet hours, HH:MM local times
+// during which email and push are held back. Equal times mean none.
+type NotificationSettings struct {
+	QuietStart string `json:"quiet_start"`
+	QuietEnd   string `json:"quiet_end"`
+}
+
+// PushSubscription is a browser's Web Push endpoint and the keys that
+// encrypt messages to it, as given by PushManager.subscribe.
+type PushSubscription struct {
+	ID        string    `json:"id"`
+	Endpoint  string    `json:"endpoint"`
+	P256dh    string    `json:"-"`
+	Auth      string    `json:"-"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
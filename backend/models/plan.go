@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+type Plan struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Type      string `json:"type"`
+	Content   string `json:"content"` // JSON encoded PlanContent
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	Status    string `json:"status"`
+	Revision  int    `json:"revision"`
+}
+
+// PlanContent is the structured shape of plans.content. Workout plans fill
+// in exercises, diet plans fill in meals; a day may carry both.
+type PlanContent struct {
+	Days []PlanDay `json:"days"`
+}
+
+type PlanDay struct {
+	Day       int            `json:"day"`
+	Weekday   string         `json:"weekday,omitempty"`
+	Title     string         `json:"title,omitempty"`
+	Exercises []PlanExercise `json:"exercises,omitempty"`
+	Meals     []PlanMeal     `json:"meals,omitempty"`
+	// Totals are the day's planned nutrition, filled in for diet plans.
+	Totals *Macros `json:"totals,omitempty"`
+}
+
+type PlanExercise struct {
+	ExerciseID  string  `json:"exercise_id,omitempty"`
+	Name        string  `json:"name"`
+	Sets        int     `json:"sets"`
+	Reps        string  `json:"reps"`
+	Load        float64 `json:"load,omitempty"`
+	RestSeconds int     `json:"rest_seconds,omitempty"`
+}
+
+type PlanMeal struct {
+	Slot     string  `json:"slot"`
+	RecipeID string  `json:"recipe_id,omitempty"`
+	Name     string  `json:"name"`
+	Servings float64 `json:"servings,omitempty"`
+}
+
+// PlanRevision is an immutable snapshot of a plan's content.
+type PlanRevision struct {
+	ID        string    `json:"id"`
+	PlanID    string    `json:"plan_id"`
+	Revision  int       `json:"revision"`
+	Content   string    `json:"content,omitempty"`
+	Author    string    `json:"author"`
+	Source    string    `json:"source"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Macros are nutrition totals; for recipes they are per serving.
+type Macros struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+	Fiber    float64 `json:"fiber,omitempty"`
+}
+
+type Recipe struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Ingredients  []string `json:"ingredients"`
+	Instructions []string `json:"instructions"`
+	Macros       Macros   `json:"macros"`
+	Tags         []string `json:"tags"`
+	Servings     int      `json:"servings"`
+	// Nutrition is computed per serving from the ingredient list.
+	Nutrition *Nutrients `json:"nutrition,omitempty"`
+	// Allergens are the allergen groups found in the ingredient list.
+	Allergens []string `json:"allergens"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RecordEvent marks a set that set a new personal record.
+type RecordEvent struct {
+	ID           string `json:"id,omitempty"`
+	ExerciseID   string `json:"exercise_id"`
+	ExerciseName string `json:"exercise_name,omitempty"`
+	// Kind is 1rm, 3rm, 5rm, volume or e1rm.
+	Kind  string  `json:"kind"`
+	Value float64 `json:"value"`
+	// Previous is the record before this one, 0 for the first.
+	Previous   float64   `json:"previous"`
+	Reps       int       `json:"reps"`
+	Load       float64   `json:"load"`
+	SessionID  string    `json:"session_id"`
+	SetID      string    `json:"set_id"`
+	AchievedAt time.Time `json:"achieved_at"`
+	Formula    string    `json:"formula,omitempty"`
+}
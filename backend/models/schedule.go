@@ -0,0 +1,14 @@
+package models
+
+// ScheduledSession is a plan day placed on a concrete local date.
+type ScheduledSession struct {
+	ID              string `json:"id"`
+	PlanID          string `json:"plan_id"`
+	PlanDay         int    `json:"plan_day"`
+	Title           string `json:"title"`
+	OriginalDate    string `json:"original_date"`
+	Date            string `json:"date"`
+	StartTime       string `json:"start_time,omitempty"`
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+	Status          string `json:"status"`
+}
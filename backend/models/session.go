@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// LoggedSet is one set performed during a training session. Load is in
+// kilograms.
+type LoggedSet struct {
+	ID              string  `json:"id"`
+	ExerciseID      string  `json:"exercise_id"`
+	ExerciseName    string  `json:"exercise_name,omitempty"`
+	Position        int     `json:"position"`
+	Reps            int     `json:"reps,omitempty"`
+	Load            float64 `json:"load,omitempty"`
+	RPE             float64 `json:"rpe,omitempty"`
+	RestSeconds     int     `json:"rest_seconds,omitempty"`
+	Tempo           string  `json:"tempo,omitempty"`
+	DurationSeconds int     `json:"duration_seconds,omitempty"`
+	Warmup          bool    `json:"warmup,omitempty"`
+	Notes           string  `json:"notes,omitempty"`
+}
+
+// SessionTotals summarise the working sets of a session; warm-up sets only
+// count towards Sets.
+type SessionTotals struct {
+	Sets        int     `json:"sets"`
+	WorkingSets int     `json:"working_sets"`
+	Reps        int     `json:"reps"`
+	Volume      float64 `json:"volume"`
+	// DurationMinutes is nil until the session is finished.
+	DurationMinutes *float64 `json:"duration_minutes"`
+	// SetsPerMuscle counts a working set fully for its exercise's primary
+	// muscles and half for the secondary ones.
+	SetsPerMuscle map[string]float64 `json:"sets_per_muscle"`
+}
+
+type TrainingSession struct {
+	ID                 string         `json:"id"`
+	WorkoutID          string         `json:"workout_id,omitempty"`
+	ScheduledSessionID string         `json:"scheduled_session_id,omitempty"`
+	Name               string         `json:"name,omitempty"`
+	StartedAt          time.Time      `json:"started_at"`
+	FinishedAt         *time.Time     `json:"finished_at"`
+	Notes              string         `json:"notes,omitempty"`
+	Sets               []LoggedSet    `json:"sets"`
+	Totals             *SessionTotals `json:"totals,omitempty"`
+}
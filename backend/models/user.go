@@ -3,17 +3,24 @@ package models
 import "time"
 
 type User struct {
-	ID           string    `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	Name         string    `json:"name,omitempty"`
-	Age          int       `json:"age,omitempty"`
-	Gender       string    `json:"gender,omitempty"`
-	Height       float64   `json:"height,omitempty"`
-	Weight       float64   `json:"weight,omitempty"`
-	Activity     string    `json:"activity_level,omitempty"`
-	Country      string    `json:"country,omitempty"`
-	Goals        string    `json:"goals,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           string   `json:"id"`
+	Email        string   `json:"email"`
+	PasswordHash string   `json:"-"`
+	Name         string   `json:"name,omitempty"`
+	Age          int      `json:"age,omitempty"`
+	Gender       string   `json:"gender,omitempty"`
+	Height       float64  `json:"height,omitempty"`
+	Weight       float64  `json:"weight,omitempty"`
+	Activity     string   `json:"activity_level,omitempty"`
+	Country      string   `json:"country,omitempty"`
+	Goals        string   `json:"goals,omitempty"`
+	Timezone     string   `json:"timezone,omitempty"`
+	Diets        []string `json:"diets"`
+	Allergens    []string `json:"allergens"`
+	E1RMFormula  string   `json:"e1rm_formula,omitempty"`
+	// Measured heart rates in bpm; zero when unknown.
+	MaxHeartRate     int       `json:"max_heart_rate,omitempty"`
+	RestingHeartRate int       `json:"resting_heart_rate,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
 }
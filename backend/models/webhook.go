@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an HTTPS endpoint subscribed to events. The secret that signs
+// deliveries is only returned when it is set.
+type Webhook struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Events      []string  `json:"events"`
+	AllUsers    bool      `json:"all_users"`
+	Active      bool      `json:"active"`
+	Secret      string    `json:"secret,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent to one webhook. ResponseStatus and
+// Error are those of the last attempt; Log lists every attempt and is
+// only filled in for a single delivery.
+type WebhookDelivery struct {
+	ID             string           `json:"id"`
+	WebhookID      string           `json:"webhook_id"`
+	EventID        string           `json:"event_id"`
+	Event          string           `json:"event"`
+	Status         string           `json:"status"` // pending, succeeded or failed
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  *time.Time       `json:"next_attempt_at,omitempty"`
+	ResponseStatus int              `json:"response_status,omitempty"`
+	Error          string           `json:"error,omitempty"`
+	RedeliveryOf   string           `json:"redelivery_of,omitempty"`
+	Payload        json.RawMessage  `json:"payload,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at,omitempty"`
+	Log            []WebhookAttempt `json:"log,omitempty"`
+}
+
+// WebhookAttempt is one request made for a delivery. StatusCode is 0 when
+// no response arrived.
+type WebhookAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
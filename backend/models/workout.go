@@ -0,0 +1,41 @@
+package models
+
+// Exercise is an entry of the exercise library.
+type Exercise struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	PrimaryMuscles   []string `json:"primary_muscles"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        []string `json:"equipment"`
+	MovementPattern  string   `json:"movement_pattern,omitempty"`
+	// Unilateral exercises train one side at a time.
+	Unilateral   bool     `json:"unilateral"`
+	Instructions []string `json:"instructions"`
+}
+
+// SetPrescription is one planned set. Reps may be a range such as "8-12";
+// timed sets use DurationSeconds instead.
+type SetPrescription struct {
+	Reps            string  `json:"reps,omitempty"`
+	Weight          float64 `json:"weight,omitempty"`
+	RPE             float64 `json:"rpe,omitempty"`
+	RestSeconds     int     `json:"rest_seconds,omitempty"`
+	DurationSeconds int     `json:"duration_seconds,omitempty"`
+	Warmup          bool    `json:"warmup,omitempty"`
+}
+
+// WorkoutExercise is an exercise of a workout with its sets, in order.
+type WorkoutExercise struct {
+	ExerciseID string            `json:"exercise_id"`
+	Exercise   *Exercise         `json:"exercise,omitempty"`
+	Notes      string            `json:"notes,omitempty"`
+	Sets       []SetPrescription `json:"sets"`
+}
+
+type Workout struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Difficulty  string            `json:"difficulty,omitempty"`
+	Exercises   []WorkoutExercise `json:"exercises"`
+}
@@ -0,0 +1,89 @@
+// Package netguard keeps outgoing requests to user-supplied URLs, such as
+// webhooks and push endpoints, off the server's own network.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlocked is returned for addresses requests may not go to.
+var ErrBlocked = errors.New("netguard: address is not public")
+
+// Ranges that IP's own methods don't cover: "this network" and carrier-grade
+// NAT.
+var blockedNets = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+}
+
+func mustCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Blocked reports whether ip is loopback, private, link-local, multicast
+// or unspecified.
+func Blocked(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, n := range blockedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHost rejects a URL host that is visibly not public: localhost or a
+// blocked IP literal. Names are only resolved when dialling, by Control.
+func CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrBlocked
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil && Blocked(ip) {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// Control is a net.Dialer Control function that refuses to connect to
+// blocked addresses. It runs after name resolution, for every connection,
+// so it also covers redirects and names that resolve differently later.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || Blocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlocked, address)
+	}
+	return nil
+}
+
+// Client returns an HTTP client that only connects to public addresses.
+func Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: Control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No Proxy: it would be the one dialled, and checked.
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
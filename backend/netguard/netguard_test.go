@@ -0,0 +1,39 @@
+package netguard
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlocked(t *testing.T) {
+	for _, ip := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "::", "100.64.0.1", "fc00::1", "fe80::1", "224.0.0.1", "::ffff:127.0.0.1",
+	} {
+		assert.True(t, Blocked(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700::1111"} {
+		assert.False(t, Blocked(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	assert.NoError(t, CheckHost("hooks.example.com"))
+	assert.NoError(t, CheckHost("8.8.8.8"))
+	for _, host := range []string{"localhost", "LOCALHOST.", "api.localhost", "127.0.0.1", "[::1]", "169.254.169.254"} {
+		assert.ErrorIs(t, CheckHost(host), ErrBlocked, host)
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Client(time.Second).Get(server.URL)
+	assert.ErrorIs(t, err, ErrBlocked)
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/mailer"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/webpush"
+)
+
+// emailChannel mails the notification to the user's address.
+type emailChannel struct{}
+
+func (emailChannel) Deliver(ctx context.Context, n models.Notification) error {
+	var to string
+	if err := db.DB.QueryRowContext(ctx, `SELECT email FROM users WHERE id = ?`, n.UserID).Scan(&to); err != nil {
+		return err
+	}
+	body := n.Body
+	if n.URL != "" {
+		body = strings.TrimSpace(body + "\n\n" + n.URL)
+	}
+	err := mailer.Send(ctx, mailer.Message{To: to, Subject: n.Title, Body: body})
+	if errors.Is(err, mailer.ErrNotConfigured) {
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// pushChannel sends the notification to each of the user's browsers.
+// Subscriptions the push service reports gone are deleted.
+type pushChannel struct{}
+
+// pushMessage is the payload a service worker gets in its push event.
+type pushMessage struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Title string `json:"title"`
+	Body  string `json:"body,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+func (pushChannel) Deliver(ctx context.Context, n models.Notification) error {
+	keys, err := webpush.KeysFromEnv()
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	rows, err := db.DB.QueryContext(ctx, `SELECT id, endpoint, p256dh, auth FROM push_subscriptions WHERE user_id = ?`, n.UserID)
+	if err != nil {
+		return err
+	}
+	type subscription struct {
+		id  string
+		sub webpush.Subscription
+	}
+	var subs []subscription
+	for rows.Next() {
+		var s subscription
+		if err := rows.Scan(&s.id, &s.sub.Endpoint, &s.sub.P256dh, &s.sub.Auth); err != nil {
+			rows.Close()
+			return err
+		}
+		subs = append(subs, s)
+	}
+	rows.Close()
+
+	payload, err := json.Marshal(pushMessage{ID: n.ID, Kind: n.Kind, Title: n.Title, Body: n.Body, URL: n.URL})
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	// A retry sends to every browser again; the topic lets push services
+	// replace a copy that has not been delivered yet.
+	opts := webpush.Options{TTL: 12 * time.Hour, Topic: strings.ReplaceAll(n.ID, "-", "")}
+	var failed error
+	for _, s := range subs {
+		_, err := webpush.Send(ctx, s.sub, payload, keys, opts)
+		switch {
+		case errors.Is(err, webpush.ErrGone):
+			db.DB.Exec(`DELETE FROM push_subscriptions WHERE id = ?`, s.id)
+		case err != nil && failed == nil:
+			failed = err
+		}
+	}
+	return failed
+}
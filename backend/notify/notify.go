@@ -0,0 +1,207 @@
+// Package notify delivers notifications to users. Every notification is
+// kept in the user's in-app inbox, where it can be marked read; channels
+// registered with Register, such as email and Web Push, send copies
+// through the job queue. During the user's quiet hours those copies are
+// held back until the quiet hours end.
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Built-in channels.
+const (
+	Email = "email"
+	Push  = "push"
+)
+
+// Channel sends a copy of a notification to its user. Errors are retried
+// by the job queue unless marked with jobs.Permanent.
+type Channel interface {
+	Deliver(ctx context.Context, n models.Notification) error
+}
+
+var channels = map[string]Channel{}
+
+// Register adds a channel that Send can deliver through. Adding a name
+// that is already taken panics.
+func Register(name string, c Channel) {
+	if _, ok := channels[name]; ok {
+		panic("notify: duplicate channel " + name)
+	}
+	channels[name] = c
+}
+
+// Channels lists the registered channels by name.
+func Channels() []string {
+	names := make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Known reports whether a channel is registered.
+func Known(name string) bool {
+	_, ok := channels[name]
+	return ok
+}
+
+// deliverJob is the kind of job that sends a notification over a channel.
+const deliverJob = "notification.deliver"
+
+type deliverPayload struct {
+	NotificationID string `json:"notification_id"`
+	Channel        string `json:"channel"`
+}
+
+func init() {
+	Register(Email, emailChannel{})
+	Register(Push, pushChannel{})
+	jobs.Register(deliverJob, jobs.Handle(deliver), jobs.Options{Timeout: time.Minute})
+}
+
+func deliver(ctx context.Context, _ models.Job, p deliverPayload) (interface{}, error) {
+	c, ok := channels[p.Channel]
+	if !ok {
+		return nil, jobs.Permanent(fmt.Errorf("unknown channel %q", p.Channel))
+	}
+	n, err := Get(p.NotificationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Deleted from the inbox before it went out.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, c.Deliver(ctx, n)
+}
+
+// Send puts n in the user's inbox and queues a copy over each of the
+// channels in via, and returns the stored notification.
+func Send(userID string, n models.Notification, via []string) (models.Notification, error) {
+	for _, name := range via {
+		if !Known(name) {
+			return n, fmt.Errorf("notify: unknown channel %q", name)
+		}
+	}
+	now := clock.Now()
+	n.ID = uuid.New().String()
+	n.UserID = userID
+	n.CreatedAt = now
+	at := now
+	if until, quiet := QuietUntil(Settings(userID), utils.UserLocation(userID), now); quiet {
+		at = until
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return n, err
+	}
+	defer tx.Rollback()
+	var data interface{}
+	if len(n.Data) > 0 {
+		data = string(n.Data)
+	}
+	_, err = tx.Exec(`INSERT INTO notifications (id, user_id, kind, title, body, url, data, reminder_id, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		n.ID, userID, n.Kind, n.Title, utils.NullIfEmpty(n.Body), utils.NullIfEmpty(n.URL), data, utils.NullIfEmpty(n.ReminderID), now)
+	if err != nil {
+		return n, err
+	}
+	sent := map[string]bool{}
+	for _, name := range via {
+		if sent[name] {
+			continue
+		}
+		sent[name] = true
+		if _, err := jobs.EnqueueTx(tx, userID, deliverJob, deliverPayload{NotificationID: n.ID, Channel: name}, at); err != nil {
+			return n, err
+		}
+	}
+	return n, tx.Commit()
+}
+
+// Columns are the notification columns Scan reads, from notifications n.
+const Columns = `n.id, n.user_id, n.kind, n.title, COALESCE(n.body, ''), COALESCE(n.url, ''), n.data, COALESCE(n.reminder_id, ''), n.read_at, n.created_at`
+
+// Scan reads a notification selected with Columns.
+func Scan(row interface{ Scan(...interface{}) error }) (models.Notification, error) {
+	var n models.Notification
+	var data *string
+	err := row.Scan(&n.ID, &n.UserID, &n.Kind, &n.Title, &n.Body, &n.URL, &data, &n.ReminderID, &n.ReadAt, &n.CreatedAt)
+	if data != nil {
+		n.Data = json.RawMessage(*data)
+	}
+	n.Read = n.ReadAt != nil
+	return n, err
+}
+
+// Get loads a notification.
+func Get(id string) (models.Notification, error) {
+	return Scan(db.DB.QueryRow(`SELECT `+Columns+` FROM notifications n WHERE n.id = ?`, id))
+}
+
+// Settings returns the user's notification settings; without any there
+// are no quiet hours.
+func Settings(userID string) models.NotificationSettings {
+	var s models.NotificationSettings
+	db.DB.QueryRow(`SELECT COALESCE(quiet_start, ''), COALESCE(quiet_end, '') FROM notification_settings WHERE user_id = ?`, userID).
+		Scan(&s.QuietStart, &s.QuietEnd)
+	return s
+}
+
+// ValidateSettings checks that quiet hours are both set, as HH:MM, or
+// both empty.
+func ValidateSettings(s models.NotificationSettings) error {
+	if s.QuietStart == "" && s.QuietEnd == "" {
+		return nil
+	}
+	for _, v := range []string{s.QuietStart, s.QuietEnd} {
+		if _, err := time.Parse("15:04", v); err != nil {
+			return errors.New("quiet_start and quiet_end must both be times of day as HH:MM")
+		}
+	}
+	return nil
+}
+
+// QuietUntil reports whether now falls in the quiet hours, read in loc,
+// and if so when they end. Quiet hours may run over midnight, as from
+// 22:00 to 07:00.
+func QuietUntil(s models.NotificationSettings, loc *time.Location, now time.Time) (time.Time, bool) {
+	start, err1 := time.Parse("15:04", s.QuietStart)
+	end, err2 := time.Parse("15:04", s.QuietEnd)
+	if err1 != nil || err2 != nil || start.Equal(end) {
+		return time.Time{}, false
+	}
+	local := now.In(loc)
+	at := func(day time.Time, t time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	}
+	// The quiet hours that began most recently, today or yesterday.
+	from := at(local, start)
+	if from.After(local) {
+		from = at(local.AddDate(0, 0, -1), start)
+	}
+	until := at(from, end)
+	if !until.After(from) {
+		until = at(from.AddDate(0, 0, 1), end)
+	}
+	if local.Before(until) {
+		return until, true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/mailer"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+type fakeMailer struct{ sent []mailer.Message }
+
+func (f *fakeMailer) Send(ctx context.Context, m mailer.Message) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func TestQuietUntil(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	night := models.NotificationSettings{QuietStart: "22:00", QuietEnd: "07:00"}
+	at := func(h, m int) time.Time { return time.Date(2025, 1, 10, h, m, 0, 0, berlin) }
+
+	for _, c := range []struct {
+		s     models.NotificationSettings
+		now   time.Time
+		until time.Time
+	}{
+		{night, at(23, 30), time.Date(2025, 1, 11, 7, 0, 0, 0, berlin)},
+		{night, at(6, 59), at(7, 0)},
+		{night, at(22, 0), time.Date(2025, 1, 11, 7, 0, 0, 0, berlin)},
+		{night, at(7, 0), time.Time{}},
+		{night, at(12, 0), time.Time{}},
+		{models.NotificationSettings{QuietStart: "13:00", QuietEnd: "14:00"}, at(13, 15), at(14, 0)},
+		{models.NotificationSettings{QuietStart: "13:00", QuietEnd: "14:00"}, at(14, 15), time.Time{}},
+		{models.NotificationSettings{}, at(23, 30), time.Time{}},
+	} {
+		until, quiet := QuietUntil(c.s, berlin, c.now.UTC())
+		assert.Equal(t, !c.until.IsZero(), quiet, "%v at %v", c.s, c.now)
+		assert.True(t, until.Equal(c.until), "%v at %v: %v", c.s, c.now, until)
+	}
+
+	assert.NoError(t, ValidateSettings(night))
+	assert.NoError(t, ValidateSettings(models.NotificationSettings{}))
+	assert.Error(t, ValidateSettings(models.NotificationSettings{QuietStart: "22:00"}))
+	assert.Error(t, ValidateSettings(models.NotificationSettings{QuietStart: "22:00", QuietEnd: "7am"}))
+}
+
+func TestSend(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	mail := &fakeMailer{}
+	old := mailer.Default
+	mailer.Default = mail
+	defer func() { mailer.Default = old }()
+
+	// 23:00 in Berlin, inside quiet hours until 07:00.
+	now := clock.NewFake(time.Date(2025, 1, 10, 22, 0, 0, 0, time.UTC))
+	defer now.Install()()
+	db.DB.Exec(`INSERT INTO users (id, email, timezone) VALUES ('user-1', 'user-1@example.com', 'Europe/Berlin')`)
+	db.DB.Exec(`INSERT INTO notification_settings (user_id, quiet_start, quiet_end) VALUES ('user-1', '22:00', '07:00')`)
+
+	_, err := Send("user-1", models.Notification{Kind: "custom", Title: "Hi"}, []string{"pigeon"})
+	assert.ErrorContains(t, err, "unknown channel")
+
+	n, err := Send("user-1", models.Notification{Kind: "custom", Title: "Log your weight", Body: "Step on the scale.", URL: "https://example.com/journal"},
+		[]string{Email, Email})
+	require.NoError(t, err)
+	stored, err := Get(n.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Log your weight", stored.Title)
+	assert.False(t, stored.Read)
+
+	// The email waits for the end of quiet hours; the inbox copy does not.
+	list, err := jobs.List(jobs.Filter{UserID: "user-1", Kind: deliverJob})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, time.Date(2025, 1, 11, 6, 0, 0, 0, time.UTC), list[0].RunAt.UTC())
+	jobs.RunDue(ctx)
+	assert.Empty(t, mail.sent)
+
+	now.Set(time.Date(2025, 1, 11, 6, 0, 0, 0, time.UTC))
+	jobs.RunDue(ctx)
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, mailer.Message{To: "user-1@example.com", Subject: "Log your weight", Body: "Step on the scale.\n\nhttps://example.com/journal"}, mail.sent[0])
+
+	// Outside quiet hours copies go straight away, and a notification
+	// deleted before its copy is sent is skipped.
+	n, err = Send("user-1", models.Notification{Kind: "custom", Title: "Gone"}, []string{Email})
+	require.NoError(t, err)
+	db.DB.Exec(`DELETE FROM notifications WHERE id = ?`, n.ID)
+	jobs.RunDue(ctx)
+	assert.Len(t, mail.sent, 1)
+}
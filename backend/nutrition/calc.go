@@ -0,0 +1,166 @@
+package nutrition
+
+import (
+	"math"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// defaultDensity (g/ml) is used for volume measures of foods without a
+// volume portion; it is the density of water.
+const defaultDensity = 1.0
+
+// Grams converts a parsed ingredient to grams of the given food. It reports
+// false when the amount cannot be expressed in grams, e.g. "2 slices" of a
+// food without a slice portion.
+func Grams(ing Ingredient, portions []models.FoodPortion) (float64, bool) {
+	if ing.Quantity == 0 {
+		return 0, true
+	}
+
+	switch UnitKind(ing.Unit) {
+	case Mass:
+		g, _ := Convert(ing.Quantity, ing.Unit, "g")
+		return g, true
+	case Volume:
+		ml, _ := Convert(ing.Quantity, ing.Unit, "ml")
+		return ml * density(portions), true
+	}
+
+	if p, ok := findPortion(portions, ing.Unit); ok {
+		return ing.Quantity * p.Grams, true
+	}
+	return 0, false
+}
+
+// density derives g/ml from the first volume portion of a food.
+func density(portions []models.FoodPortion) float64 {
+	for _, p := range portions {
+		if ml, ok := Convert(1, p.Unit, "ml"); ok && ml > 0 && p.Grams > 0 {
+			return p.Grams / ml
+		}
+	}
+	return defaultDensity
+}
+
+// findPortion matches a count unit against a food's portions. A missing
+// unit ("2 eggs") takes the food's typical single item.
+func findPortion(portions []models.FoodPortion, unit string) (models.FoodPortion, bool) {
+	candidates := []string{unit}
+	if unit == "" || unit == "whole" || unit == "piece" {
+		candidates = []string{unit, "medium", "whole", "piece", "each", "large", "small"}
+	}
+	for _, c := range candidates {
+		for _, p := range portions {
+			if p.Unit == c && c != "" {
+				return p, true
+			}
+		}
+	}
+	if unit == "" {
+		for _, p := range portions {
+			if UnitKind(p.Unit) == Count && p.Grams > 0 {
+				return p, true
+			}
+		}
+	}
+	return models.FoodPortion{}, false
+}
+
+// Scale returns n multiplied by factor.
+func Scale(n models.Nutrients, factor float64) models.Nutrients {
+	return models.Nutrients{
+		Calories:    n.Calories * factor,
+		Protein:     n.Protein * factor,
+		Carbs:       n.Carbs * factor,
+		Fat:         n.Fat * factor,
+		Fiber:       n.Fiber * factor,
+		Sugar:       n.Sugar * factor,
+		SodiumMg:    n.SodiumMg * factor,
+		PotassiumMg: n.PotassiumMg * factor,
+		CalciumMg:   n.CalciumMg * factor,
+		IronMg:      n.IronMg * factor,
+		VitaminCMg:  n.VitaminCMg * factor,
+	}
+}
+
+// Add returns the sum of a and b.
+func Add(a, b models.Nutrients) models.Nutrients {
+	return models.Nutrients{
+		Calories:    a.Calories + b.Calories,
+		Protein:     a.Protein + b.Protein,
+		Carbs:       a.Carbs + b.Carbs,
+		Fat:         a.Fat + b.Fat,
+		Fiber:       a.Fiber + b.Fiber,
+		Sugar:       a.Sugar + b.Sugar,
+		SodiumMg:    a.SodiumMg + b.SodiumMg,
+		PotassiumMg: a.PotassiumMg + b.PotassiumMg,
+		CalciumMg:   a.CalciumMg + b.CalciumMg,
+		IronMg:      a.IronMg + b.IronMg,
+		VitaminCMg:  a.VitaminCMg + b.VitaminCMg,
+	}
+}
+
+// Round rounds every nutrient to one decimal place.
+func Round(n models.Nutrients) models.Nutrients {
+	r := func(f float64) float64 { return math.Round(f*10) / 10 }
+	return models.Nutrients{
+		Calories:    math.Round(n.Calories),
+		Protein:     r(n.Protein),
+		Carbs:       r(n.Carbs),
+		Fat:         r(n.Fat),
+		Fiber:       r(n.Fiber),
+		Sugar:       r(n.Sugar),
+		SodiumMg:    math.Round(n.SodiumMg),
+		PotassiumMg: math.Round(n.PotassiumMg),
+		CalciumMg:   math.Round(n.CalciumMg),
+		IronMg:      r(n.IronMg),
+		VitaminCMg:  r(n.VitaminCMg),
+	}
+}
+
+// FoodLookup resolves an ingredient name to a food, returning nil when
+// nothing matches.
+type FoodLookup func(name string) (*models.Food, error)
+
+// ComputeRecipe parses every ingredient line, resolves it to a food and
+// returns the nutrition per serving together with the per-line breakdown.
+// Unresolved lines contribute nothing and are marked so callers can report
+// them.
+func ComputeRecipe(lines []string, servings int, lookup FoodLookup) (models.Nutrients, []models.RecipeIngredient, error) {
+	if servings <= 0 {
+		servings = 1
+	}
+
+	var total models.Nutrients
+	parsed := make([]models.RecipeIngredient, 0, len(lines))
+	for i, line := range lines {
+		ing := ParseIngredient(line)
+		ri := models.RecipeIngredient{
+			Position: i,
+			Raw:      ing.Raw,
+			Quantity: ing.Quantity,
+			Unit:     ing.Unit,
+			Name:     ing.Name,
+		}
+
+		if ing.Name != "" {
+			food, err := lookup(ing.Name)
+			if err != nil {
+				return total, nil, err
+			}
+			if food != nil {
+				ri.FoodID = food.ID
+				ri.FoodName = food.Name
+				if g, ok := Grams(ing, food.Portions); ok {
+					ri.Grams = math.Round(g*10) / 10
+					ri.Resolved = true
+					total = Add(total, Scale(food.Per100g, g/100))
+				}
+			}
+		}
+		parsed = append(parsed, ri)
+	}
+
+	return Round(Scale(total, 1/float64(servings))), parsed, nil
+}
@@ -0,0 +1,319 @@
+package nutrition
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// FoodData Central nutrient numbers for the values we keep. The Atwater
+// energy numbers are fallbacks for Foundation foods that omit 208.
+const (
+	fdcEnergy         = "208"
+	fdcEnergyAtwaterG = "957"
+	fdcEnergyAtwaterS = "958"
+)
+
+func setFDCNutrient(n *models.Nutrients, number string, amount float64) {
+	switch number {
+	case fdcEnergy:
+		n.Calories = amount
+	case fdcEnergyAtwaterG, fdcEnergyAtwaterS:
+		if n.Calories == 0 {
+			n.Calories = amount
+		}
+	case "203":
+		n.Protein = amount
+	case "204":
+		n.Fat = amount
+	case "205":
+		n.Carbs = amount
+	case "291":
+		n.Fiber = amount
+	case "269":
+		n.Sugar = amount
+	case "307":
+		n.SodiumMg = amount
+	case "306":
+		n.PotassiumMg = amount
+	case "301":
+		n.CalciumMg = amount
+	case "303":
+		n.IronMg = amount
+	case "401":
+		n.VitaminCMg = amount
+	}
+}
+
+// fdcPortion turns an FDC portion into grams per household unit. Unit names
+// come from the measure unit when it is known and otherwise from the
+// modifier or description ("large", "1 cup, chopped").
+func fdcPortion(amount, gramWeight float64, unitName, modifier, description string) (models.FoodPortion, bool) {
+	if gramWeight <= 0 {
+		return models.FoodPortion{}, false
+	}
+	if amount <= 0 {
+		amount = 1
+	}
+
+	unit := ""
+	if name, _, ok := NormalizeUnit(unitName); ok {
+		unit = name
+	} else if fields := strings.Fields(strings.Trim(modifier, " ,")); len(fields) > 0 {
+		if name, _, ok := NormalizeUnit(strings.Trim(fields[0], ",")); ok {
+			unit = name
+		}
+	}
+	if unit == "" && description != "" {
+		ing := ParseIngredient(description)
+		if ing.Unit != "" {
+			unit = ing.Unit
+			if ing.Quantity > 0 {
+				amount = ing.Quantity
+			}
+		}
+	}
+	if unit == "" {
+		return models.FoodPortion{}, false
+	}
+	return models.FoodPortion{Unit: unit, Grams: gramWeight / amount}, true
+}
+
+func addPortion(f *models.Food, p models.FoodPortion) {
+	for _, existing := range f.Portions {
+		if existing.Unit == p.Unit {
+			return
+		}
+	}
+	f.Portions = append(f.Portions, p)
+}
+
+type fdcJSONFood struct {
+	FdcID        int    `json:"fdcId"`
+	Description  string `json:"description"`
+	FoodCategory struct {
+		Description string `json:"description"`
+	} `json:"foodCategory"`
+	WweiaFoodCategory struct {
+		Description string `json:"wweiaFoodCategoryDescription"`
+	} `json:"wweiaFoodCategory"`
+	FoodNutrients []struct {
+		Nutrient struct {
+			Number   string `json:"number"`
+			UnitName string `json:"unitName"`
+		} `json:"nutrient"`
+		Amount float64 `json:"amount"`
+	} `json:"foodNutrients"`
+	FoodPortions []struct {
+		Amount             float64 `json:"amount"`
+		GramWeight         float64 `json:"gramWeight"`
+		Modifier           string  `json:"modifier"`
+		PortionDescription string  `json:"portionDescription"`
+		MeasureUnit        struct {
+			Name string `json:"name"`
+		} `json:"measureUnit"`
+	} `json:"foodPortions"`
+}
+
+// ReadFDCJSON streams an FDC JSON download (Foundation, SR Legacy, Survey or
+// Branded) and calls fn for each food. The file is either a bare array or
+// an object wrapping the array, as the official downloads are, and is never
+// held in memory as a whole.
+func ReadFDCJSON(r io.Reader, fn func(models.Food) error) (int, error) {
+	dec := json.NewDecoder(r)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, err
+	}
+	if tok == json.Delim('{') {
+		if _, err := dec.Token(); err != nil { // the wrapper key
+			return 0, err
+		}
+		if tok, err = dec.Token(); err != nil {
+			return 0, err
+		}
+	}
+	if tok != json.Delim('[') {
+		return 0, fmt.Errorf("expected an array of foods")
+	}
+
+	count := 0
+	for dec.More() {
+		var raw fdcJSONFood
+		if err := dec.Decode(&raw); err != nil {
+			return count, fmt.Errorf("food %d: %w", count+1, err)
+		}
+
+		f := models.Food{
+			ID:       fdcFoodID(raw.FdcID),
+			FdcID:    raw.FdcID,
+			Name:     raw.Description,
+			Category: raw.FoodCategory.Description,
+		}
+		if f.Category == "" {
+			f.Category = raw.WweiaFoodCategory.Description
+		}
+		for _, fn := range raw.FoodNutrients {
+			if fn.Nutrient.UnitName == "kJ" {
+				continue
+			}
+			setFDCNutrient(&f.Per100g, fn.Nutrient.Number, fn.Amount)
+		}
+		for _, p := range raw.FoodPortions {
+			if portion, ok := fdcPortion(p.Amount, p.GramWeight, p.MeasureUnit.Name, p.Modifier, p.PortionDescription); ok {
+				addPortion(&f, portion)
+			}
+		}
+
+		if err := fn(f); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func fdcFoodID(fdcID int) string {
+	return "fdc:" + strconv.Itoa(fdcID)
+}
+
+// csvTable reads a CSV file with a header row and calls fn with a column
+// accessor for every record.
+func csvTable(path string, fn func(col func(string) string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.TrimPrefix(h, "\ufeff")] = i
+	}
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		col := func(name string) string {
+			if i, ok := index[name]; ok && i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		if err := fn(col); err != nil {
+			return err
+		}
+	}
+}
+
+// ReadFDCCSV reads an unpacked FDC CSV download directory (food.csv,
+// nutrient.csv, food_nutrient.csv and optionally food_portion.csv,
+// measure_unit.csv and food_category.csv) and calls fn for each food.
+// food_nutrient.csv, by far the largest file, is streamed row by row.
+func ReadFDCCSV(dir string, fn func(models.Food) error) (int, error) {
+	categories := map[string]string{}
+	if err := csvTable(filepath.Join(dir, "food_category.csv"), func(col func(string) string) error {
+		categories[col("id")] = col("description")
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	foods := map[int]*models.Food{}
+	var order []int
+	err := csvTable(filepath.Join(dir, "food.csv"), func(col func(string) string) error {
+		id, err := strconv.Atoi(col("fdc_id"))
+		if err != nil {
+			return nil
+		}
+		foods[id] = &models.Food{ID: fdcFoodID(id), FdcID: id, Name: col("description"), Category: categories[col("food_category_id")]}
+		order = append(order, id)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	nutrientNumbers := map[string]string{}
+	err = csvTable(filepath.Join(dir, "nutrient.csv"), func(col func(string) string) error {
+		if col("unit_name") != "kJ" {
+			nutrientNumbers[col("id")] = col("nutrient_nbr")
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = csvTable(filepath.Join(dir, "food_nutrient.csv"), func(col func(string) string) error {
+		id, _ := strconv.Atoi(col("fdc_id"))
+		f, ok := foods[id]
+		if !ok {
+			return nil
+		}
+		number, ok := nutrientNumbers[col("nutrient_id")]
+		if !ok {
+			return nil
+		}
+		amount, err := strconv.ParseFloat(col("amount"), 64)
+		if err != nil {
+			return nil
+		}
+		setFDCNutrient(&f.Per100g, number, amount)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	measureUnits := map[string]string{}
+	if err := csvTable(filepath.Join(dir, "measure_unit.csv"), func(col func(string) string) error {
+		measureUnits[col("id")] = col("name")
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	if err := csvTable(filepath.Join(dir, "food_portion.csv"), func(col func(string) string) error {
+		id, _ := strconv.Atoi(col("fdc_id"))
+		f, ok := foods[id]
+		if !ok {
+			return nil
+		}
+		amount, _ := strconv.ParseFloat(col("amount"), 64)
+		grams, _ := strconv.ParseFloat(col("gram_weight"), 64)
+		if p, ok := fdcPortion(amount, grams, measureUnits[col("measure_unit_id")], col("modifier"), col("portion_description")); ok {
+			addPortion(f, p)
+		}
+		return nil
+	}); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	for i, id := range order {
+		if err := fn(*foods[id]); err != nil {
+			return i, err
+		}
+	}
+	return len(order), nil
+}
@@ -0,0 +1,73 @@
+package nutrition
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestReadFDCJSON(t *testing.T) {
+	dump := `{"FoundationFoods": [{
+		"fdcId": 748967,
+		"description": "Eggs, Grade A, Large, egg whole",
+		"foodCategory": {"description": "Dairy and Egg Products"},
+		"foodNutrients": [
+			{"nutrient": {"number": "203", "unitName": "g"}, "amount": 12.4},
+			{"nutrient": {"number": "268", "unitName": "kJ"}, "amount": 598},
+			{"nutrient": {"number": "957", "unitName": "kcal"}, "amount": 148},
+			{"nutrient": {"number": "301", "unitName": "mg"}, "amount": 48}
+		],
+		"foodPortions": [
+			{"amount": 1, "gramWeight": 50, "modifier": "large", "measureUnit": {"name": "undetermined"}},
+			{"amount": 2, "gramWeight": 486, "measureUnit": {"name": "cup"}}
+		]
+	}]}`
+
+	var foods []models.Food
+	n, err := ReadFDCJSON(strings.NewReader(dump), func(f models.Food) error {
+		foods = append(foods, f)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "fdc:748967", foods[0].ID)
+	assert.Equal(t, "Dairy and Egg Products", foods[0].Category)
+	assert.Equal(t, 148.0, foods[0].Per100g.Calories)
+	assert.Equal(t, 12.4, foods[0].Per100g.Protein)
+	assert.Equal(t, 48.0, foods[0].Per100g.CalciumMg)
+	assert.Equal(t, []models.FoodPortion{{Unit: "large", Grams: 50}, {Unit: "cup", Grams: 243}}, foods[0].Portions)
+}
+
+func TestReadFDCCSV(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"food.csv":          "\"fdc_id\",\"data_type\",\"description\",\"food_category_id\"\n\"1\",\"sr_legacy_food\",\"Rice, white, cooked\",\"20\"\n",
+		"food_category.csv": "id,code,description\n20,2000,Cereal Grains and Pasta\n",
+		"nutrient.csv":      "id,name,unit_name,nutrient_nbr\n1008,Energy,KCAL,208\n1062,Energy,kJ,268\n1003,Protein,G,203\n",
+		"food_nutrient.csv": "id,fdc_id,nutrient_id,amount\n10,1,1008,130\n11,1,1062,544\n12,1,1003,2.7\n",
+		"measure_unit.csv":  "id,name\n1000,cup\n",
+		"food_portion.csv":  "id,fdc_id,seq_num,amount,measure_unit_id,portion_description,modifier,gram_weight\n5,1,1,1,1000,,,158\n",
+	}
+	for name, body := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644))
+	}
+
+	var foods []models.Food
+	n, err := ReadFDCCSV(dir, func(f models.Food) error {
+		foods = append(foods, f)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "Rice, white, cooked", foods[0].Name)
+	assert.Equal(t, "Cereal Grains and Pasta", foods[0].Category)
+	assert.Equal(t, 130.0, foods[0].Per100g.Calories)
+	assert.Equal(t, 2.7, foods[0].Per100g.Protein)
+	assert.Equal(t, []models.FoodPortion{{Unit: "cup", Grams: 158}}, foods[0].Portions)
+}
@@ -0,0 +1,140 @@
+package nutrition
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Ingredient is an ingredient line split into its parts. Quantity is zero
+// for lines like "salt to taste".
+type Ingredient struct {
+	Raw      string  `json:"raw"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit,omitempty"`
+	Name     string  `json:"name"`
+	Note     string  `json:"note,omitempty"`
+}
+
+var vulgarFractions = map[rune]string{
+	'¼': "1/4", '½': "1/2", '¾': "3/4", '⅓': "1/3", '⅔': "2/3",
+	'⅛': "1/8", '⅜': "3/8", '⅝': "5/8", '⅞': "7/8", '⅕': "1/5",
+}
+
+var (
+	gluedUnit   = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)([a-zA-Z]+\.?)$`)
+	parenthetic = regexp.MustCompile(`\([^)]*\)`)
+	rangeToken  = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)[-–](\d+(?:[.,]\d+)?)$`)
+)
+
+// ParseIngredient splits a free-text ingredient line such as
+// "1 1/2 cups rolled oats, toasted" into quantity, unit, name and note.
+// Ranges ("2-3 cloves") use their midpoint.
+func ParseIngredient(line string) Ingredient {
+	ing := Ingredient{Raw: strings.TrimSpace(line)}
+
+	s := strings.TrimLeft(ing.Raw, "-*•· ")
+	var b strings.Builder
+	for _, r := range s {
+		if frac, ok := vulgarFractions[r]; ok {
+			b.WriteString(" " + frac + " ")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	s = parenthetic.ReplaceAllString(b.String(), " ")
+
+	var tokens []string
+	for _, tok := range strings.Fields(s) {
+		if m := gluedUnit.FindStringSubmatch(tok); m != nil {
+			if _, _, ok := NormalizeUnit(m[2]); ok {
+				tokens = append(tokens, m[1], m[2])
+				continue
+			}
+		}
+		tokens = append(tokens, tok)
+	}
+
+	qty, n := parseQuantity(tokens)
+	ing.Quantity = qty
+	tokens = tokens[n:]
+
+	if len(tokens) > 1 {
+		if name, _, ok := NormalizeUnit(tokens[0] + " " + tokens[1]); ok {
+			ing.Unit = name
+			tokens = tokens[2:]
+		}
+	}
+	if ing.Unit == "" && len(tokens) > 0 && n > 0 {
+		if name, _, ok := NormalizeUnit(tokens[0]); ok {
+			ing.Unit = name
+			tokens = tokens[1:]
+		}
+	}
+	if len(tokens) > 0 && strings.EqualFold(tokens[0], "of") {
+		tokens = tokens[1:]
+	}
+
+	rest := strings.Join(tokens, " ")
+	if i := strings.Index(rest, ","); i >= 0 {
+		ing.Note = strings.TrimSpace(rest[i+1:])
+		rest = rest[:i]
+	}
+	for _, suffix := range []string{" to taste", " as needed", " for garnish"} {
+		if strings.HasSuffix(strings.ToLower(rest), suffix) {
+			ing.Note = strings.TrimSpace(strings.TrimPrefix(suffix, " ") + " " + ing.Note)
+			rest = rest[:len(rest)-len(suffix)]
+		}
+	}
+	ing.Name = strings.TrimSpace(rest)
+
+	// A line without an amount, like "egg yolk", means one of the item.
+	if n == 0 && ing.Quantity == 0 && ing.Note == "" {
+		ing.Quantity = 1
+	}
+	return ing
+}
+
+// parseQuantity reads a leading amount (integer, decimal, fraction, mixed
+// number or range) and returns it with the number of tokens consumed.
+func parseQuantity(tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 0, 0
+	}
+	if m := rangeToken.FindStringSubmatch(tokens[0]); m != nil {
+		a, _ := parseNumber(m[1])
+		b, _ := parseNumber(m[2])
+		return (a + b) / 2, 1
+	}
+
+	total, ok := parseNumber(tokens[0])
+	if !ok {
+		return 0, 0
+	}
+	n := 1
+	if n < len(tokens) && strings.Contains(tokens[n], "/") {
+		if frac, ok := parseNumber(tokens[n]); ok {
+			total += frac
+			n++
+		}
+	}
+	if n+1 < len(tokens) && (tokens[n] == "-" || tokens[n] == "to" || tokens[n] == "–") {
+		if upper, ok := parseNumber(tokens[n+1]); ok {
+			return (total + upper) / 2, n + 2
+		}
+	}
+	return total, n
+}
+
+func parseNumber(s string) (float64, bool) {
+	if i := strings.Index(s, "/"); i > 0 {
+		num, err1 := strconv.ParseFloat(s[:i], 64)
+		den, err2 := strconv.ParseFloat(s[i+1:], 64)
+		if err1 != nil || err2 != nil || den == 0 {
+			return 0, false
+		}
+		return num / den, true
+	}
+	f, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return f, err == nil
+}
@@ -0,0 +1,70 @@
+package nutrition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestParseIngredient(t *testing.T) {
+	cases := []struct {
+		line string
+		want Ingredient
+	}{
+		{"200g chicken breast", Ingredient{Quantity: 200, Unit: "g", Name: "chicken breast"}},
+		{"1 1/2 cups rolled oats, toasted", Ingredient{Quantity: 1.5, Unit: "cup", Name: "rolled oats", Note: "toasted"}},
+		{"½ tsp salt", Ingredient{Quantity: 0.5, Unit: "tsp", Name: "salt"}},
+		{"2-3 cloves garlic", Ingredient{Quantity: 2.5, Unit: "clove", Name: "garlic"}},
+		{"2 large eggs", Ingredient{Quantity: 2, Unit: "large", Name: "eggs"}},
+		{"1 (14 oz) can of chickpeas", Ingredient{Quantity: 1, Unit: "can", Name: "chickpeas"}},
+		{"3 fl oz milk", Ingredient{Quantity: 3, Unit: "fl oz", Name: "milk"}},
+		{"Salt to taste", Ingredient{Quantity: 0, Name: "Salt", Note: "to taste"}},
+		{"- 1.5 lbs ground beef", Ingredient{Quantity: 1.5, Unit: "lb", Name: "ground beef"}},
+	}
+	for _, c := range cases {
+		got := ParseIngredient(c.line)
+		got.Raw = ""
+		assert.Equal(t, c.want, got, c.line)
+	}
+}
+
+func TestGrams(t *testing.T) {
+	portions := []models.FoodPortion{{Unit: "cup", Grams: 90}, {Unit: "large", Grams: 50}}
+
+	g, ok := Grams(ParseIngredient("2 cups oats"), portions)
+	assert.True(t, ok)
+	assert.InDelta(t, 180, g, 0.1)
+
+	g, ok = Grams(ParseIngredient("1 tbsp oats"), portions)
+	assert.True(t, ok)
+	assert.InDelta(t, 5.6, g, 0.1)
+
+	g, ok = Grams(ParseIngredient("3 eggs"), portions)
+	assert.True(t, ok)
+	assert.Equal(t, 150.0, g)
+
+	g, ok = Grams(ParseIngredient("8 oz steak"), nil)
+	assert.True(t, ok)
+	assert.InDelta(t, 226.8, g, 0.1)
+
+	_, ok = Grams(ParseIngredient("2 slices bread"), portions)
+	assert.False(t, ok)
+}
+
+func TestComputeRecipe(t *testing.T) {
+	oats := &models.Food{ID: "oats", Name: "Oats", Per100g: models.Nutrients{Calories: 380, Protein: 13, Carbs: 68, Fat: 6.5}}
+	lookup := func(name string) (*models.Food, error) {
+		if name == "oats" {
+			return oats, nil
+		}
+		return nil, nil
+	}
+
+	per, lines, err := ComputeRecipe([]string{"100 g oats", "1 pinch cardamom"}, 2, lookup)
+	assert.NoError(t, err)
+	assert.Equal(t, 190.0, per.Calories)
+	assert.Equal(t, 6.5, per.Protein)
+	assert.True(t, lines[0].Resolved)
+	assert.False(t, lines[1].Resolved)
+}
@@ -0,0 +1,71 @@
+package nutrition
+
+import "github.com/terr0r/fitness.ai/backend/models"
+
+// EntryMacros reads the nutrients stored on a food journal entry. Missing
+// or non-numeric values count as zero.
+func EntryMacros(data map[string]interface{}) models.Macros {
+	num := func(key string) float64 {
+		switch v := data[key].(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		}
+		return 0
+	}
+	return models.Macros{
+		Calories: num("calories"),
+		Protein:  num("protein"),
+		Carbs:    num("carbs"),
+		Fat:      num("fat"),
+		Fiber:    num("fiber"),
+	}
+}
+
+// AddMacros returns the sum of a and b.
+func AddMacros(a, b models.Macros) models.Macros {
+	return models.Macros{
+		Calories: a.Calories + b.Calories,
+		Protein:  a.Protein + b.Protein,
+		Carbs:    a.Carbs + b.Carbs,
+		Fat:      a.Fat + b.Fat,
+		Fiber:    a.Fiber + b.Fiber,
+	}
+}
+
+// ScaleMacros returns m multiplied by factor.
+func ScaleMacros(m models.Macros, factor float64) models.Macros {
+	return models.Macros{
+		Calories: m.Calories * factor,
+		Protein:  m.Protein * factor,
+		Carbs:    m.Carbs * factor,
+		Fat:      m.Fat * factor,
+		Fiber:    m.Fiber * factor,
+	}
+}
+
+// RoundMacros rounds calories to whole numbers and the rest to one
+// decimal place, as Round does for nutrients.
+func RoundMacros(m models.Macros) models.Macros {
+	return Round(models.Nutrients{Calories: m.Calories, Protein: m.Protein, Carbs: m.Carbs, Fat: m.Fat, Fiber: m.Fiber}).Macros()
+}
+
+// Remaining is what is left of the targets after eating totals; it is
+// negative where a target was overshot.
+func Remaining(targets, totals models.Macros) models.Macros {
+	return RoundMacros(AddMacros(targets, ScaleMacros(totals, -1)))
+}
+
+// Average is the mean of daily totals. Callers pass only days with
+// entries, so days nothing was logged do not read as fasting.
+func Average(days []models.Macros) models.Macros {
+	var sum models.Macros
+	for _, d := range days {
+		sum = AddMacros(sum, d)
+	}
+	if len(days) == 0 {
+		return sum
+	}
+	return RoundMacros(ScaleMacros(sum, 1/float64(len(days))))
+}
@@ -0,0 +1,256 @@
+package nutrition
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// NormalizeBarcode reduces a scanned EAN-13, EAN-8, UPC-A or GTIN-14 code
+// to the form products are stored under: UPC-A, and GTIN-14 with a leading
+// zero, become EAN-13. It reports false for anything else, including codes
+// with a wrong check digit.
+func NormalizeBarcode(code string) (string, bool) {
+	code = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	switch len(code) {
+	case 8, 13:
+	case 12:
+		code = "0" + code
+	case 14:
+		if code[0] != '0' {
+			return "", false
+		}
+		code = code[1:]
+	default:
+		return "", false
+	}
+
+	// GS1 check digit: weights 3 and 1 alternate leftwards from the digit
+	// before it.
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	if (10-sum%10)%10 != int(code[len(code)-1]-'0') {
+		return "", false
+	}
+	return code, true
+}
+
+// nutrientLimits bounds plausible values per 100g, keyed like Nutrients.
+// Dump values outside them are dropped and overrides outside them
+// rejected.
+var nutrientLimits = map[string]float64{
+	"calories":     900,
+	"protein":      100,
+	"carbs":        100,
+	"fat":          100,
+	"fiber":        100,
+	"sugar":        100,
+	"sodium_mg":    100000,
+	"potassium_mg": 100000,
+	"calcium_mg":   100000,
+	"iron_mg":      100000,
+	"vitamin_c_mg": 100000,
+}
+
+// setNutrients overlays values keyed like Nutrients onto n.
+func setNutrients(n *models.Nutrients, values map[string]float64) {
+	var m map[string]float64
+	b, _ := json.Marshal(n)
+	json.Unmarshal(b, &m)
+	for k, v := range values {
+		m[k] = v
+	}
+	b, _ = json.Marshal(m)
+	json.Unmarshal(b, n)
+}
+
+// Open Food Facts nutriments and the Nutrients keys they fill, with the
+// factor converting them; the dump gives minerals and vitamins in grams.
+var offNutriments = []struct {
+	key, nutrient string
+	factor        float64
+}{
+	{"energy-kcal_100g", "calories", 1},
+	{"energy-kj_100g", "calories", 1 / 4.184},
+	{"energy_100g", "calories", 1 / 4.184},
+	{"proteins_100g", "protein", 1},
+	{"carbohydrates_100g", "carbs", 1},
+	{"fat_100g", "fat", 1},
+	{"fiber_100g", "fiber", 1},
+	{"sugars_100g", "sugar", 1},
+	{"sodium_100g", "sodium_mg", 1000},
+	{"salt_100g", "sodium_mg", 400},
+	{"potassium_100g", "potassium_mg", 1000},
+	{"calcium_100g", "calcium_mg", 1000},
+	{"iron_100g", "iron_mg", 1000},
+	{"vitamin-c_100g", "vitamin_c_mg", 1000},
+}
+
+// offProduct builds a product from a dump record; field reads text fields
+// and num numeric ones. The first of several nutriments for the same
+// nutrient wins. Records without a valid barcode, or with neither a name
+// nor any nutrients, are skipped.
+func offProduct(field func(string) string, num func(string) (float64, bool)) (models.Product, bool) {
+	barcode, ok := NormalizeBarcode(field("code"))
+	if !ok {
+		return models.Product{}, false
+	}
+	p := models.Product{
+		Barcode:     barcode,
+		Name:        strings.TrimSpace(field("product_name")),
+		Brand:       strings.TrimSpace(strings.Split(field("brands"), ",")[0]),
+		Quantity:    strings.TrimSpace(field("quantity")),
+		ServingSize: strings.TrimSpace(field("serving_size")),
+		Source:      "openfoodfacts",
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSpace(field("generic_name"))
+	}
+	if g, ok := num("serving_quantity"); ok && g > 0 && g <= 5000 {
+		p.ServingGrams = g
+	}
+
+	values := map[string]float64{}
+	for _, n := range offNutriments {
+		if _, done := values[n.nutrient]; done {
+			continue
+		}
+		v, ok := num(n.key)
+		if !ok {
+			continue
+		}
+		v *= n.factor
+		if v >= 0 && v <= nutrientLimits[n.nutrient] {
+			values[n.nutrient] = v
+		}
+	}
+	if p.Name == "" && len(values) == 0 {
+		return models.Product{}, false
+	}
+	setNutrients(&p.Per100g, values)
+	return p, true
+}
+
+// offNumber reads a dump value that may be a number or numeric text.
+func offNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ReadOFFCSV streams the Open Food Facts CSV export and calls fn for each
+// usable product. The export is tab separated and unquoted, one product
+// per line, so lines are split directly rather than with encoding/csv,
+// which stray quotes in product names would derail.
+func ReadOFFCSV(r io.Reader, fn func(models.Product) error) (int, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	line, err := br.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return 0, fmt.Errorf("reading header: %w", err)
+	}
+	index := map[string]int{}
+	for i, h := range strings.Split(strings.TrimRight(line, "\r\n"), "\t") {
+		index[strings.TrimPrefix(h, "\ufeff")] = i
+	}
+	if _, ok := index["code"]; !ok {
+		return 0, fmt.Errorf("not an Open Food Facts export: no code column")
+	}
+
+	count := 0
+	for {
+		line, err := br.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			rec := strings.Split(line, "\t")
+			field := func(name string) string {
+				if i, ok := index[name]; ok && i < len(rec) {
+					return rec[i]
+				}
+				return ""
+			}
+			num := func(name string) (float64, bool) {
+				if s := field(name); s != "" {
+					return offNumber(s)
+				}
+				return 0, false
+			}
+			if p, ok := offProduct(field, num); ok {
+				if err := fn(p); err != nil {
+					return count, err
+				}
+				count++
+			}
+		}
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+	}
+}
+
+// ReadOFFJSONL streams the Open Food Facts JSONL export, one product
+// object per line, and calls fn for each usable product.
+func ReadOFFJSONL(r io.Reader, fn func(models.Product) error) (int, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	count, line := 0, 0
+	for {
+		var raw map[string]interface{}
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return count, nil
+		}
+		line++
+		if err != nil {
+			return count, fmt.Errorf("product %d: %w", line, err)
+		}
+
+		nutriments, _ := raw["nutriments"].(map[string]interface{})
+		field := func(name string) string {
+			switch v := raw[name].(type) {
+			case string:
+				return v
+			case json.Number:
+				return v.String()
+			}
+			return ""
+		}
+		num := func(name string) (float64, bool) {
+			if name == "serving_quantity" {
+				return offNumber(raw[name])
+			}
+			return offNumber(nutriments[name])
+		}
+		if p, ok := offProduct(field, num); ok {
+			if err := fn(p); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+}
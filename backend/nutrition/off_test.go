@@ -0,0 +1,88 @@
+package nutrition
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestNormalizeBarcode(t *testing.T) {
+	cases := map[string]string{
+		"3017620422003":   "3017620422003",
+		"3017-6204 22003": "3017620422003",
+		"049000028911":    "0049000028911", // UPC-A
+		"00049000028911":  "0049000028911", // GTIN-14
+		"96385074":        "96385074",
+		"3017620422004":   "", // bad check digit
+		"12345":           "",
+		"30176204220O3":   "",
+	}
+	for in, want := range cases {
+		got, ok := NormalizeBarcode(in)
+		assert.Equal(t, want, got, in)
+		assert.Equal(t, want != "", ok, in)
+	}
+}
+
+func collectProducts(t *testing.T, read func(fn func(models.Product) error) (int, error)) []models.Product {
+	t.Helper()
+	var products []models.Product
+	n, err := read(func(p models.Product) error {
+		products = append(products, p)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, len(products), n)
+	return products
+}
+
+func TestReadOFFCSV(t *testing.T) {
+	dump := strings.Join([]string{
+		"code\tproduct_name\tgeneric_name\tbrands\tquantity\tserving_size\tserving_quantity\tenergy-kcal_100g\tenergy_100g\tproteins_100g\tcarbohydrates_100g\tfat_100g\tsalt_100g\tcalcium_100g",
+		"3017620422003\tNutella \"the original\tHazelnut spread\tFerrero,Nutella\t400 g\t15 g\t15\t539\t2252\t6.3\t57.5\t30.9\t0.107\t",
+		"049000028911\t\tCola\t\t\t\t\t\t180\t\t10.6\t\t\t0.002",
+		"12345\tStore bread\t\t\t\t\t\t250\t\t\t\t\t\t",
+		"96385074\t\t\t\t\t\t\t\t\t\t\t\t\t",
+	}, "\n")
+
+	products := collectProducts(t, func(fn func(models.Product) error) (int, error) {
+		return ReadOFFCSV(strings.NewReader(dump), fn)
+	})
+	assert.Len(t, products, 2)
+
+	p := products[0]
+	assert.Equal(t, "3017620422003", p.Barcode)
+	assert.Equal(t, `Nutella "the original`, p.Name)
+	assert.Equal(t, "Ferrero", p.Brand)
+	assert.Equal(t, 15.0, p.ServingGrams)
+	assert.Equal(t, 539.0, p.Per100g.Calories)
+	assert.InDelta(t, 42.8, p.Per100g.SodiumMg, 0.001)
+
+	// Energy in kJ only, the generic name standing in for a missing one
+	// and minerals converted from grams.
+	p = products[1]
+	assert.Equal(t, "0049000028911", p.Barcode)
+	assert.Equal(t, "Cola", p.Name)
+	assert.InDelta(t, 43.0, p.Per100g.Calories, 0.1)
+	assert.InDelta(t, 2.0, p.Per100g.CalciumMg, 0.001)
+}
+
+func TestReadOFFJSONL(t *testing.T) {
+	dump := `{"code":"3017620422003","product_name":"Nutella","brands":"Ferrero","serving_quantity":"15","nutriments":{"energy-kcal_100g":539,"proteins_100g":"6.3","sugars_100g":56.3,"fat_100g":-4}}
+{"code":3017620422004,"product_name":"Bad check digit"}
+{"code":"96385074","product_name":"Mints","nutriments":{"energy-kcal_100g":9000,"energy-kj_100g":1600}}
+`
+	products := collectProducts(t, func(fn func(models.Product) error) (int, error) {
+		return ReadOFFJSONL(strings.NewReader(dump), fn)
+	})
+	assert.Len(t, products, 2)
+	assert.Equal(t, models.Nutrients{Calories: 539, Protein: 6.3, Sugar: 56.3}, products[0].Per100g)
+	assert.Equal(t, 15.0, products[0].ServingGrams)
+	// Implausible values are dropped in favour of the next source.
+	assert.InDelta(t, 382.4, products[1].Per100g.Calories, 0.1)
+
+	_, err := ReadOFFJSONL(strings.NewReader(`{"code":`), func(models.Product) error { return nil })
+	assert.Error(t, err)
+}
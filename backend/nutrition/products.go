@@ -0,0 +1,189 @@
+package nutrition
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// ErrProductNotFound is returned for barcodes neither the dump nor the
+// user's overrides know.
+var ErrProductNotFound = errors.New("product not found")
+
+// ErrInvalidOverride wraps the problems SaveProductOverride finds with an
+// override.
+var ErrInvalidOverride = errors.New("invalid override")
+
+const productColumns = `p.barcode, p.name, COALESCE(p.brand, ''), COALESCE(p.quantity, ''), COALESCE(p.serving_size, ''), COALESCE(p.serving_grams, 0),
+	p.calories, p.protein, p.carbs, p.fat, p.fiber, p.sugar, p.sodium_mg, p.potassium_mg, p.calcium_mg, p.iron_mg, p.vitamin_c_mg`
+
+// SaveProduct inserts or replaces a product from the dump.
+func SaveProduct(tx *sql.Tx, p models.Product) error {
+	n := p.Per100g
+	_, err := tx.Exec(`INSERT INTO products (barcode, name, brand, quantity, serving_size, serving_grams, calories, protein, carbs, fat, fiber, sugar, sodium_mg, potassium_mg, calcium_mg, iron_mg, vitamin_c_mg)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (barcode) DO UPDATE SET name = excluded.name, brand = excluded.brand, quantity = excluded.quantity,
+			serving_size = excluded.serving_size, serving_grams = excluded.serving_grams, calories = excluded.calories,
+			protein = excluded.protein, carbs = excluded.carbs, fat = excluded.fat, fiber = excluded.fiber, sugar = excluded.sugar,
+			sodium_mg = excluded.sodium_mg, potassium_mg = excluded.potassium_mg, calcium_mg = excluded.calcium_mg,
+			iron_mg = excluded.iron_mg, vitamin_c_mg = excluded.vitamin_c_mg`,
+		p.Barcode, p.Name, p.Brand, p.Quantity, p.ServingSize, p.ServingGrams,
+		n.Calories, n.Protein, n.Carbs, n.Fat, n.Fiber, n.Sugar, n.SodiumMg, n.PotassiumMg, n.CalciumMg, n.IronMg, n.VitaminCMg)
+	return err
+}
+
+// ImportProducts saves products produced by one of the Open Food Facts
+// readers in batches.
+func ImportProducts(read func(fn func(models.Product) error) (int, error)) (int, error) {
+	return importBatched(read, SaveProduct)
+}
+
+func loadDumpProduct(barcode string) (*models.Product, error) {
+	var p models.Product
+	n := &p.Per100g
+	err := db.DB.QueryRow(`SELECT `+productColumns+` FROM products p WHERE p.barcode = ?`, barcode).Scan(
+		&p.Barcode, &p.Name, &p.Brand, &p.Quantity, &p.ServingSize, &p.ServingGrams,
+		&n.Calories, &n.Protein, &n.Carbs, &n.Fat, &n.Fiber, &n.Sugar, &n.SodiumMg, &n.PotassiumMg, &n.CalciumMg, &n.IronMg, &n.VitaminCMg)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.Source = "openfoodfacts"
+	return &p, nil
+}
+
+func loadOverride(userID, barcode string) (*models.ProductOverride, error) {
+	var data string
+	err := db.DB.QueryRow(`SELECT data FROM product_overrides WHERE user_id = ? AND barcode = ?`, userID, barcode).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var o models.ProductOverride
+	if err := json.Unmarshal([]byte(data), &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// LookupProduct returns the product for a normalised barcode as the user
+// sees it: the dump's data with their override applied, and nutrients per
+// serving when the serving size is known.
+func LookupProduct(userID, barcode string) (models.Product, error) {
+	p, err := loadDumpProduct(barcode)
+	if err != nil {
+		return models.Product{}, err
+	}
+	o, err := loadOverride(userID, barcode)
+	if err != nil {
+		return models.Product{}, err
+	}
+	if p == nil && o == nil {
+		return models.Product{}, ErrProductNotFound
+	}
+	if p == nil {
+		p = &models.Product{Barcode: barcode, Source: "user"}
+	}
+	if o != nil {
+		ApplyOverride(p, *o)
+	}
+
+	p.Per100g = Round(p.Per100g)
+	if p.ServingGrams > 0 {
+		serving := Round(Scale(p.Per100g, p.ServingGrams/100))
+		p.PerServing = &serving
+	}
+	return *p, nil
+}
+
+// ApplyOverride replaces the product fields the override gives and
+// records which they were.
+func ApplyOverride(p *models.Product, o models.ProductOverride) {
+	if o.Name != nil {
+		p.Name = *o.Name
+		p.Overridden = append(p.Overridden, "name")
+	}
+	if o.Brand != nil {
+		p.Brand = *o.Brand
+		p.Overridden = append(p.Overridden, "brand")
+	}
+	if o.ServingSize != nil {
+		p.ServingSize = *o.ServingSize
+		p.Overridden = append(p.Overridden, "serving_size")
+	}
+	if o.ServingGrams != nil {
+		p.ServingGrams = *o.ServingGrams
+		p.Overridden = append(p.Overridden, "serving_grams")
+	}
+	if len(o.Per100g) > 0 {
+		setNutrients(&p.Per100g, o.Per100g)
+		keys := make([]string, 0, len(o.Per100g))
+		for k := range o.Per100g {
+			keys = append(keys, "per_100g."+k)
+		}
+		sort.Strings(keys)
+		p.Overridden = append(p.Overridden, keys...)
+	}
+}
+
+// SaveProductOverride checks and stores a user's override for a
+// normalised barcode. Products missing from the dump need at least a name.
+func SaveProductOverride(userID, barcode string, o models.ProductOverride) error {
+	if o.Name != nil {
+		name := strings.TrimSpace(*o.Name)
+		if name == "" {
+			return fmt.Errorf("%w: name cannot be empty", ErrInvalidOverride)
+		}
+		o.Name = &name
+	}
+	if o.ServingGrams != nil && (*o.ServingGrams <= 0 || *o.ServingGrams > 5000) {
+		return fmt.Errorf("%w: serving_grams must be between 0 and 5000", ErrInvalidOverride)
+	}
+	for k, v := range o.Per100g {
+		limit, ok := nutrientLimits[k]
+		if !ok {
+			return fmt.Errorf("%w: per_100g: unknown nutrient %q", ErrInvalidOverride, k)
+		}
+		if v < 0 || v > limit {
+			return fmt.Errorf("%w: per_100g: %s must be between 0 and %g", ErrInvalidOverride, k, limit)
+		}
+	}
+	if o.Name == nil && o.Brand == nil && o.ServingSize == nil && o.ServingGrams == nil && len(o.Per100g) == 0 {
+		return fmt.Errorf("%w: override must change at least one field", ErrInvalidOverride)
+	}
+	p, err := loadDumpProduct(barcode)
+	if err != nil {
+		return err
+	}
+	if p == nil && o.Name == nil {
+		return fmt.Errorf("%w: name is required for products missing from the database", ErrInvalidOverride)
+	}
+
+	data, _ := json.Marshal(o)
+	_, err = db.DB.Exec(`INSERT INTO product_overrides (user_id, barcode, data, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id, barcode) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`,
+		userID, barcode, string(data), time.Now())
+	return err
+}
+
+// DeleteProductOverride removes a user's override, reporting whether there
+// was one.
+func DeleteProductOverride(userID, barcode string) (bool, error) {
+	res, err := db.DB.Exec(`DELETE FROM product_overrides WHERE user_id = ? AND barcode = ?`, userID, barcode)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
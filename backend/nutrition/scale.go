@@ -0,0 +1,257 @@
+package nutrition
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Measurement systems for scaled output.
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// ScaledIngredient is an ingredient line after scaling and rounding to a
+// unit a cook would actually measure with.
+type ScaledIngredient struct {
+	Original  string  `json:"original"`
+	Quantity  float64 `json:"quantity"`
+	Unit      string  `json:"unit,omitempty"`
+	Name      string  `json:"name"`
+	Display   string  `json:"display"`
+	NonLinear bool    `json:"non_linear,omitempty"`
+	Warning   string  `json:"warning,omitempty"`
+}
+
+// Ingredients that should not simply be multiplied: leaveners and strong
+// seasonings are usually scaled by less than the recipe and adjusted to taste.
+var sublinearIngredients = []string{"baking powder", "baking soda", "yeast", "salt", "chili", "chilli", "cayenne", "vanilla extract"}
+
+// ScaleIngredient multiplies an ingredient line by factor and re-expresses
+// it in the requested system ("metric", "imperial" or "" to keep the
+// original one). Whole items such as "1 egg" are rounded to a whole number,
+// pluralised to match, and flagged as non-linear whenever they are scaled.
+func ScaleIngredient(line string, factor float64, system string) ScaledIngredient {
+	ing := ParseIngredient(line)
+	out := ScaledIngredient{Original: ing.Raw, Name: ing.Name}
+
+	if ing.Quantity == 0 {
+		out.Display = ing.Raw
+		if ing.Note != "" {
+			out.NonLinear = true
+			out.Warning = "adjust " + ing.Note
+		}
+		return out
+	}
+
+	qty := ing.Quantity * factor
+	kind := UnitKind(ing.Unit)
+	if system == "" {
+		system = systemOf(ing.Unit)
+	}
+
+	switch kind {
+	case Mass:
+		out.Quantity, out.Unit = roundMass(qty, ing.Unit, system)
+	case Volume:
+		out.Quantity, out.Unit = roundVolume(qty, ing.Unit, system)
+	default:
+		out.Unit = ing.Unit
+		out.Quantity = math.Round(qty*2) / 2
+		if ing.Unit == "" || sizeUnits[ing.Unit] {
+			out.Quantity = math.Round(qty)
+		}
+		if out.Quantity == 0 {
+			out.Quantity = 1
+		}
+		if math.Abs(out.Quantity-qty) > 1e-9 {
+			out.NonLinear = true
+			out.Warning = fmt.Sprintf("rounded from %s to whole items", formatDecimal(qty))
+		} else if factor != 1 {
+			// Three eggs in a double batch may still want to be four.
+			out.NonLinear = true
+			out.Warning = "counted items may not scale linearly"
+		}
+	}
+
+	lower := strings.ToLower(ing.Name)
+	for _, s := range sublinearIngredients {
+		if strings.Contains(lower, s) && factor != 1 {
+			out.NonLinear = true
+			out.Warning = "does not scale linearly; adjust to taste"
+			break
+		}
+	}
+
+	name, unit := ing.Name, out.Unit
+	if kind == Count {
+		if sizeUnits[unit] || unit == "" {
+			name = inflectLastWord(name, ing.Quantity, out.Quantity)
+		} else if out.Quantity > 1 {
+			// Units are stored singular ("clove").
+			unit = plural(unit)
+		}
+	}
+	parts := []string{FormatQuantity(out.Quantity, out.Unit)}
+	if unit != "" {
+		parts = append(parts, unit)
+	}
+	out.Display = strings.Join(append(parts, name), " ")
+	if ing.Note != "" {
+		out.Display += ", " + ing.Note
+	}
+	return out
+}
+
+// Count units that describe the item rather than measure it: "2 large eggs".
+var sizeUnits = map[string]bool{"large": true, "medium": true, "small": true, "whole": true}
+
+// inflectLastWord makes the last word of name plural or singular when the
+// amount crosses one, so "1 egg" doubled reads "2 eggs" and "2 eggs" halved
+// reads "1 egg". Names are left alone otherwise, as they are already written
+// for the original amount.
+func inflectLastWord(name string, from, to float64) string {
+	i := strings.LastIndex(name, " ") + 1
+	word := name[i:]
+	switch {
+	case word == "":
+		return name
+	case from <= 1 && to > 1:
+		word = plural(word)
+	case from > 1 && to <= 1:
+		word = singular(word)
+	}
+	return name[:i] + word
+}
+
+var irregularPlurals = map[string]string{"leaf": "leaves", "loaf": "loaves", "knife": "knives", "half": "halves"}
+
+func plural(word string) string {
+	lower := strings.ToLower(word)
+	if p, ok := irregularPlurals[lower]; ok {
+		return word[:len(word)-len(lower)] + p
+	}
+	for _, end := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(lower, end) {
+			return word + "es"
+		}
+	}
+	if n := len(lower); n > 1 && (lower[n-1] == 'y' || lower[n-1] == 'o') && !strings.ContainsRune("aeiou", rune(lower[n-2])) {
+		if lower[n-1] == 'y' {
+			return word[:n-1] + "ies"
+		}
+		return word + "es"
+	}
+	return word + "s"
+}
+
+func singular(word string) string {
+	lower := strings.ToLower(word)
+	for s, p := range irregularPlurals {
+		if lower == p {
+			return s
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(lower) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(lower, "oes"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "zes"),
+		strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"), strings.HasSuffix(lower, "sses"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "us"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func systemOf(unit string) string {
+	switch unit {
+	case "oz", "lb", "fl oz", "cup", "pint", "quart", "tsp", "tbsp":
+		return Imperial
+	}
+	return Metric
+}
+
+func roundMass(qty float64, unit, system string) (float64, string) {
+	g, _ := Convert(qty, unit, "g")
+	if system == Imperial {
+		oz := roundTo(g/28.3495, 0.25)
+		if oz >= 16 {
+			return roundTo(oz/16, 0.25), "lb"
+		}
+		return math.Max(oz, 0.25), "oz"
+	}
+	switch {
+	case g >= 1000:
+		return roundTo(g/1000, 0.05), "kg"
+	case g >= 100:
+		return roundTo(g, 5), "g"
+	case g >= 1:
+		return math.Round(g), "g"
+	}
+	return math.Round(g*1000) / 1000, "g"
+}
+
+func roundVolume(qty float64, unit, system string) (float64, string) {
+	ml, _ := Convert(qty, unit, "ml")
+
+	// Spoon measures are shared by both systems and are what people reach
+	// for with small amounts.
+	if ml < 3*14.7868 || unit == "pinch" || unit == "dash" {
+		if unit == "pinch" || unit == "dash" {
+			return math.Max(math.Round(qty), 1), unit
+		}
+		if ml < 14.7868 {
+			return math.Max(roundTo(ml/4.92892, 0.125), 0.125), "tsp"
+		}
+		return roundTo(ml/14.7868, 0.5), "tbsp"
+	}
+
+	if system == Imperial {
+		cups := ml / 236.588
+		if cups >= 4 {
+			return roundTo(ml/946.353, 0.25), "quart"
+		}
+		return roundTo(cups, 0.25), "cup"
+	}
+	if ml >= 1000 {
+		return roundTo(ml/1000, 0.05), "l"
+	}
+	return roundTo(ml, 5), "ml"
+}
+
+func roundTo(v, step float64) float64 {
+	return math.Round(v/step) * step
+}
+
+// FormatQuantity renders spoon and cup amounts as kitchen fractions
+// ("1 1/2") and everything else as a trimmed decimal.
+func FormatQuantity(q float64, unit string) string {
+	switch unit {
+	case "tsp", "tbsp", "cup", "oz", "lb", "quart", "":
+	default:
+		return formatDecimal(q)
+	}
+
+	whole := math.Floor(q)
+	frac := q - whole
+	names := []struct {
+		v float64
+		s string
+	}{{0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.375, "3/8"}, {0.5, "1/2"}, {0.625, "5/8"}, {2.0 / 3, "2/3"}, {0.75, "3/4"}, {0.875, "7/8"}}
+	for _, n := range names {
+		if math.Abs(frac-n.v) < 0.01 {
+			if whole == 0 {
+				return n.s
+			}
+			return strconv.Itoa(int(whole)) + " " + n.s
+		}
+	}
+	return formatDecimal(q)
+}
+
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
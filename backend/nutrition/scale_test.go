@@ -0,0 +1,39 @@
+package nutrition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScaleIngredient(t *testing.T) {
+	cases := []struct {
+		line    string
+		factor  float64
+		system  string
+		display string
+		flagged bool
+	}{
+		{"200 g chicken breast", 4, "", "800 g chicken breast", false},
+		{"300 g flour", 4, "", "1.2 kg flour", false},
+		{"1 cup milk", 0.25, "", "1/4 cup milk", false},
+		{"1 cup milk", 0.5, Metric, "120 ml milk", false},
+		{"1 tbsp olive oil", 0.25, "", "3/4 tsp olive oil", false},
+		{"2 tbsp olive oil", 4, "", "1/2 cup olive oil", false},
+		{"454 g beef mince", 1, Imperial, "1 lb beef mince", false},
+		{"1 egg", 0.25, "", "1 egg", true},
+		{"1 egg", 2, "", "2 eggs", true},
+		{"1 egg", 1, "", "1 egg", false},
+		{"2 eggs", 0.5, "", "1 egg", true},
+		{"1 large tomato", 3, "", "3 large tomatoes", true},
+		{"1 clove garlic", 2, "", "2 cloves garlic", true},
+		{"3 eggs", 1.5, "", "5 eggs", true},
+		{"1 tsp baking powder", 2, "", "2 tsp baking powder", true},
+		{"salt to taste", 2, "", "salt to taste", true},
+	}
+	for _, c := range cases {
+		got := ScaleIngredient(c.line, c.factor, c.system)
+		assert.Equal(t, c.display, got.Display, c.line)
+		assert.Equal(t, c.flagged, got.NonLinear, c.line)
+	}
+}
@@ -0,0 +1,273 @@
+package nutrition
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+const foodColumns = `f.id, COALESCE(f.fdc_id, 0), f.name, COALESCE(f.category, ''), f.calories, f.protein, f.carbs, f.fat, f.fiber, f.sugar, f.sodium_mg, f.potassium_mg, f.calcium_mg, f.iron_mg, f.vitamin_c_mg`
+
+func scanFood(row interface{ Scan(...interface{}) error }) (models.Food, error) {
+	var f models.Food
+	n := &f.Per100g
+	err := row.Scan(&f.ID, &f.FdcID, &f.Name, &f.Category, &n.Calories, &n.Protein, &n.Carbs, &n.Fat, &n.Fiber, &n.Sugar, &n.SodiumMg, &n.PotassiumMg, &n.CalciumMg, &n.IronMg, &n.VitaminCMg)
+	return f, err
+}
+
+// SaveFood inserts or replaces a food and its portions.
+func SaveFood(tx *sql.Tx, f models.Food) error {
+	n := f.Per100g
+	var fdcID interface{}
+	if f.FdcID != 0 {
+		fdcID = f.FdcID
+	}
+	_, err := tx.Exec(`INSERT INTO foods (id, fdc_id, name, category, calories, protein, carbs, fat, fiber, sugar, sodium_mg, potassium_mg, calcium_mg, iron_mg, vitamin_c_mg)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, category = excluded.category, calories = excluded.calories,
+			protein = excluded.protein, carbs = excluded.carbs, fat = excluded.fat, fiber = excluded.fiber, sugar = excluded.sugar,
+			sodium_mg = excluded.sodium_mg, potassium_mg = excluded.potassium_mg, calcium_mg = excluded.calcium_mg,
+			iron_mg = excluded.iron_mg, vitamin_c_mg = excluded.vitamin_c_mg`,
+		f.ID, fdcID, f.Name, f.Category, n.Calories, n.Protein, n.Carbs, n.Fat, n.Fiber, n.Sugar, n.SodiumMg, n.PotassiumMg, n.CalciumMg, n.IronMg, n.VitaminCMg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM food_portions WHERE food_id = ?`, f.ID); err != nil {
+		return err
+	}
+	for _, p := range f.Portions {
+		if _, err := tx.Exec(`INSERT INTO food_portions (food_id, unit, grams) VALUES (?, ?, ?)`, f.ID, p.Unit, p.Grams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportFoods saves foods produced by one of the readers, committing every
+// batchSize foods so large dumps do not build one huge transaction.
+func ImportFoods(read func(fn func(models.Food) error) (int, error)) (int, error) {
+	return importBatched(read, SaveFood)
+}
+
+// importBatched saves the items produced by read, committing every
+// batchSize items.
+func importBatched[T any](read func(fn func(T) error) (int, error), save func(*sql.Tx, T) error) (int, error) {
+	const batchSize = 1000
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	n, err := read(func(item T) error {
+		if err := save(tx, item); err != nil {
+			return err
+		}
+		pending++
+		if pending < batchSize {
+			return nil
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		pending = 0
+		tx, err = db.DB.Begin()
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return n, err
+	}
+	return n, tx.Commit()
+}
+
+func loadPortions(food *models.Food) error {
+	rows, err := db.DB.Query(`SELECT unit, grams FROM food_portions WHERE food_id = ? ORDER BY unit`, food.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p models.FoodPortion
+		if err := rows.Scan(&p.Unit, &p.Grams); err != nil {
+			return err
+		}
+		food.Portions = append(food.Portions, p)
+	}
+	return rows.Err()
+}
+
+// LoadFood returns a food with its portions.
+func LoadFood(id string) (models.Food, error) {
+	f, err := scanFood(db.DB.QueryRow(`SELECT `+foodColumns+` FROM foods f WHERE f.id = ?`, id))
+	if err != nil {
+		return f, err
+	}
+	return f, loadPortions(&f)
+}
+
+// SearchFoods ranks foods by how well their name matches q.
+func SearchFoods(q string, limit int) ([]models.Food, error) {
+	foods := []models.Food{}
+	match := matchExpression(q, " ")
+	if match == "" {
+		return foods, nil
+	}
+
+	rows, err := db.DB.Query(`SELECT `+foodColumns+` FROM foods_fts JOIN foods f ON f.id = foods_fts.food_id
+		WHERE foods_fts MATCH ? ORDER BY bm25(foods_fts), length(f.name) LIMIT ?`, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		f, err := scanFood(rows)
+		if err != nil {
+			return nil, err
+		}
+		foods = append(foods, f)
+	}
+	return foods, rows.Err()
+}
+
+// Descriptive words that rarely appear in food database names and would
+// only stop an ingredient from matching.
+var ingredientStopWords = map[string]bool{
+	"fresh": true, "freshly": true, "chopped": true, "diced": true, "minced": true, "sliced": true,
+	"grated": true, "shredded": true, "finely": true, "roughly": true, "thinly": true, "large": true,
+	"medium": true, "small": true, "boneless": true, "skinless": true, "organic": true, "of": true,
+	"and": true, "or": true, "a": true, "the": true, "optional": true, "ground": true, "to": true,
+}
+
+func matchExpression(q, op string) string {
+	var terms []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		if !ingredientStopWords[w] {
+			terms = append(terms, `"`+w+`"`)
+		}
+	}
+	return strings.Join(terms, op)
+}
+
+// MatchFood picks the best food for an ingredient name: every significant
+// word must match, with the shortest, best-ranked name winning. It returns
+// nil when nothing matches.
+func MatchFood(name string) (*models.Food, error) {
+	foods, err := SearchFoods(name, 1)
+	if err != nil || len(foods) == 0 {
+		return nil, err
+	}
+	f := foods[0]
+	if err := loadPortions(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// RecomputeRecipe recalculates a recipe's per-serving nutrition from its
+// ingredient lines and stores the parsed lines. The computed values replace
+// the recipe's macros only when every line resolves; otherwise the stored
+// macros are kept, as partial totals would undercount them.
+func RecomputeRecipe(recipeID string) (models.Nutrients, []models.RecipeIngredient, error) {
+	var ingredientsJSON string
+	var servings int
+	err := db.DB.QueryRow(`SELECT COALESCE(ingredients, '[]'), COALESCE(servings, 1) FROM recipes WHERE id = ?`, recipeID).Scan(&ingredientsJSON, &servings)
+	if err != nil {
+		return models.Nutrients{}, nil, err
+	}
+	var lines []string
+	json.Unmarshal([]byte(ingredientsJSON), &lines)
+
+	total, parsed, err := ComputeRecipe(lines, servings, MatchFood)
+	if err != nil {
+		return total, nil, err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return total, nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM recipe_ingredients WHERE recipe_id = ?`, recipeID); err != nil {
+		return total, nil, err
+	}
+	resolved := len(parsed) > 0
+	for _, ri := range parsed {
+		var foodID interface{}
+		if ri.FoodID != "" {
+			foodID = ri.FoodID
+		}
+		_, err := tx.Exec(`INSERT INTO recipe_ingredients (recipe_id, position, raw, quantity, unit, name, food_id, grams, resolved) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			recipeID, ri.Position, ri.Raw, ri.Quantity, ri.Unit, ri.Name, foodID, ri.Grams, ri.Resolved)
+		if err != nil {
+			return total, nil, err
+		}
+		resolved = resolved && ri.Resolved
+	}
+
+	if resolved {
+		nutritionJSON, _ := json.Marshal(total)
+		macrosJSON, _ := json.Marshal(total.Macros())
+		if _, err := tx.Exec(`UPDATE recipes SET nutrition = ?, macros = ? WHERE id = ?`, string(nutritionJSON), string(macrosJSON), recipeID); err != nil {
+			return total, nil, err
+		}
+	} else if _, err := tx.Exec(`UPDATE recipes SET nutrition = NULL WHERE id = ?`, recipeID); err != nil {
+		return total, nil, err
+	}
+
+	return total, parsed, tx.Commit()
+}
+
+// RecomputeAllRecipes refreshes every recipe, e.g. after a food import.
+func RecomputeAllRecipes() (int, error) {
+	rows, err := db.DB.Query(`SELECT id FROM recipes`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for i, id := range ids {
+		if _, _, err := RecomputeRecipe(id); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+// LoadRecipeIngredients returns the stored parse of a recipe's ingredients.
+func LoadRecipeIngredients(recipeID string) ([]models.RecipeIngredient, error) {
+	rows, err := db.DB.Query(`SELECT ri.position, ri.raw, COALESCE(ri.quantity, 0), COALESCE(ri.unit, ''), COALESCE(ri.name, ''),
+		COALESCE(ri.food_id, ''), COALESCE(f.name, ''), COALESCE(ri.grams, 0), ri.resolved
+		FROM recipe_ingredients ri LEFT JOIN foods f ON f.id = ri.food_id
+		WHERE ri.recipe_id = ? ORDER BY ri.position`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.RecipeIngredient{}
+	for rows.Next() {
+		var ri models.RecipeIngredient
+		if err := rows.Scan(&ri.Position, &ri.Raw, &ri.Quantity, &ri.Unit, &ri.Name, &ri.FoodID, &ri.FoodName, &ri.Grams, &ri.Resolved); err != nil {
+			return nil, err
+		}
+		list = append(list, ri)
+	}
+	return list, rows.Err()
+}
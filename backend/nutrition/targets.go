@@ -0,0 +1,70 @@
+package nutrition
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+var activityFactors = map[string]float64{
+	"sedentary":   1.2,
+	"light":       1.375,
+	"moderate":    1.55,
+	"active":      1.725,
+	"very_active": 1.9,
+}
+
+// ErrIncompleteProfile is returned when the profile lacks the body stats
+// needed to estimate energy needs.
+var ErrIncompleteProfile = errors.New("profile needs age, height and weight to compute targets")
+
+// Targets estimates daily calorie and macro targets from a profile using the
+// Mifflin-St Jeor equation, an activity multiplier and the user's goal.
+// Protein is set per kg of body weight, fat to a quarter of energy and carbs
+// fill the rest.
+func Targets(u models.User) (models.Macros, error) {
+	if u.Age <= 0 || u.Height <= 0 || u.Weight <= 0 {
+		return models.Macros{}, ErrIncompleteProfile
+	}
+
+	bmr := 10*u.Weight + 6.25*u.Height - 5*float64(u.Age)
+	switch strings.ToLower(u.Gender) {
+	case "male", "m", "man":
+		bmr += 5
+	case "female", "f", "woman":
+		bmr -= 161
+	default:
+		bmr -= 78
+	}
+
+	factor, ok := activityFactors[strings.ReplaceAll(strings.ToLower(u.Activity), " ", "_")]
+	if !ok {
+		factor = activityFactors["light"]
+	}
+	calories := bmr * factor
+
+	proteinPerKg := 1.6
+	goal := strings.ToLower(u.Goals)
+	switch {
+	case strings.Contains(goal, "lose"), strings.Contains(goal, "cut"), strings.Contains(goal, "fat loss"), strings.Contains(goal, "weight loss"):
+		calories -= 500
+		proteinPerKg = 2.0
+	case strings.Contains(goal, "muscle"), strings.Contains(goal, "gain"), strings.Contains(goal, "bulk"):
+		calories += 300
+		proteinPerKg = 1.8
+	}
+
+	protein := proteinPerKg * u.Weight
+	fat := calories * 0.25 / 9
+	carbs := math.Max(calories-protein*4-fat*9, 0) / 4
+
+	return models.Macros{
+		Calories: math.Round(calories),
+		Protein:  math.Round(protein),
+		Carbs:    math.Round(carbs),
+		Fat:      math.Round(fat),
+		Fiber:    math.Round(calories / 1000 * 14),
+	}, nil
+}
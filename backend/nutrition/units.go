@@ -0,0 +1,118 @@
+package nutrition
+
+import "strings"
+
+// Unit kinds.
+const (
+	Mass   = "mass"
+	Volume = "volume"
+	Count  = "count"
+)
+
+type unitDef struct {
+	Name string
+	Kind string
+	// Base is grams for mass units and millilitres for volume units.
+	Base float64
+}
+
+var units = []unitDef{
+	{"mg", Mass, 0.001},
+	{"g", Mass, 1},
+	{"kg", Mass, 1000},
+	{"oz", Mass, 28.3495},
+	{"lb", Mass, 453.592},
+	{"ml", Volume, 1},
+	{"cl", Volume, 10},
+	{"dl", Volume, 100},
+	{"l", Volume, 1000},
+	{"pinch", Volume, 0.31},
+	{"dash", Volume, 0.62},
+	{"tsp", Volume, 4.92892},
+	{"tbsp", Volume, 14.7868},
+	{"fl oz", Volume, 29.5735},
+	{"cup", Volume, 236.588},
+	{"pint", Volume, 473.176},
+	{"quart", Volume, 946.353},
+}
+
+var unitAliases = map[string]string{
+	"milligram": "mg", "milligrams": "mg",
+	"gram": "g", "grams": "g", "gr": "g", "grammes": "g",
+	"kilogram": "kg", "kilograms": "kg", "kgs": "kg", "kilo": "kg", "kilos": "kg",
+	"ounce": "oz", "ounces": "oz",
+	"pound": "lb", "pounds": "lb", "lbs": "lb",
+	"millilitre": "ml", "milliliter": "ml", "millilitres": "ml", "milliliters": "ml", "mls": "ml",
+	"centilitre": "cl", "centiliter": "cl",
+	"decilitre": "dl", "deciliter": "dl",
+	"litre": "l", "liter": "l", "litres": "l", "liters": "l", "ltr": "l",
+	"pinches": "pinch", "dashes": "dash",
+	"teaspoon": "tsp", "teaspoons": "tsp", "tsps": "tsp",
+	"tablespoon": "tbsp", "tablespoons": "tbsp", "tbsps": "tbsp", "tbs": "tbsp", "tbl": "tbsp",
+	"fluid ounce": "fl oz", "fluid ounces": "fl oz", "floz": "fl oz", "fl. oz": "fl oz",
+	"cups": "cup", "c": "cup",
+	"pints": "pint", "pt": "pint",
+	"quarts": "quart", "qt": "quart",
+}
+
+// countUnits are household units whose weight depends on the food; they are
+// resolved through the food's portions.
+var countUnits = map[string]string{
+	"clove": "clove", "cloves": "clove",
+	"slice": "slice", "slices": "slice",
+	"piece": "piece", "pieces": "piece", "pc": "piece", "pcs": "piece",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"stalk": "stalk", "stalks": "stalk",
+	"sprig": "sprig", "sprigs": "sprig",
+	"leaf": "leaf", "leaves": "leaf",
+	"head": "head", "heads": "head",
+	"bunch": "bunch", "bunches": "bunch",
+	"fillet": "fillet", "fillets": "fillet",
+	"scoop": "scoop", "scoops": "scoop",
+	"large": "large", "medium": "medium", "small": "small", "whole": "whole",
+}
+
+// NormalizeUnit maps a unit spelling to its canonical name and kind. Unknown
+// words return ok=false.
+func NormalizeUnit(s string) (name, kind string, ok bool) {
+	s = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+	if alias, found := unitAliases[s]; found {
+		s = alias
+	}
+	for _, u := range units {
+		if u.Name == s {
+			return u.Name, u.Kind, true
+		}
+	}
+	if c, found := countUnits[s]; found {
+		return c, Count, true
+	}
+	return "", "", false
+}
+
+func unitBase(name string) (unitDef, bool) {
+	for _, u := range units {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return unitDef{}, false
+}
+
+// Convert converts a quantity between two units of the same kind.
+func Convert(qty float64, from, to string) (float64, bool) {
+	a, okA := unitBase(from)
+	b, okB := unitBase(to)
+	if !okA || !okB || a.Kind != b.Kind {
+		return 0, false
+	}
+	return qty * a.Base / b.Base, true
+}
+
+// UnitKind reports whether a canonical unit measures mass, volume or count.
+func UnitKind(name string) string {
+	if u, ok := unitBase(name); ok {
+		return u.Kind
+	}
+	return Count
+}
@@ -0,0 +1,206 @@
+package planning
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Change describes a single structural difference between two plan contents.
+type Change struct {
+	Kind string `json:"kind"`
+	Day  int    `json:"day"`
+	Name string `json:"name,omitempty"`
+	Slot string `json:"slot,omitempty"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+const (
+	DayAdded        = "day_added"
+	DayRemoved      = "day_removed"
+	ExerciseAdded   = "exercise_added"
+	ExerciseRemoved = "exercise_removed"
+	SetsChanged     = "sets_changed"
+	RepsChanged     = "reps_changed"
+	LoadChanged     = "load_changed"
+	MealAdded       = "meal_added"
+	MealRemoved     = "meal_removed"
+	MealSwapped     = "meal_swapped"
+	ServingsChanged = "servings_changed"
+)
+
+// Diff compares two plan contents day by day. Days are matched on their day
+// number, exercises on exercise ID (or name when no ID is set) and meals on
+// their slot, so reordering alone never shows up as a change.
+func Diff(from, to models.PlanContent) []Change {
+	changes := []Change{}
+
+	fromDays := indexDays(from.Days)
+	toDays := indexDays(to.Days)
+
+	for _, n := range unionKeys(fromDays, toDays) {
+		a, inFrom := fromDays[n]
+		b, inTo := toDays[n]
+		switch {
+		case !inFrom:
+			changes = append(changes, Change{Kind: DayAdded, Day: n, Name: b.Title})
+		case !inTo:
+			changes = append(changes, Change{Kind: DayRemoved, Day: n, Name: a.Title})
+		default:
+			changes = append(changes, diffExercises(n, a.Exercises, b.Exercises)...)
+			changes = append(changes, diffMeals(n, a.Meals, b.Meals)...)
+		}
+	}
+
+	return changes
+}
+
+// diffExercises matches exercises on their key; an exercise listed more
+// than once in a day is matched by occurrence, as meals in a slot are.
+func diffExercises(day int, from, to []models.PlanExercise) []Change {
+	var changes []Change
+
+	a := indexExercises(from)
+	b := indexExercises(to)
+
+	var order []string
+	for _, ex := range from {
+		order = appendUnique(order, exerciseKey(ex))
+	}
+	for _, ex := range to {
+		order = appendUnique(order, exerciseKey(ex))
+	}
+
+	for _, k := range order {
+		olds, news := a[k], b[k]
+		for i := 0; i < len(olds) || i < len(news); i++ {
+			switch {
+			case i >= len(olds):
+				changes = append(changes, Change{Kind: ExerciseAdded, Day: day, Name: news[i].Name})
+			case i >= len(news):
+				changes = append(changes, Change{Kind: ExerciseRemoved, Day: day, Name: olds[i].Name})
+			default:
+				old, cur := olds[i], news[i]
+				if old.Sets != cur.Sets {
+					changes = append(changes, Change{Kind: SetsChanged, Day: day, Name: cur.Name, From: strconv.Itoa(old.Sets), To: strconv.Itoa(cur.Sets)})
+				}
+				if old.Reps != cur.Reps {
+					changes = append(changes, Change{Kind: RepsChanged, Day: day, Name: cur.Name, From: old.Reps, To: cur.Reps})
+				}
+				if old.Load != cur.Load {
+					changes = append(changes, Change{Kind: LoadChanged, Day: day, Name: cur.Name, From: formatFloat(old.Load), To: formatFloat(cur.Load)})
+				}
+			}
+		}
+	}
+	return changes
+}
+
+func diffMeals(day int, from, to []models.PlanMeal) []Change {
+	var changes []Change
+
+	a := indexMeals(from)
+	b := indexMeals(to)
+
+	var order []string
+	for _, m := range from {
+		order = appendUnique(order, m.Slot)
+	}
+	for _, m := range to {
+		order = appendUnique(order, m.Slot)
+	}
+
+	for _, slot := range order {
+		olds, news := a[slot], b[slot]
+		n := len(olds)
+		if len(news) > n {
+			n = len(news)
+		}
+		for i := 0; i < n; i++ {
+			switch {
+			case i >= len(olds):
+				changes = append(changes, Change{Kind: MealAdded, Day: day, Slot: slot, Name: news[i].Name})
+			case i >= len(news):
+				changes = append(changes, Change{Kind: MealRemoved, Day: day, Slot: slot, Name: olds[i].Name})
+			case mealKey(olds[i]) != mealKey(news[i]):
+				changes = append(changes, Change{Kind: MealSwapped, Day: day, Slot: slot, From: olds[i].Name, To: news[i].Name})
+			case olds[i].Servings != news[i].Servings:
+				changes = append(changes, Change{Kind: ServingsChanged, Day: day, Slot: slot, Name: news[i].Name, From: formatFloat(olds[i].Servings), To: formatFloat(news[i].Servings)})
+			}
+		}
+	}
+	return changes
+}
+
+func indexDays(days []models.PlanDay) map[int]models.PlanDay {
+	m := make(map[int]models.PlanDay, len(days))
+	for i, d := range days {
+		n := d.Day
+		if n == 0 {
+			n = i + 1
+		}
+		m[n] = d
+	}
+	return m
+}
+
+func indexExercises(exercises []models.PlanExercise) map[string][]models.PlanExercise {
+	m := map[string][]models.PlanExercise{}
+	for _, ex := range exercises {
+		k := exerciseKey(ex)
+		m[k] = append(m[k], ex)
+	}
+	return m
+}
+
+func indexMeals(meals []models.PlanMeal) map[string][]models.PlanMeal {
+	m := map[string][]models.PlanMeal{}
+	for _, meal := range meals {
+		m[meal.Slot] = append(m[meal.Slot], meal)
+	}
+	return m
+}
+
+func unionKeys(a, b map[int]models.PlanDay) []int {
+	var keys []int
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func exerciseKey(ex models.PlanExercise) string {
+	if ex.ExerciseID != "" {
+		return "id:" + ex.ExerciseID
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(ex.Name))
+}
+
+func mealKey(m models.PlanMeal) string {
+	if m.RecipeID != "" {
+		return "id:" + m.RecipeID
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(m.Name))
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,62 @@
+package planning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestDiff(t *testing.T) {
+	from := models.PlanContent{Days: []models.PlanDay{
+		{Day: 1, Exercises: []models.PlanExercise{
+			{Name: "Bench Press", Sets: 3, Reps: "8"},
+			{Name: "Dips", Sets: 3, Reps: "10"},
+		}, Meals: []models.PlanMeal{
+			{Slot: "breakfast", RecipeID: "r1", Name: "Oats"},
+		}},
+		{Day: 2, Title: "Rest"},
+	}}
+	to := models.PlanContent{Days: []models.PlanDay{
+		{Day: 1, Exercises: []models.PlanExercise{
+			{Name: "bench press", Sets: 4, Reps: "6"},
+			{Name: "Push Up", Sets: 2, Reps: "15"},
+		}, Meals: []models.PlanMeal{
+			{Slot: "breakfast", RecipeID: "r2", Name: "Eggs"},
+		}},
+	}}
+
+	changes := Diff(from, to)
+
+	assert.Equal(t, []Change{
+		{Kind: SetsChanged, Day: 1, Name: "bench press", From: "3", To: "4"},
+		{Kind: RepsChanged, Day: 1, Name: "bench press", From: "8", To: "6"},
+		{Kind: ExerciseRemoved, Day: 1, Name: "Dips"},
+		{Kind: ExerciseAdded, Day: 1, Name: "Push Up"},
+		{Kind: MealSwapped, Day: 1, Slot: "breakfast", From: "Oats", To: "Eggs"},
+		{Kind: DayRemoved, Day: 2, Name: "Rest"},
+	}, changes)
+}
+
+func TestDiff_Identical(t *testing.T) {
+	c := models.PlanContent{Days: []models.PlanDay{{Day: 1, Exercises: []models.PlanExercise{{Name: "Squat", Sets: 5, Reps: "5"}}}}}
+	assert.Empty(t, Diff(c, c))
+}
+
+func TestDiff_RepeatedExercise(t *testing.T) {
+	from := models.PlanContent{Days: []models.PlanDay{{Day: 1, Exercises: []models.PlanExercise{
+		{Name: "Squat", Sets: 3, Reps: "5"},
+		{Name: "Squat", Sets: 2, Reps: "10"},
+	}}}}
+	to := models.PlanContent{Days: []models.PlanDay{{Day: 1, Exercises: []models.PlanExercise{
+		{Name: "Squat", Sets: 3, Reps: "5"},
+		{Name: "Squat", Sets: 3, Reps: "10"},
+		{Name: "Squat", Sets: 1, Reps: "20"},
+	}}}}
+
+	assert.Equal(t, []Change{
+		{Kind: SetsChanged, Day: 1, Name: "Squat", From: "2", To: "3"},
+		{Kind: ExerciseAdded, Day: 1, Name: "Squat"},
+	}, Diff(from, to))
+	assert.Empty(t, Diff(from, from))
+}
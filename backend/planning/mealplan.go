@@ -0,0 +1,267 @@
+package planning
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Default meal slots, in the order they are filled.
+var DefaultSlots = []string{"breakfast", "lunch", "dinner", "snack"}
+
+// MealPlanOptions controls the diet plan generator.
+type MealPlanOptions struct {
+	Days    int
+	Slots   []string
+	Targets models.Macros
+	// Tolerance is the accepted relative deviation from the calorie and
+	// macro targets, e.g. 0.1 for ±10%.
+	Tolerance float64
+	// NoRepeatDays keeps a recipe from reappearing within that many days.
+	NoRepeatDays int
+	Seed         int64
+}
+
+// Deviation compares one nutrient of a day with its target.
+type Deviation struct {
+	Target  float64 `json:"target"`
+	Actual  float64 `json:"actual"`
+	Diff    float64 `json:"diff"`
+	Percent float64 `json:"percent"`
+}
+
+// DayReport says how far a generated day is from the targets.
+type DayReport struct {
+	Day             int                  `json:"day"`
+	Deviations      map[string]Deviation `json:"deviations"`
+	WithinTolerance bool                 `json:"within_tolerance"`
+	// VarietyRelaxed is set when the catalog was too small to honour the
+	// no-repeat window for this day.
+	VarietyRelaxed bool `json:"variety_relaxed,omitempty"`
+}
+
+var ErrNoRecipes = errors.New("no recipes match the requested restrictions")
+
+// Candidate tries per day; the best scoring combination wins.
+const mealPlanAttempts = 400
+
+// Portions a meal may be scaled to when fitting a day to its targets.
+const (
+	minServings = 0.5
+	maxServings = 2.0
+)
+
+// GenerateMealPlan fills each day's slots with recipes so the day's totals
+// land as close as possible to the targets. For every day it samples
+// combinations of eligible recipes (respecting the no-repeat window), scales
+// the portions towards the calorie target and keeps the combination with the
+// smallest weighted error.
+func GenerateMealPlan(recipes []models.Recipe, opts MealPlanOptions) (models.PlanContent, []DayReport, error) {
+	if len(recipes) == 0 {
+		return models.PlanContent{}, nil, ErrNoRecipes
+	}
+	if opts.Days <= 0 {
+		opts.Days = 7
+	}
+	if len(opts.Slots) == 0 {
+		opts.Slots = DefaultSlots
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 0.1
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	bySlot := map[string][]models.Recipe{}
+	for _, slot := range opts.Slots {
+		for _, r := range recipes {
+			if fitsSlot(r, slot) {
+				bySlot[slot] = append(bySlot[slot], r)
+			}
+		}
+		if len(bySlot[slot]) == 0 && slot == "breakfast" {
+			// Without breakfast recipes, any untagged main meal will do.
+			for _, r := range recipes {
+				if !hasSlotTag(r) {
+					bySlot[slot] = append(bySlot[slot], r)
+				}
+			}
+		}
+		if len(bySlot[slot]) == 0 {
+			return models.PlanContent{}, nil, errors.New("no recipes available for " + slot)
+		}
+	}
+
+	lastUsed := map[string]int{}
+	content := models.PlanContent{}
+	var reports []DayReport
+
+	for day := 1; day <= opts.Days; day++ {
+		var best []models.PlanMeal
+		var bestTotals models.Macros
+		bestScore := math.Inf(1)
+		relaxed := false
+
+		for best == nil {
+			for attempt := 0; attempt < mealPlanAttempts; attempt++ {
+				picks, ok := pickRecipes(rng, bySlot, opts, lastUsed, day, relaxed)
+				if !ok {
+					continue
+				}
+				meals, totals := portionMeals(picks, opts.Slots, opts.Targets.Calories)
+				if score := macroError(totals, opts.Targets); score < bestScore {
+					best, bestTotals, bestScore = meals, totals, score
+				}
+			}
+			if best == nil && relaxed {
+				return models.PlanContent{}, nil, errors.New("not enough distinct recipes to fill every slot")
+			}
+			relaxed = true
+		}
+
+		for _, m := range best {
+			lastUsed[m.RecipeID] = day
+		}
+		totals := roundMacros(bestTotals)
+		content.Days = append(content.Days, models.PlanDay{Day: day, Meals: best, Totals: &totals})
+		report := reportDay(day, bestTotals, opts)
+		report.VarietyRelaxed = relaxed
+		reports = append(reports, report)
+	}
+
+	return content, reports, nil
+}
+
+// fitsSlot prefers explicit meal tags; untagged recipes are treated as
+// main meals, and light ones can also serve as snacks. Breakfast takes
+// untagged recipes only when there are no breakfast recipes at all.
+func fitsSlot(r models.Recipe, slot string) bool {
+	for _, t := range r.Tags {
+		if strings.EqualFold(t, slot) {
+			return true
+		}
+	}
+	if hasSlotTag(r) {
+		return false
+	}
+	switch slot {
+	case "snack":
+		return r.Macros.Calories > 0 && r.Macros.Calories <= 350
+	case "breakfast":
+		return false
+	}
+	return true
+}
+
+// hasSlotTag reports whether a recipe is tagged with a meal slot.
+func hasSlotTag(r models.Recipe) bool {
+	for _, t := range r.Tags {
+		for _, s := range DefaultSlots {
+			if strings.EqualFold(t, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pickRecipes draws one distinct recipe per slot. Unless relaxed, recipes
+// used within the no-repeat window are skipped.
+func pickRecipes(rng *rand.Rand, bySlot map[string][]models.Recipe, opts MealPlanOptions, lastUsed map[string]int, day int, relaxed bool) ([]models.Recipe, bool) {
+	picks := make([]models.Recipe, 0, len(opts.Slots))
+	chosen := map[string]bool{}
+	for _, slot := range opts.Slots {
+		pool := bySlot[slot]
+		found := false
+		for tries := 0; tries < len(pool)*2 && !found; tries++ {
+			r := pool[rng.Intn(len(pool))]
+			if chosen[r.ID] {
+				continue
+			}
+			if used, ok := lastUsed[r.ID]; ok && !relaxed && opts.NoRepeatDays > 0 && day-used < opts.NoRepeatDays {
+				continue
+			}
+			picks = append(picks, r)
+			chosen[r.ID] = true
+			found = true
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return picks, true
+}
+
+// portionMeals scales every meal by the same factor towards the calorie
+// target, rounded to quarter servings within sensible bounds.
+func portionMeals(picks []models.Recipe, slots []string, targetCalories float64) ([]models.PlanMeal, models.Macros) {
+	var base float64
+	for _, r := range picks {
+		base += r.Macros.Calories
+	}
+	scale := 1.0
+	if base > 0 && targetCalories > 0 {
+		scale = targetCalories / base
+	}
+
+	var totals models.Macros
+	meals := make([]models.PlanMeal, len(picks))
+	for i, r := range picks {
+		servings := math.Round(scale*4) / 4
+		servings = math.Max(minServings, math.Min(maxServings, servings))
+		meals[i] = models.PlanMeal{Slot: slots[i], RecipeID: r.ID, Name: r.Name, Servings: servings}
+		totals.Calories += r.Macros.Calories * servings
+		totals.Protein += r.Macros.Protein * servings
+		totals.Carbs += r.Macros.Carbs * servings
+		totals.Fat += r.Macros.Fat * servings
+		totals.Fiber += r.Macros.Fiber * servings
+	}
+	return meals, totals
+}
+
+// macroError weighs calories and protein above carbs and fat.
+func macroError(actual, target models.Macros) float64 {
+	rel := func(a, t float64) float64 {
+		if t <= 0 {
+			return 0
+		}
+		return math.Abs(a-t) / t
+	}
+	return 3*rel(actual.Calories, target.Calories) +
+		2*rel(actual.Protein, target.Protein) +
+		rel(actual.Carbs, target.Carbs) +
+		rel(actual.Fat, target.Fat)
+}
+
+func reportDay(day int, actual models.Macros, opts MealPlanOptions) DayReport {
+	report := DayReport{Day: day, Deviations: map[string]Deviation{}, WithinTolerance: true}
+	pairs := map[string][2]float64{
+		"calories": {actual.Calories, opts.Targets.Calories},
+		"protein":  {actual.Protein, opts.Targets.Protein},
+		"carbs":    {actual.Carbs, opts.Targets.Carbs},
+		"fat":      {actual.Fat, opts.Targets.Fat},
+	}
+	for name, p := range pairs {
+		if p[1] <= 0 {
+			continue
+		}
+		d := Deviation{
+			Target:  p[1],
+			Actual:  math.Round(p[0]*10) / 10,
+			Diff:    math.Round((p[0]-p[1])*10) / 10,
+			Percent: math.Round((p[0]-p[1])/p[1]*1000) / 10,
+		}
+		report.Deviations[name] = d
+		if math.Abs(p[0]-p[1])/p[1] > opts.Tolerance {
+			report.WithinTolerance = false
+		}
+	}
+	return report
+}
+
+func roundMacros(m models.Macros) models.Macros {
+	r := func(f float64) float64 { return math.Round(f*10) / 10 }
+	return models.Macros{Calories: math.Round(m.Calories), Protein: r(m.Protein), Carbs: r(m.Carbs), Fat: r(m.Fat), Fiber: r(m.Fiber)}
+}
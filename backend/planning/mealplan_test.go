@@ -0,0 +1,78 @@
+package planning
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func testRecipes() []models.Recipe {
+	var recipes []models.Recipe
+	for i := 0; i < 6; i++ {
+		recipes = append(recipes,
+			models.Recipe{ID: fmt.Sprintf("b%d", i), Name: "Breakfast", Tags: []string{"breakfast"}, Macros: models.Macros{Calories: 400 + float64(i*20), Protein: 25, Carbs: 45, Fat: 12}},
+			models.Recipe{ID: fmt.Sprintf("m%d", i), Name: "Main", Macros: models.Macros{Calories: 650 + float64(i*30), Protein: 45, Carbs: 70, Fat: 20}},
+			models.Recipe{ID: fmt.Sprintf("s%d", i), Name: "Snack", Tags: []string{"snack"}, Macros: models.Macros{Calories: 200, Protein: 15, Carbs: 20, Fat: 7}},
+		)
+	}
+	return recipes
+}
+
+func TestGenerateMealPlan(t *testing.T) {
+	targets := models.Macros{Calories: 2200, Protein: 140, Carbs: 230, Fat: 65}
+
+	content, reports, err := GenerateMealPlan(testRecipes(), MealPlanOptions{
+		Days:         7,
+		Targets:      targets,
+		Tolerance:    0.15,
+		NoRepeatDays: 2,
+		Seed:         42,
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, content.Days, 7)
+	assert.Len(t, reports, 7)
+
+	for i, day := range content.Days {
+		assert.Len(t, day.Meals, 4)
+		assert.Equal(t, "breakfast", day.Meals[0].Slot)
+		assert.Equal(t, "b", day.Meals[0].RecipeID[:1])
+		assert.Equal(t, "s", day.Meals[3].RecipeID[:1])
+		assert.True(t, reports[i].WithinTolerance, "day %d: %+v", i+1, reports[i].Deviations)
+
+		if i > 0 {
+			for _, m := range day.Meals {
+				for _, prev := range content.Days[i-1].Meals {
+					assert.NotEqual(t, prev.RecipeID, m.RecipeID, "recipe repeated on consecutive days")
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateMealPlan_NoRecipesForSlot(t *testing.T) {
+	recipes := []models.Recipe{{ID: "m", Name: "Main", Macros: models.Macros{Calories: 600}}}
+	_, _, err := GenerateMealPlan(recipes, MealPlanOptions{Targets: models.Macros{Calories: 2000}})
+	assert.EqualError(t, err, "no recipes available for snack")
+
+	recipes = []models.Recipe{{ID: "d", Name: "Dinner", Tags: []string{"dinner"}, Macros: models.Macros{Calories: 600}}}
+	_, _, err = GenerateMealPlan(recipes, MealPlanOptions{Targets: models.Macros{Calories: 2000}})
+	assert.EqualError(t, err, "no recipes available for breakfast")
+}
+
+func TestGenerateMealPlan_UntaggedBreakfast(t *testing.T) {
+	var recipes []models.Recipe
+	for i := 0; i < 4; i++ {
+		recipes = append(recipes, models.Recipe{ID: fmt.Sprintf("m%d", i), Name: "Main", Macros: models.Macros{Calories: 600, Protein: 40, Carbs: 60, Fat: 20}})
+	}
+	content, _, err := GenerateMealPlan(recipes, MealPlanOptions{
+		Days:    2,
+		Slots:   []string{"breakfast", "lunch", "dinner"},
+		Targets: models.Macros{Calories: 1800},
+		Seed:    1,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, content.Days[0].Meals, 3)
+}
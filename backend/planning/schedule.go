@@ -0,0 +1,129 @@
+package planning
+
+import (
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Occurrence is a single dated session produced by expanding a plan.
+type Occurrence struct {
+	Day   int
+	Date  time.Time
+	Title string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday,
+}
+
+// ParseWeekday accepts full or three-letter English day names.
+func ParseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for name, d := range weekdays {
+		if s == name || (len(s) == 3 && strings.HasPrefix(name, s)) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Expand turns a plan's training days into dated occurrences between from and
+// to inclusive, clipped to the plan's own start and end. Days that name a
+// weekday repeat weekly on it; otherwise the days form a cycle that starts on
+// planStart and advances one calendar day per plan day. Days without
+// exercises are rest days and produce nothing.
+func Expand(content models.PlanContent, planStart, planEnd, from, to time.Time) []Occurrence {
+	var out []Occurrence
+	eachPlanDay(content, planStart, planEnd, from, to, func(date time.Time, i int) {
+		d := content.Days[i]
+		if len(d.Exercises) == 0 {
+			return
+		}
+		n := d.Day
+		if n == 0 {
+			n = i + 1
+		}
+		out = append(out, Occurrence{Day: n, Date: date, Title: dayTitle(d)})
+	})
+	return out
+}
+
+// DatedDay is a plan day placed on a calendar date.
+type DatedDay struct {
+	Date time.Time
+	Day  models.PlanDay
+}
+
+// MealDays places the plan's days that contain meals on dates between from
+// and to, following the same rules as Expand.
+func MealDays(content models.PlanContent, planStart, planEnd, from, to time.Time) []DatedDay {
+	var out []DatedDay
+	eachPlanDay(content, planStart, planEnd, from, to, func(date time.Time, i int) {
+		if len(content.Days[i].Meals) > 0 {
+			out = append(out, DatedDay{Date: date, Day: content.Days[i]})
+		}
+	})
+	return out
+}
+
+// eachPlanDay calls fn with the index of every plan day falling on each date
+// in range.
+func eachPlanDay(content models.PlanContent, planStart, planEnd, from, to time.Time, fn func(date time.Time, i int)) {
+	if !planStart.IsZero() && from.Before(planStart) {
+		from = planStart
+	}
+	if !planEnd.IsZero() && to.After(planEnd) {
+		to = planEnd
+	}
+	if to.Before(from) || len(content.Days) == 0 {
+		return
+	}
+
+	byWeekday := map[time.Weekday][]int{}
+	weekly := false
+	for i, d := range content.Days {
+		if wd, ok := ParseWeekday(d.Weekday); ok {
+			byWeekday[wd] = append(byWeekday[wd], i)
+			weekly = true
+		}
+	}
+
+	anchor := planStart
+	if anchor.IsZero() {
+		anchor = from
+	}
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		if weekly {
+			for _, i := range byWeekday[date.Weekday()] {
+				fn(date, i)
+			}
+			continue
+		}
+		fn(date, daysBetween(anchor, date)%len(content.Days))
+	}
+}
+
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+func dayTitle(d models.PlanDay) string {
+	if d.Title != "" {
+		return d.Title
+	}
+	names := make([]string, 0, len(d.Exercises))
+	for _, ex := range d.Exercises {
+		names = append(names, ex.Name)
+	}
+	if len(names) > 3 {
+		names = append(names[:3], "...")
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,45 @@
+package planning
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestExpand_Weekdays(t *testing.T) {
+	content := models.PlanContent{Days: []models.PlanDay{
+		{Day: 1, Weekday: "Mon", Title: "Upper", Exercises: []models.PlanExercise{{Name: "Bench"}}},
+		{Day: 2, Weekday: "thursday", Title: "Lower", Exercises: []models.PlanExercise{{Name: "Squat"}}},
+	}}
+
+	// 2026-10-19 is a Monday.
+	got := Expand(content, date("2026-10-19"), time.Time{}, date("2026-10-01"), date("2026-10-29"))
+
+	assert.Equal(t, []Occurrence{
+		{Day: 1, Date: date("2026-10-19"), Title: "Upper"},
+		{Day: 2, Date: date("2026-10-22"), Title: "Lower"},
+		{Day: 1, Date: date("2026-10-26"), Title: "Upper"},
+		{Day: 2, Date: date("2026-10-29"), Title: "Lower"},
+	}, got)
+}
+
+func TestExpand_CycleSkipsRestDays(t *testing.T) {
+	content := models.PlanContent{Days: []models.PlanDay{
+		{Exercises: []models.PlanExercise{{Name: "Squat"}, {Name: "Row"}}},
+		{Title: "Rest"},
+	}}
+
+	got := Expand(content, date("2026-10-01"), date("2026-10-04"), date("2026-09-01"), date("2026-12-01"))
+
+	assert.Equal(t, []Occurrence{
+		{Day: 1, Date: date("2026-10-01"), Title: "Squat, Row"},
+		{Day: 1, Date: date("2026-10-03"), Title: "Squat, Row"},
+	}, got)
+}
@@ -0,0 +1,111 @@
+package recipeimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Column names accepted for each recipe field, lowercased.
+var csvColumns = map[string]string{
+	"name": "name", "title": "name", "recipe": "name",
+	"description": "description", "summary": "description",
+	"ingredients":  "ingredients",
+	"instructions": "instructions", "directions": "instructions", "method": "instructions", "steps": "instructions",
+	"servings": "servings", "yield": "servings", "serves": "servings",
+	"tags": "tags", "categories": "tags", "keywords": "tags",
+	"calories": "calories", "protein": "protein", "carbs": "carbs", "carbohydrates": "carbs", "fat": "fat", "fiber": "fiber", "fibre": "fiber",
+}
+
+// ParseCSV reads one recipe per row. The header names the columns (see
+// csvColumns); ingredients and instructions hold one item per line or are
+// separated by "|", and tags are comma or semicolon separated. Columns with
+// other names are reported as unmapped on every row that fills them.
+func ParseCSV(r io.Reader) ([]Result, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	fields := make([]string, len(header))
+	hasName := false
+	for i, h := range header {
+		h = strings.TrimPrefix(h, "\ufeff")
+		header[i] = strings.TrimSpace(h)
+		fields[i] = csvColumns[strings.ToLower(header[i])]
+		hasName = hasName || fields[i] == "name"
+	}
+	if !hasName {
+		return nil, fmt.Errorf("CSV needs a name or title column")
+	}
+
+	var results []Result
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		var res Result
+		rec := &res.Recipe
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if i >= len(fields) || value == "" {
+				continue
+			}
+			var bad bool
+			switch fields[i] {
+			case "name":
+				rec.Name = value
+			case "description":
+				rec.Description = value
+			case "ingredients":
+				rec.Ingredients = splitItems(value)
+			case "instructions":
+				rec.Instructions = splitItems(value)
+			case "servings":
+				rec.Servings = parseYield(value)
+			case "tags":
+				rec.Tags = splitTags(value)
+			case "calories":
+				rec.Macros.Calories, bad = csvNumber(value)
+			case "protein":
+				rec.Macros.Protein, bad = csvNumber(value)
+			case "carbs":
+				rec.Macros.Carbs, bad = csvNumber(value)
+			case "fat":
+				rec.Macros.Fat, bad = csvNumber(value)
+			case "fiber":
+				rec.Macros.Fiber, bad = csvNumber(value)
+			default:
+				res.Unmapped = append(res.Unmapped, header[i])
+			}
+			if bad {
+				res.Error = fmt.Sprintf("line %d: %s is not a number", line, header[i])
+			}
+		}
+		if res.Error == "" && rec.Name == "" {
+			res.Error = fmt.Sprintf("line %d: recipe has no name", line)
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func splitItems(s string) []string {
+	return splitLines(strings.ReplaceAll(s, "|", "\n"))
+}
+
+func csvNumber(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "kcal"), "g")), 64)
+	if err != nil {
+		return 0, true
+	}
+	return f, false
+}
@@ -0,0 +1,245 @@
+package recipeimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+var ldScript = regexp.MustCompile(`(?is)<script[^>]*type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+// Keys that describe the JSON-LD node itself rather than the recipe.
+var ldIgnored = map[string]bool{"@context": true, "@type": true, "@id": true}
+
+// ParseJSONLD reads schema.org Recipe objects from a JSON-LD document or
+// from the ld+json script blocks of an HTML page. Recipes nested in @graph
+// or in arrays are found too.
+func ParseJSONLD(data []byte) ([]Result, error) {
+	var blocks [][]byte
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "<") {
+		for _, m := range ldScript.FindAllSubmatch(data, -1) {
+			blocks = append(blocks, m[1])
+		}
+		if len(blocks) == 0 {
+			return nil, fmt.Errorf("no JSON-LD found in the page")
+		}
+	} else {
+		blocks = [][]byte{data}
+	}
+
+	var results []Result
+	for _, b := range blocks {
+		var doc interface{}
+		if err := json.Unmarshal(b, &doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON-LD: %v", err)
+		}
+		for _, node := range findRecipes(doc) {
+			results = append(results, recipeFromLD(node))
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no schema.org Recipe found")
+	}
+	return results, nil
+}
+
+func findRecipes(v interface{}) []map[string]interface{} {
+	var found []map[string]interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			found = append(found, findRecipes(item)...)
+		}
+	case map[string]interface{}:
+		if isType(v["@type"], "Recipe") {
+			return []map[string]interface{}{v}
+		}
+		if graph, ok := v["@graph"]; ok {
+			found = append(found, findRecipes(graph)...)
+		}
+	}
+	return found
+}
+
+func isType(t interface{}, want string) bool {
+	switch t := t.(type) {
+	case string:
+		return t == want || strings.HasSuffix(t, "/"+want)
+	case []interface{}:
+		for _, item := range t {
+			if isType(item, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func recipeFromLD(node map[string]interface{}) Result {
+	var res Result
+	rec := &res.Recipe
+	// Sorted keys keep the order of tags gathered from several fields
+	// stable.
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := node[key]
+		if ldIgnored[key] || isEmpty(value) {
+			continue
+		}
+		switch key {
+		case "name":
+			rec.Name = ldText(value)
+		case "description":
+			rec.Description = ldText(value)
+		case "recipeIngredient", "ingredients":
+			rec.Ingredients = ldStrings(value)
+		case "recipeInstructions":
+			rec.Instructions = ldInstructions(value)
+		case "recipeYield", "yield":
+			for _, y := range ldStrings(value) {
+				if n := parseYield(y); n > 0 {
+					rec.Servings = n
+					break
+				}
+			}
+		case "keywords":
+			for _, k := range ldStrings(value) {
+				rec.Tags = appendTags(rec.Tags, splitTags(k)...)
+			}
+		case "recipeCategory", "recipeCuisine", "suitableForDiet":
+			for _, c := range ldStrings(value) {
+				rec.Tags = appendTags(rec.Tags, dietName(c))
+			}
+		case "nutrition":
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				res.Unmapped = append(res.Unmapped, key)
+				continue
+			}
+			for _, k := range ldNutrition(obj, &rec.Macros) {
+				res.Unmapped = append(res.Unmapped, "nutrition."+k)
+			}
+		default:
+			res.Unmapped = append(res.Unmapped, key)
+		}
+	}
+	return res
+}
+
+// dietName turns schema.org diets such as "https://schema.org/VeganDiet"
+// into plain tags.
+func dietName(s string) string {
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSuffix(s, "Diet")
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// ldText returns a value as plain text, decoding HTML entities that sites
+// often leave in their JSON-LD.
+func ldText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(html.UnescapeString(v))
+	case float64:
+		return fmt.Sprint(v)
+	case map[string]interface{}:
+		if t, ok := v["text"]; ok {
+			return ldText(t)
+		}
+		if n, ok := v["name"]; ok {
+			return ldText(n)
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return ldText(v[0])
+		}
+	}
+	return ""
+}
+
+func ldStrings(v interface{}) []string {
+	list := []string{}
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s := ldText(item); s != "" {
+				list = append(list, s)
+			}
+		}
+	default:
+		if s := ldText(v); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// ldInstructions flattens the instruction shapes in the wild: one text
+// blob, a list of strings, HowToStep objects and HowToSections of steps.
+func ldInstructions(v interface{}) []string {
+	steps := []string{}
+	switch v := v.(type) {
+	case string:
+		steps = append(steps, splitLines(html.UnescapeString(v))...)
+	case []interface{}:
+		for _, item := range v {
+			steps = append(steps, ldInstructions(item)...)
+		}
+	case map[string]interface{}:
+		if items, ok := v["itemListElement"]; ok {
+			steps = append(steps, ldInstructions(items)...)
+		} else if s := ldText(v); s != "" {
+			steps = append(steps, s)
+		}
+	}
+	return steps
+}
+
+// ldNutrition reads a NutritionInformation object into macros and returns
+// the properties it had no field for.
+func ldNutrition(obj map[string]interface{}, m *models.Macros) []string {
+	var unmapped []string
+	for key, value := range obj {
+		if ldIgnored[key] || isEmpty(value) {
+			continue
+		}
+		amount := parseAmount(ldText(value))
+		switch key {
+		case "calories":
+			m.Calories = amount
+		case "proteinContent":
+			m.Protein = amount
+		case "carbohydrateContent":
+			m.Carbs = amount
+		case "fatContent":
+			m.Fat = amount
+		case "fiberContent":
+			m.Fiber = amount
+		default:
+			unmapped = append(unmapped, key)
+		}
+	}
+	return unmapped
+}
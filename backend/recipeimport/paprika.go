@@ -0,0 +1,118 @@
+package recipeimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Paprika bookkeeping fields that carry nothing worth reporting.
+var paprikaIgnored = map[string]bool{
+	"uid": true, "hash": true, "photo_hash": true, "created": true, "scale": true, "in_trash": true,
+	"is_pinned": true, "on_favorites": true, "on_grocery_list": true, "photo": true, "photo_large": true,
+}
+
+// Limits on what an archive may unpack to, so a small upload cannot expand
+// into gigabytes. Entries hold one recipe, its photo included.
+const (
+	maxEntrySize      = 16 << 20
+	maxPaprikaEntries = 5000
+)
+
+// readLimited reads r whole, failing once it exceeds maxEntrySize.
+func readLimited(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxEntrySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxEntrySize {
+		return nil, fmt.Errorf("recipe is larger than %d MB unpacked", maxEntrySize>>20)
+	}
+	return data, nil
+}
+
+// ParsePaprika reads a Paprika export: either a .paprikarecipes zip archive
+// of gzipped recipes or a single gzipped .paprikarecipe file.
+func ParsePaprika(data []byte) ([]Result, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		res, err := paprikaRecipe(data)
+		if err != nil {
+			return nil, err
+		}
+		return []Result{res}, nil
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Paprika archive: %v", err)
+	}
+	if len(zr.File) > maxPaprikaEntries {
+		return nil, fmt.Errorf("Paprika archive has more than %d entries", maxPaprikaEntries)
+	}
+	var results []Result
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		entry, err := readLimited(rc)
+		rc.Close()
+		var res Result
+		if err == nil {
+			res, err = paprikaRecipe(entry)
+		}
+		if err != nil {
+			res = Result{Error: fmt.Sprintf("%s: %v", f.Name, err)}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func paprikaRecipe(data []byte) (Result, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return Result{}, err
+		}
+		if data, err = readLimited(zr); err != nil {
+			return Result{}, err
+		}
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Result{}, fmt.Errorf("invalid Paprika recipe: %v", err)
+	}
+
+	var res Result
+	rec := &res.Recipe
+	for key, value := range fields {
+		if paprikaIgnored[key] || isEmpty(value) || value == false || value == 0.0 {
+			continue
+		}
+		switch key {
+		case "name":
+			rec.Name = ldText(value)
+		case "description":
+			rec.Description = ldText(value)
+		case "ingredients":
+			rec.Ingredients = splitLines(ldText(value))
+		case "directions":
+			rec.Instructions = splitLines(ldText(value))
+		case "servings":
+			rec.Servings = parseYield(ldText(value))
+		case "categories":
+			rec.Tags = appendTags(rec.Tags, ldStrings(value)...)
+		default:
+			res.Unmapped = append(res.Unmapped, key)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,160 @@
+// Package recipeimport reads recipes exported from other apps and web pages:
+// schema.org Recipe JSON-LD, Paprika exports and plain CSV.
+package recipeimport
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Supported formats.
+const (
+	JSONLD  = "jsonld"
+	Paprika = "paprika"
+	CSV     = "csv"
+)
+
+// Result is one recipe read from the input. Unmapped names the source
+// fields that had a value but no place in the recipe model; Error is set
+// when the entry could not be turned into a recipe at all.
+type Result struct {
+	Recipe   models.Recipe `json:"recipe"`
+	Unmapped []string      `json:"unmapped"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Detect guesses the format of data from its first bytes: zip and gzip
+// archives are Paprika exports, JSON and HTML are JSON-LD, anything else is
+// taken for CSV.
+func Detect(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return Paprika
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[' || trimmed[0] == '<') {
+		return JSONLD
+	}
+	return CSV
+}
+
+// Parse reads every recipe in data. An error means the input as a whole
+// could not be read; problems with single recipes are reported on their
+// Result.
+func Parse(format string, data []byte) ([]Result, error) {
+	if format == "" {
+		format = Detect(data)
+	}
+	var results []Result
+	var err error
+	switch format {
+	case JSONLD:
+		results, err = ParseJSONLD(data)
+	case Paprika:
+		results, err = ParsePaprika(data)
+	case CSV:
+		results, err = ParseCSV(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		finish(&results[i])
+	}
+	return results, nil
+}
+
+// finish fills defaults and flags entries that cannot be saved.
+func finish(res *Result) {
+	rec := &res.Recipe
+	rec.Name = strings.TrimSpace(rec.Name)
+	if rec.Name == "" && res.Error == "" {
+		res.Error = "recipe has no name"
+	}
+	if rec.Ingredients == nil {
+		rec.Ingredients = []string{}
+	}
+	if rec.Instructions == nil {
+		rec.Instructions = []string{}
+	}
+	if rec.Tags == nil {
+		rec.Tags = []string{}
+	}
+	if rec.Servings <= 0 {
+		rec.Servings = 1
+	}
+	if res.Unmapped == nil {
+		res.Unmapped = []string{}
+	}
+	sort.Strings(res.Unmapped)
+}
+
+var leadingNumber = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// parseYield reads servings from yields such as "4", "Serves 4-6" or
+// "12 cookies", taking the first number.
+func parseYield(s string) int {
+	m := leadingNumber.FindString(s)
+	if m == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.Replace(m, ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return int(f + 0.5)
+}
+
+// parseAmount reads the number from nutrition values like "250 kcal" or
+// "12.5 g".
+func parseAmount(s string) float64 {
+	m := leadingNumber.FindString(s)
+	f, _ := strconv.ParseFloat(strings.Replace(m, ",", ".", 1), 64)
+	return f
+}
+
+// splitLines splits multi-line text into trimmed, non-empty lines.
+func splitLines(s string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitTags splits comma or semicolon separated keywords.
+func splitTags(s string) []string {
+	tags := []string{}
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+func appendTags(tags []string, more ...string) []string {
+	for _, t := range more {
+		dup := false
+		for _, existing := range tags {
+			if strings.EqualFold(existing, t) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,150 @@
+package recipeimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const recipePage = `<!doctype html>
+<html><head>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "WebPage", "name": "Best Chili"},
+  {"@type": ["Recipe"], "name": "Best Chili &amp; Rice",
+   "recipeYield": ["4", "4 servings"],
+   "keywords": "chili, weeknight",
+   "recipeCategory": "Dinner",
+   "suitableForDiet": "https://schema.org/GlutenFreeDiet",
+   "recipeIngredient": ["500 g beef mince", "1 can kidney beans"],
+   "recipeInstructions": [
+     {"@type": "HowToSection", "name": "Prep", "itemListElement": [{"@type": "HowToStep", "text": "Brown the beef."}]},
+     {"@type": "HowToStep", "text": "Add the beans and simmer."}
+   ],
+   "nutrition": {"@type": "NutritionInformation", "calories": "540 kcal", "proteinContent": "38 g", "sodiumContent": "900 mg"},
+   "totalTime": "PT45M",
+   "image": "https://example.com/chili.jpg",
+   "author": {"@type": "Person", "name": "Ana"}}
+]}
+</script>
+</head><body></body></html>`
+
+func TestParseJSONLD(t *testing.T) {
+	assert.Equal(t, JSONLD, Detect([]byte(recipePage)))
+
+	results, err := Parse("", []byte(recipePage))
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	rec := results[0].Recipe
+	assert.Equal(t, "Best Chili & Rice", rec.Name)
+	assert.Equal(t, 4, rec.Servings)
+	assert.Equal(t, []string{"500 g beef mince", "1 can kidney beans"}, rec.Ingredients)
+	assert.Equal(t, []string{"Brown the beef.", "Add the beans and simmer."}, rec.Instructions)
+	assert.Equal(t, []string{"chili", "weeknight", "Dinner", "GlutenFree"}, rec.Tags)
+	assert.Equal(t, 540.0, rec.Macros.Calories)
+	assert.Equal(t, 38.0, rec.Macros.Protein)
+	assert.Equal(t, []string{"author", "image", "nutrition.sodiumContent", "totalTime"}, results[0].Unmapped)
+
+	_, err = Parse(JSONLD, []byte(`{"@type": "Person", "name": "Ana"}`))
+	assert.EqualError(t, err, "no schema.org Recipe found")
+}
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestParsePaprika(t *testing.T) {
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	entries := map[string]string{
+		"Pancakes.paprikarecipe": `{"uid": "A1", "name": "Pancakes", "servings": "Makes 8 pancakes", "ingredients": "1 cup flour\n2 eggs\n\n1 cup milk",
+			"directions": "Whisk.\r\nFry.", "categories": ["Breakfast"], "rating": 0, "source_url": "https://example.com", "notes": "", "prep_time": "10 min"}`,
+		"Broken.paprikarecipe": `{"name":`,
+	}
+	for _, name := range []string{"Pancakes.paprikarecipe", "Broken.paprikarecipe"} {
+		w, _ := zw.Create(name)
+		w.Write(gzipped(t, entries[name]))
+	}
+	require.NoError(t, zw.Close())
+
+	assert.Equal(t, Paprika, Detect(archive.Bytes()))
+	results, err := Parse("", archive.Bytes())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	rec := results[0].Recipe
+	assert.Equal(t, "Pancakes", rec.Name)
+	assert.Equal(t, 8, rec.Servings)
+	assert.Equal(t, []string{"1 cup flour", "2 eggs", "1 cup milk"}, rec.Ingredients)
+	assert.Equal(t, []string{"Whisk.", "Fry."}, rec.Instructions)
+	assert.Equal(t, []string{"Breakfast"}, rec.Tags)
+	assert.Equal(t, []string{"prep_time", "source_url"}, results[0].Unmapped)
+	assert.True(t, strings.HasPrefix(results[1].Error, "Broken.paprikarecipe: invalid Paprika recipe"))
+
+	single, err := Parse("", gzipped(t, entries["Pancakes.paprikarecipe"]))
+	require.NoError(t, err)
+	assert.Equal(t, "Pancakes", single[0].Recipe.Name)
+}
+
+func TestParsePaprika_Limits(t *testing.T) {
+	bomb := gzipped(t, strings.Repeat(" ", maxEntrySize+1))
+	_, err := Parse("", bomb)
+	assert.EqualError(t, err, "recipe is larger than 16 MB unpacked")
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	w, _ := zw.Create("Big.paprikarecipe")
+	w.Write(bomb)
+	require.NoError(t, zw.Close())
+	results, err := Parse("", archive.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "Big.paprikarecipe: recipe is larger than 16 MB unpacked", results[0].Error)
+
+	archive.Reset()
+	zw = zip.NewWriter(&archive)
+	for i := 0; i <= maxPaprikaEntries; i++ {
+		zw.Create(fmt.Sprintf("%d.paprikarecipe", i))
+	}
+	require.NoError(t, zw.Close())
+	_, err = Parse("", archive.Bytes())
+	assert.EqualError(t, err, "Paprika archive has more than 5000 entries")
+}
+
+func TestParseCSV(t *testing.T) {
+	data := "Title,Ingredients,Method,Serves,Tags,Calories,Rating\n" +
+		"Overnight Oats,\"50 g oats|150 ml milk\",Mix and chill.,2,\"breakfast; quick\",320 kcal,5\n" +
+		",1 apple,,,,,\n" +
+		"Toast,1 slice bread,Toast it.,,,lots,\n"
+
+	assert.Equal(t, CSV, Detect([]byte(data)))
+	results, err := Parse("", []byte(data))
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	rec := results[0].Recipe
+	assert.Equal(t, "Overnight Oats", rec.Name)
+	assert.Equal(t, []string{"50 g oats", "150 ml milk"}, rec.Ingredients)
+	assert.Equal(t, []string{"Mix and chill."}, rec.Instructions)
+	assert.Equal(t, 2, rec.Servings)
+	assert.Equal(t, []string{"breakfast", "quick"}, rec.Tags)
+	assert.Equal(t, 320.0, rec.Macros.Calories)
+	assert.Equal(t, []string{"Rating"}, results[0].Unmapped)
+
+	assert.Equal(t, "line 3: recipe has no name", results[1].Error)
+	assert.Equal(t, "line 4: Calories is not a number", results[2].Error)
+	assert.Equal(t, 1, results[2].Recipe.Servings)
+
+	_, err = Parse(CSV, []byte("ingredients\n1 egg\n"))
+	assert.EqualError(t, err, "CSV needs a name or title column")
+}
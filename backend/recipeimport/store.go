@@ -0,0 +1,39 @@
+package recipeimport
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/dietary"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+)
+
+// Save stores an imported recipe as the recipes API would: diet tags are
+// derived from the ingredients and nutrition is computed where the foods
+// are known. userID owns the recipe, or is empty for catalog recipes. It
+// returns the new recipe's ID.
+func Save(userID string, rec models.Recipe) (string, error) {
+	rec.ID = uuid.New().String()
+	rec.Tags = dietary.ApplyTags(rec.Tags, dietary.Analyze(rec.Ingredients))
+
+	ingredients, _ := json.Marshal(rec.Ingredients)
+	instructions, _ := json.Marshal(rec.Instructions)
+	macros, _ := json.Marshal(rec.Macros)
+	tags, _ := json.Marshal(rec.Tags)
+	var owner interface{}
+	if userID != "" {
+		owner = userID
+	}
+	_, err := db.DB.Exec(`INSERT INTO recipes (id, user_id, name, description, ingredients, instructions, macros, tags, servings) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.ID, owner, rec.Name, rec.Description, string(ingredients), string(instructions), string(macros), string(tags), rec.Servings)
+	if err != nil {
+		return "", err
+	}
+
+	if _, _, err := nutrition.RecomputeRecipe(rec.ID); err != nil {
+		return rec.ID, err
+	}
+	return rec.ID, nil
+}
@@ -0,0 +1,387 @@
+// Package reminders turns users' reminder rules into notifications.
+//
+// A rule names local times of day and weekdays, read in the user's
+// timezone. Each rule stores the next time it matches, in UTC; the
+// scheduler sends the rules that have come due and moves them on to
+// their next match. Before sending, the rule's kind can check whether the
+// reminder is still needed, so a weigh-in reminder stays quiet on a day
+// with a weight entry. Times are read through clock.Now, so tests can
+// drive the scheduler with a fake clock.
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/notify"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+// Custom reminders are always sent; other kinds are registered by the
+// code that knows when they are needed.
+const Custom = "custom"
+
+const (
+	// Occurrences missed by more than this, say while the server was
+	// down, are skipped rather than sent late.
+	maxLateness = time.Hour
+	// How often Run looks for due reminders.
+	pollInterval = 30 * time.Second
+	// Reminders sent per pass.
+	batchSize = 500
+	// Limits on a rule.
+	maxTimes   = 24
+	maxTitle   = 100
+	maxMessage = 500
+)
+
+// Weekdays are the day names rules use, indexed by time.Weekday.
+var Weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Kind is a type of reminder with its defaults.
+type Kind struct {
+	Name    string `json:"name"`
+	Title   string `json:"title"`
+	Message string `json:"message,omitempty"`
+	// URL is where the notification takes the user.
+	URL string `json:"url,omitempty"`
+	// Check decides whether the reminder is needed on date, the user's
+	// local YYYY-MM-DD, and may return a message to send instead of the
+	// rule's. Without a check the reminder is always sent.
+	Check func(userID, date string) (send bool, message string, err error) `json:"-"`
+}
+
+var registry = map[string]Kind{}
+
+// Register makes a kind of reminder available to rules. Kinds are fixed
+// once the server is up, so a name used twice is a bug and panics.
+func Register(k Kind) {
+	if _, ok := registry[k.Name]; ok {
+		panic("reminders: duplicate kind " + k.Name)
+	}
+	registry[k.Name] = k
+}
+
+// Lookup returns a registered kind.
+func Lookup(name string) (Kind, bool) {
+	k, ok := registry[name]
+	return k, ok
+}
+
+// Kinds lists the registered kinds by name.
+func Kinds() []Kind {
+	kinds := make([]Kind, 0, len(registry))
+	for _, k := range registry {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Name < kinds[j].Name })
+	return kinds
+}
+
+func init() {
+	Register(Kind{Name: Custom, Title: "Reminder"})
+}
+
+// Validate checks a rule and tidies it: the title defaults to the kind's,
+// times are sorted and days put in week order, without repeats.
+func Validate(r *models.Reminder) error {
+	k, ok := Lookup(r.Kind)
+	if !ok {
+		return fmt.Errorf("unknown reminder kind %q", r.Kind)
+	}
+	r.Title = strings.TrimSpace(r.Title)
+	if r.Title == "" {
+		r.Title = k.Title
+	}
+	if len(r.Title) > maxTitle {
+		return fmt.Errorf("title must be at most %d characters", maxTitle)
+	}
+	if len(r.Message) > maxMessage {
+		return fmt.Errorf("message must be at most %d characters", maxMessage)
+	}
+
+	if len(r.Times) == 0 || len(r.Times) > maxTimes {
+		return fmt.Errorf("give between 1 and %d times", maxTimes)
+	}
+	times := map[string]bool{}
+	for _, s := range r.Times {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q; use HH:MM", s)
+		}
+		times[t.Format("15:04")] = true
+	}
+	r.Times = r.Times[:0]
+	for s := range times {
+		r.Times = append(r.Times, s)
+	}
+	sort.Strings(r.Times)
+
+	days := map[string]bool{}
+	for _, d := range r.Days {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if weekday(d) < 0 {
+			return fmt.Errorf("invalid day %q; use mon, tue, ... sun", d)
+		}
+		days[d] = true
+	}
+	r.Days = []string{}
+	for _, d := range Weekdays {
+		if days[d] {
+			r.Days = append(r.Days, d)
+		}
+	}
+
+	seen := map[string]bool{}
+	channels := []string{}
+	for _, c := range r.Channels {
+		if !notify.Known(c) {
+			return fmt.Errorf("unknown channel %q; use %s", c, strings.Join(notify.Channels(), " or "))
+		}
+		if !seen[c] {
+			seen[c] = true
+			channels = append(channels, c)
+		}
+	}
+	r.Channels = channels
+	return nil
+}
+
+func weekday(name string) int {
+	for i, d := range Weekdays {
+		if d == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// NextRun returns the first time after after that r matches in loc. On a
+// day the clocks go forward, a time in the gap falls an hour later.
+func NextRun(r models.Reminder, loc *time.Location, after time.Time) (time.Time, bool) {
+	days := map[int]bool{}
+	for _, d := range r.Days {
+		days[weekday(d)] = true
+	}
+	local := after.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, 12, 0, 0, 0, loc)
+		if len(days) > 0 && !days[int(day.Weekday())] {
+			continue
+		}
+		for _, s := range r.Times {
+			t, err := time.Parse("15:04", s)
+			if err != nil {
+				continue
+			}
+			at := time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if at.After(after) {
+				return at.UTC(), true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// Columns are the reminder columns Scan reads, from reminders r.
+const Columns = `r.id, r.user_id, r.kind, r.title, COALESCE(r.message, ''), r.times, r.days, r.channels, r.active,
+	r.next_run_at, r.last_run_at, r.created_at, r.updated_at`
+
+// Scan reads a reminder selected with Columns, and its user's ID.
+func Scan(row interface{ Scan(...interface{}) error }) (models.Reminder, string, error) {
+	var r models.Reminder
+	var userID, times, days, channels string
+	var next, last *string
+	err := row.Scan(&r.ID, &userID, &r.Kind, &r.Title, &r.Message, &times, &days, &channels, &r.Active,
+		&next, &last, &r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return r, userID, err
+	}
+	json.Unmarshal([]byte(times), &r.Times)
+	json.Unmarshal([]byte(days), &r.Days)
+	json.Unmarshal([]byte(channels), &r.Channels)
+	if r.Days == nil {
+		r.Days = []string{}
+	}
+	if r.Channels == nil {
+		r.Channels = []string{}
+	}
+	for _, p := range []struct {
+		s  *string
+		to **time.Time
+	}{{next, &r.NextRunAt}, {last, &r.LastRunAt}} {
+		if p.s != nil {
+			if t, err := time.Parse(time.RFC3339, *p.s); err == nil {
+				*p.to = &t
+			}
+		}
+	}
+	return r, userID, nil
+}
+
+// Schedule sets when an active reminder next runs, from now in the
+// user's timezone; paused reminders never do.
+func Schedule(userID string, r *models.Reminder) {
+	r.NextRunAt = nil
+	if !r.Active {
+		return
+	}
+	if next, ok := NextRun(*r, utils.UserLocation(userID), clock.Now()); ok {
+		r.NextRunAt = &next
+	}
+}
+
+// Reschedule moves all the user's active reminders to their next match,
+// as after a change of timezone.
+func Reschedule(userID string) error {
+	rows, err := db.DB.Query(`SELECT `+Columns+` FROM reminders r WHERE r.user_id = ? AND r.active = 1`, userID)
+	if err != nil {
+		return err
+	}
+	var list []models.Reminder
+	for rows.Next() {
+		r, _, err := Scan(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		list = append(list, r)
+	}
+	rows.Close()
+	for _, r := range list {
+		Schedule(userID, &r)
+		if _, err := db.DB.Exec(`UPDATE reminders SET next_run_at = ? WHERE id = ?`, nextRunValue(r), r.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nextRunValue(r models.Reminder) interface{} {
+	if r.NextRunAt == nil {
+		return nil
+	}
+	return utils.FormatTime(*r.NextRunAt)
+}
+
+// Start sends reminders as they come due until ctx is done. The channel
+// it returns is closed once it has stopped, so shutdown can wait for a
+// batch being sent.
+func Start(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := SendDue(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("reminders: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return done
+}
+
+// SendDue sends every reminder that has come due, moves each on to its
+// next match, and returns how many notifications it sent.
+func SendDue(ctx context.Context) (int, error) {
+	now := clock.Now()
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+Columns+` FROM reminders r WHERE r.active = 1 AND r.next_run_at <= ?
+		ORDER BY r.next_run_at LIMIT ?`, utils.FormatTime(now), batchSize)
+	if err != nil {
+		return 0, err
+	}
+	type due struct {
+		r      models.Reminder
+		userID string
+	}
+	var list []due
+	for rows.Next() {
+		r, userID, err := Scan(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		list = append(list, due{r, userID})
+	}
+	rows.Close()
+
+	sent := 0
+	for _, d := range list {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := fire(d.userID, d.r, now)
+		if err != nil {
+			log.Printf("reminders: reminder %s: %v", d.r.ID, err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// fire moves a due reminder on and sends it, unless it is too late or its
+// kind finds it is not needed. It reports whether it sent anything.
+func fire(userID string, r models.Reminder, now time.Time) (bool, error) {
+	loc := utils.UserLocation(userID)
+	at := *r.NextRunAt
+	var next interface{}
+	if t, ok := NextRun(r, loc, now); ok {
+		next = utils.FormatTime(t)
+	}
+	// Claim the occurrence, so it is sent once even if passes overlap.
+	res, err := db.DB.Exec(`UPDATE reminders SET next_run_at = ?, last_run_at = ? WHERE id = ? AND next_run_at = ?`,
+		next, utils.FormatTime(now), r.ID, utils.FormatTime(at))
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 || now.Sub(at) > maxLateness {
+		return false, nil
+	}
+
+	k, ok := Lookup(r.Kind)
+	if !ok {
+		return false, fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	date := at.In(loc).Format("2006-01-02")
+	message := r.Message
+	if message == "" {
+		message = k.Message
+	}
+	if k.Check != nil {
+		send, m, err := k.Check(userID, date)
+		if err != nil || !send {
+			return false, err
+		}
+		if m != "" && r.Message == "" {
+			message = m
+		}
+	}
+
+	data, _ := json.Marshal(map[string]string{"date": date, "scheduled_for": utils.FormatTime(at)})
+	_, err = notify.Send(userID, models.Notification{
+		Kind: r.Kind, Title: r.Title, Body: message, URL: k.URL, ReminderID: r.ID, Data: data,
+	}, r.Channels)
+	return err == nil, err
+}
+
+// Get loads one of the user's reminders.
+func Get(userID, id string) (models.Reminder, error) {
+	r, _, err := Scan(db.DB.QueryRow(`SELECT `+Columns+` FROM reminders r WHERE r.id = ? AND r.user_id = ?`, id, userID))
+	return r, err
+}
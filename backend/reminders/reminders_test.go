@@ -0,0 +1,169 @@
+package reminders
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+// skipped lists the dates the "test.check" kind was asked about.
+var skipped []string
+
+func init() {
+	Register(Kind{Name: "test.check", Title: "Checked", Check: func(userID, date string) (bool, string, error) {
+		if date == "2025-03-04" {
+			skipped = append(skipped, date)
+			return false, "", nil
+		}
+		return true, "Checked on " + date, nil
+	}})
+}
+
+func TestValidate(t *testing.T) {
+	r := models.Reminder{Kind: Custom, Times: []string{"21:00", "7:30", "21:00"}, Days: []string{"Sun", "mon", "sun"}, Channels: []string{"push", "push"}}
+	require.NoError(t, Validate(&r))
+	assert.Equal(t, "Reminder", r.Title)
+	assert.Equal(t, []string{"07:30", "21:00"}, r.Times)
+	assert.Equal(t, []string{"sun", "mon"}, r.Days)
+	assert.Equal(t, []string{"push"}, r.Channels)
+
+	for _, bad := range []models.Reminder{
+		{Kind: "nap", Times: []string{"12:00"}},
+		{Kind: Custom},
+		{Kind: Custom, Times: []string{"25:00"}},
+		{Kind: Custom, Times: []string{"12:00"}, Days: []string{"someday"}},
+		{Kind: Custom, Times: []string{"12:00"}, Channels: []string{"pigeon"}},
+	} {
+		assert.Error(t, Validate(&bad), "%+v", bad)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+	r := models.Reminder{Times: []string{"01:30", "08:00"}}
+
+	// Later the same day, then the first time the next day.
+	next, ok := NextRun(r, london, time.Date(2025, 1, 10, 5, 0, 0, 0, time.UTC))
+	require.True(t, ok)
+	assert.Equal(t, time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC), next)
+	next, _ = NextRun(r, london, next)
+	assert.Equal(t, time.Date(2025, 1, 11, 1, 30, 0, 0, time.UTC), next)
+
+	// In summer time local 08:00 is 07:00 UTC; on the day the clocks go
+	// forward 01:30 does not exist and falls an hour later.
+	next, _ = NextRun(r, london, time.Date(2025, 3, 29, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 3, 30, 1, 30, 0, 0, time.UTC), next)
+	next, _ = NextRun(r, london, next)
+	assert.Equal(t, time.Date(2025, 3, 30, 7, 0, 0, 0, time.UTC), next)
+
+	// Only on the chosen weekdays: 2025-01-10 is a Friday.
+	r.Days = []string{"mon"}
+	next, _ = NextRun(r, london, time.Date(2025, 1, 10, 5, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 1, 13, 1, 30, 0, 0, time.UTC), next)
+	// The same weekday a week on, once today's times have passed.
+	next, _ = NextRun(r, london, time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 1, 20, 1, 30, 0, 0, time.UTC), next)
+}
+
+func TestSendDue(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+	ctx := context.Background()
+
+	// 2025-03-03 is a Monday; 07:00 in New York is 12:00 UTC.
+	now := clock.NewFake(time.Date(2025, 3, 3, 11, 0, 0, 0, time.UTC))
+	defer now.Install()()
+	db.DB.Exec(`INSERT INTO users (id, email, timezone) VALUES ('user-1', 'user-1@example.com', 'America/New_York')`)
+
+	add := func(kind string, times ...string) models.Reminder {
+		r := models.Reminder{ID: kind, Kind: kind, Times: times, Active: true}
+		require.NoError(t, Validate(&r))
+		Schedule("user-1", &r)
+		_, err := db.DB.Exec(`INSERT INTO reminders (id, user_id, kind, title, times, active, next_run_at) VALUES (?, 'user-1', ?, ?, ?, 1, ?)`,
+			r.ID, r.Kind, r.Title, `["`+times[0]+`"]`, nextRunValue(r))
+		require.NoError(t, err)
+		return r
+	}
+	inbox := func() []models.Notification {
+		rows, err := db.DB.Query(`SELECT id, title, COALESCE(body, ''), data FROM notifications ORDER BY created_at`)
+		require.NoError(t, err)
+		defer rows.Close()
+		var list []models.Notification
+		for rows.Next() {
+			var n models.Notification
+			var data string
+			rows.Scan(&n.ID, &n.Title, &n.Body, &data)
+			n.Data = []byte(data)
+			list = append(list, n)
+		}
+		return list
+	}
+
+	custom := add(Custom, "07:00")
+	add("test.check", "07:00")
+	assert.Equal(t, time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC), *custom.NextRunAt)
+
+	sent, err := SendDue(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	now.Set(time.Date(2025, 3, 3, 12, 0, 30, 0, time.UTC))
+	sent, err = SendDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	list := inbox()
+	require.Len(t, list, 2)
+	assert.Equal(t, "Reminder", list[0].Title)
+	assert.JSONEq(t, `{"date": "2025-03-03", "scheduled_for": "2025-03-03T12:00:00Z"}`, string(list[0].Data))
+	assert.Equal(t, "Checked on 2025-03-03", list[1].Body)
+
+	// Each occurrence is sent once, then the rule moves to the next day.
+	sent, _ = SendDue(ctx)
+	assert.Zero(t, sent)
+	r, err := Get("user-1", custom.ID)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 4, 12, 0, 0, 0, time.UTC), *r.NextRunAt)
+	assert.NotNil(t, r.LastRunAt)
+
+	// The kind's check can skip a day.
+	now.Set(time.Date(2025, 3, 4, 12, 1, 0, 0, time.UTC))
+	sent, _ = SendDue(ctx)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []string{"2025-03-04"}, skipped)
+
+	// Occurrences missed by hours are skipped, not sent late.
+	now.Set(time.Date(2025, 3, 5, 15, 0, 0, 0, time.UTC))
+	sent, _ = SendDue(ctx)
+	assert.Zero(t, sent)
+	assert.Len(t, inbox(), 3)
+	r, _ = Get("user-1", custom.ID)
+	assert.Equal(t, time.Date(2025, 3, 6, 12, 0, 0, 0, time.UTC), *r.NextRunAt)
+
+	// A move to another timezone reschedules from local time there.
+	db.DB.Exec(`UPDATE users SET timezone = 'Europe/Berlin' WHERE id = 'user-1'`)
+	require.NoError(t, Reschedule("user-1"))
+	r, _ = Get("user-1", custom.ID)
+	assert.Equal(t, time.Date(2025, 3, 6, 6, 0, 0, 0, time.UTC), *r.NextRunAt)
+}
+
+func TestStartStops(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := Start(ctx)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reminders did not stop")
+	}
+}
@@ -0,0 +1,78 @@
+package shopping
+
+import "strings"
+
+const (
+	Produce    = "Produce"
+	Meat       = "Meat & Seafood"
+	Dairy      = "Dairy & Eggs"
+	Bakery     = "Bakery"
+	Pantry     = "Pantry"
+	Spices     = "Spices & Condiments"
+	Frozen     = "Frozen"
+	Beverages  = "Beverages"
+	OtherAisle = "Other"
+)
+
+// aisleOrder is the order aisles appear in a list, roughly the walk through
+// a typical store.
+var aisleOrder = []string{Produce, Bakery, Meat, Dairy, Pantry, Spices, Frozen, Beverages, OtherAisle}
+
+// aisleKeywords are checked in order, so more specific phrases ("peanut
+// butter", "chicken stock") come before the single words they contain.
+var aisleKeywords = []struct {
+	keyword string
+	aisle   string
+}{
+	{"peanut butter", Pantry}, {"almond butter", Pantry}, {"coconut milk", Pantry},
+	{"stock", Pantry}, {"broth", Pantry}, {"frozen", Frozen}, {"ice cream", Frozen},
+	{"soy sauce", Spices}, {"fish sauce", Spices}, {"hot sauce", Spices}, {"tomato paste", Pantry},
+	{"garlic powder", Spices}, {"onion powder", Spices}, {"chili powder", Spices},
+	{"baking powder", Pantry}, {"baking soda", Pantry}, {"protein powder", Pantry},
+	{"almond milk", Dairy}, {"oat milk", Dairy}, {"soy milk", Dairy},
+
+	{"chicken", Meat}, {"beef", Meat}, {"pork", Meat}, {"lamb", Meat}, {"turkey", Meat},
+	{"bacon", Meat}, {"ham", Meat}, {"sausage", Meat}, {"mince", Meat}, {"steak", Meat},
+	{"salmon", Meat}, {"tuna", Meat}, {"cod", Meat}, {"shrimp", Meat}, {"prawn", Meat}, {"fish", Meat},
+
+	{"milk", Dairy}, {"cheese", Dairy}, {"yogurt", Dairy}, {"yoghurt", Dairy}, {"butter", Dairy},
+	{"cream", Dairy}, {"egg", Dairy}, {"feta", Dairy}, {"parmesan", Dairy}, {"mozzarella", Dairy},
+	{"tofu", Dairy}, {"tempeh", Dairy},
+
+	{"bread", Bakery}, {"bagel", Bakery}, {"tortilla", Bakery}, {"wrap", Bakery}, {"pita", Bakery},
+	{"bun", Bakery}, {"roll", Bakery},
+
+	{"salt", Spices}, {"pepper flakes", Spices}, {"black pepper", Spices}, {"cumin", Spices},
+	{"paprika", Spices}, {"turmeric", Spices}, {"cinnamon", Spices}, {"oregano", Spices},
+	{"thyme", Spices}, {"vinegar", Spices}, {"mustard", Spices}, {"ketchup", Spices},
+	{"mayonnaise", Spices}, {"sauce", Spices}, {"spice", Spices}, {"vanilla", Spices},
+
+	{"rice", Pantry}, {"pasta", Pantry}, {"noodle", Pantry}, {"oat", Pantry}, {"flour", Pantry},
+	{"sugar", Pantry}, {"honey", Pantry}, {"oil", Pantry}, {"bean", Pantry}, {"lentil", Pantry},
+	{"chickpea", Pantry}, {"quinoa", Pantry}, {"couscous", Pantry}, {"cereal", Pantry},
+	{"nut", Pantry}, {"almond", Pantry}, {"seed", Pantry}, {"canned", Pantry}, {"syrup", Pantry},
+
+	{"water", Beverages}, {"juice", Beverages}, {"coffee", Beverages}, {"tea", Beverages},
+
+	{"apple", Produce}, {"banana", Produce}, {"berry", Produce}, {"lemon", Produce}, {"lime", Produce},
+	{"orange", Produce}, {"avocado", Produce}, {"tomato", Produce}, {"onion", Produce},
+	{"garlic", Produce}, {"ginger", Produce}, {"potato", Produce}, {"carrot", Produce},
+	{"pepper", Produce}, {"spinach", Produce}, {"lettuce", Produce}, {"kale", Produce},
+	{"broccoli", Produce}, {"cucumber", Produce}, {"zucchini", Produce}, {"courgette", Produce},
+	{"mushroom", Produce}, {"celery", Produce}, {"cabbage", Produce}, {"herb", Produce},
+	{"basil", Produce}, {"parsley", Produce}, {"cilantro", Produce}, {"coriander", Produce},
+	{"mint", Produce}, {"scallion", Produce}, {"shallot", Produce}, {"leek", Produce},
+	{"fruit", Produce}, {"vegetable", Produce}, {"squash", Produce}, {"pumpkin", Produce},
+	{"mango", Produce}, {"pear", Produce}, {"grape", Produce}, {"peach", Produce}, {"kiwi", Produce},
+}
+
+// AisleFor guesses the grocery aisle of an ingredient from its name.
+func AisleFor(name string) string {
+	padded := " " + strings.ToLower(name) + " "
+	for _, k := range aisleKeywords {
+		if strings.Contains(padded, " "+k.keyword) {
+			return k.aisle
+		}
+	}
+	return OtherAisle
+}
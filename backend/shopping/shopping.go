@@ -0,0 +1,221 @@
+// Package shopping turns the meals of a diet plan into a grocery list.
+package shopping
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/terr0r/fitness.ai/backend/nutrition"
+)
+
+// Item is one line of the shopping list. Mass is summed in grams and volume
+// in millilitres; count items keep their own unit.
+type Item struct {
+	Name     string   `json:"name"`
+	Quantity float64  `json:"quantity"`
+	Unit     string   `json:"unit,omitempty"`
+	Display  string   `json:"display"`
+	Aisle    string   `json:"aisle"`
+	Recipes  []string `json:"recipes"`
+}
+
+type Aisle struct {
+	Name  string `json:"name"`
+	Items []Item `json:"items"`
+}
+
+// List is an aggregated shopping list grouped by aisle.
+type List struct {
+	Aisles []Aisle `json:"aisles"`
+	// Pantry lists the staples left off the list because the user keeps
+	// them at home.
+	Pantry []string `json:"pantry"`
+}
+
+// Builder accumulates scaled ingredient lines and merges duplicates.
+type Builder struct {
+	items map[string]*Item
+	order []string
+}
+
+func NewBuilder() *Builder {
+	return &Builder{items: map[string]*Item{}}
+}
+
+// Add records an ingredient line from recipe, multiplied by factor.
+func (b *Builder) Add(line string, factor float64, recipe string) {
+	ing := nutrition.ParseIngredient(line)
+	if ing.Name == "" {
+		return
+	}
+	name := NormalizeName(ing.Name)
+
+	qty := ing.Quantity * factor
+	unit := ing.Unit
+	switch nutrition.UnitKind(ing.Unit) {
+	case nutrition.Mass:
+		qty, _ = nutrition.Convert(qty, ing.Unit, "g")
+		unit = "g"
+	case nutrition.Volume:
+		qty, _ = nutrition.Convert(qty, ing.Unit, "ml")
+		unit = "ml"
+	}
+
+	key := name + "|" + unit
+	item, ok := b.items[key]
+	if !ok {
+		item = &Item{Name: name, Unit: unit, Aisle: AisleFor(name)}
+		b.items[key] = item
+		b.order = append(b.order, key)
+	}
+	item.Quantity += qty
+	for _, r := range item.Recipes {
+		if r == recipe {
+			return
+		}
+	}
+	item.Recipes = append(item.Recipes, recipe)
+}
+
+// Build groups the merged items by aisle, leaving out anything matching a
+// pantry staple.
+func (b *Builder) Build(pantry []string) List {
+	list := List{Aisles: []Aisle{}, Pantry: []string{}}
+	byAisle := map[string][]Item{}
+
+	for _, key := range b.order {
+		item := *b.items[key]
+		if staple := matchStaple(item.Name, pantry); staple != "" {
+			list.Pantry = appendUnique(list.Pantry, item.Name)
+			continue
+		}
+		item.Quantity, item.Unit = displayUnit(item.Quantity, item.Unit)
+		item.Display = formatItem(item)
+		byAisle[item.Aisle] = append(byAisle[item.Aisle], item)
+	}
+
+	for _, name := range aisleOrder {
+		items := byAisle[name]
+		if len(items) == 0 {
+			continue
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+		list.Aisles = append(list.Aisles, Aisle{Name: name, Items: items})
+	}
+	return list
+}
+
+// Words that end in s without being plurals.
+var notPlural = map[string]bool{"molasses": true, "grits": true, "swiss": true, "series": true}
+
+// NormalizeName lowercases a name and reduces simple plurals so "Eggs" and
+// "egg" merge. Words ending in "us" or "is" ("hummus", "couscous") are
+// singular already.
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return name
+	}
+	last := words[len(words)-1]
+	switch {
+	case notPlural[last], strings.HasSuffix(last, "us"), strings.HasSuffix(last, "is"):
+	case strings.HasSuffix(last, "ies") && len(last) > 4:
+		last = last[:len(last)-3] + "y"
+	case strings.HasSuffix(last, "oes") && len(last) > 4:
+		last = last[:len(last)-2]
+	case strings.HasSuffix(last, "s") && !strings.HasSuffix(last, "ss") && len(last) > 3:
+		last = last[:len(last)-1]
+	}
+	words[len(words)-1] = last
+	return strings.Join(words, " ")
+}
+
+func matchStaple(name string, pantry []string) string {
+	for _, p := range pantry {
+		p = NormalizeName(p)
+		if p != "" && (name == p || strings.Contains(" "+name+" ", " "+p+" ")) {
+			return p
+		}
+	}
+	return ""
+}
+
+func displayUnit(qty float64, unit string) (float64, string) {
+	switch {
+	case unit == "g" && qty >= 1000:
+		return round(qty/1000, 100), "kg"
+	case unit == "ml" && qty >= 1000:
+		return round(qty/1000, 100), "l"
+	case unit == "g" || unit == "ml":
+		return math.Round(qty), unit
+	}
+	return round(qty, 100), unit
+}
+
+func round(f float64, precision float64) float64 {
+	return math.Round(f*precision) / precision
+}
+
+func formatItem(item Item) string {
+	parts := []string{}
+	if item.Quantity > 0 {
+		parts = append(parts, strconv.FormatFloat(item.Quantity, 'f', -1, 64))
+		if item.Unit != "" {
+			parts = append(parts, item.Unit)
+		}
+	}
+	return strings.Join(append(parts, item.Name), " ")
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// WriteMarkdown renders the list as a Markdown checklist.
+func (l List) WriteMarkdown(w io.Writer, title string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+	for _, a := range l.Aisles {
+		fmt.Fprintf(&b, "\n## %s\n\n", a.Name)
+		for _, item := range a.Items {
+			fmt.Fprintf(&b, "- [ ] %s\n", item.Display)
+		}
+	}
+	if len(l.Pantry) > 0 {
+		b.WriteString("\n## Already in your pantry\n\n")
+		for _, p := range l.Pantry {
+			fmt.Fprintf(&b, "- %s\n", p)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV renders the list with the columns aisle, item, quantity, unit and
+// recipes (semicolon separated).
+func (l List) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"aisle", "item", "quantity", "unit", "recipes"})
+	for _, a := range l.Aisles {
+		for _, item := range a.Items {
+			qty := ""
+			if item.Quantity > 0 {
+				qty = strconv.FormatFloat(item.Quantity, 'f', -1, 64)
+			}
+			cw.Write([]string{a.Name, item.Name, qty, item.Unit, strings.Join(item.Recipes, "; ")})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
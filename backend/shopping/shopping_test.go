@@ -0,0 +1,71 @@
+package shopping
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuilderMergesAndGroups(t *testing.T) {
+	b := NewBuilder()
+	b.Add("200g chicken breast", 1, "Chicken Bowl")
+	b.Add("200 g Chicken Breast", 1, "Chicken Wrap")
+	b.Add("1 cup milk", 1, "Porridge")
+	b.Add("250 ml milk", 2, "Porridge")
+	b.Add("2 eggs", 1, "Omelette")
+	b.Add("1 egg", 1, "Pancakes")
+	b.Add("1 tbsp olive oil", 3, "Chicken Bowl")
+	b.Add("1 kg potatoes", 1.5, "Roast")
+
+	list := b.Build([]string{"Olive Oil"})
+
+	assert.Equal(t, []string{"olive oil"}, list.Pantry)
+
+	byName := map[string]Item{}
+	var aisles []string
+	for _, a := range list.Aisles {
+		aisles = append(aisles, a.Name)
+		for _, item := range a.Items {
+			byName[item.Name] = item
+		}
+	}
+	assert.Equal(t, []string{Produce, Meat, Dairy}, aisles)
+	assert.Equal(t, "400 g chicken breast", byName["chicken breast"].Display)
+	assert.Equal(t, []string{"Chicken Bowl", "Chicken Wrap"}, byName["chicken breast"].Recipes)
+	assert.Equal(t, "737 ml milk", byName["milk"].Display)
+	assert.Equal(t, "3 egg", byName["egg"].Display)
+	assert.Equal(t, "1.5 kg potato", byName["potato"].Display)
+}
+
+func TestNormalizeName(t *testing.T) {
+	for in, want := range map[string]string{
+		"Eggs":            "egg",
+		"cherry tomatoes": "cherry tomato",
+		"Blueberries":     "blueberry",
+		"glass":           "glass",
+		"hummus":          "hummus",
+		"couscous":        "couscous",
+		"green asparagus": "green asparagus",
+		"molasses":        "molasses",
+		"chickpeas":       "chickpea",
+		"  Rolled Oats  ": "rolled oat",
+	} {
+		assert.Equal(t, want, NormalizeName(in), in)
+	}
+}
+
+func TestListFormats(t *testing.T) {
+	b := NewBuilder()
+	b.Add("2 bananas", 1, "Smoothie")
+	b.Add("salt to taste", 1, "Smoothie")
+	list := b.Build(nil)
+
+	var md bytes.Buffer
+	list.WriteMarkdown(&md, "Shopping list")
+	assert.Equal(t, "# Shopping list\n\n## Produce\n\n- [ ] 2 banana\n\n## Spices & Condiments\n\n- [ ] salt\n", md.String())
+
+	var csv bytes.Buffer
+	list.WriteCSV(&csv)
+	assert.Equal(t, "aisle,item,quantity,unit,recipes\nProduce,banana,2,,Smoothie\nSpices & Condiments,salt,,,Smoothie\n", csv.String())
+}
@@ -25,7 +25,7 @@ func SetupTestDB(t *testing.T) func() {
 	tempFile.Close()
 
 	// Open the SQLite database
-	testDB, err := sql.Open("sqlite", dbPath)
+	testDB, err := sql.Open("sqlite", db.WithBusyTimeout(dbPath))
 	if err != nil {
 		os.Remove(dbPath)
 		t.Fatalf("Failed to open test database: %v", err)
@@ -0,0 +1,244 @@
+package training
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Bucket periods for analytics.
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// ParsePeriod checks a bucket period; "" means weekly buckets.
+func ParsePeriod(s string) (string, error) {
+	switch k := Key(s); k {
+	case "":
+		return PeriodWeek, nil
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unknown period %q", s)
+	}
+}
+
+// PeriodStart returns the first day of the bucket containing date. Weeks
+// start on Monday.
+func PeriodStart(date time.Time, period string) time.Time {
+	y, m, d := date.Date()
+	switch period {
+	case PeriodWeek:
+		offset := (int(date.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case PeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func nextPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// A working set counts as hard at this RPE or above. Sets logged without
+// an RPE are assumed to be hard.
+const HardSetRPE = 7
+
+// ExerciseLoad aggregates the working sets of one exercise in one session.
+// Date is the session's local calendar date at midnight UTC.
+type ExerciseLoad struct {
+	SessionID  string
+	Date       time.Time
+	ExerciseID string
+	Sets       int
+	HardSets   int
+	Reps       int
+	Tonnage    float64
+	// IntensitySum adds up load/e1RM over the IntensitySets sets that had
+	// both.
+	IntensitySum  float64
+	IntensitySets int
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// Buckets rolls exercise loads into consecutive buckets covering from..to,
+// including empty ones. exercises maps exercise IDs to their library
+// entries for the per-muscle counts.
+func Buckets(loads []ExerciseLoad, exercises map[string]models.Exercise, period string, from, to time.Time) []models.TrainingBucket {
+	type acc struct {
+		bucket        models.TrainingBucket
+		sessions      map[string]bool
+		muscles       map[string]map[string]bool
+		intensitySum  float64
+		intensitySets int
+	}
+	var accs []*acc
+	index := map[time.Time]*acc{}
+	for start := PeriodStart(from, period); !start.After(to); start = nextPeriod(start, period) {
+		a := &acc{
+			bucket: models.TrainingBucket{
+				Start:             start.Format("2006-01-02"),
+				End:               nextPeriod(start, period).AddDate(0, 0, -1).Format("2006-01-02"),
+				HardSetsPerMuscle: map[string]float64{},
+				Frequency:         map[string]int{},
+			},
+			sessions: map[string]bool{},
+			muscles:  map[string]map[string]bool{},
+		}
+		accs = append(accs, a)
+		index[start] = a
+	}
+
+	for _, l := range loads {
+		if l.Date.Before(from) || l.Date.After(to) {
+			continue
+		}
+		a := index[PeriodStart(l.Date, period)]
+		if a == nil {
+			continue
+		}
+		b := &a.bucket
+		a.sessions[l.SessionID] = true
+		b.Sets += l.Sets
+		b.HardSets += l.HardSets
+		b.Reps += l.Reps
+		b.Tonnage += l.Tonnage
+		a.intensitySum += l.IntensitySum
+		a.intensitySets += l.IntensitySets
+
+		ex := exercises[l.ExerciseID]
+		for _, m := range ex.PrimaryMuscles {
+			b.HardSetsPerMuscle[m] += float64(l.HardSets)
+			if a.muscles[m] == nil {
+				a.muscles[m] = map[string]bool{}
+			}
+			a.muscles[m][l.SessionID] = true
+		}
+		for _, m := range ex.SecondaryMuscles {
+			b.HardSetsPerMuscle[m] += float64(l.HardSets) * secondaryMuscleShare
+		}
+	}
+
+	buckets := make([]models.TrainingBucket, len(accs))
+	for i, a := range accs {
+		b := a.bucket
+		b.Sessions = len(a.sessions)
+		b.Tonnage = round1(b.Tonnage)
+		for m, sessions := range a.muscles {
+			b.Frequency[m] = len(sessions)
+		}
+		if a.intensitySets > 0 {
+			v := round1(a.intensitySum / float64(a.intensitySets) * 100)
+			b.AvgIntensity = &v
+		}
+		buckets[i] = b
+	}
+	return buckets
+}
+
+// WeeklyHardSets averages the hard sets per muscle over the weeks spanned
+// by from..to.
+func WeeklyHardSets(buckets []models.TrainingBucket, from, to time.Time) map[string]float64 {
+	weeks := (to.Sub(from).Hours()/24 + 1) / 7
+	avg := map[string]float64{}
+	for _, b := range buckets {
+		for m, n := range b.HardSetsPerMuscle {
+			avg[m] += n
+		}
+	}
+	for m := range avg {
+		avg[m] = round1(avg[m] / weeks)
+	}
+	return avg
+}
+
+// Workload metrics.
+const (
+	MetricTonnage  = "tonnage"
+	MetricHardSets = "hard_sets"
+)
+
+// ParseMetric checks a workload metric; "" means tonnage.
+func ParseMetric(s string) (string, error) {
+	switch k := Key(s); k {
+	case "":
+		return MetricTonnage, nil
+	case MetricTonnage, MetricHardSets:
+		return k, nil
+	default:
+		return "", fmt.Errorf("unknown workload metric %q", s)
+	}
+}
+
+// Workload statuses and the ratio band considered safe.
+const (
+	WorkloadInsufficient = "insufficient_data"
+	WorkloadLow          = "low"
+	WorkloadOptimal      = "optimal"
+	WorkloadSpike        = "spike"
+
+	acwrLow   = 0.8
+	acwrSpike = 1.5
+)
+
+// Workload computes the acute:chronic workload ratio on date: the load of
+// the last 7 days against the weekly average of the last 28. loads must
+// include the 27 days before date.
+func Workload(loads []ExerciseLoad, metric string, date time.Time) models.Workload {
+	w := models.Workload{Date: date.Format("2006-01-02"), Metric: metric}
+	acuteFrom := date.AddDate(0, 0, -6)
+	chronicFrom := date.AddDate(0, 0, -27)
+	var older bool
+	for _, l := range loads {
+		if l.Date.Before(chronicFrom) || l.Date.After(date) {
+			continue
+		}
+		v := l.Tonnage
+		if metric == MetricHardSets {
+			v = float64(l.HardSets)
+		}
+		w.Chronic += v
+		if !l.Date.Before(acuteFrom) {
+			w.Acute += v
+		} else if v > 0 {
+			older = true
+		}
+	}
+	w.Acute = round1(w.Acute)
+	w.Chronic = round1(w.Chronic / 4)
+
+	// Without any training before the acute week the ratio only says the
+	// user just started.
+	if !older || w.Chronic == 0 {
+		w.Status = WorkloadInsufficient
+		return w
+	}
+	ratio := math.Round(w.Acute/w.Chronic*100) / 100
+	w.Ratio = &ratio
+	switch {
+	case ratio > acwrSpike:
+		w.Status = WorkloadSpike
+		w.Warning = fmt.Sprintf("This week's load is %.1fx your 4-week average; spikes above %.1fx raise injury risk.", ratio, acwrSpike)
+	case ratio < acwrLow:
+		w.Status = WorkloadLow
+	default:
+		w.Status = WorkloadOptimal
+	}
+	return w
+}
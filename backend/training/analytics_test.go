@@ -0,0 +1,95 @@
+package training
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestPeriodStart(t *testing.T) {
+	// 2026-10-21 is a Wednesday.
+	assert.Equal(t, day("2026-10-19"), PeriodStart(day("2026-10-21"), PeriodWeek))
+	assert.Equal(t, day("2026-10-19"), PeriodStart(day("2026-10-25"), PeriodWeek))
+	assert.Equal(t, day("2026-10-01"), PeriodStart(day("2026-10-21"), PeriodMonth))
+	assert.Equal(t, day("2026-10-21"), PeriodStart(day("2026-10-21"), PeriodDay))
+
+	_, err := ParsePeriod("fortnight")
+	assert.Error(t, err)
+	p, _ := ParsePeriod("")
+	assert.Equal(t, PeriodWeek, p)
+}
+
+func TestBuckets(t *testing.T) {
+	exercises := map[string]models.Exercise{
+		"squat": {PrimaryMuscles: []string{"quads"}, SecondaryMuscles: []string{"glutes"}},
+		"bench": {PrimaryMuscles: []string{"chest"}},
+	}
+	loads := []ExerciseLoad{
+		{SessionID: "a", Date: day("2026-10-19"), ExerciseID: "squat", Sets: 3, HardSets: 2, Reps: 15, Tonnage: 1500, IntensitySum: 2.4, IntensitySets: 3},
+		{SessionID: "a", Date: day("2026-10-19"), ExerciseID: "bench", Sets: 3, HardSets: 3, Reps: 15, Tonnage: 1200, IntensitySum: 1.5, IntensitySets: 2},
+		{SessionID: "b", Date: day("2026-10-22"), ExerciseID: "squat", Sets: 2, HardSets: 2, Reps: 10, Tonnage: 1000},
+		{SessionID: "c", Date: day("2026-11-02"), ExerciseID: "bench", Sets: 1, HardSets: 1, Reps: 5, Tonnage: 400},
+		// Outside the range.
+		{SessionID: "d", Date: day("2026-11-09"), ExerciseID: "bench", Sets: 5, HardSets: 5, Reps: 25, Tonnage: 2000},
+	}
+
+	buckets := Buckets(loads, exercises, PeriodWeek, day("2026-10-19"), day("2026-11-08"))
+	assert.Len(t, buckets, 3)
+
+	first := buckets[0]
+	assert.Equal(t, "2026-10-19", first.Start)
+	assert.Equal(t, "2026-10-25", first.End)
+	assert.Equal(t, 2, first.Sessions)
+	assert.Equal(t, 8, first.Sets)
+	assert.Equal(t, 7, first.HardSets)
+	assert.Equal(t, 3700.0, first.Tonnage)
+	assert.Equal(t, 78.0, *first.AvgIntensity)
+	assert.Equal(t, map[string]float64{"quads": 4, "glutes": 2, "chest": 3}, first.HardSetsPerMuscle)
+	assert.Equal(t, map[string]int{"quads": 2, "chest": 1}, first.Frequency)
+
+	assert.Equal(t, 0, buckets[1].Sessions)
+	assert.Nil(t, buckets[1].AvgIntensity)
+	assert.Equal(t, 400.0, buckets[2].Tonnage)
+
+	weekly := WeeklyHardSets(buckets, day("2026-10-19"), day("2026-11-08"))
+	assert.Equal(t, map[string]float64{"quads": 1.3, "glutes": 0.7, "chest": 1.3}, weekly)
+
+	months := Buckets(loads, exercises, PeriodMonth, day("2026-10-19"), day("2026-11-08"))
+	assert.Len(t, months, 2)
+	assert.Equal(t, "2026-10-01", months[0].Start)
+	assert.Equal(t, "2026-10-31", months[0].End)
+	assert.Equal(t, 3700.0, months[0].Tonnage)
+}
+
+func TestWorkload(t *testing.T) {
+	date := day("2026-10-28")
+	var loads []ExerciseLoad
+	// Three steady weeks of 1000 kg, then 2000 kg in the last one.
+	for i, v := range []float64{1000, 1000, 1000, 2000} {
+		loads = append(loads, ExerciseLoad{Date: date.AddDate(0, 0, -21+7*i), Tonnage: v, HardSets: 10})
+	}
+
+	w := Workload(loads, MetricTonnage, date)
+	assert.Equal(t, 2000.0, w.Acute)
+	assert.Equal(t, 1250.0, w.Chronic)
+	assert.Equal(t, 1.6, *w.Ratio)
+	assert.Equal(t, WorkloadSpike, w.Status)
+	assert.NotEmpty(t, w.Warning)
+
+	w = Workload(loads, MetricHardSets, date)
+	assert.Equal(t, 1.0, *w.Ratio)
+	assert.Equal(t, WorkloadOptimal, w.Status)
+	assert.Empty(t, w.Warning)
+
+	// A first week of training has nothing to compare against.
+	w = Workload(loads[3:], MetricTonnage, date)
+	assert.Nil(t, w.Ratio)
+	assert.Equal(t, WorkloadInsufficient, w.Status)
+}
@@ -0,0 +1,73 @@
+// Package training holds the exercise vocabulary and the calculations
+// behind workout logging and analytics.
+package training
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MuscleGroups are the muscle groups exercises are tagged with.
+var MuscleGroups = []string{
+	"chest", "upper_back", "lats", "traps", "front_delts", "side_delts", "rear_delts", "biceps", "triceps", "forearms",
+	"abs", "obliques", "lower_back", "glutes", "quads", "hamstrings", "adductors", "abductors", "calves", "neck",
+}
+
+// Equipment lists the kinds of equipment an exercise can need.
+var Equipment = []string{
+	"barbell", "dumbbell", "kettlebell", "machine", "cable", "bodyweight", "band", "bench", "pull_up_bar",
+	"smith_machine", "ez_bar", "trap_bar", "medicine_ball", "cardio_machine", "other",
+}
+
+// MovementPatterns classify exercises by how they move the body.
+var MovementPatterns = []string{
+	"squat", "hinge", "lunge", "horizontal_push", "vertical_push", "horizontal_pull", "vertical_pull",
+	"carry", "rotation", "anti_rotation", "isolation", "locomotion",
+}
+
+// Difficulties are the workout difficulty levels.
+var Difficulties = []string{"beginner", "intermediate", "advanced"}
+
+// Key normalises a vocabulary value: "Upper back" and "upper-back" both
+// become "upper_back".
+func Key(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+// Normalize checks every value against vocab and returns them normalised,
+// without duplicates. kind names the vocabulary in the error.
+func Normalize(kind string, vocab, values []string) ([]string, error) {
+	out := []string{}
+	for _, v := range values {
+		k := Key(v)
+		if !contains(vocab, k) {
+			return nil, fmt.Errorf("unknown %s %q", kind, v)
+		}
+		if !contains(out, k) {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+// NormalizeOne is Normalize for a single optional value.
+func NormalizeOne(kind string, vocab []string, value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return "", nil
+	}
+	list, err := Normalize(kind, vocab, []string{value})
+	if err != nil {
+		return "", err
+	}
+	return list[0], nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package training
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Stand-ins for values the user has not measured, and the plausible range
+// of measured heart rates.
+const (
+	defaultAge           = 30
+	defaultRestingHR     = 60
+	minMaxHR, maxMaxHR   = 100, 230
+	minRestHR, maxRestHR = 25, 120
+)
+
+// ValidateHeartRates checks measured heart rates; zero means unknown.
+func ValidateHeartRates(max, resting int) error {
+	if max != 0 && (max < minMaxHR || max > maxMaxHR) {
+		return fmt.Errorf("max_heart_rate must be between %d and %d", minMaxHR, maxMaxHR)
+	}
+	if resting != 0 && (resting < minRestHR || resting > maxRestHR) {
+		return fmt.Errorf("resting_heart_rate must be between %d and %d", minRestHR, maxRestHR)
+	}
+	if max != 0 && resting != 0 && resting >= max {
+		return fmt.Errorf("resting_heart_rate must be below max_heart_rate")
+	}
+	return nil
+}
+
+// NewHRProfile combines measured heart rates with estimates: the maximum
+// from age (Tanaka: 208 - 0.7 × age) and a typical resting rate.
+func NewHRProfile(age int, gender string, max, resting int) models.HRProfile {
+	p := models.HRProfile{Max: max, Resting: resting, Female: strings.EqualFold(gender, "female")}
+	if p.Max == 0 {
+		if age <= 0 {
+			age = defaultAge
+		}
+		p.Max = int(math.Round(208 - 0.7*float64(age)))
+		p.MaxEstimated = true
+	}
+	if p.Resting == 0 || p.Resting >= p.Max {
+		p.Resting = defaultRestingHR
+		p.RestingEstimated = true
+	}
+	return p
+}
+
+// Zone boundaries as fractions of heart rate reserve (Karvonen). Zone 1
+// also takes in everything below its lower bound.
+var zoneBounds = []float64{0.5, 0.6, 0.7, 0.8, 0.9, 1.0}
+
+var zoneNames = []string{"recovery", "endurance", "tempo", "threshold", "vo2max"}
+
+// reserve is the fraction of heart rate reserve bpm stands for, clamped
+// to 0..1.
+func reserve(p models.HRProfile, bpm float64) float64 {
+	x := (bpm - float64(p.Resting)) / float64(p.Max-p.Resting)
+	return math.Max(0, math.Min(1, x))
+}
+
+func zoneBPM(p models.HRProfile, fraction float64) int {
+	return int(math.Round(float64(p.Resting) + fraction*float64(p.Max-p.Resting)))
+}
+
+// Zones lists the five heart rate zones of a profile.
+func Zones(p models.HRProfile) []models.HRZone {
+	zones := make([]models.HRZone, len(zoneNames))
+	for i, name := range zoneNames {
+		zones[i] = models.HRZone{Zone: i + 1, Name: name, MinBPM: zoneBPM(p, zoneBounds[i]), MaxBPM: zoneBPM(p, zoneBounds[i+1])}
+	}
+	zones[len(zones)-1].MaxBPM = p.Max + 1
+	return zones
+}
+
+func zoneOf(x float64) int {
+	for i := len(zoneNames) - 1; i > 0; i-- {
+		if x >= zoneBounds[i] {
+			return i
+		}
+	}
+	return 0
+}
+
+// A reading stands for the time until the next one, but no longer than
+// this; longer gaps are pauses.
+const maxSampleGap = 60
+
+// Heart rate reserve at lactate threshold, used to scale Load.
+const thresholdReserve = 0.85
+
+// trimpWeight is Banister's weighting of a minute at heart rate reserve x.
+func trimpWeight(p models.HRProfile, x float64) float64 {
+	if p.Female {
+		return x * 0.86 * math.Exp(1.67*x)
+	}
+	return x * 0.64 * math.Exp(1.92*x)
+}
+
+// HeartRateLoad computes time in zone, TRIMP and load from a session's
+// heart rate series, or nil when there is none.
+func HeartRateLoad(samples []models.HeartRateSample, p models.HRProfile) *models.HeartRateLoad {
+	if len(samples) < 2 {
+		return nil
+	}
+	l := &models.HeartRateLoad{TimeInZones: make([]float64, len(zoneNames))}
+	for i, s := range samples[:len(samples)-1] {
+		dt := float64(samples[i+1].Offset - s.Offset)
+		if dt <= 0 {
+			continue
+		}
+		dt = math.Min(dt, maxSampleGap)
+		x := reserve(p, float64(s.BPM))
+		l.TimeInZones[zoneOf(x)] += dt
+		l.TRIMP += dt / 60 * trimpWeight(p, x)
+	}
+	for i := range l.TimeInZones {
+		l.TimeInZones[i] = round1(l.TimeInZones[i])
+	}
+	l.Load = round1(l.TRIMP / (60 * trimpWeight(p, thresholdReserve)) * 100)
+	l.TRIMP = round1(l.TRIMP)
+	return l
+}
+
+// Time constants of the fitness and fatigue averages, in days.
+const (
+	ctlDays = 42
+	atlDays = 7
+)
+
+// FitnessCurve runs the fitness/fatigue model over daily loads from start
+// and reports the days from..to. Starting well before from lets the
+// averages settle; daily maps local dates at midnight UTC to the day's
+// total load.
+func FitnessCurve(daily map[time.Time]float64, start, from, to time.Time) []models.FitnessDay {
+	days := []models.FitnessDay{}
+	var ctl, atl float64
+	for d := start; !d.After(to); d = d.AddDate(0, 0, 1) {
+		tsb := ctl - atl
+		load := daily[d]
+		ctl += (load - ctl) / ctlDays
+		atl += (load - atl) / atlDays
+		if d.Before(from) {
+			continue
+		}
+		days = append(days, models.FitnessDay{
+			Date: d.Format("2006-01-02"),
+			Load: round1(load),
+			CTL:  round1(ctl),
+			ATL:  round1(atl),
+			TSB:  round1(tsb),
+		})
+	}
+	return days
+}
@@ -0,0 +1,74 @@
+package training
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestHRProfile(t *testing.T) {
+	p := NewHRProfile(40, "male", 0, 0)
+	assert.Equal(t, models.HRProfile{Max: 180, Resting: 60, MaxEstimated: true, RestingEstimated: true}, p)
+
+	p = NewHRProfile(40, "Female", 195, 48)
+	assert.Equal(t, 195, p.Max)
+	assert.Equal(t, 48, p.Resting)
+	assert.False(t, p.MaxEstimated)
+	assert.True(t, p.Female)
+
+	zones := Zones(NewHRProfile(40, "", 0, 0))
+	assert.Len(t, zones, 5)
+	assert.Equal(t, models.HRZone{Zone: 1, Name: "recovery", MinBPM: 120, MaxBPM: 132}, zones[0])
+	assert.Equal(t, models.HRZone{Zone: 5, Name: "vo2max", MinBPM: 168, MaxBPM: 181}, zones[4])
+
+	assert.NoError(t, ValidateHeartRates(0, 0))
+	assert.NoError(t, ValidateHeartRates(185, 50))
+	assert.Error(t, ValidateHeartRates(300, 0))
+	assert.Error(t, ValidateHeartRates(0, 10))
+	assert.Error(t, ValidateHeartRates(110, 115))
+}
+
+func TestHeartRateLoad(t *testing.T) {
+	p := NewHRProfile(40, "male", 0, 0)
+	assert.Nil(t, HeartRateLoad(nil, p))
+
+	// A minute each in zones 1, 3 and 5, then a reading followed by a long
+	// gap that only counts for a minute.
+	l := HeartRateLoad([]models.HeartRateSample{
+		{Offset: 0, BPM: 130}, {Offset: 60, BPM: 150}, {Offset: 120, BPM: 170}, {Offset: 180, BPM: 100}, {Offset: 600, BPM: 100},
+	}, p)
+	assert.Equal(t, []float64{120, 0, 60, 0, 60}, l.TimeInZones)
+	assert.InDelta(t, 7.0, l.TRIMP, 0.05)
+	assert.InDelta(t, 4.2, l.Load, 0.05)
+
+	// An hour at threshold scores 100.
+	var hour []models.HeartRateSample
+	for i := 0; i <= 60; i++ {
+		hour = append(hour, models.HeartRateSample{Offset: 60 * i, BPM: 162})
+	}
+	assert.Equal(t, 100.0, HeartRateLoad(hour, p).Load)
+	assert.Equal(t, []float64{0, 0, 0, 3600, 0}, HeartRateLoad(hour, p).TimeInZones)
+}
+
+func TestFitnessCurve(t *testing.T) {
+	start := day("2026-10-01")
+	daily := map[time.Time]float64{start: 42}
+
+	days := FitnessCurve(daily, start, start, day("2026-10-02"))
+	assert.Equal(t, []models.FitnessDay{
+		{Date: "2026-10-01", Load: 42, CTL: 1, ATL: 6, TSB: 0},
+		{Date: "2026-10-02", Load: 0, CTL: 1, ATL: 5.1, TSB: -5},
+	}, days)
+
+	// Steady training lifts fitness towards the daily load, and the days
+	// before from only warm the model up.
+	for d := start; d.Before(day("2027-03-01")); d = d.AddDate(0, 0, 1) {
+		daily[d] = 50
+	}
+	days = FitnessCurve(daily, start, day("2027-02-27"), day("2027-02-28"))
+	assert.Len(t, days, 2)
+	assert.InDelta(t, 50, days[1].CTL, 2)
+	assert.InDelta(t, 0, days[1].TSB, 2)
+}
@@ -0,0 +1,162 @@
+package training
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Estimated one-rep max formulas.
+const (
+	Epley    = "epley"
+	Brzycki  = "brzycki"
+	Lander   = "lander"
+	Lombardi = "lombardi"
+	Mayhew   = "mayhew"
+	OConner  = "oconner"
+	Wathan   = "wathan"
+)
+
+// DefaultFormula is used when the user has not picked one.
+const DefaultFormula = Epley
+
+var formulas = map[string]func(load, reps float64) float64{
+	Epley:    func(w, r float64) float64 { return w * (1 + r/30) },
+	Brzycki:  func(w, r float64) float64 { return w * 36 / (37 - r) },
+	Lander:   func(w, r float64) float64 { return 100 * w / (101.3 - 2.67123*r) },
+	Lombardi: func(w, r float64) float64 { return w * math.Pow(r, 0.1) },
+	Mayhew:   func(w, r float64) float64 { return 100 * w / (52.2 + 41.9*math.Exp(-0.055*r)) },
+	OConner:  func(w, r float64) float64 { return w * (1 + 0.025*r) },
+	Wathan:   func(w, r float64) float64 { return 100 * w / (48.8 + 53.8*math.Exp(-0.075*r)) },
+}
+
+// Formulas lists the supported formula names.
+var Formulas = []string{Epley, Brzycki, Lander, Lombardi, Mayhew, OConner, Wathan}
+
+// ParseFormula checks a formula name; "" means the default.
+func ParseFormula(s string) (string, error) {
+	if s == "" {
+		return DefaultFormula, nil
+	}
+	k := Key(s)
+	if _, ok := formulas[k]; !ok {
+		return "", fmt.Errorf("unknown e1RM formula %q", s)
+	}
+	return k, nil
+}
+
+// Estimates from sets above this many reps are too unreliable to keep.
+const maxE1RMReps = 12
+
+// E1RM estimates the one-rep max from a set. A single is its own max; sets
+// of more than 12 reps give no estimate.
+func E1RM(load float64, reps int, formula string) float64 {
+	if load <= 0 || reps <= 0 || reps > maxE1RMReps {
+		return 0
+	}
+	if reps == 1 {
+		return load
+	}
+	f, ok := formulas[formula]
+	if !ok {
+		f = formulas[DefaultFormula]
+	}
+	return math.Round(f(load, float64(reps))*10) / 10
+}
+
+// Record kinds.
+const (
+	Record1RM    = "1rm"
+	Record3RM    = "3rm"
+	Record5RM    = "5rm"
+	RecordVolume = "volume"
+	RecordE1RM   = "e1rm"
+)
+
+// RecordKinds lists the record kinds in display order.
+var RecordKinds = []string{Record1RM, Record3RM, Record5RM, RecordVolume, RecordE1RM}
+
+// HistorySet is a logged set with the session it belongs to.
+type HistorySet struct {
+	models.LoggedSet
+	SessionID string
+	At        time.Time
+}
+
+// Records replays a lifter's sets in order and returns an event each time
+// a record improves. An nRM is the heaviest load lifted for at least n
+// reps. Within one session only the best improvement per exercise and kind
+// is kept, measured against the record from before that session. Warm-ups
+// and unloaded sets never count.
+func Records(history []HistorySet, formula string) []models.RecordEvent {
+	sorted := append([]HistorySet(nil), history...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].At.Equal(sorted[j].At) {
+			return sorted[i].At.Before(sorted[j].At)
+		}
+		if sorted[i].SessionID != sorted[j].SessionID {
+			return sorted[i].SessionID < sorted[j].SessionID
+		}
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	best := map[string]float64{}
+	// Index into events of this session's event per exercise and kind.
+	sessionEvent := map[string]int{}
+	session := ""
+	var events []models.RecordEvent
+
+	for _, s := range sorted {
+		if s.Warmup || s.Load <= 0 || s.Reps <= 0 {
+			continue
+		}
+		if s.SessionID != session {
+			session = s.SessionID
+			sessionEvent = map[string]int{}
+		}
+
+		values := map[string]float64{
+			RecordVolume: math.Round(float64(s.Reps)*s.Load*10) / 10,
+			RecordE1RM:   E1RM(s.Load, s.Reps, formula),
+		}
+		for kind, n := range map[string]int{Record1RM: 1, Record3RM: 3, Record5RM: 5} {
+			if s.Reps >= n {
+				values[kind] = s.Load
+			}
+		}
+
+		for _, kind := range RecordKinds {
+			value := values[kind]
+			key := s.ExerciseID + "|" + kind
+			if value <= 0 || value <= best[key] {
+				continue
+			}
+			event := models.RecordEvent{
+				ExerciseID: s.ExerciseID,
+				Kind:       kind,
+				Value:      value,
+				Previous:   best[key],
+				Reps:       s.Reps,
+				Load:       s.Load,
+				SessionID:  s.SessionID,
+				SetID:      s.ID,
+				AchievedAt: s.At,
+			}
+			if kind == RecordE1RM {
+				event.Formula = formula
+			}
+			if i, ok := sessionEvent[key]; ok {
+				event.Previous = events[i].Previous
+				events[i] = event
+			} else {
+				sessionEvent[key] = len(events)
+				events = append(events, event)
+			}
+			best[key] = value
+		}
+	}
+	return events
+}
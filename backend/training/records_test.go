@@ -0,0 +1,72 @@
+package training
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestE1RM(t *testing.T) {
+	assert.Equal(t, 100.0, E1RM(100, 1, Brzycki))
+	assert.Equal(t, 116.7, E1RM(100, 5, Epley))
+	assert.Equal(t, 112.5, E1RM(100, 5, Brzycki))
+	assert.Equal(t, 0.0, E1RM(100, 15, Epley))
+	assert.Equal(t, 0.0, E1RM(0, 5, Epley))
+
+	f, err := ParseFormula("Brzycki")
+	assert.NoError(t, err)
+	assert.Equal(t, Brzycki, f)
+	f, _ = ParseFormula("")
+	assert.Equal(t, Epley, f)
+	_, err = ParseFormula("guess")
+	assert.Error(t, err)
+}
+
+func TestRecords(t *testing.T) {
+	day1 := time.Date(2026, 10, 1, 7, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 7)
+	set := func(id, session string, at time.Time, pos, reps int, load float64, warmup bool) HistorySet {
+		return HistorySet{
+			LoggedSet: models.LoggedSet{ID: id, ExerciseID: "squat", Position: pos, Reps: reps, Load: load, Warmup: warmup},
+			SessionID: session, At: at,
+		}
+	}
+	// Given out of order on purpose.
+	history := []HistorySet{
+		set("d", "s2", day2, 1, 3, 110, false),
+		set("a", "s1", day1, 1, 5, 200, true),
+		set("b", "s1", day1, 2, 5, 100, false),
+		set("c", "s1", day1, 3, 5, 102.5, false),
+		set("e", "s2", day2, 2, 8, 90, false),
+	}
+
+	events := Records(history, Epley)
+	type rec struct {
+		Kind     string
+		Value    float64
+		Previous float64
+		SetID    string
+	}
+	var got []rec
+	for _, e := range events {
+		got = append(got, rec{e.Kind, e.Value, e.Previous, e.SetID})
+	}
+	assert.Equal(t, []rec{
+		// Session one: the second working set beats the first, collapsing
+		// to one event per kind; the warm-up never counts.
+		{Record1RM, 102.5, 0, "c"},
+		{Record3RM, 102.5, 0, "c"},
+		{Record5RM, 102.5, 0, "c"},
+		{RecordVolume, 512.5, 0, "c"},
+		{RecordE1RM, 119.6, 0, "c"},
+		// Session two: a heavier triple, then a bigger volume set.
+		{Record1RM, 110, 102.5, "d"},
+		{Record3RM, 110, 102.5, "d"},
+		{RecordE1RM, 121, 119.6, "d"},
+		{RecordVolume, 720, 512.5, "e"},
+	}, got)
+	assert.Equal(t, Epley, events[4].Formula)
+	assert.Equal(t, day2, events[5].AchievedAt)
+}
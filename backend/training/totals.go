@@ -0,0 +1,39 @@
+package training
+
+import (
+	"math"
+
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+// Weight of a working set for an exercise's secondary muscles.
+const secondaryMuscleShare = 0.5
+
+// Totals computes a session's totals. exercises maps exercise IDs to their
+// library entries for the per-muscle counts.
+func Totals(s models.TrainingSession, exercises map[string]models.Exercise) models.SessionTotals {
+	t := models.SessionTotals{SetsPerMuscle: map[string]float64{}}
+	for _, set := range s.Sets {
+		t.Sets++
+		if set.Warmup {
+			continue
+		}
+		t.WorkingSets++
+		t.Reps += set.Reps
+		t.Volume += float64(set.Reps) * set.Load
+
+		ex := exercises[set.ExerciseID]
+		for _, m := range ex.PrimaryMuscles {
+			t.SetsPerMuscle[m]++
+		}
+		for _, m := range ex.SecondaryMuscles {
+			t.SetsPerMuscle[m] += secondaryMuscleShare
+		}
+	}
+	t.Volume = math.Round(t.Volume*10) / 10
+	if s.FinishedAt != nil {
+		d := math.Round(s.FinishedAt.Sub(s.StartedAt).Minutes()*10) / 10
+		t.DurationMinutes = &d
+	}
+	return t
+}
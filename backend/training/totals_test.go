@@ -0,0 +1,38 @@
+package training
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/terr0r/fitness.ai/backend/models"
+)
+
+func TestTotals(t *testing.T) {
+	start := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+	s := models.TrainingSession{
+		StartedAt: start,
+		Sets: []models.LoggedSet{
+			{ExerciseID: "squat", Reps: 5, Load: 60, Warmup: true},
+			{ExerciseID: "squat", Reps: 5, Load: 100},
+			{ExerciseID: "squat", Reps: 4, Load: 100},
+			{ExerciseID: "plank", DurationSeconds: 60},
+		},
+	}
+	exercises := map[string]models.Exercise{
+		"squat": {PrimaryMuscles: []string{"quads", "glutes"}, SecondaryMuscles: []string{"lower_back"}},
+		"plank": {PrimaryMuscles: []string{"abs"}},
+	}
+
+	totals := Totals(s, exercises)
+	assert.Equal(t, 4, totals.Sets)
+	assert.Equal(t, 3, totals.WorkingSets)
+	assert.Equal(t, 9, totals.Reps)
+	assert.Equal(t, 900.0, totals.Volume)
+	assert.Nil(t, totals.DurationMinutes)
+	assert.Equal(t, map[string]float64{"quads": 2, "glutes": 2, "lower_back": 1, "abs": 1}, totals.SetsPerMuscle)
+
+	finished := start.Add(52*time.Minute + 30*time.Second)
+	s.FinishedAt = &finished
+	assert.Equal(t, 52.5, *Totals(s, exercises).DurationMinutes)
+}
@@ -0,0 +1,9 @@
+package utils
+
+// NullIfEmpty stores an empty string as NULL.
+func NullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "time"
+
+// FormatTime formats t as RFC 3339 in UTC, the form times are stored in
+// when they are compared as text, such as run_at and next_run_at.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
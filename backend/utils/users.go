@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/db"
+
+	// Embed the zone database so user timezones resolve on minimal hosts.
+	_ "time/tzdata"
+)
+
+// UserLocation returns the user's configured timezone, falling back to UTC.
+func UserLocation(userID string) *time.Location {
+	var tz *string
+	db.DB.QueryRow(`SELECT timezone FROM users WHERE id = ?`, userID).Scan(&tz)
+	if tz != nil && *tz != "" {
+		if loc, err := time.LoadLocation(*tz); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
@@ -0,0 +1,243 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/models"
+	"github.com/terr0r/fitness.ai/backend/netguard"
+	"github.com/terr0r/fitness.ai/backend/utils"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it fails; with
+	// the backoff below the last try is about 20 hours after the event.
+	MaxAttempts = 12
+	baseDelay   = 30 * time.Second
+	maxDelay    = 6 * time.Hour
+	// Part of a response body kept in the attempt log.
+	maxResponseBody = 1 << 10
+)
+
+var (
+	// Client sends deliveries, to public addresses only. Redirects are not
+	// followed: the subscribed URL is the one that gets the data.
+	Client = func() *http.Client {
+		c := netguard.Client(10 * time.Second)
+		c.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		return c
+	}()
+)
+
+// deliverJob is the kind of job that makes one attempt at a delivery.
+const deliverJob = "webhook.deliver"
+
+type deliverPayload struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+func init() {
+	jobs.Register(deliverJob, jobs.Handle(func(ctx context.Context, _ models.Job, p deliverPayload) (interface{}, error) {
+		return nil, Deliver(ctx, p.DeliveryID)
+	}), jobs.Options{Timeout: time.Minute})
+}
+
+// Backoff is the wait after the given failed attempt: 30s, 1m, 2m, ...
+// up to 6h.
+func Backoff(attempt int) time.Duration {
+	d := baseDelay
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, maxDelay)
+}
+
+type event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt string      `json:"created_at"`
+	UserID    string      `json:"user_id"`
+	Data      interface{} `json:"data"`
+}
+
+// Emit queues event for every active webhook of the user, and every
+// all-users webhook, that subscribes to it, with a job for each delivery.
+// Events are a side effect of the change that caused them, so failing to
+// queue one is logged rather than returned.
+func Emit(userID, name string, data interface{}) {
+	if err := emit(userID, name, data); err != nil {
+		log.Printf("webhooks: failed to queue %s for user %s: %v", name, userID, err)
+	}
+}
+
+func emit(userID, name string, data interface{}) error {
+	rows, err := db.DB.Query(`SELECT w.id FROM webhooks w WHERE w.active = 1 AND (w.user_id = ? OR w.all_users = 1)
+		AND EXISTS (SELECT 1 FROM json_each(w.events) e WHERE e.value = ?)`, userID, name)
+	if err != nil {
+		return err
+	}
+	var hooks []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		hooks = append(hooks, id)
+	}
+	rows.Close()
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	now := clock.Now()
+	eventID := uuid.New().String()
+	payload, err := json.Marshal(event{ID: eventID, Type: name, CreatedAt: utils.FormatTime(now), UserID: userID, Data: data})
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, hook := range hooks {
+		id := uuid.New().String()
+		_, err := tx.Exec(`INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, payload, next_attempt_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, id, hook, eventID, name, string(payload), utils.FormatTime(now), now, now)
+		if err != nil {
+			return err
+		}
+		if _, err := jobs.EnqueueTx(tx, "", deliverJob, deliverPayload{DeliveryID: id}, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Redeliver queues a copy of a delivery, with the same event, and returns
+// its ID. The copy has no job until it has been attempted once with
+// Deliver.
+func Redeliver(deliveryID string) (string, error) {
+	id := uuid.New().String()
+	now := clock.Now()
+	res, err := db.DB.Exec(`INSERT INTO webhook_deliveries (id, webhook_id, event_id, event, payload, redelivery_of, created_at, updated_at)
+		SELECT ?, webhook_id, event_id, event, payload, id, ?, ? FROM webhook_deliveries WHERE id = ?`, id, now, now, deliveryID)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", sql.ErrNoRows
+	}
+	return id, nil
+}
+
+// Deliver makes one attempt at a pending delivery and records it. A
+// failure is queued for a retry after the backoff, or fails the delivery
+// once it has had MaxAttempts; only database problems are returned as
+// errors.
+func Deliver(ctx context.Context, id string) error {
+	var url, secret, name, payload string
+	var attempts int
+	var active bool
+	err := db.DB.QueryRowContext(ctx, `SELECT w.url, w.secret, w.active, d.event, d.payload, d.attempts FROM webhook_deliveries d
+		JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = ? AND d.status = 'pending'`, id).Scan(&url, &secret, &active, &name, &payload, &attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		// Queued before the webhook was turned off; it can be redelivered
+		// once it is back on.
+		_, err := db.DB.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'failed', next_attempt_at = NULL, error = 'webhook is inactive',
+			updated_at = ? WHERE id = ?`, clock.Now(), id)
+		return err
+	}
+
+	start, began := clock.Now(), time.Now()
+	status, body, sendErr := send(ctx, url, secret, id, name, []byte(payload), start)
+	attempts++
+	duration := time.Since(began).Milliseconds()
+
+	var errText interface{}
+	if sendErr != nil {
+		errText = sendErr.Error()
+	}
+	var code interface{}
+	if status != 0 {
+		code = status
+	}
+	now := clock.Now()
+	retry := now.Add(Backoff(attempts))
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`INSERT INTO webhook_attempts (delivery_id, attempt, status_code, error, response_body, duration_ms, attempted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, id, attempts, code, errText, body, duration, start)
+	if err != nil {
+		return err
+	}
+	switch {
+	case sendErr == nil:
+		_, err = tx.Exec(`UPDATE webhook_deliveries SET status = 'succeeded', attempts = ?, next_attempt_at = NULL, response_status = ?, error = NULL,
+			updated_at = ?, delivered_at = ? WHERE id = ?`, attempts, code, now, now, id)
+	case attempts >= MaxAttempts:
+		_, err = tx.Exec(`UPDATE webhook_deliveries SET status = 'failed', attempts = ?, next_attempt_at = NULL, response_status = ?, error = ?,
+			updated_at = ? WHERE id = ?`, attempts, code, errText, now, id)
+	default:
+		_, err = tx.Exec(`UPDATE webhook_deliveries SET attempts = ?, next_attempt_at = ?, response_status = ?, error = ?, updated_at = ? WHERE id = ?`,
+			attempts, utils.FormatTime(retry), code, errText, now, id)
+		if err == nil {
+			_, err = jobs.EnqueueTx(tx, "", deliverJob, deliverPayload{DeliveryID: id}, retry)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// send posts a delivery and returns the response status and the start of
+// its body. Anything but a 2xx response is an error.
+func send(ctx context.Context, url, secret, id, name string, payload []byte, at time.Time) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Fitness.ai-Webhooks/1")
+	req.Header.Set(EventHeader, name)
+	req.Header.Set(DeliveryHeader, id)
+	req.Header.Set(SignatureHeader, Sign(secret, at, payload))
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(body), fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, string(body), nil
+}
@@ -0,0 +1,129 @@
+// Package webhooks sends signed event notifications to the HTTPS endpoints
+// users subscribe, retrying failed deliveries with exponential backoff.
+//
+// Every delivery is a POST of a JSON event:
+//
+//	{"id": "...", "type": "journal.created", "created_at": "...", "user_id": "...", "data": {...}}
+//
+// with the headers X-Fitness-Event, X-Fitness-Delivery and
+// X-Fitness-Signature. The signature is "t=<unix time>,v1=<hex>", where
+// the hex is the HMAC-SHA256, keyed with the webhook's secret, of the time,
+// a dot and the raw body. Receivers should check it with Verify, or the
+// same steps, and ignore events whose id they have already handled:
+// retries and redeliveries repeat the id.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/terr0r/fitness.ai/backend/netguard"
+)
+
+// Events that can be subscribed to.
+const (
+	JournalCreated   = "journal.created"
+	SessionCompleted = "session.completed"
+	RecordAchieved   = "pr.achieved"
+	GoalCompleted    = "goal.completed"
+	PlanUpdated      = "plan.updated"
+)
+
+// Events lists every event in the order they are documented.
+var Events = []string{JournalCreated, SessionCompleted, RecordAchieved, GoalCompleted, PlanUpdated}
+
+// Headers set on every delivery.
+const (
+	EventHeader     = "X-Fitness-Event"
+	DeliveryHeader  = "X-Fitness-Delivery"
+	SignatureHeader = "X-Fitness-Signature"
+)
+
+// KnownEvent reports whether name is one of Events.
+func KnownEvent(name string) bool {
+	for _, e := range Events {
+		if e == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateURL accepts absolute https URLs only; deliveries carry personal
+// data. Hosts that are plainly local are refused here, and Client refuses
+// the rest when it connects.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("url must be an absolute https URL")
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("url must use https")
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials")
+	}
+	if netguard.CheckHost(u.Hostname()) != nil {
+		return fmt.Errorf("url must be a public address")
+	}
+	return nil
+}
+
+// NewSecret returns a random signing secret.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func mac(secret string, ts int64, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", ts)
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Sign returns the X-Fitness-Signature header for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(mac(secret, ts, body)))
+}
+
+// Verify checks a signature header against body and rejects signatures
+// older or newer than tolerance, which stops old deliveries from being
+// replayed.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts int64
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return fmt.Errorf("malformed signature header")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("signature timestamp is outside the tolerance")
+	}
+	want := mac(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return fmt.Errorf("signature does not match")
+}
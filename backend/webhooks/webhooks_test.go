@@ -0,0 +1,178 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/terr0r/fitness.ai/backend/clock"
+	"github.com/terr0r/fitness.ai/backend/db"
+	"github.com/terr0r/fitness.ai/backend/jobs"
+	"github.com/terr0r/fitness.ai/backend/netguard"
+	"github.com/terr0r/fitness.ai/backend/testutils"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	at := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"e1"}`)
+	header := Sign("secret", at, body)
+	assert.Regexp(t, `^t=1741003200,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify("secret", header, body, at.Add(time.Minute), 5*time.Minute))
+	assert.ErrorContains(t, Verify("other", header, body, at, 5*time.Minute), "does not match")
+	assert.ErrorContains(t, Verify("secret", header, []byte(`{"id":"e2"}`), at, 5*time.Minute), "does not match")
+	assert.ErrorContains(t, Verify("secret", header, body, at.Add(time.Hour), 5*time.Minute), "tolerance")
+	assert.ErrorContains(t, Verify("secret", "v1=abc", body, at, 5*time.Minute), "malformed")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, Backoff(1))
+	assert.Equal(t, time.Minute, Backoff(2))
+	assert.Equal(t, 8*time.Minute, Backoff(5))
+	assert.Equal(t, 6*time.Hour, Backoff(20))
+}
+
+func TestValidateURL(t *testing.T) {
+	assert.NoError(t, ValidateURL("https://hooks.example.com/fitness"))
+	assert.ErrorContains(t, ValidateURL("http://hooks.example.com"), "https")
+	assert.Error(t, ValidateURL("/relative"))
+	assert.ErrorContains(t, ValidateURL("https://user:pw@hooks.example.com"), "credentials")
+	for _, raw := range []string{
+		"https://localhost/hook", "https://127.0.0.1:8443/hook", "https://[::1]/hook",
+		"https://10.0.0.5/hook", "https://169.254.169.254/latest/meta-data",
+	} {
+		assert.ErrorContains(t, ValidateURL(raw), "public", raw)
+	}
+}
+
+func TestClientRefusesLocalAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := Client.Get(server.URL)
+	assert.ErrorIs(t, err, netguard.ErrBlocked)
+}
+
+func TestDelivery(t *testing.T) {
+	teardown := testutils.SetupTestDB(t)
+	defer teardown()
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	status := http.StatusInternalServerError
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	oldClient := Client
+	defer func() { Client = oldClient }()
+	Client = server.Client()
+	now := clock.NewFake(time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC))
+	defer now.Install()()
+
+	db.DB.Exec(`INSERT INTO users (id, email) VALUES ('u1', 'u1@example.com'), ('u2', 'u2@example.com')`)
+	db.DB.Exec(`INSERT INTO webhooks (id, user_id, url, secret, events) VALUES ('h1', 'u1', ?, 'secret', '["journal.created"]')`, server.URL)
+	db.DB.Exec(`INSERT INTO webhooks (id, user_id, url, secret, events, all_users) VALUES ('h2', 'u2', ?, 'admin', '["journal.created"]', 1)`, server.URL)
+
+	require.NoError(t, emit("u1", JournalCreated, map[string]interface{}{"id": "j1"}))
+	// Nobody subscribes to plan changes.
+	require.NoError(t, emit("u1", PlanUpdated, map[string]interface{}{}))
+	var queued, queuedJobs int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`).Scan(&queued)
+	db.DB.QueryRow(`SELECT COUNT(*) FROM jobs WHERE kind = 'webhook.deliver' AND status = 'queued'`).Scan(&queuedJobs)
+	assert.Equal(t, 2, queued)
+	assert.Equal(t, 2, queuedJobs)
+
+	ctx := context.Background()
+	n, err := jobs.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.Len(t, received, 2)
+	r := received[0]
+	assert.Equal(t, JournalCreated, r.Header.Get(EventHeader))
+	var secret string
+	db.DB.QueryRow(`SELECT w.secret FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = ?`,
+		r.Header.Get(DeliveryHeader)).Scan(&secret)
+	assert.NoError(t, Verify(secret, r.Header.Get(SignatureHeader), bodies[0], now.Now(), time.Minute))
+	var e struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		UserID string `json:"user_id"`
+		Data   struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(bodies[0], &e)
+	assert.Equal(t, JournalCreated, e.Type)
+	assert.Equal(t, "u1", e.UserID)
+	assert.Equal(t, "j1", e.Data.ID)
+
+	// Failures are retried once the backoff has passed.
+	n, _ = jobs.RunDue(ctx)
+	assert.Equal(t, 0, n)
+	now.Advance(Backoff(1))
+	status = http.StatusNoContent
+	n, _ = jobs.RunDue(ctx)
+	assert.Equal(t, 2, n)
+	var succeeded, attempts int
+	db.DB.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'succeeded' AND attempts = 2`).Scan(&succeeded)
+	db.DB.QueryRow(`SELECT COUNT(*) FROM webhook_attempts`).Scan(&attempts)
+	assert.Equal(t, 2, succeeded)
+	assert.Equal(t, 4, attempts)
+
+	// A delivery that keeps failing gives up after MaxAttempts.
+	status = http.StatusGone
+	require.NoError(t, emit("u2", JournalCreated, nil))
+	for i := 0; i < MaxAttempts+2; i++ {
+		jobs.RunDue(ctx)
+		now.Advance(maxDelay)
+	}
+	var state, lastErr string
+	var tries, code int
+	db.DB.QueryRow(`SELECT status, attempts, response_status, error FROM webhook_deliveries WHERE webhook_id = 'h2' AND status != 'succeeded'`).
+		Scan(&state, &tries, &code, &lastErr)
+	assert.Equal(t, "failed", state)
+	assert.Equal(t, MaxAttempts, tries)
+	assert.Equal(t, http.StatusGone, code)
+	assert.Contains(t, lastErr, "410")
+
+	// A redelivery repeats the event under a new delivery.
+	var failed string
+	db.DB.QueryRow(`SELECT id FROM webhook_deliveries WHERE status = 'failed'`).Scan(&failed)
+	status = http.StatusOK
+	id, err := Redeliver(failed)
+	require.NoError(t, err)
+	require.NoError(t, Deliver(ctx, id))
+	var eventID, original string
+	db.DB.QueryRow(`SELECT status, event_id, redelivery_of FROM webhook_deliveries WHERE id = ?`, id).Scan(&state, &eventID, &original)
+	assert.Equal(t, "succeeded", state)
+	assert.Equal(t, failed, original)
+	_, err = Redeliver("missing")
+	assert.Error(t, err)
+
+	// Deliveries queued before a webhook was turned off are not sent.
+	require.NoError(t, emit("u1", JournalCreated, nil))
+	db.DB.Exec(`UPDATE webhooks SET active = 0`)
+	sent := len(received)
+	_, err = jobs.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Len(t, received, sent)
+	db.DB.QueryRow(`SELECT status, error FROM webhook_deliveries ORDER BY created_at DESC, rowid DESC LIMIT 1`).Scan(&state, &lastErr)
+	assert.Equal(t, "failed", state)
+	assert.Equal(t, "webhook is inactive", lastErr)
+}
@@ -0,0 +1,298 @@
+// Package webpush sends Web Push messages: payloads are encrypted for the
+// browser with aes128gcm (RFC 8291) and the server identifies itself with
+// VAPID (RFC 8292).
+//
+// VAPID keys are a P-256 key pair, base64url encoded without padding: the
+// public key as an uncompressed point, which browsers are given as the
+// applicationServerKey, and the private key as its 32-byte scalar. They
+// are read from VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY, with
+// VAPID_SUBJECT (a mailto: or https: URL) telling push services whom to
+// contact.
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/terr0r/fitness.ai/backend/netguard"
+)
+
+const (
+	// recordSize is the aes128gcm record size advertised; a payload is
+	// always sent as a single record.
+	recordSize = 4096
+	// MaxPayload is the largest payload that still fits the 4096 bytes
+	// every push service accepts, after the header, delimiter and tag.
+	MaxPayload = recordSize - 16 - 1 - 86
+	// How long the VAPID token is valid; push services allow up to a day.
+	tokenLifetime = 12 * time.Hour
+)
+
+// Subscription is a browser's push endpoint with its keys, base64url
+// encoded as PushSubscription.toJSON gives them.
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Validate checks that the endpoint is a public https URL and the keys
+// decode to a P-256 point and a 16-byte secret.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.New("endpoint must be an https URL")
+	}
+	if netguard.CheckHost(u.Hostname()) != nil {
+		return errors.New("endpoint must be a public address")
+	}
+	raw, err := decode(s.P256dh)
+	if err == nil {
+		_, err = ecdh.P256().NewPublicKey(raw)
+	}
+	if err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	if secret, err := decode(s.Auth); err != nil || len(secret) != 16 {
+		return errors.New("invalid auth secret")
+	}
+	return nil
+}
+
+// Keys are the VAPID keys and subject a server sends with.
+type Keys struct {
+	Public  string
+	Private string
+	Subject string
+}
+
+// Options are sent with a message.
+type Options struct {
+	// TTL is how long the push service keeps an undelivered message.
+	TTL time.Duration
+	// Topic lets a later message with the same topic replace an
+	// undelivered one; at most 32 URL-safe base64 characters.
+	Topic string
+	// Urgency is very-low, low, normal or high.
+	Urgency string
+}
+
+var (
+	// ErrNotConfigured is returned by KeysFromEnv without VAPID keys.
+	ErrNotConfigured = errors.New("webpush: VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY are not set")
+	// ErrGone means the subscription has expired or been removed and
+	// should be deleted.
+	ErrGone = errors.New("webpush: subscription is gone")
+)
+
+// Client sends messages to push services. Endpoints come from browsers,
+// so it only connects to public addresses.
+var Client = netguard.Client(10 * time.Second)
+
+var encoding = base64.RawURLEncoding
+
+// decode reads base64url, tolerating padding and the standard alphabet,
+// as clients differ.
+func decode(s string) ([]byte, error) {
+	s = strings.NewReplacer("+", "-", "/", "_").Replace(strings.TrimRight(s, "="))
+	return encoding.DecodeString(s)
+}
+
+// GenerateKeys returns a new VAPID key pair.
+func GenerateKeys() (public, private string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return encoding.EncodeToString(key.PublicKey().Bytes()), encoding.EncodeToString(key.Bytes()), nil
+}
+
+// KeysFromEnv reads the server's VAPID keys.
+func KeysFromEnv() (Keys, error) {
+	k := Keys{Public: os.Getenv("VAPID_PUBLIC_KEY"), Private: os.Getenv("VAPID_PRIVATE_KEY"), Subject: os.Getenv("VAPID_SUBJECT")}
+	if k.Public == "" || k.Private == "" {
+		return k, ErrNotConfigured
+	}
+	if k.Subject == "" {
+		k.Subject = "mailto:admin@localhost"
+	}
+	return k, nil
+}
+
+// Send encrypts payload for sub and posts it to the push service,
+// returning its response status. A 404 or 410 response is ErrGone;
+// anything else but a 2xx response is an error too.
+func Send(ctx context.Context, sub Subscription, payload []byte, keys Keys, opts Options) (int, error) {
+	if len(payload) > MaxPayload {
+		return 0, fmt.Errorf("webpush: payload is %d bytes, over %d", len(payload), MaxPayload)
+	}
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return 0, err
+	}
+	endpoint, err := url.Parse(sub.Endpoint)
+	if err != nil || endpoint.Scheme != "https" {
+		return 0, fmt.Errorf("webpush: endpoint must be an https URL")
+	}
+	auth, err := vapidHeader(endpoint, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	req.Header.Set("Authorization", auth)
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+
+	resp, err := Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return resp.StatusCode, ErrGone
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return resp.StatusCode, fmt.Errorf("webpush: push service responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// vapidHeader returns the Authorization header for a push service: a
+// short-lived ES256 token for its origin, and the public key.
+func vapidHeader(endpoint *url.URL, keys Keys) (string, error) {
+	raw, err := decode(keys.Private)
+	if err != nil {
+		return "", fmt.Errorf("webpush: invalid VAPID private key: %w", err)
+	}
+	private, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return "", fmt.Errorf("webpush: invalid VAPID private key: %w", err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": time.Now().Add(tokenLifetime).Unix(),
+		"sub": keys.Subject,
+	}).SignedString(private)
+	if err != nil {
+		return "", err
+	}
+	return "vapid t=" + token + ", k=" + keys.Public, nil
+}
+
+// Encrypt encrypts payload for sub as a single aes128gcm record, with a
+// fresh key pair and salt.
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	local, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(sub, payload, local, salt)
+}
+
+func encrypt(sub Subscription, payload []byte, local *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	rawKey, err := decode(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid p256dh key: %w", err)
+	}
+	remote, err := ecdh.P256().NewPublicKey(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid p256dh key: %w", err)
+	}
+	secret, err := decode(sub.Auth)
+	if err != nil || len(secret) != 16 {
+		return nil, errors.New("webpush: invalid auth secret")
+	}
+	shared, err := local.ECDH(remote)
+	if err != nil {
+		return nil, err
+	}
+	localPublic := local.PublicKey().Bytes()
+	cek, nonce, err := contentKeys(shared, secret, salt, remote.Bytes(), localPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// The 0x02 delimiter marks the last, and only, record.
+	plain := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(localPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(localPublic)))
+	header = append(header, localPublic...)
+	return gcm.Seal(header, nonce, plain, nil), nil
+}
+
+// contentKeys derives the content encryption key and nonce from the ECDH
+// secret, as RFC 8291 section 3.4 describes. uaPublic is the browser's
+// key and asPublic the sender's.
+func contentKeys(shared, auth, salt, uaPublic, asPublic []byte) (cek, nonce []byte, err error) {
+	prkKey, err := hkdf.Extract(sha256.New, shared, auth)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublic) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cek, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16); err != nil {
+		return nil, nil, err
+	}
+	if nonce, err = hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12); err != nil {
+		return nil, nil, err
+	}
+	return cek, nonce, nil
+}
@@ -0,0 +1,128 @@
+package webpush
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The example in RFC 8291 appendix A.
+const (
+	rfcPlaintext  = "When I grow up, I want to be a watermelon"
+	rfcASPrivate  = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPrivate  = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcUAPublic   = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcSalt       = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuthSecret = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcBody       = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func TestEncryptMatchesRFC(t *testing.T) {
+	raw, _ := decode(rfcASPrivate)
+	local, err := ecdh.P256().NewPrivateKey(raw)
+	require.NoError(t, err)
+	salt, _ := decode(rfcSalt)
+	body, err := encrypt(Subscription{P256dh: rfcUAPublic, Auth: rfcAuthSecret}, []byte(rfcPlaintext), local, salt)
+	require.NoError(t, err)
+	assert.Equal(t, rfcBody, encoding.EncodeToString(body))
+}
+
+// decryptFor decrypts a message the way a browser holding uaPrivate would.
+func decryptFor(t *testing.T, uaPrivate, auth string, body []byte) string {
+	raw, _ := decode(uaPrivate)
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	require.NoError(t, err)
+	secret, _ := decode(auth)
+
+	salt, idLen := body[:16], int(body[20])
+	senderPublic := body[21 : 21+idLen]
+	sender, err := ecdh.P256().NewPublicKey(senderPublic)
+	require.NoError(t, err)
+	shared, err := key.ECDH(sender)
+	require.NoError(t, err)
+	cek, nonce, err := contentKeys(shared, secret, salt, key.PublicKey().Bytes(), senderPublic)
+	require.NoError(t, err)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plain, err := gcm.Open(nil, nonce, body[21+idLen:], nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), plain[len(plain)-1])
+	return string(plain[:len(plain)-1])
+}
+
+func TestSubscriptionValidate(t *testing.T) {
+	sub := Subscription{Endpoint: "https://push.example.com/abc", P256dh: rfcUAPublic, Auth: rfcAuthSecret}
+	assert.NoError(t, sub.Validate())
+	for _, endpoint := range []string{"http://push.example.com/abc", "https://localhost/abc", "https://127.0.0.1/abc", "https://[fe80::1]/abc"} {
+		sub.Endpoint = endpoint
+		assert.ErrorContains(t, sub.Validate(), "endpoint", endpoint)
+	}
+	sub.Endpoint = "https://push.example.com/abc"
+	sub.Auth = "short"
+	assert.ErrorContains(t, sub.Validate(), "auth")
+}
+
+func TestSend(t *testing.T) {
+	public, private, err := GenerateKeys()
+	require.NoError(t, err)
+	keys := Keys{Public: public, Private: private, Subject: "mailto:ops@example.com"}
+	sub := Subscription{P256dh: rfcUAPublic, Auth: rfcAuthSecret}
+
+	var got *http.Request
+	var body []byte
+	status := http.StatusCreated
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	oldClient := Client
+	defer func() { Client = oldClient }()
+	Client = server.Client()
+	sub.Endpoint = server.URL + "/push/abc"
+
+	code, err := Send(context.Background(), sub, []byte(`{"title":"Weigh in"}`), keys, Options{Topic: "reminder", Urgency: "normal"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, "aes128gcm", got.Header.Get("Content-Encoding"))
+	assert.Equal(t, "86400", got.Header.Get("TTL"))
+	assert.Equal(t, "reminder", got.Header.Get("Topic"))
+	assert.Equal(t, `{"title":"Weigh in"}`, decryptFor(t, rfcUAPrivate, rfcAuthSecret, body))
+
+	// The VAPID token is signed for the push service's origin.
+	auth := got.Header.Get("Authorization")
+	require.True(t, strings.HasPrefix(auth, "vapid t="), auth)
+	token, k, _ := strings.Cut(strings.TrimPrefix(auth, "vapid t="), ", k=")
+	assert.Equal(t, public, k)
+	raw, _ := decode(public)
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), raw)
+	require.NoError(t, err)
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return key, nil },
+		jwt.WithValidMethods([]string{"ES256"}))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, claims["aud"])
+	assert.Equal(t, "mailto:ops@example.com", claims["sub"])
+
+	status = http.StatusGone
+	_, err = Send(context.Background(), sub, []byte(`{}`), keys, Options{})
+	assert.ErrorIs(t, err, ErrGone)
+	status = http.StatusTooManyRequests
+	_, err = Send(context.Background(), sub, []byte(`{}`), keys, Options{})
+	assert.ErrorContains(t, err, "429")
+	_, err = Send(context.Background(), sub, make([]byte, MaxPayload+1), keys, Options{})
+	assert.ErrorContains(t, err, "payload")
+}